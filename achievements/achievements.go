@@ -0,0 +1,188 @@
+// Package achievements defines the achievements of the Snake game and keeps the ones the player has unlocked.
+//
+// The unlocked achievements are stored in the `achievements.json` file of the data directory.
+package achievements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const fileName = "achievements.json" // the name of the file with the unlocked achievements in the data directory
+
+// Progress is the state of the game an achievement is checked against after every tick.
+// Fields:
+// - Score, Length, Food, Ticks: the score, the length of the snake, the food eaten and the ticks played so far.
+// - Cut: whether the snake has bitten itself during the tick.
+// - AteInCorner: whether the snake has eaten food in a corner of the board during the tick.
+type Progress struct {
+	Score       int
+	Length      int
+	Food        int
+	Ticks       int
+	Cut         bool
+	AteInCorner bool
+}
+
+// Achievement is a goal the player can reach during a game.
+// Fields:
+// - ID: the identifier of the achievement, used in the store and for the message keys of its name and description.
+// - Color: the color of the achievement's badge, in the "#RRGGBB" format.
+// - reached: reports whether the goal is reached.
+type Achievement struct {
+	ID      string
+	Color   string
+	reached func(p Progress) bool
+}
+
+// All lists the achievements in the order they're shown.
+var All = []Achievement{
+	{"first_bite", "#8BC34A", func(p Progress) bool { return p.Food >= 1 }},
+	{"cornered", "#FF9800", func(p Progress) bool { return p.AteInCorner }},
+	{"ouroboros", "#AB47BC", func(p Progress) bool { return p.Cut }},
+	{"long_snake", "#26C6DA", func(p Progress) bool { return p.Length >= 20 }},
+	{"score_100", "#FFEB3B", func(p Progress) bool { return p.Score >= 100 }},
+	{"score_500", "#FFC107", func(p Progress) bool { return p.Score >= 500 }},
+	{"marathon", "#EF5350", func(p Progress) bool { return p.Ticks >= 2000 }},
+}
+
+// Store keeps the unlocked achievements. It's safe for concurrent use.
+// Fields:
+// - mu: guards unlocked.
+// - unlocked: the time every unlocked achievement was unlocked at, by ID.
+type Store struct {
+	mu       sync.Mutex
+	unlocked map[string]time.Time
+}
+
+// New creates an empty store, for games without a data directory.
+func New() *Store {
+	return &Store{unlocked: make(map[string]time.Time)}
+}
+
+// Path returns the location of the file with the unlocked achievements.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - string: The path to the file.
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, fileName)
+}
+
+// Load reads the unlocked achievements.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - *Store: The store; empty if no achievement has been unlocked yet or the file cannot be read.
+// - error: An error if the file exists but cannot be read or parsed.
+func Load(dataDir string) (*Store, error) {
+	s := New()
+	data, err := os.ReadFile(Path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("error reading achievements %s: %w", Path(dataDir), err)
+	}
+	if err = json.Unmarshal(data, &s.unlocked); err != nil {
+		return s, fmt.Errorf("error parsing achievements %s: %w", Path(dataDir), err)
+	}
+	return s, nil
+}
+
+// Save writes the unlocked achievements, creating the directory if needed.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (s *Store) Save(dataDir string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.unlocked, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding achievements: %w", err)
+	}
+	path := Path(dataDir)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing achievements %s: %w", path, err)
+	}
+	return nil
+}
+
+// Check unlocks the achievements reached with the progress that haven't been unlocked yet.
+//
+// Parameters:
+// - p (Progress): The state of the game.
+//
+// Returns:
+// - []Achievement: The newly unlocked achievements, in the order of All.
+func (s *Store) Check(p Progress) []Achievement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unlocked []Achievement
+	for _, a := range All {
+		if _, ok := s.unlocked[a.ID]; !ok && a.reached(p) {
+			s.unlocked[a.ID] = time.Now()
+			unlocked = append(unlocked, a)
+		}
+	}
+	return unlocked
+}
+
+// Unlocked reports whether the achievement has been unlocked.
+func (s *Store) Unlocked(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.unlocked[id]
+	return ok
+}
+
+// Count returns the number of unlocked achievements of All; achievements of other versions of the game are not counted.
+func (s *Store) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, a := range All {
+		if _, ok := s.unlocked[a.ID]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Unlocks returns the time every unlocked achievement was unlocked at, by ID, including the achievements
+// of other versions of the game.
+func (s *Store) Unlocks() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.unlocked)
+}
+
+// Replace replaces the unlocked achievements, e.g. with the ones synced from another machine.
+//
+// Parameters:
+// - unlocked (map[string]time.Time): The time every unlocked achievement was unlocked at, by ID.
+func (s *Store) Replace(unlocked map[string]time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unlocked = maps.Clone(unlocked)
+	if s.unlocked == nil {
+		s.unlocked = make(map[string]time.Time)
+	}
+}
@@ -0,0 +1,166 @@
+// Package botapi serves the game to bots over gRPC, so they can be written in any language.
+//
+// The service is described by snake.proto in this directory: a bot calls Play, a bidirectional stream
+// of actions and observations. The package serves it either from headless games played in lockstep with the bot
+// (Headless) or from the game rendered in the window (Live).
+//
+// Like the netplay package, which implements WebSocket itself, the package has no dependencies: it speaks the gRPC
+// protocol over the HTTP/2 of net/http and encodes the few protobuf messages of the service by hand.
+package botapi
+
+import (
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/internal/protowire"
+)
+
+// The directions of the protocol, as on the screen; the engine calls them differently, since its y axis grows
+// in the Up direction while the board is drawn with y growing downwards.
+const (
+	dirUnspecified = 0
+	dirUp          = 1
+	dirRight       = 2
+	dirDown        = 3
+	dirLeft        = 4
+)
+
+// Action is the decision of a bot for the next tick.
+// Fields:
+// - Dir: the new direction of the snake.
+// - Turn: whether the action turns the snake; false if the snake keeps its direction.
+// - Seed: headless, the first action of a game: the seed of the game; 0 for a random one.
+// - Cells: headless, the first action of a game: the size of the board; 0 for the default one.
+type Action struct {
+	Dir   engine.Dir
+	Turn  bool
+	Seed  int64
+	Cells int
+}
+
+// Observation is the state of a game after a tick, as sent to the bots.
+// Fields:
+// - Tick: the number of ticks played.
+// - Cells: the number of cells along each side of the board.
+// - Snake: the segments of the snake, the head first.
+// - Dir: the direction the snake moves in.
+// - Food: the position of the food.
+// - Score: the current score.
+// - Speed: the interval between two ticks, in milliseconds.
+// - GameOver: whether the game has ended.
+// - Seed: the seed of the game.
+// - Ate: whether the snake has eaten the food during the last tick.
+// - Cut: whether the snake has bitten itself during the last tick.
+type Observation struct {
+	Tick     int
+	Cells    int
+	Snake    []engine.Point
+	Dir      engine.Dir
+	Food     engine.Point
+	Score    int
+	Speed    int
+	GameOver bool
+	Seed     int64
+	Ate      bool
+	Cut      bool
+}
+
+// Observe describes the game of the engine after a tick.
+//
+// Parameters:
+// - e (*engine.Engine): The engine running the game.
+// - res (engine.StepResult): What has happened during the last tick.
+//
+// Returns:
+// - Observation: The state of the game.
+func Observe(e *engine.Engine, res engine.StepResult) Observation {
+	return Observation{
+		Tick:     e.Tick,
+		Cells:    e.BoardSize(),
+		Snake:    append([]engine.Point(nil), e.Snake.Parts...),
+		Dir:      e.Snake.Direction,
+		Food:     e.Food,
+		Score:    e.Score,
+		Speed:    e.Speed,
+		GameOver: e.GameOver,
+		Seed:     e.Seed(),
+		Ate:      res.Ate,
+		Cut:      res.Cut,
+	}
+}
+
+// toWire returns the direction of the protocol of the engine direction.
+func toWire(d engine.Dir) uint64 {
+	switch d {
+	case engine.Down:
+		return dirUp
+	case engine.Right:
+		return dirRight
+	case engine.Up:
+		return dirDown
+	default:
+		return dirLeft
+	}
+}
+
+// fromWire returns the engine direction of the direction of the protocol.
+//
+// Returns:
+// - engine.Dir: The direction.
+// - bool: False for DIRECTION_UNSPECIFIED and the unknown values.
+func fromWire(d uint64) (engine.Dir, bool) {
+	switch d {
+	case dirUp:
+		return engine.Down, true
+	case dirRight:
+		return engine.Right, true
+	case dirDown:
+		return engine.Up, true
+	case dirLeft:
+		return engine.Left, true
+	default:
+		return 0, false
+	}
+}
+
+// marshal encodes the observation as the Observation message of snake.proto.
+func (o Observation) marshal() []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, uint64(o.Tick))
+	b = protowire.AppendVarintField(b, 2, uint64(o.Cells))
+	for _, p := range o.Snake {
+		b = protowire.AppendBytesField(b, 3, marshalPoint(p))
+	}
+	b = protowire.AppendVarintField(b, 4, toWire(o.Dir))
+	b = protowire.AppendBytesField(b, 5, marshalPoint(o.Food))
+	b = protowire.AppendVarintField(b, 6, uint64(o.Score))
+	b = protowire.AppendVarintField(b, 7, uint64(o.Speed))
+	b = protowire.AppendBoolField(b, 8, o.GameOver)
+	b = protowire.AppendVarintField(b, 9, uint64(o.Seed))
+	b = protowire.AppendBoolField(b, 10, o.Ate)
+	b = protowire.AppendBoolField(b, 11, o.Cut)
+	return b
+}
+
+// marshalPoint encodes the point as the Point message of snake.proto.
+func marshalPoint(p engine.Point) []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, uint64(int64(p.X)))
+	return protowire.AppendVarintField(b, 2, uint64(int64(p.Y)))
+}
+
+// unmarshalAction decodes the Action message of snake.proto. Unknown fields are skipped, so newer bots
+// can talk to older servers.
+func unmarshalAction(b []byte) (Action, error) {
+	var a Action
+	err := protowire.EachField(b, func(num int, v uint64, _ []byte) error {
+		switch num {
+		case 1:
+			a.Dir, a.Turn = fromWire(v)
+		case 2:
+			a.Seed = int64(v)
+		case 3:
+			a.Cells = int(int32(v))
+		}
+		return nil
+	})
+	return a, err
+}
@@ -0,0 +1,172 @@
+// Package botapi serves the game to bots over gRPC, so they can be written in any language.
+//
+// The service is described by snake.proto in this directory: a bot calls Play, a bidirectional stream
+// of actions and observations. The package serves it either from headless games played in lockstep with the bot
+// (Headless) or from the game rendered in the window (Live).
+//
+// Like the netplay package, which implements WebSocket itself, the package has no dependencies: it speaks the gRPC
+// protocol over the HTTP/2 of net/http and encodes the few protobuf messages of the service by hand.
+package botapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	playMethod = "/snake.bot.v1.Bot/Play" // the path of the Play calls
+	messageMax = 64 << 10                 // the largest action accepted, in bytes
+)
+
+// The gRPC status codes sent by the server.
+const (
+	statusOK              = 0
+	statusInvalidArgument = 3
+	statusUnimplemented   = 12
+	statusInternal        = 13
+)
+
+// statusError is an error ending a call with a gRPC status other than OK.
+// Fields:
+// - code: the gRPC status code.
+// - msg: the description of the error sent to the bot.
+type statusError struct {
+	code int
+	msg  string
+}
+
+// Error returns the description of the error.
+func (e *statusError) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", e.code, e.msg)
+}
+
+// stream is a call of Play: the actions received from the bot and the observations sent to it.
+// Fields:
+// - body: the request body, with the length-prefixed actions.
+// - w: the response, with the length-prefixed observations.
+// - rc: flushes the observations to the bot as soon as they're written.
+// - done: closed when the bot has cancelled the call or disconnected.
+type stream struct {
+	body io.Reader
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	done <-chan struct{}
+}
+
+// Recv waits for the next action of the bot.
+//
+// Returns:
+// - Action: The action.
+// - error: io.EOF if the bot has closed its side of the stream, or an error if the action can't be read.
+func (s *stream) Recv() (Action, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.body, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Action{}, &statusError{statusInvalidArgument, "truncated message"}
+		}
+		return Action{}, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	switch {
+	case header[0] != 0:
+		return Action{}, &statusError{statusUnimplemented, "compressed messages aren't supported"}
+	case size > messageMax:
+		return Action{}, &statusError{statusInvalidArgument, "message too large"}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(s.body, msg); err != nil {
+		return Action{}, &statusError{statusInvalidArgument, "truncated message"}
+	}
+	a, err := unmarshalAction(msg)
+	if err != nil {
+		return Action{}, &statusError{statusInvalidArgument, err.Error()}
+	}
+	return a, nil
+}
+
+// Send sends the observation to the bot right away.
+func (s *stream) Send(o Observation) error {
+	msg := o.marshal()
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := s.w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// serveGRPC handles an HTTP/2 request of a gRPC call: it calls play for the calls of Play, and ends the call
+// with the status of the error play returns.
+//
+// Parameters:
+// - w (http.ResponseWriter): The response.
+// - r (*http.Request): The request.
+// - play (func(*stream) error): Plays the game of the call until the stream ends.
+func serveGRPC(w http.ResponseWriter, r *http.Request, play func(*stream) error) {
+	if r.ProtoMajor != 2 || r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC over HTTP/2 expected", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	if r.URL.Path != playMethod {
+		//a call that fails right away has no trailers: the status is in the headers
+		w.Header().Set("Grpc-Status", strconv.Itoa(statusUnimplemented))
+		w.Header().Set("Grpc-Message", url.PathEscape("unknown method "+r.URL.Path))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	rc := http.NewResponseController(w)
+	//the calls are long-lived: a game lasts as long as the bot plays
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	endCall(w, play(&stream{body: r.Body, w: w, rc: rc, done: r.Context().Done()}))
+}
+
+// endCall sends the trailers with the status of the call: OK if err is nil or io.EOF.
+func endCall(w http.ResponseWriter, err error) {
+	code, msg := statusOK, ""
+	var se *statusError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+	case errors.As(err, &se):
+		code, msg = se.code, se.msg
+	default:
+		code, msg = statusInternal, err.Error()
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", url.PathEscape(msg))
+	}
+}
+
+// ListenAndServe serves the bot API on the address over HTTP/2 without TLS, which the gRPC libraries
+// call an insecure channel; the API is meant for bots on the same machine or network.
+//
+// Parameters:
+// - addr (string): The address to listen on, e.g. "localhost:50051".
+// - handler (http.Handler): Headless or Live.
+//
+// Returns:
+// - error: The error that has stopped the server.
+func ListenAndServe(addr string, handler http.Handler) error {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		Protocols:         &protocols,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,238 @@
+package botapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/internal/protowire"
+)
+
+// frame returns the gRPC frame of a message: the compression flag, the length and the message.
+func frame(compressed byte, msg []byte) []byte {
+	b := []byte{compressed, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// action encodes an Action message of snake.proto.
+func action(dir uint64, seed int64, cells int) []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, dir)
+	b = protowire.AppendVarintField(b, 2, uint64(seed))
+	return protowire.AppendVarintField(b, 3, uint64(cells))
+}
+
+// statusCode returns the gRPC status code of an error, or -1 if it isn't a statusError.
+func statusCode(err error) int {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return -1
+}
+
+func TestRecv(t *testing.T) {
+	oversized := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(oversized[1:], messageMax+1)
+	unknown := protowire.AppendBytesField(action(dirLeft, 0, 0), 15, []byte("from a newer bot"))
+	tests := []struct {
+		name   string
+		body   []byte
+		want   Action
+		status int   // the expected gRPC status of the error, or -1 for none
+		err    error // the expected error that isn't a status, if any
+	}{
+		{name: "action", body: frame(0, action(dirRight, 7, 12)), want: Action{Dir: engine.Right, Turn: true, Seed: 7, Cells: 12}, status: -1},
+		{name: "keep direction", body: frame(0, action(dirUnspecified, 0, 0)), want: Action{}, status: -1},
+		{name: "up is down in the engine", body: frame(0, action(dirUp, 0, 0)), want: Action{Dir: engine.Down, Turn: true}, status: -1},
+		{name: "unknown field skipped", body: frame(0, unknown), want: Action{Dir: engine.Left, Turn: true}, status: -1},
+		{name: "end of stream", body: nil, status: -1, err: io.EOF},
+		{name: "truncated header", body: []byte{0, 0, 0}, status: statusInvalidArgument},
+		{name: "compressed", body: frame(1, action(dirUp, 0, 0)), status: statusUnimplemented},
+		{name: "oversized", body: oversized, status: statusInvalidArgument},
+		{name: "truncated message", body: frame(0, action(dirUp, 1, 20))[:7], status: statusInvalidArgument},
+		{name: "malformed message", body: frame(0, []byte{0x08, 0xff}), status: statusInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stream{body: bytes.NewReader(tt.body)}
+			got, err := s.Recv()
+			if code := statusCode(err); code != tt.status {
+				t.Fatalf("Recv() error = %v, want status %d", err, tt.status)
+			}
+			if tt.status < 0 && !errors.Is(err, tt.err) {
+				t.Fatalf("Recv() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Recv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendFraming(t *testing.T) {
+	rec := httptest.NewRecorder()
+	s := &stream{w: rec, rc: http.NewResponseController(rec)}
+	o := Observation{Tick: 3, Cells: 10, Score: 300, Snake: []engine.Point{{X: 2, Y: 1}}}
+	if err := s.Send(o); err != nil {
+		t.Fatal(err)
+	}
+	b := rec.Body.Bytes()
+	if len(b) < 5 || b[0] != 0 {
+		t.Fatalf("frame header = %v, want an uncompressed frame", b)
+	}
+	if size := binary.BigEndian.Uint32(b[1:5]); int(size) != len(b)-5 || !bytes.Equal(b[5:], o.marshal()) {
+		t.Errorf("frame of %d bytes with length %d, want the marshaled observation", len(b)-5, size)
+	}
+}
+
+func TestEndCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  string
+		message string
+	}{
+		{name: "ok", err: nil, status: "0"},
+		{name: "bot closed the stream", err: io.EOF, status: "0"},
+		{name: "status", err: &statusError{statusInvalidArgument, "message too large"}, status: "3", message: "message%20too%20large"},
+		{name: "other error", err: errors.New("broken pipe"), status: "13", message: "broken%20pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(http.StatusOK)
+			endCall(rec, tt.err)
+			trailer := rec.Result().Trailer
+			if got := trailer.Get("Grpc-Status"); got != tt.status {
+				t.Errorf("Grpc-Status = %q, want %q", got, tt.status)
+			}
+			if got := trailer.Get("Grpc-Message"); got != tt.message {
+				t.Errorf("Grpc-Message = %q, want %q", got, tt.message)
+			}
+		})
+	}
+}
+
+// newH2CServer starts a server of the handler speaking HTTP/2 without TLS, and returns it with a client for it.
+func newH2CServer(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	t.Cleanup(transport.CloseIdleConnections)
+	return srv, &http.Client{Transport: transport}
+}
+
+// observationFields decodes the varint fields of an Observation message by number.
+func observationFields(t *testing.T, b []byte) map[int]uint64 {
+	t.Helper()
+	fields := make(map[int]uint64)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("malformed observation")
+		}
+		b = b[n:]
+		if typ != protowire.Varint {
+			if n = protowire.SkipField(b, typ); n < 0 {
+				t.Fatalf("malformed observation")
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			t.Fatalf("malformed observation")
+		}
+		fields[num] = v
+		b = b[n:]
+	}
+	return fields
+}
+
+// readFrame reads a gRPC frame from the response and returns its message.
+func readFrame(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	return msg
+}
+
+func TestPlayOverH2C(t *testing.T) {
+	srv, client := newH2CServer(t, NewHeadless(10))
+	body, actions := io.Pipe()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+playMethod, body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	go actions.Write(frame(0, action(dirUnspecified, 42, 12)))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.Header.Get("Content-Type") != "application/grpc" {
+		t.Fatalf("response %s with %q, want HTTP/2 with application/grpc", resp.Proto, resp.Header.Get("Content-Type"))
+	}
+	first := observationFields(t, readFrame(t, resp.Body))
+	if first[1] != 0 || first[2] != 12 || first[9] != 42 {
+		t.Errorf("first observation: tick %d, cells %d, seed %d; want tick 0, cells 12, seed 42", first[1], first[2], first[9])
+	}
+	actions.Write(frame(0, action(dirDown, 0, 0)))
+	if next := observationFields(t, readFrame(t, resp.Body)); next[1] != 1 || next[4] != dirDown {
+		t.Errorf("next observation: tick %d, dir %d; want tick 1, dir %d", next[1], next[4], dirDown)
+	}
+	actions.Write(frame(1, nil))
+	actions.Close()
+	if rest, _ := io.ReadAll(resp.Body); len(rest) != 0 {
+		t.Errorf("unexpected %d bytes after the observations", len(rest))
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "12" {
+		t.Errorf("Grpc-Status trailer = %q, want 12 for a compressed message", got)
+	}
+}
+
+func TestServeGRPCRejects(t *testing.T) {
+	srv, client := newH2CServer(t, NewHeadless(10))
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		code        int
+		status      string // the expected Grpc-Status header
+	}{
+		{name: "unknown method", path: "/snake.bot.v1.Bot/Watch", contentType: "application/grpc", code: http.StatusOK, status: "12"},
+		{name: "not gRPC", path: playMethod, contentType: "application/json", code: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+tt.path, strings.NewReader(""))
+			req.Header.Set("Content-Type", tt.contentType)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code || resp.Header.Get("Grpc-Status") != tt.status {
+				t.Errorf("got %d with Grpc-Status %q, want %d with %q", resp.StatusCode, resp.Header.Get("Grpc-Status"), tt.code, tt.status)
+			}
+		})
+	}
+}
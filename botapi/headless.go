@@ -0,0 +1,73 @@
+// Package botapi serves the game to bots over gRPC, so they can be written in any language.
+//
+// The service is described by snake.proto in this directory: a bot calls Play, a bidirectional stream
+// of actions and observations. The package serves it either from headless games played in lockstep with the bot
+// (Headless) or from the game rendered in the window (Live).
+//
+// Like the netplay package, which implements WebSocket itself, the package has no dependencies: it speaks the gRPC
+// protocol over the HTTP/2 of net/http and encodes the few protobuf messages of the service by hand.
+package botapi
+
+import (
+	"cmp"
+	"math/rand"
+	"net/http"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const CellsMax = 60 // the largest board a headless game can be played on
+
+// Headless serves the bot API from headless games: every call of Play plays its own games, one after another,
+// and every action of the bot advances the game by one tick, as fast as the bot answers.
+// Any number of bots can play at the same time.
+// Fields:
+// - cells: the size of the board of the games whose first action doesn't choose one.
+type Headless struct {
+	cells int
+}
+
+// NewHeadless creates the server of headless games.
+//
+// Parameters:
+// - cells (int): The size of the board of the games whose first action doesn't choose one.
+//
+// Returns:
+// - *Headless: The server.
+func NewHeadless(cells int) *Headless {
+	return &Headless{cells: min(max(cells, engine.MinCells), CellsMax)}
+}
+
+// ServeHTTP handles the gRPC calls of the bots.
+func (h *Headless) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveGRPC(w, r, h.play)
+}
+
+// play plays the games of a call in lockstep with the bot: the first action of a game starts it and is answered
+// with the first observation, and every following action turns the snake and advances the game by a tick.
+// Once the game is over, the next action starts a new one.
+func (h *Headless) play(s *stream) error {
+	var e *engine.Engine
+	for {
+		a, err := s.Recv()
+		if err != nil {
+			return err
+		}
+		var res engine.StepResult
+		if e == nil || e.GameOver {
+			seed := a.Seed
+			if seed == 0 {
+				seed = rand.Int63()
+			}
+			e = engine.NewSized(seed, min(max(cmp.Or(a.Cells, h.cells), engine.MinCells), CellsMax))
+		} else {
+			if a.Turn {
+				e.Turn(a.Dir)
+			}
+			res = e.Step()
+		}
+		if err = s.Send(Observe(e, res)); err != nil {
+			return err
+		}
+	}
+}
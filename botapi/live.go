@@ -0,0 +1,127 @@
+// Package botapi serves the game to bots over gRPC, so they can be written in any language.
+//
+// The service is described by snake.proto in this directory: a bot calls Play, a bidirectional stream
+// of actions and observations. The package serves it either from headless games played in lockstep with the bot
+// (Headless) or from the game rendered in the window (Live).
+//
+// Like the netplay package, which implements WebSocket itself, the package has no dependencies: it speaks the gRPC
+// protocol over the HTTP/2 of net/http and encodes the few protobuf messages of the service by hand.
+package botapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	liveQueue = 64 // the number of observations a bot may lag behind the game; further ones are dropped
+	turnsMax  = 4  // the largest number of turns queued for the next ticks; further turns are dropped
+)
+
+// Live serves the bot API from the game rendered in the window: the bots get an observation after every tick,
+// and their actions turn the snake like the arrow keys. Any number of bots can watch the game, and all of them
+// can steer it.
+// Fields:
+// - mu: guards watchers.
+// - watchers: the queues of the observations of the connected bots.
+// - turns: the turns of the bots, for the next ticks.
+type Live struct {
+	mu       sync.Mutex
+	watchers map[chan Observation]struct{}
+	turns    chan engine.Dir
+}
+
+// NewLive creates the server of the rendered game.
+func NewLive() *Live {
+	return &Live{
+		watchers: make(map[chan Observation]struct{}),
+		turns:    make(chan engine.Dir, turnsMax),
+	}
+}
+
+// ServeHTTP handles the gRPC calls of the bots.
+func (l *Live) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveGRPC(w, r, l.play)
+}
+
+// Publish sends the observation to the connected bots. A bot too slow to keep up misses observations.
+//
+// It's called by the game logic goroutine after every tick.
+func (l *Live) Publish(o Observation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for queue := range l.watchers {
+		select {
+		case queue <- o:
+		default:
+		}
+	}
+}
+
+// Turn returns the next turn the bots have asked for, if any.
+//
+// It's called by the game logic goroutine before every tick, so the snake turns at most once per tick.
+func (l *Live) Turn() (engine.Dir, bool) {
+	select {
+	case dir := <-l.turns:
+		return dir, true
+	default:
+		return 0, false
+	}
+}
+
+// Queued returns the number of turns the bots have queued for the next ticks, e.g. for a debug overlay.
+func (l *Live) Queued() int {
+	return len(l.turns)
+}
+
+// play sends the observations of the game to the bot and queues its turns until the bot ends the call.
+// A bot that only watches may close its side of the stream right away.
+func (l *Live) play(s *stream) error {
+	queue := make(chan Observation, liveQueue)
+	l.mu.Lock()
+	l.watchers[queue] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.watchers, queue)
+		l.mu.Unlock()
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			a, err := s.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if a.Turn {
+				select {
+				case l.turns <- a.Dir:
+				default:
+				}
+			}
+		}
+	}()
+	for {
+		select {
+		case o := <-queue:
+			if err := s.Send(o); err != nil {
+				return err
+			}
+		case err := <-errs:
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			//the bot only watches from now on
+			errs = nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package challenges provides the weekly challenges of the Snake game: every week all players get the same game,
+// played from a seed derived from the week with a special set of rules, and each challenge has its own score table.
+//
+// The rule sets rotate by the week number. The built-in schedule can be replaced with a `challenges.json` file
+// in the data directory.
+package challenges
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	scheduleFile = "challenges.json" // the name of the schedule file in the data directory
+	dirName      = "challenges"      // the name of the directory with the score tables in the data directory
+	tableSize    = 10                // the number of scores kept in a score table
+)
+
+// Rules is a set of rules a challenge is played with.
+// Fields:
+// - Name: the name of the rule set shown to the player.
+// - Cells: the side of the board in cells.
+// - Terrain: the special tiles of the board, such as ice, mud and spikes, one string per row (see engine.Terrain);
+// empty for a plain board.
+type Rules struct {
+	Name    string         `json:"name"`
+	Cells   int            `json:"cells"`
+	Terrain engine.Terrain `json:"terrain,omitempty"`
+}
+
+// defaultSchedule is the rotation of the rule sets used when there is no schedule file.
+var defaultSchedule = []Rules{
+	{Name: "Wide open, 30x30 board", Cells: 30},
+	{Name: "Tight squeeze, 12x12 board", Cells: 12},
+	{Name: "Classic sprint, 20x20 board", Cells: engine.Cells},
+	{Name: "Big field, 25x25 board", Cells: 25},
+}
+
+// Challenge is the challenge of a single week.
+// Fields:
+// - Week: the ISO week of the challenge, such as "2026-W42", which identifies it.
+// - Seed: the seed of the game, the same for all players.
+// - Rules: the rules of the game.
+type Challenge struct {
+	Week  string
+	Seed  int64
+	Rules Rules
+}
+
+// Entry is a score in the score table of a challenge.
+// Fields:
+// - Score: the final score.
+// - Length: the final length of the snake.
+// - At: the time the game ended.
+type Entry struct {
+	Score  int       `json:"score"`
+	Length int       `json:"length"`
+	At     time.Time `json:"at"`
+}
+
+// LoadSchedule reads the rotation of the rule sets from the schedule file, falling back to the built-in schedule
+// if the file doesn't exist. Rule sets with a board smaller than engine.MinCells or with unknown tiles are skipped.
+//
+// Parameters:
+// - dataDir (string): The data directory; empty for the built-in schedule.
+//
+// Returns:
+// - []Rules: The rule sets in the order they rotate.
+// - error: An error if the schedule file exists but cannot be read or has no valid rule set; the built-in schedule is returned with it.
+func LoadSchedule(dataDir string) ([]Rules, error) {
+	if dataDir == "" {
+		return defaultSchedule, nil
+	}
+	path := filepath.Join(dataDir, scheduleFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultSchedule, nil
+	}
+	if err != nil {
+		return defaultSchedule, fmt.Errorf("error reading challenge schedule %s: %w", path, err)
+	}
+	var schedule []Rules
+	if err = json.Unmarshal(data, &schedule); err != nil {
+		return defaultSchedule, fmt.Errorf("error parsing challenge schedule %s: %w", path, err)
+	}
+	schedule = slices.DeleteFunc(schedule, func(r Rules) bool { return r.Cells < engine.MinCells || r.Terrain.Validate() != nil })
+	if len(schedule) == 0 {
+		return defaultSchedule, fmt.Errorf("challenge schedule %s has no valid rule set", path)
+	}
+	return schedule, nil
+}
+
+// SaveSchedule writes the rotation of the rule sets to the schedule file, e.g. after editing the levels,
+// creating the data directory if needed.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - schedule ([]Rules): The rule sets in the order they rotate.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func SaveSchedule(dataDir string, schedule []Rules) error {
+	path := filepath.Join(dataDir, scheduleFile)
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding challenge schedule: %w", err)
+	}
+	if err = os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing challenge schedule %s: %w", path, err)
+	}
+	return nil
+}
+
+// ForWeek returns the challenge of the week the given time falls into.
+//
+// Parameters:
+// - t (time.Time): A time in the week.
+// - schedule ([]Rules): The rotation of the rule sets; must not be empty.
+//
+// Returns:
+// - Challenge: The challenge of the week.
+func ForWeek(t time.Time, schedule []Rules) Challenge {
+	year, week := t.UTC().ISOWeek()
+	id := fmt.Sprintf("%d-W%02d", year, week)
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return Challenge{Week: id, Seed: int64(h.Sum64() >> 1), Rules: schedule[(year*53+week)%len(schedule)]}
+}
+
+// tablePath returns the location of the score table of the challenge.
+func tablePath(dataDir, week string) string {
+	return filepath.Join(dataDir, dirName, week+".json")
+}
+
+// LoadTable reads the score table of the challenge.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - week (string): The week of the challenge.
+//
+// Returns:
+// - []Entry: The best scores, the best first; empty if the challenge hasn't been played yet.
+// - error: An error if the table exists but cannot be read or parsed.
+func LoadTable(dataDir, week string) ([]Entry, error) {
+	path := tablePath(dataDir, week)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading challenge scores %s: %w", path, err)
+	}
+	var table []Entry
+	if err = json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("error parsing challenge scores %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// AddScore adds a score to the table of the challenge, keeping the tableSize best scores.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - week (string): The week of the challenge.
+// - e (Entry): The score.
+//
+// Returns:
+// - []Entry: The updated table, the best first.
+// - int: The place of the score in the table, starting from 1; 0 if it didn't make it into the table.
+// - error: An error if the table cannot be read or written.
+func AddScore(dataDir, week string, e Entry) ([]Entry, int, error) {
+	table, err := LoadTable(dataDir, week)
+	if err != nil {
+		return nil, 0, err
+	}
+	place := slices.IndexFunc(table, func(old Entry) bool { return e.Score > old.Score })
+	if place < 0 {
+		place = len(table)
+	}
+	if place >= tableSize {
+		return table, 0, nil
+	}
+	table = slices.Insert(table, place, e)
+	table = table[:min(len(table), tableSize)]
+
+	path := tablePath(dataDir, week)
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return nil, 0, fmt.Errorf("error encoding challenge scores: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, 0, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return nil, 0, fmt.Errorf("error writing challenge scores %s: %w", path, err)
+	}
+	return table, place + 1, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/replay"
+	"github.com/DenisKhanov/Snake/stats"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+// command is a subcommand of the program.
+// Fields:
+// - name: the name of the subcommand, the first command line argument.
+// - usage: the arguments of the subcommand.
+// - summary: what the subcommand does.
+// - run: executes the subcommand with the arguments following its name and returns the exit status.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the subcommands of the program.
+var commands = []command{
+	{"play", "[flags]", "start the game; the default when no subcommand is given", playCommand},
+	{"replay", "export|verify [flags] <run.replay> ...", "render a recorded game into a GIF or verify its score", replayCommand},
+	{"simulate", "[-n N] [-policy NAME] [-seed S] [-cells N] [-ticks N] [-q] [-ascii] [-trajectories FILE]", "play games headlessly with a bot and print the statistics of the scores", simulateCommand},
+	{"train", "ga [flags]", "evolve a bot with a genetic algorithm over headless games", trainCommand},
+	{"serve", "leaderboard|token|match|bots|api|ssh [flags]", "run a self-hosted leaderboard, match, bot, game API or SSH server, or issue leaderboard tokens", serveCommand},
+	{"stats", "export [-portable] <out.csv|out.json>", "export the history of all games played", statsCommand},
+	{"settings", "export|import [-portable] <settings.json>", "move the settings to another machine", settingsCommand},
+	{"edit", "list|show|add|set|remove [-portable] [arguments]", "edit the levels: the boards and the terrain of the weekly challenges", editCommand},
+}
+
+// aliases maps the names of the subcommands of older versions to their current form.
+var aliases = map[string][]string{
+	"export-replay": {"replay", "export"},
+	"verify-replay": {"replay", "verify"},
+}
+
+// run executes the subcommand given as the first command line argument.
+// If there is no subcommand, or the first argument is a flag, the game is started with the command line flags,
+// as with the play subcommand.
+func run() {
+	args := os.Args[1:]
+	if alias, ok := aliases[firstArg(args)]; ok {
+		args = append(alias, args[1:]...)
+	}
+	name := firstArg(args)
+	if name == "" || strings.HasPrefix(name, "-") {
+		os.Exit(playCommand(args))
+	}
+	if name == "help" {
+		printCommands()
+		os.Exit(0)
+	}
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(args[1:]))
+		}
+	}
+	fmt.Printf("Unknown subcommand %q.\n", name)
+	printCommands()
+	os.Exit(2)
+}
+
+// firstArg returns the first argument, or an empty string if there are none.
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// printCommands prints the list of the subcommands.
+func printCommands() {
+	fmt.Println("Usage: snake <subcommand> [arguments]")
+	fmt.Println()
+	for _, c := range commands {
+		fmt.Printf("  %-9s %s\n            %s\n", c.name, c.usage, c.summary)
+	}
+	fmt.Println()
+	fmt.Println("Run a subcommand with -h for the description of its flags.")
+}
+
+// replayCommand implements the replay subcommand, which has two actions: export and verify.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func replayCommand(args []string) int {
+	switch firstArg(args) {
+	case "export":
+		return exportReplay(args[1:])
+	case "verify":
+		return verifyReplay(args[1:])
+	default:
+		fmt.Println("Usage: snake replay export [-size N] [profiling flags] <run.replay> <out.gif>")
+		fmt.Println("       snake replay verify <run.replay>")
+		return 2
+	}
+}
+
+// verifyReplay implements the replay verify action.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program: 0 if the replay is valid, 1 if it isn't.
+func verifyReplay(args []string) int {
+	fs := flag.NewFlagSet("replay verify", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake replay verify <run.replay>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	r, err := replay.Load(fs.Arg(0))
+	if err == nil {
+		err = r.Verify()
+	}
+	if err != nil {
+		fmt.Println("Replay rejected:", err)
+		return 1
+	}
+	fmt.Printf("Replay verified: score %d, length %d, %d ticks\n", r.Score, r.Length, r.Ticks)
+	return 0
+}
+
+// statsCommand implements the stats subcommand, which has a single action: export.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func statsCommand(args []string) int {
+	fs := flag.NewFlagSet("stats export", flag.ExitOnError)
+	portable := fs.Bool("portable", false, "read the history from the data directory next to the executable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake stats export [-portable] <out.csv|out.json>")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "export" {
+		fs.Usage()
+		return 2
+	}
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dataDir, err := config.Dir(*portable)
+	if err != nil {
+		fmt.Println("Failed to find data directory:", err)
+		return 1
+	}
+	n, err := stats.Export(dataDir, fs.Arg(0))
+	if err != nil {
+		fmt.Println("Failed to export statistics:", err)
+		return 1
+	}
+	fmt.Printf("Exported %d games to %s\n", n, fs.Arg(0))
+	return 0
+}
+
+// settingsCommand implements the settings subcommand, which exports the settings to a file or imports them from one.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func settingsCommand(args []string) int {
+	fs := flag.NewFlagSet("settings", flag.ExitOnError)
+	portable := fs.Bool("portable", false, "use the data directory next to the executable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake settings export|import [-portable] <settings.json>")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "export" && args[0] != "import" {
+		fs.Usage()
+		return 2
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dataDir, err := config.Dir(*portable)
+	if err != nil {
+		fmt.Println("Failed to find data directory:", err)
+		return 1
+	}
+	cfg, err := config.Load(config.Path(dataDir))
+	if err != nil {
+		fmt.Println("Failed to load settings:", err)
+		return 1
+	}
+	if action == "export" {
+		if err = cfg.Export(fs.Arg(0), version.Get().Version); err != nil {
+			fmt.Println("Failed to export settings:", err)
+			return 1
+		}
+		fmt.Println("Settings exported to", fs.Arg(0))
+		return 0
+	}
+	unknown, err := cfg.Import(fs.Arg(0))
+	if err == nil {
+		err = cfg.Save(config.Path(dataDir))
+	}
+	if err != nil {
+		fmt.Println("Failed to import settings:", err)
+		return 1
+	}
+	if len(unknown) > 0 {
+		fmt.Println("Skipped settings unknown to this version:", strings.Join(unknown, ", "))
+	}
+	fmt.Println("Settings imported from", fs.Arg(0))
+	return 0
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/DenisKhanov/Snake/challenges"
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// editUsage describes the actions of the edit subcommand.
+const editUsage = `Usage: snake edit list [-portable]
+       snake edit show [-portable] <level>
+       snake edit add [-portable] <cells> <name>
+       snake edit set [-portable] <level> <x> <y> plain|ice|mud|spikes
+       snake edit remove [-portable] <level>`
+
+// editCommand implements the edit subcommand, the level editor: it lists, shows, adds, changes and removes
+// the levels of the game, the rule sets of the weekly challenges kept in the `challenges.json` schedule file.
+// The levels are numbered from 1 in the order they rotate, and the cells of the board are counted from the top-left
+// corner, like in the developer console. Editing the built-in schedule writes it to the schedule file first.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func editCommand(args []string) int {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	portable := fs.Bool("portable", false, "use the data directory next to the executable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), editUsage)
+		fs.PrintDefaults()
+	}
+	arity := map[string]int{"list": 0, "show": 1, "add": 2, "set": 4, "remove": 1}
+	action := firstArg(args)
+	n, ok := arity[action]
+	if !ok {
+		fs.Usage()
+		return 2
+	}
+	fs.Parse(args[1:])
+	if fs.NArg() != n {
+		fs.Usage()
+		return 2
+	}
+	dataDir, err := config.Dir(*portable)
+	if err != nil {
+		fmt.Println("Failed to find data directory:", err)
+		return 1
+	}
+	schedule, err := challenges.LoadSchedule(dataDir)
+	if err != nil {
+		fmt.Println("Failed to load the levels:", err)
+		return 1
+	}
+	schedule = slices.Clone(schedule)
+	if action == "list" {
+		for i, r := range schedule {
+			fmt.Printf("%2d. %s (%dx%d)\n", i+1, r.Name, r.Cells, r.Cells)
+		}
+		return 0
+	}
+	if action == "add" {
+		cells, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || cells < engine.MinCells {
+			fmt.Printf("The board must have at least %d cells along each side\n", engine.MinCells)
+			return 2
+		}
+		schedule = append(schedule, challenges.Rules{Name: fs.Arg(1), Cells: cells})
+		return saveLevels(dataDir, schedule, len(schedule))
+	}
+	level, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || level < 1 || level > len(schedule) {
+		fmt.Printf("There is no level %s, the levels are numbered from 1 to %d\n", fs.Arg(0), len(schedule))
+		return 2
+	}
+	switch action {
+	case "show":
+		printLevel(level, schedule[level-1])
+		return 0
+	case "remove":
+		if len(schedule) == 1 {
+			fmt.Println("The last level can't be removed")
+			return 1
+		}
+		schedule = slices.Delete(schedule, level-1, level)
+		return saveLevels(dataDir, schedule, 0)
+	}
+	r := &schedule[level-1]
+	x, errX := strconv.Atoi(fs.Arg(1))
+	y, errY := strconv.Atoi(fs.Arg(2))
+	if errX != nil || errY != nil || x < 0 || y < 0 || x >= r.Cells || y >= r.Cells {
+		fmt.Printf("The cell must be on the board, from 0 to %d\n", r.Cells-1)
+		return 2
+	}
+	tile, ok := engine.TileNames[strings.ToLower(fs.Arg(3))]
+	if !ok {
+		fmt.Printf("Unknown tile %q, expected plain, ice, mud or spikes\n", fs.Arg(3))
+		return 2
+	}
+	r.Terrain = r.Terrain.With(engine.Point{X: float64(x), Y: float64(y)}, tile)
+	return saveLevels(dataDir, schedule, level)
+}
+
+// saveLevels writes the edited levels to the schedule file and shows the changed level.
+//
+// Parameters:
+//
+//	dataDir (string): The data directory.
+//	schedule ([]challenges.Rules): The levels.
+//	level (int): The changed level, from 1; 0 to show none.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func saveLevels(dataDir string, schedule []challenges.Rules, level int) int {
+	if err := challenges.SaveSchedule(dataDir, schedule); err != nil {
+		fmt.Println("Failed to save the levels:", err)
+		return 1
+	}
+	if level > 0 {
+		printLevel(level, schedule[level-1])
+	}
+	fmt.Printf("Saved %d levels\n", len(schedule))
+	return 0
+}
+
+// printLevel prints a level with its board, one character per cell, in the notation of the terrain.
+//
+// Parameters:
+//
+//	level (int): The number of the level, from 1.
+//	r (challenges.Rules): The level.
+func printLevel(level int, r challenges.Rules) {
+	fmt.Printf("%d. %s (%dx%d)\n", level, r.Name, r.Cells, r.Cells)
+	row := make([]byte, r.Cells)
+	for y := range r.Cells {
+		for x := range r.Cells {
+			row[x] = byte(r.Terrain.At(engine.Point{X: float64(x), Y: float64(y)}))
+		}
+		fmt.Println(string(row))
+	}
+}
@@ -0,0 +1,60 @@
+//go:build !headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/DenisKhanov/Snake/game"
+	"github.com/DenisKhanov/Snake/player"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+// parseFlags parses the flags of the play subcommand into the game options.
+//
+// If the -version flag is given, the version information is printed and the program exits.
+// If the -pprof flag is given, the profiling endpoints are served for the whole session.
+// If the -player flag names an unknown player, the known ones are printed and the program exits.
+//
+// Parameters:
+//
+//	args ([]string): The command line arguments following the subcommand name.
+//
+// Returns:
+//
+//	game.Options: The options that override the configuration file for the current session.
+func parseFlags(args []string) game.Options {
+	var opts game.Options
+	flag.StringVar(&opts.Title, "title", "", "override the title of the game window")
+	flag.IntVar(&opts.Display, "display", -1, "index of the monitor to open the window on (0 is the primary one)")
+	flag.StringVar(&opts.Assets, "assets", "", "directory with fonts and images overriding the embedded ones")
+	flag.BoolVar(&opts.Dev, "dev", false, "development mode: reload assets when they change on disk")
+	flag.BoolVar(&opts.Portable, "portable", false, "store config, scores, stats and replays next to the executable")
+	flag.StringVar(&opts.BotAPI, "bot-api", "", "serve the gRPC bot API on this address, e.g. localhost:50051, so a bot can steer the snake")
+	flag.StringVar(&opts.Live, "live", "", "serve a live view of the game on this address, e.g. localhost:8090, to watch it in a browser")
+	flag.BoolVar(&opts.ASCII, "ascii", false, "print the board as ASCII art to the standard output after every tick, for debugging")
+	flag.BoolVar(&opts.Debug, "debug", false, "enable the developer console, opened with ~, for changing the running game while testing")
+	playerName := flag.String("player", "", fmt.Sprintf("let an AI play instead of the keyboard: one of %v, or a genome file saved by snake train ga", player.Names()))
+	showVersion := flag.Bool("version", false, "print the version information and exit")
+	var prof profileFlags
+	prof.register(flag.CommandLine, false)
+	flag.CommandLine.Parse(args)
+	if *showVersion {
+		fmt.Println("SnakeGO", version.Get())
+		os.Exit(0)
+	}
+	if *playerName != "" {
+		var err error
+		if opts.Player, err = newPlayer(*playerName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if _, err := prof.start(); err != nil {
+		log.Println(err)
+	}
+	return opts
+}
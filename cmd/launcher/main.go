@@ -0,0 +1,131 @@
+//go:build windows
+
+// The launcher of SnakeGO on Windows. The game imports SDL2.dll and libmcfgthread-1.dll at load time, so the
+// Windows loader looks for them before any code of the game runs, and the DLLs can't be extracted by the game
+// itself. The launcher embeds the game together with its DLLs, extracts them into one directory and starts
+// the game from there: the directory of the executable is the first one the loader searches, so the game always
+// loads the embedded DLLs, whatever DLLs are placed next to the launcher or in the working directory.
+//
+// The game is built into the payload first:
+//
+//	go generate ./cmd/launcher
+//	go build -o SnakeGO.exe ./cmd/launcher
+package main
+
+//go:generate go build -o payload/SnakeGO-game.exe ..
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// gameExe is the name of the game executable in the payload.
+const gameExe = "SnakeGO-game.exe"
+
+//go:embed payload
+var payload embed.FS // the game and the DLLs it needs to run on windows
+
+// main is the entry point of the launcher that performs the following steps:
+// 1. Extracts the game and the DLL files (`libmcfgthread-1.dll` and `SDL2.dll`) from the embedded resources
+// into the per-user cache directory, unless they are already there and unmodified.
+// 2. Starts the extracted game with the command line arguments of the launcher, in the current directory.
+// 3. Exits with the exit status of the game.
+//
+// The files are not written to the current directory, because it may be read-only, and a DLL
+// placed there by someone else would be loaded instead of the embedded one.
+// If extraction fails, it prints an error message and exits the program with a non-zero status code.
+func main() {
+	dir, err := installDir()
+	if err != nil {
+		fmt.Println("Failed to find the game directory:", err)
+		os.Exit(1)
+	}
+	files, err := fs.ReadDir(payload, "payload")
+	if err != nil {
+		fmt.Println("Failed to read the embedded files:", err)
+		os.Exit(1)
+	}
+	for _, file := range files {
+		data, err := payload.ReadFile("payload/" + file.Name())
+		if err == nil {
+			err = extractFile(filepath.Join(dir, file.Name()), data)
+		}
+		if err != nil {
+			fmt.Println("Failed to extract file:", err)
+			os.Exit(1)
+		}
+	}
+	game := filepath.Join(dir, gameExe)
+	if _, err = os.Stat(game); err != nil {
+		fmt.Println("The launcher was built without the game, run `go generate ./cmd/launcher` before building it")
+		os.Exit(1)
+	}
+	cmd := exec.Command(game, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		fmt.Println("Failed to start the game:", err)
+		os.Exit(1)
+	}
+}
+
+// installDir returns the directory the embedded files are extracted to, creating it if needed.
+//
+// Returns:
+//
+//	string: The `SnakeGO\bin` directory inside the per-user cache directory (`%LocalAppData%`).
+//	error: If the cache directory cannot be determined or created, an error is returned; otherwise, nil.
+func installDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %w", err)
+	}
+	dir := filepath.Join(cache, "SnakeGO", "bin")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// extractFile saves the provided byte data to a file with the specified filename.
+//
+// If the file already exists and its SHA-256 checksum matches the checksum of the data, it is left untouched.
+// Otherwise (the file is missing, corrupted, tampered with or left from another version of the game) the data
+// is written to a temporary file which then replaces the existing one, so the file is never left half-written.
+//
+// Parameters:
+//
+//	filename (string): The name of the file to which the data will be written.
+//	data ([]byte): The byte slice containing the data to be saved in the file.
+//
+// Returns:
+//
+//	error: If there is an error writing the data to the file, an error is returned; otherwise, nil.
+func extractFile(filename string, data []byte) error {
+	want := sha256.Sum256(data)
+	if existing, err := os.ReadFile(filename); err == nil {
+		got := sha256.Sum256(existing)
+		if bytes.Equal(got[:], want[:]) {
+			return nil
+		}
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0755); err != nil {
+		return fmt.Errorf("error writing file %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error replacing file %s: %w", filename, err)
+	}
+	return nil
+}
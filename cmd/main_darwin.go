@@ -0,0 +1,32 @@
+//go:build darwin
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// init locks the main goroutine to the main OS thread.
+//
+// On macOS, SDL and the windowing system may only be used from the main thread of the process,
+// so the game loop must never be moved to another thread by the Go scheduler.
+func init() {
+	runtime.LockOSThread()
+}
+
+// main is the entry point of the program that performs the following steps:
+// 1. Removes the process serial number argument (`-psn_...`) that older versions of macOS pass
+// to applications launched from Finder, since it isn't a valid flag.
+// 2. Executes the subcommand given on the command line, or starts the game with the parsed command line flags.
+func main() {
+	args := os.Args[:1]
+	for _, arg := range os.Args[1:] {
+		if !strings.HasPrefix(arg, "-psn_") {
+			args = append(args, arg)
+		}
+	}
+	os.Args = args
+	run()
+}
@@ -0,0 +1,11 @@
+//go:build !darwin
+
+package main
+
+// main is the entry point of the program that performs the following steps:
+//
+// The subcommand given on the command line is executed, or, if there is none, the command line flags
+// are parsed and the game is started with them.
+func main() {
+	run()
+}
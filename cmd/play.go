@@ -0,0 +1,77 @@
+//go:build !headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/DenisKhanov/Snake/game"
+	"github.com/DenisKhanov/Snake/player"
+)
+
+// playCommand implements the play subcommand, which starts the game.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func playCommand(args []string) int {
+	game.RunGame(parseFlags(args))
+	return 0
+}
+
+// watchPlayer starts the game in a window with the snake controlled by the given player, e.g. a trained bot.
+//
+// Parameters:
+//
+//	p (player.Player): The player.
+//	title (string): The title of the window.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func watchPlayer(p player.Player, title string) int {
+	game.RunGame(game.Options{Title: title, Display: -1, Player: p})
+	return 0
+}
+
+// exportReplay implements the replay export action.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func exportReplay(args []string) int {
+	fs := flag.NewFlagSet("replay export", flag.ExitOnError)
+	size := fs.Int("size", 400, "side of the exported board in pixels")
+	var prof profileFlags
+	prof.register(fs, true)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake replay export [-size N] [profiling flags] <run.replay> <out.gif>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	stop, err := prof.start()
+	defer stop()
+	if err != nil {
+		fmt.Println("Failed to start profiling:", err)
+		return 1
+	}
+	if err = game.ExportReplay(fs.Arg(0), fs.Arg(1), *size); err != nil {
+		fmt.Println("Failed to export replay:", err)
+		return 1
+	}
+	fmt.Println("Replay exported to", fs.Arg(1))
+	return 0
+}
@@ -0,0 +1,68 @@
+//go:build headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DenisKhanov/Snake/player"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+// noRenderer is printed by the subcommands that need the renderer of the game, which a headless build doesn't have.
+const noRenderer = "This is a headless build without the renderer, so it can't %s.\n" +
+	"Use a regular build for that; this one runs the serve, simulate, train, replay verify, stats and settings subcommands\n" +
+	"(see snake help).\n"
+
+// playCommand implements the play subcommand of a headless build: it only prints the version with -version,
+// since the game can't be played without the renderer.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func playCommand(args []string) int {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	showVersion := fs.Bool("version", false, "print the version information and exit")
+	fs.Parse(args)
+	if *showVersion {
+		fmt.Println("SnakeGO", version.Get())
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, noRenderer, "start the game")
+	return 1
+}
+
+// watchPlayer is the -watch flag of train ga in a headless build, which can't show the game.
+//
+// Parameters:
+//
+//	p (player.Player): The player.
+//	title (string): The title of the window.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func watchPlayer(p player.Player, title string) int {
+	fmt.Fprintf(os.Stderr, noRenderer, "show the game")
+	return 1
+}
+
+// exportReplay implements the replay export action of a headless build, which can't render the replay.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func exportReplay(args []string) int {
+	fmt.Fprintf(os.Stderr, noRenderer, "export replays")
+	return 1
+}
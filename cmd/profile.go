@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// profileFlags holds the profiling flags.
+//
+// The -pprof flag is available both for the game and for the headless subcommands; the profile files
+// are written only by the headless subcommands, which have a clear beginning and end.
+type profileFlags struct {
+	pprof string
+	cpu   string
+	mem   string
+	trace string
+}
+
+// register adds the profiling flags to the flag set.
+//
+// Parameters:
+//
+//	fs (*flag.FlagSet): The flag set to add the flags to.
+//	files (bool): Whether the flags writing profile files are added too.
+func (p *profileFlags) register(fs *flag.FlagSet, files bool) {
+	fs.StringVar(&p.pprof, "pprof", "", "serve net/http/pprof on the given address, e.g. :6060")
+	if files {
+		fs.StringVar(&p.cpu, "cpuprofile", "", "write a CPU profile to the file")
+		fs.StringVar(&p.mem, "memprofile", "", "write a heap profile to the file when done")
+		fs.StringVar(&p.trace, "trace", "", "write an execution trace to the file")
+	}
+}
+
+// start starts the profiling requested by the flags.
+//
+// Returns:
+//
+//	func(): The function that stops the profiling and writes the remaining profiles; it must be called before exiting.
+//	error: An error if a profile file cannot be created; otherwise, nil.
+func (p *profileFlags) start() (func(), error) {
+	if p.pprof != "" {
+		go func() {
+			log.Println("pprof is available at", p.pprof+"/debug/pprof/")
+			if err := http.ListenAndServe(p.pprof, nil); err != nil {
+				log.Println("error serving pprof:", err)
+			}
+		}()
+	}
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if p.cpu != "" {
+		f, err := os.Create(p.cpu)
+		if err != nil {
+			return stop, fmt.Errorf("error creating CPU profile: %w", err)
+		}
+		if err = pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return stop, fmt.Errorf("error starting CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if p.trace != "" {
+		f, err := os.Create(p.trace)
+		if err != nil {
+			stop()
+			return func() {}, fmt.Errorf("error creating trace: %w", err)
+		}
+		if err = trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return func() {}, fmt.Errorf("error starting trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+	if p.mem != "" {
+		stops = append(stops, func() {
+			if err := writeHeapProfile(p.mem); err != nil {
+				log.Println(err)
+			}
+		})
+	}
+	return stop, nil
+}
+
+// writeHeapProfile writes the heap profile to the file, after a garbage collection
+// that brings the statistics up to date.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating heap profile: %w", err)
+	}
+	defer f.Close()
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("error writing heap profile: %w", err)
+	}
+	return nil
+}
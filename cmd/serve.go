@@ -0,0 +1,360 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DenisKhanov/Snake/botapi"
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/leaderboard"
+	"github.com/DenisKhanov/Snake/metrics"
+	"github.com/DenisKhanov/Snake/netplay"
+	"github.com/DenisKhanov/Snake/restapi"
+	"github.com/DenisKhanov/Snake/sshd"
+	"github.com/DenisKhanov/Snake/tui"
+)
+
+// serveCommand implements the serve subcommand, which has six actions: leaderboard, which runs the leaderboard
+// server, token, which issues a token for signing the submissions, match, which runs the server of the online
+// versus mode, bots, which serves headless games to bots over gRPC, api, which serves headless games over
+// a JSON API, and ssh, which lets anyone play in their terminal over SSH.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveCommand(args []string) int {
+	switch firstArg(args) {
+	case "leaderboard":
+		return serveLeaderboard(args[1:])
+	case "token":
+		return serveToken(args[1:])
+	case "match":
+		return serveMatch(args[1:])
+	case "bots":
+		return serveBots(args[1:])
+	case "api":
+		return serveAPI(args[1:])
+	case "ssh":
+		return serveSSH(args[1:])
+	default:
+		fmt.Println("Usage: snake serve leaderboard [-addr ADDR] [-db FILE] [-import FILE] [-cert FILE -key FILE] [-tokens FILE] [-rate N] [-verify] [-profiles DIR] [-metrics ADDR]")
+		fmt.Println("       snake serve token [-tokens FILE] CLIENT")
+		fmt.Println("       snake serve match [-addr ADDR] [-cells N] [-delay D] [-ratings FILE] [-cert FILE -key FILE] [-metrics ADDR]")
+		fmt.Println("       snake serve bots [-addr ADDR] [-cells N]")
+		fmt.Println("       snake serve api [-addr ADDR] [-cells N] [-games N]")
+		fmt.Println("       snake serve ssh [-addr ADDR] [-hostkey FILE] [-scores FILE] [-cells N] [-metrics ADDR]")
+		return 2
+	}
+}
+
+// serveLeaderboard implements the serve leaderboard action, which runs a self-hosted leaderboard server
+// compatible with the leaderboard client of the game.
+//
+// The game submits scores only over HTTPS: either pass a certificate with -cert and -key,
+// or run the server behind a reverse proxy that terminates TLS. With -tokens, only submissions signed with
+// a token from the file are accepted; with -rate, every client can submit only so many scores per minute;
+// with -verify, every submission must come with its replay, which is re-simulated to check the score;
+// with -tokens and -profiles, the players can sync their settings, achievements and history across machines,
+// kept under the accounts of their tokens.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveLeaderboard(args []string) int {
+	fs := flag.NewFlagSet("serve leaderboard", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	db := fs.String("db", "leaderboard.db", "database file the entries are stored in")
+	importJSON := fs.String("import", "", "JSON file of a leaderboard kept by an older version, whose entries are added to the database")
+	cert := fs.String("cert", "", "TLS certificate file; without it, the server speaks plain HTTP for a reverse proxy")
+	key := fs.String("key", "", "TLS key file")
+	tokens := fs.String("tokens", "", "file with the issued tokens; without it, submissions aren't signed")
+	rate := fs.Int("rate", 10, "submissions accepted per minute from a client; 0 for no limit")
+	verify := fs.Bool("verify", false, "re-simulate the replay of every submission before accepting it")
+	profiles := fs.String("profiles", "", "directory the synced profiles of the players are kept in; needs -tokens")
+	metricsAddr := fs.String("metrics", "", "address to serve the Prometheus metrics on at /metrics, e.g. localhost:9100")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve leaderboard [-addr ADDR] [-db FILE] [-import FILE] [-cert FILE -key FILE] [-tokens FILE] [-rate N] [-verify] [-profiles DIR] [-metrics ADDR]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || (*cert == "") != (*key == "") || *rate < 0 || (*profiles != "" && *tokens == "") {
+		fs.Usage()
+		return 2
+	}
+	opts := leaderboard.Options{Rate: *rate, Verify: *verify, Profiles: *profiles}
+	if *tokens != "" {
+		var err error
+		if opts.Tokens, err = leaderboard.LoadTokens(*tokens); err != nil {
+			fmt.Println("Failed to load tokens:", err)
+			return 1
+		}
+		if len(opts.Tokens) == 0 {
+			fmt.Println("No tokens in", *tokens+"; issue one with snake serve token")
+			return 1
+		}
+	}
+	board, err := leaderboard.NewServer(*db, opts)
+	if err != nil {
+		fmt.Println("Failed to open leaderboard:", err)
+		return 1
+	}
+	defer board.Close()
+	if *importJSON != "" {
+		n, err := board.Import(*importJSON)
+		if err != nil {
+			fmt.Println("Failed to import leaderboard:", err)
+			return 1
+		}
+		log.Printf("imported %d entries from %s", n, *importJSON)
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           board,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	log.Printf("leaderboard server listening on %s, storing entries in %s", *addr, *db)
+	serveMetrics(*metricsAddr)
+	if *cert != "" {
+		err = srv.ListenAndServeTLS(*cert, *key)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	fmt.Println("Leaderboard server stopped:", err)
+	return 1
+}
+
+// serveToken implements the serve token action, which issues a token for a client of the leaderboard server
+// and prints it. The player puts the token into the leaderboard_token setting of the game's configuration.
+// Issuing a token for a client again replaces its previous token; the server loads the tokens on start.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveToken(args []string) int {
+	fs := flag.NewFlagSet("serve token", flag.ExitOnError)
+	tokens := fs.String("tokens", "tokens.json", "file with the issued tokens")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve token [-tokens FILE] CLIENT")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	token, err := leaderboard.IssueToken(*tokens, fs.Arg(0))
+	if err != nil {
+		fmt.Println("Failed to issue token:", err)
+		return 1
+	}
+	fmt.Println(token)
+	return 0
+}
+
+// serveMatch implements the serve match action, which runs the match server of the online versus mode.
+// The players connect to ws://ADDR/match, or wss:// with -cert and -key or behind a reverse proxy
+// that terminates TLS, and are paired in the order they connect. The ratings of the ranked matches
+// are kept in the file of -ratings.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveMatch(args []string) int {
+	fs := flag.NewFlagSet("serve match", flag.ExitOnError)
+	addr := fs.String("addr", ":8081", "address to listen on")
+	cells := fs.Int("cells", engine.Cells, "size of the shared board")
+	delay := fs.Duration("delay", 5*time.Second, "how late the spectators see the games, so they can't coach the players")
+	ratingsPath := fs.String("ratings", "ratings.json", "file the ratings of the ranked matches are stored in")
+	cert := fs.String("cert", "", "TLS certificate file; without it, the server speaks plain WebSocket")
+	key := fs.String("key", "", "TLS key file")
+	metricsAddr := fs.String("metrics", "", "address to serve the Prometheus metrics on at /metrics, e.g. localhost:9100")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve match [-addr ADDR] [-cells N] [-delay D] [-ratings FILE] [-cert FILE -key FILE] [-metrics ADDR]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || (*cert == "") != (*key == "") || *cells < engine.MinCells || *cells > netplay.CellsMax || *delay < 0 {
+		fs.Usage()
+		return 2
+	}
+	ratings, err := netplay.LoadRatings(*ratingsPath)
+	if err != nil {
+		fmt.Println("Failed to open ratings:", err)
+		return 1
+	}
+	//no read and write timeouts: the matches are long-lived connections with their own timeouts
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           netplay.NewServer(*cells, *delay, ratings),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Printf("match server listening on %s, boards of %d cells, spectators %s behind, storing ratings in %s",
+		*addr, *cells, *delay, *ratingsPath)
+	serveMetrics(*metricsAddr)
+	if *cert != "" {
+		err = srv.ListenAndServeTLS(*cert, *key)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	fmt.Println("Match server stopped:", err)
+	return 1
+}
+
+// serveBots implements the serve bots action, which serves headless games to bots over gRPC: every action
+// of a bot advances its game by one tick, so bots written in any language can play and simulate games
+// as fast as they answer. The service is described by botapi/snake.proto.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveBots(args []string) int {
+	fs := flag.NewFlagSet("serve bots", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:50051", "address to listen on")
+	cells := fs.Int("cells", engine.Cells, "size of the board of the games whose bot doesn't choose one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve bots [-addr ADDR] [-cells N]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || *cells < engine.MinCells || *cells > botapi.CellsMax {
+		fs.Usage()
+		return 2
+	}
+	log.Printf("bot API listening on %s, boards of %d cells", *addr, *cells)
+	err := botapi.ListenAndServe(*addr, botapi.NewHeadless(*cells))
+	fmt.Println("Bot server stopped:", err)
+	return 1
+}
+
+// serveAPI implements the serve api action, which serves headless games over a JSON API: clients create games,
+// play moves and read the state and the results with plain HTTP requests.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveAPI(args []string) int {
+	fs := flag.NewFlagSet("serve api", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8082", "address to listen on")
+	cells := fs.Int("cells", engine.Cells, "size of the board of the games whose request doesn't choose one")
+	games := fs.Int("games", restapi.GamesMax, "largest number of games kept at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve api [-addr ADDR] [-cells N] [-games N]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || *cells < engine.MinCells || *cells > restapi.CellsMax || *games < 1 {
+		fs.Usage()
+		return 2
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           restapi.NewServer(*cells, *games),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	log.Printf("game API listening on %s, boards of %d cells", *addr, *cells)
+	err := srv.ListenAndServe()
+	fmt.Println("Game API server stopped:", err)
+	return 1
+}
+
+// serveSSH implements the serve ssh action, which lets anyone play the game in their terminal with an SSH client,
+// e.g. ssh -p 2222 play@host: every connection plays its own games, and all players share a high score table
+// under their user names. No password is asked. The host key is generated on the first start.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func serveSSH(args []string) int {
+	fs := flag.NewFlagSet("serve ssh", flag.ExitOnError)
+	addr := fs.String("addr", ":2222", "address to listen on")
+	hostKey := fs.String("hostkey", "ssh_host_ed25519_key", "file with the host key; generated if missing")
+	scoresPath := fs.String("scores", "ssh_scores.json", "file the high scores are stored in")
+	cells := fs.Int("cells", engine.Cells, "size of the board")
+	metricsAddr := fs.String("metrics", "", "address to serve the Prometheus metrics on at /metrics, e.g. localhost:9100")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake serve ssh [-addr ADDR] [-hostkey FILE] [-scores FILE] [-cells N] [-metrics ADDR]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || *cells < engine.MinCells || *cells > botapi.CellsMax {
+		fs.Usage()
+		return 2
+	}
+	key, err := sshd.LoadHostKey(*hostKey)
+	if err != nil {
+		fmt.Println("Failed to load host key:", err)
+		return 1
+	}
+	scores, err := tui.LoadScores(*scoresPath)
+	if err != nil {
+		fmt.Println("Failed to load high scores:", err)
+		return 1
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Println("Failed to listen:", err)
+		return 1
+	}
+	srv := &sshd.Server{
+		HostKey: key,
+		Handler: func(s *sshd.Session) {
+			tui.Play(s, tui.PlayerName(s.User), scores, *cells)
+		},
+	}
+	log.Printf("SSH server listening on %s, boards of %d cells, storing high scores in %s", *addr, *cells, *scoresPath)
+	serveMetrics(*metricsAddr)
+	err = srv.Serve(ln)
+	fmt.Println("SSH server stopped:", err)
+	return 1
+}
+
+// serveMetrics serves the Prometheus metrics of the server in the background if an address is given.
+//
+// Parameters:
+//
+//	addr (string): The address of the metrics, from the -metrics flag; empty disables them.
+func serveMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+	log.Printf("serving metrics on http://%s/metrics", addr)
+	go func() {
+		log.Println("the metrics server has stopped:", metrics.ListenAndServe(addr))
+	}()
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/gym"
+	"github.com/DenisKhanov/Snake/player"
+	"github.com/DenisKhanov/Snake/train"
+)
+
+// simulateCommand implements the simulate subcommand, which plays many games headlessly with a bot and prints
+// the distribution of their results, e.g. for balancing new rules, foods and modes: the percentiles of the scores,
+// how the games have ended, the average length and the food eaten per minute of game time.
+//
+// The games run in parallel on all processors, each with its own seed, so the results depend only on the seeds.
+// With -trajectories, every step of the games is written to a file for machine learning: the observation
+// the bot has moved from, in the encoding of -obs, its action, the reward and whether the game has ended;
+// the games then run one after another, so the file lists them in order, and so they do with -ascii, which prints
+// the board after every tick.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func simulateCommand(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	games := fs.Int("games", 10, "number of games to play")
+	fs.IntVar(games, "n", 10, "shorthand for -games")
+	policy := fs.String("policy", "greedy", fmt.Sprintf("the bot playing the games: one of %v, or a genome file saved by snake train ga", player.Names()))
+	seed := fs.Int64("seed", 0, "seed of the first game, the next games use the following seeds; 0 means a random seed")
+	cells := fs.Int("cells", engine.Cells, "side of the board in cells")
+	maxTicks := fs.Int("ticks", 10000, "largest number of ticks of a single game")
+	quiet := fs.Bool("q", false, "print only the summary, not every game")
+	ascii := fs.Bool("ascii", false, "print the board as ASCII art after every tick, for debugging; the games run one after another")
+	trajectories := fs.String("trajectories", "", "write the observations, actions and rewards of every step to this .npz, .csv or .jsonl file")
+	obs := fs.String("obs", "features", "encoding of the observations written with -trajectories: grid or features")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake simulate [-n N] [-policy NAME] [-seed S] [-cells N] [-ticks N] [-q] [-ascii] [-trajectories FILE [-obs grid|features]]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	encodings := map[string]gym.Encoding{"grid": gym.Grid, "features": gym.Features}
+	encoding, ok := encodings[*obs]
+	if *games < 1 || *cells < engine.MinCells || *maxTicks < 1 || !ok {
+		fs.Usage()
+		return 2
+	}
+	bot, err := newPlayer(*policy)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	var out gym.TrajectoryWriter
+	if *trajectories != "" {
+		if out, err = gym.CreateTrajectories(*trajectories); err != nil {
+			fmt.Println("Failed to create the trajectory file:", err)
+			return 1
+		}
+	}
+
+	opts := gym.Options{Cells: *cells, Encoding: encoding, MaxTicks: *maxTicks}
+	var board io.Writer
+	if *ascii {
+		board = os.Stdout
+	}
+	results := make([]simResult, *games)
+	if out != nil || board != nil {
+		for i := range results {
+			opts.Seed = *seed + int64(i)
+			if results[i], err = simulateGame(bot, opts, i, out, board); err != nil {
+				fmt.Println("Failed to write the trajectories:", err)
+				out.Close()
+				return 1
+			}
+		}
+	} else {
+		var wg sync.WaitGroup
+		next := make(chan int)
+		for range runtime.GOMAXPROCS(0) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					opts := opts
+					opts.Seed = *seed + int64(i)
+					results[i], _ = simulateGame(bot, opts, i, nil, nil)
+				}
+			}()
+		}
+		for i := range results {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+	if !*quiet {
+		for _, r := range results {
+			fmt.Printf("seed %d: score %d, length %d, %d ticks, %s\n", r.seed, r.score, r.length, r.ticks, r.end)
+		}
+	}
+	printSimSummary(os.Stdout, *policy, results)
+	if out != nil {
+		if err := out.Close(); err != nil {
+			fmt.Println("Failed to write the trajectories:", err)
+			return 1
+		}
+		fmt.Println("Trajectories written to", *trajectories)
+	}
+	return 0
+}
+
+// newPlayer returns the player of the -player and -policy flags: a built-in player, or a genome file
+// saved by snake train ga if the name ends with .json.
+//
+// Parameters:
+//
+//	name (string): The name of the player or the genome file.
+//
+// Returns:
+//
+//	player.Player: The player.
+//	error: An error if the player is unknown or the genome cannot be loaded.
+func newPlayer(name string) (player.Player, error) {
+	if strings.HasSuffix(name, ".json") {
+		g, err := train.LoadGenome(name)
+		if err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	return player.New(name)
+}
+
+// endTickLimit is how a game cut short by -ticks has ended.
+const endTickLimit = "tick limit"
+
+// simResult is the outcome of a simulated game.
+// Fields:
+// - seed: the seed of the game.
+// - score: the final score.
+// - length: the final length of the snake.
+// - food: the number of food items eaten.
+// - ticks: the number of ticks played.
+// - elapsed: the game time played.
+// - bites: the number of times the snake has bitten its body.
+// - end: how the game has ended: the wall the snake has hit, e.g. "top wall", or endTickLimit.
+type simResult struct {
+	seed    int64
+	score   int
+	length  int
+	food    int
+	ticks   int
+	elapsed time.Duration
+	bites   int
+	end     string
+}
+
+// simulateGame plays a game with a bot.
+//
+// Parameters:
+//
+//	bot (player.Player): The bot; it's shared by the games running in parallel, so it must not keep any state.
+//	opts (gym.Options): The options of the environment, with the seed of the game.
+//	episode (int): The number of the game, for the trajectories.
+//	out (gym.TrajectoryWriter): Where the steps of the game are written; nil to not write them.
+//	board (io.Writer): Where the board is printed as ASCII art after every tick; nil to not print it.
+//
+// Returns:
+//
+//	simResult: The outcome of the game.
+//	error: An error if the steps cannot be written.
+func simulateGame(bot player.Player, opts gym.Options, episode int, out gym.TrajectoryWriter, board io.Writer) (simResult, error) {
+	env := gym.New(opts)
+	observation := env.Reset()
+	r := simResult{seed: opts.Seed}
+	for tick := 0; ; tick++ {
+		before := env.State()
+		action := gym.ActionOf(bot.NextMove(before))
+		next, reward, done := env.Step(action)
+		if s := env.State(); len(s.Snake) < len(before.Snake) {
+			r.bites++
+		}
+		if out != nil {
+			t := gym.Transition{Episode: episode, Tick: tick, Obs: observation, Action: action, Reward: reward, Done: done}
+			if err := out.Write(t); err != nil {
+				return r, err
+			}
+		}
+		if board != nil {
+			fmt.Fprintln(board, env.State())
+		}
+		observation = next
+		if done {
+			break
+		}
+	}
+	s := env.State()
+	r.score, r.length, r.food, r.ticks, r.elapsed = s.Score, len(s.Snake), s.AteFood, s.Tick, s.Elapsed
+	r.end = endTickLimit
+	if s.GameOver {
+		//the snake dies before moving, so its direction points at the wall it has hit; the engine's Up is down on the screen
+		r.end = map[engine.Dir]string{engine.Up: "bottom wall", engine.Right: "right wall", engine.Down: "top wall", engine.Left: "left wall"}[s.Direction]
+	}
+	return r, nil
+}
+
+// printSimSummary prints the distribution of the results of the simulated games.
+//
+// Parameters:
+//
+//	w (io.Writer): The destination.
+//	policy (string): The name of the bot.
+//	results ([]simResult): The outcomes of the games; at least one.
+func printSimSummary(w io.Writer, policy string, results []simResult) {
+	n := float64(len(results))
+	scores := make([]int, len(results))
+	var score, length, food, ticks, bites float64
+	var elapsed time.Duration
+	longest := 0
+	ends := map[string]int{}
+	for i, r := range results {
+		scores[i] = r.score
+		score += float64(r.score)
+		length += float64(r.length)
+		food += float64(r.food)
+		ticks += float64(r.ticks)
+		bites += float64(r.bites)
+		elapsed += r.elapsed
+		longest = max(longest, r.length)
+		ends[r.end]++
+	}
+	slices.Sort(scores)
+	//the nearest-rank percentile: the smallest score at least p percent of the games don't beat
+	percentile := func(p int) int {
+		return scores[max((p*len(scores)+99)/100-1, 0)]
+	}
+
+	fmt.Fprintf(w, "%d games played by %s\n", len(results), policy)
+	fmt.Fprintf(w, "score:    mean %.1f, min %d, p10 %d, p25 %d, median %d, p75 %d, p90 %d, p99 %d, best %d\n",
+		score/n, scores[0], percentile(10), percentile(25), percentile(50), percentile(75), percentile(90), percentile(99), scores[len(scores)-1])
+	fmt.Fprintf(w, "length:   mean %.1f, longest %d\n", length/n, longest)
+	perMinute := 0.0
+	if elapsed > 0 {
+		perMinute = food / elapsed.Minutes()
+	}
+	fmt.Fprintf(w, "food:     %.1f per game, %.1f per minute of game time\n", food/n, perMinute)
+	fmt.Fprintf(w, "duration: %.0f ticks, %s of game time per game\n", ticks/n, (elapsed / time.Duration(len(results))).Round(time.Second/10))
+	fmt.Fprintf(w, "bites:    %.2f per game\n", bites/n)
+	causes := slices.Collect(maps.Keys(ends))
+	slices.SortFunc(causes, func(a, b string) int { return cmp.Or(cmp.Compare(ends[b], ends[a]), cmp.Compare(a, b)) })
+	for i, cause := range causes {
+		label := ""
+		if i == 0 {
+			label = "ended:"
+		}
+		fmt.Fprintf(w, "%-9s %-12s %5.1f%% (%d)\n", label, cause, float64(ends[cause])*100/n, ends[cause])
+	}
+}
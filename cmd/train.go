@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/train"
+)
+
+// trainCommand implements the train subcommand, which has one action: ga.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the subcommand name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func trainCommand(args []string) int {
+	switch firstArg(args) {
+	case "ga":
+		return trainGA(args[1:])
+	default:
+		fmt.Println("Usage: snake train ga [flags]")
+		return 2
+	}
+}
+
+// trainGA implements the train ga action, which evolves a bot with a genetic algorithm over headless games,
+// saving the best genome every few generations and at the end. With -watch, the champion then plays
+// the game in the window.
+//
+// Parameters:
+//
+//	args ([]string): The arguments following the action name.
+//
+// Returns:
+//
+//	int: The exit status of the program.
+func trainGA(args []string) int {
+	fs := flag.NewFlagSet("train ga", flag.ExitOnError)
+	var cfg train.Config
+	fs.IntVar(&cfg.Generations, "generations", 50, "number of generations to breed")
+	fs.IntVar(&cfg.Population, "population", 100, "number of genomes of a generation")
+	fs.IntVar(&cfg.Games, "games", 5, "number of games every genome plays to be rated")
+	fs.IntVar(&cfg.Cells, "cells", engine.Cells, "side of the board in cells")
+	fs.IntVar(&cfg.Hidden, "hidden", 8, "number of hidden neurons of the networks; -1 for none")
+	fs.Int64Var(&cfg.Seed, "seed", 0, "seed of the evolution; 0 means a random seed")
+	out := fs.String("out", "champion.json", "file the best genome is saved to")
+	every := fs.Int("every", 5, "save the best genome every N generations, besides at the end")
+	resume := fs.String("resume", "", "start from the genome saved in this file instead of random networks")
+	watch := fs.Bool("watch", false, "let the champion play the game in a window once the evolution is over")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snake train ga [-generations N] [-population N] [-games N] [-cells N] [-hidden N] [-seed S] [-out FILE] [-every N] [-resume FILE] [-watch]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || cfg.Generations < 1 || cfg.Population < 2 || cfg.Games < 1 || cfg.Cells < engine.MinCells || *every < 1 {
+		fs.Usage()
+		return 2
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if *resume != "" {
+		var err error
+		if cfg.Start, err = train.LoadGenome(*resume); err != nil {
+			fmt.Println("Failed to load the genome:", err)
+			return 1
+		}
+	}
+
+	failed := false
+	champion := train.Evolve(cfg, func(gen train.Generation) bool {
+		fmt.Printf("generation %d: best %.2f, mean %.2f\n", gen.Number, gen.Best.Fitness, gen.Mean)
+		if gen.Number%*every == 0 || gen.Number == cfg.Generations {
+			if err := gen.Best.Save(*out); err != nil {
+				fmt.Println("Failed to save the genome:", err)
+				failed = true
+				return false
+			}
+		}
+		return true
+	})
+	if failed {
+		return 1
+	}
+	fmt.Printf("Champion of generation %d saved to %s; watch it with snake -player %s\n", champion.Generation, *out, *out)
+	if *watch {
+		return watchPlayer(&champion, fmt.Sprintf("Champion of generation %d", champion.Generation))
+	}
+	return 0
+}
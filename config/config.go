@@ -0,0 +1,306 @@
+// Package config contains the user settings of the Snake game and the functions for loading and saving them.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	appDir         = "SnakeGO"
+	portableDir    = "SnakeGO-data"
+	fileName       = "config.toml"
+	legacyFileName = "config.json" // the configuration file of older versions, read until the settings are saved
+)
+
+// fileHeader is written at the top of the configuration file.
+const fileHeader = "# The settings of SnakeGO. The keys are described in the Settings section of the README.\n\n"
+
+// Config holds the user settings that are remembered between game sessions.
+// Fields:
+// - Fullscreen: whether the game window is opened in fullscreen mode.
+// - Title: the title of the game window; empty means the default title.
+// - VSync: whether the rendering is synchronized with the display refresh rate.
+// - FPSCap: the maximum number of frames rendered per second; 0 means no cap.
+// - RenderScale: the internal resolution of the board in percent of the game area size.
+// - Display: the index of the monitor the window opens on when no saved window position is available (0 is the primary one).
+// - GIFOnGameOver: whether a GIF clip of the last seconds of the game is saved automatically when the game ends.
+// - SaveBestReplay: whether the replay of a game that beats the best score is kept in addition to the replay of the last game.
+// - CheckUpdates: whether the game checks for a newer release on launch (opt-in, off by default).
+// - LeaderboardURL: the HTTPS address of an online leaderboard the scores are submitted to; empty (the default)
+// disables the leaderboard.
+// - LeaderboardToken: the token issued by the leaderboard server in the "client:secret" form, which the
+// submissions are signed with; empty if the server doesn't require one.
+// - PlayerName: the name the scores are submitted to the leaderboard under, also shown to the opponents
+// in the online versus mode.
+// - PlayerID: the secret random identifier the ratings of the ranked versus matches are kept under on the match server;
+// generated on the first online match. Copying it to another computer carries the ratings over.
+// - VersusURL: the address of the match server of the online versus mode, e.g. "wss://example.com/match";
+// empty (the default) disables the mode.
+// - TelegramBotToken: the token of the player's Telegram bot, issued by @BotFather, which sends the summaries
+// of the games to TelegramChatID from the game over screen; empty (the default) disables the sharing.
+// - TelegramChatID: the identifier of the Telegram chat the summaries are sent to: a user, a group or a @channel.
+// - MasterVolume: the volume of all sounds in percent; the music and effects volumes are relative to it.
+// - MusicVolume: the volume of the background music in percent.
+// - SFXVolume: the volume of the sound effects in percent.
+// - Muted: whether all sounds are muted.
+// - Language: the language of the on-screen text; empty until it's detected from the system on the first run.
+// - UIScale: the size of the HUD text and menus in percent (75-200), independent of the size of the board.
+// - Palette: the name of the color palette of the board.
+// - ReducedMotion: whether the animations are disabled: the camera jumps instead of gliding, the background doesn't
+// change and the HUD doesn't pulse.
+// - NoFlashing: whether the flashing effects are replaced with steady color changes, for photosensitive players.
+// - Captions: whether the sound effects are described with captions on the screen, for deaf and hard-of-hearing players.
+// - HeadOutline: whether the snake's head is drawn with a bright outline and an arrow showing the direction.
+// - Assist: whether the beginner assist is on: the speed is capped at a gentle level and time slows down near the walls.
+// - Hints: whether the safe-move hints are drawn around the snake's head, for practicing: the moves into a wall
+// or the body are marked red, and the ones keeping a way to the tail open green.
+// - Ghost: whether new games are played from the seed of the best game, raced by a translucent ghost of it.
+// - Speedrun: whether the speedrun timer with the split times is shown.
+// - Wind: whether gusts of wind push the snake sideways every few ticks, announced by an arrow; it applies from
+// the next game on, and never to the weekly challenge.
+// - BoardSize: the number of cells along each side of the board (10-60); 0 means the classic 20x20 board.
+// The large cell mode overrides it.
+// - LargeCells: whether the game is played on a small board with huge cells and high-contrast outlines, for low-vision players.
+// - overrides: the settings overridden by environment variables for the session, see ApplyEnv.
+type Config struct {
+	Fullscreen  bool   `json:"fullscreen" toml:"fullscreen"`
+	Title       string `json:"title,omitempty" toml:"title,omitempty"`
+	VSync       bool   `json:"vsync" toml:"vsync"`
+	FPSCap      int    `json:"fps_cap" toml:"fps_cap"`
+	RenderScale int    `json:"render_scale" toml:"render_scale"`
+	Display     int    `json:"display" toml:"display"`
+
+	GIFOnGameOver    bool   `json:"gif_on_game_over" toml:"gif_on_game_over"`
+	SaveBestReplay   bool   `json:"save_best_replay" toml:"save_best_replay"`
+	CheckUpdates     bool   `json:"check_updates" toml:"check_updates"`
+	LeaderboardURL   string `json:"leaderboard_url,omitempty" toml:"leaderboard_url,omitempty"`
+	LeaderboardToken string `json:"leaderboard_token,omitempty" toml:"leaderboard_token,omitempty"`
+	PlayerName       string `json:"player_name,omitempty" toml:"player_name,omitempty"`
+	PlayerID         string `json:"player_id,omitempty" toml:"player_id,omitempty"`
+	VersusURL        string `json:"versus_url,omitempty" toml:"versus_url,omitempty"`
+	TelegramBotToken string `json:"telegram_bot_token,omitempty" toml:"telegram_bot_token,omitempty"`
+	TelegramChatID   string `json:"telegram_chat_id,omitempty" toml:"telegram_chat_id,omitempty"`
+
+	MasterVolume int  `json:"master_volume" toml:"master_volume"`
+	MusicVolume  int  `json:"music_volume" toml:"music_volume"`
+	SFXVolume    int  `json:"sfx_volume" toml:"sfx_volume"`
+	Muted        bool `json:"muted" toml:"muted"`
+
+	Language      string `json:"language" toml:"language"`
+	UIScale       int    `json:"ui_scale" toml:"ui_scale"`
+	Palette       string `json:"palette" toml:"palette"`
+	ReducedMotion bool   `json:"reduced_motion" toml:"reduced_motion"`
+	NoFlashing    bool   `json:"no_flashing" toml:"no_flashing"`
+	BoardSize     int    `json:"board_size,omitempty" toml:"board_size,omitempty"`
+	LargeCells    bool   `json:"large_cells" toml:"large_cells"`
+	HeadOutline   bool   `json:"head_outline" toml:"head_outline"`
+	Captions      bool   `json:"captions" toml:"captions"`
+	Assist        bool   `json:"assist" toml:"assist"`
+	Hints         bool   `json:"hints" toml:"hints"`
+	Ghost         bool   `json:"ghost" toml:"ghost"`
+	Speedrun      bool   `json:"speedrun" toml:"speedrun"`
+	Wind          bool   `json:"wind" toml:"wind"`
+
+	overrides map[string]override
+}
+
+// Default creates and returns a new instance of Config with default values.
+// By default the rendering is synchronized with the display, which keeps the CPU usage low.
+func Default() *Config {
+	return &Config{
+		VSync:          true,
+		SaveBestReplay: true,
+		RenderScale:    100,
+		MasterVolume:   100,
+		MusicVolume:    70,
+		SFXVolume:      80,
+		UIScale:        100,
+	}
+}
+
+// Dir returns the directory where the game keeps its data: the configuration, scores, statistics and replays.
+//
+// Normally it's the `SnakeGO` directory in the user's configuration directory. In portable mode it's
+// the `SnakeGO-data` directory next to the executable, so the whole game can be carried on a USB stick.
+//
+// Parameters:
+// - portable (bool): Whether the game runs in portable mode.
+//
+// Returns:
+// - string: The data directory.
+// - error: An error if the directory cannot be determined.
+func Dir(portable bool) (string, error) {
+	if portable {
+		exe, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("error finding executable: %w", err)
+		}
+		return filepath.Join(filepath.Dir(exe), portableDir), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding config directory: %w", err)
+	}
+	return filepath.Join(dir, appDir), nil
+}
+
+// Path returns the location of the configuration file in the data directory.
+//
+// Parameters:
+// - dir (string): The data directory.
+//
+// Returns:
+// - string: The full path to the configuration file.
+func Path(dir string) string {
+	return filepath.Join(dir, fileName)
+}
+
+// Load reads the configuration from the TOML file at the given path.
+//
+// If the file doesn't exist, the JSON configuration file of older versions in the same directory is read instead,
+// and if there is none either, the default configuration is returned without an error,
+// so the first launch of the game doesn't require any setup. Settings missing in the file
+// keep their default values.
+//
+// Parameters:
+// - path (string): The path to the configuration file.
+//
+// Returns:
+// - *Config: The loaded configuration.
+// - error: An error if the file exists but cannot be read or parsed.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	found, err := readTOML(path, cfg)
+	if err == nil && !found {
+		_, err = readJSON(filepath.Join(filepath.Dir(path), legacyFileName), cfg)
+	}
+	if err != nil {
+		return Default(), err
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration to the TOML file at the given path, creating the directory if needed.
+// The overrides from the environment are not written, see ApplyEnv. The JSON configuration file of older versions
+// is removed once the settings are saved, so it can't be mistaken for the current one.
+//
+// Parameters:
+// - path (string): The path to the configuration file.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (c *Config) Save(path string) error {
+	v, err := c.fileValue()
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(fileHeader)
+	if err = toml.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = writePrivate(path, buf.Bytes()); err != nil {
+		return err
+	}
+	legacy := filepath.Join(filepath.Dir(path), legacyFileName)
+	if err = os.Remove(legacy); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing %s: %w", legacy, err)
+	}
+	return nil
+}
+
+// readTOML decodes the TOML file at the given path into v.
+//
+// Parameters:
+// - path (string): The path to the file.
+// - v (any): A pointer to the value to decode into.
+//
+// Returns:
+// - bool: True if the file exists, false otherwise.
+// - error: An error if the file exists but cannot be read or parsed.
+func readTOML(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if err = toml.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// readJSON decodes the JSON file at the given path into v.
+//
+// Parameters:
+// - path (string): The path to the file.
+// - v (any): A pointer to the value to decode into.
+//
+// Returns:
+// - bool: True if the file exists, false otherwise.
+// - error: An error if the file exists but cannot be read or parsed.
+func readJSON(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// writeJSON encodes v as indented JSON and writes it to the file at the given path,
+// creating the directory if needed.
+//
+// Parameters:
+// - path (string): The path to the file.
+// - v (any): The value to encode.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	return writePrivate(path, data)
+}
+
+// writePrivate writes the data to the file at the given path, readable and writable by the owner only,
+// as the settings hold secrets such as the leaderboard token. A file written by an older version with wider
+// permissions is tightened too, since writing an existing file keeps its permissions.
+//
+// Parameters:
+// - path (string): The path to the file.
+// - data ([]byte): The contents of the file.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func writePrivate(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("error restricting permissions of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package config contains the user settings of the Snake game and the functions for loading and saving them.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const envPrefix = "SNAKE_" // the prefix of the environment variables overriding the settings
+
+// override is a setting overridden by an environment variable.
+// Fields:
+// - env: the value from the environment, as JSON.
+// - file: the value before the override, as JSON, which is what the configuration file keeps.
+type override struct {
+	env  json.RawMessage
+	file json.RawMessage
+}
+
+// ApplyEnv overrides the settings with the SNAKE_* environment variables: every setting can be overridden with
+// the variable named after its key in the configuration file, e.g. SNAKE_FPS_CAP=60 or SNAKE_LARGE_CELLS=true.
+//
+// The overrides last for the session only: while a setting keeps the value from the environment, Save writes
+// the value it had before the override, so the configuration file isn't changed by the environment.
+// Variables with invalid values are skipped and reported; SNAKE_* variables that don't match a setting are ignored.
+//
+// Parameters:
+// - environ ([]string): The environment in the "KEY=value" form, as returned by os.Environ.
+//
+// Returns:
+// - []string: The keys of the overridden settings.
+// - error: An error describing the variables with invalid values; nil if all values are valid.
+func (c *Config) ApplyEnv(environ []string) ([]string, error) {
+	v := reflect.ValueOf(c).Elem()
+	fields := make(map[string]int)
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[strings.ToUpper(name)] = i
+		}
+	}
+
+	var applied, invalid []string
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+		i, ok := fields[strings.TrimPrefix(key, envPrefix)]
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		before, _ := json.Marshal(field.Interface())
+		if err := setField(field, value); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		after, _ := json.Marshal(field.Interface())
+		name := strings.ToLower(strings.TrimPrefix(key, envPrefix))
+		if c.overrides == nil {
+			c.overrides = make(map[string]override)
+		}
+		if o, ok := c.overrides[name]; ok {
+			before = o.file
+		}
+		c.overrides[name] = override{env: after, file: before}
+		applied = append(applied, name)
+	}
+	if len(invalid) > 0 {
+		return applied, fmt.Errorf("invalid environment overrides: %s", strings.Join(invalid, "; "))
+	}
+	return applied, nil
+}
+
+// setField parses the value of an environment variable into a setting.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't an integer", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.String:
+		field.SetString(value)
+	default:
+		return fmt.Errorf("the setting can't be set from the environment")
+	}
+	return nil
+}
+
+// fileValue returns the configuration as it's written to the configuration file: the settings that still
+// have their values from the environment are replaced with the values they had before the overrides.
+func (c *Config) fileValue() (*Config, error) {
+	if len(c.overrides) == 0 {
+		return c, nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for key, o := range c.overrides {
+		if bytes.Equal(m[key], o.env) {
+			m[key] = o.file
+		}
+	}
+	if data, err = json.Marshal(m); err != nil {
+		return nil, err
+	}
+	file := *c
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
@@ -0,0 +1,178 @@
+// Package config contains the user settings of the Snake game and the functions for loading and saving them.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+const transferFormat = 1 // the current version of the settings export format
+
+// ErrNewerFormat is returned when importing settings exported by a newer version of the game in a format
+// this version doesn't know.
+var ErrNewerFormat = errors.New("settings were exported in a newer format")
+
+// machineKeys lists the settings that describe the machine rather than the player, so they're
+// neither exported nor imported.
+var machineKeys = []string{"display"}
+
+// secretKeys lists the settings that are secrets, such as tokens: they're redacted from the crash reports, and
+// neither exported nor imported, as the exported files and the synced profiles leave the machine.
+var secretKeys = []string{"leaderboard_token", "telegram_bot_token", "player_id"}
+
+// redacted replaces the secrets in the redacted copies of the configuration.
+const redacted = "[redacted]"
+
+// Bundle is the file the settings are exported to, for moving them to another machine.
+// Fields:
+// - Format: the version of the export format.
+// - GameVersion: the version of the game that exported the settings.
+// - ExportedAt: the time of the export.
+// - Settings: the settings, with the same keys as the configuration file.
+type Bundle struct {
+	Format      int                        `json:"format"`
+	GameVersion string                     `json:"game_version"`
+	ExportedAt  time.Time                  `json:"exported_at"`
+	Settings    map[string]json.RawMessage `json:"settings"`
+}
+
+// Export writes all settings except the machine-specific ones and the secrets to a file that can be imported
+// on another machine.
+//
+// Parameters:
+// - path (string): The path of the exported file; the directory is created if needed.
+// - gameVersion (string): The version of the game.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (c *Config) Export(path, gameVersion string) error {
+	settings, err := c.Portable()
+	if err != nil {
+		return err
+	}
+	return writeJSON(path, Bundle{Format: transferFormat, GameVersion: gameVersion, ExportedAt: time.Now(), Settings: settings})
+}
+
+// Portable returns all settings except the machine-specific ones and the secrets, with the same keys
+// as the configuration file, e.g. for exporting them or syncing them to another machine.
+//
+// Returns:
+// - map[string]json.RawMessage: The settings by key.
+// - error: An error if the settings cannot be encoded.
+func (c *Config) Portable() (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding settings: %w", err)
+	}
+	var settings map[string]json.RawMessage
+	if err = json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error encoding settings: %w", err)
+	}
+	for _, key := range slices.Concat(machineKeys, secretKeys) {
+		delete(settings, key)
+	}
+	return settings, nil
+}
+
+// Import merges the settings from an exported file into the configuration.
+//
+// The merge is safe: settings missing in the file keep their current values, the machine-specific settings
+// and the secrets are never changed, and settings unknown to this version of the game, e.g. added by a newer version,
+// are skipped and reported instead of failing the import. If any known setting has a value of a wrong type,
+// nothing is changed.
+//
+// Parameters:
+// - path (string): The path to the exported file.
+//
+// Returns:
+// - []string: The keys of the skipped unknown settings, sorted.
+// - error: An error if the file cannot be read, isn't an export, was exported in a newer format or has invalid values.
+func (c *Config) Import(path string) ([]string, error) {
+	var b Bundle
+	found, err := readJSON(path, &b)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("error importing settings: %s doesn't exist", path)
+	}
+	if b.Format < 1 || b.Settings == nil {
+		return nil, fmt.Errorf("error importing settings: %s isn't a settings export", path)
+	}
+	if b.Format > transferFormat {
+		return nil, fmt.Errorf("error importing settings from %s (game version %q): %w", path, b.GameVersion, ErrNewerFormat)
+	}
+	unknown, err := c.Merge(b.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("error importing settings from %s: %w", path, err)
+	}
+	return unknown, nil
+}
+
+// Merge merges the settings into the configuration, like Import: settings missing in the map keep their
+// current values, the machine-specific settings and the secrets are never changed, and settings unknown to this
+// version of the game are skipped and reported. If any known setting has a value of a wrong type, nothing is changed.
+//
+// Parameters:
+// - settings (map[string]json.RawMessage): The settings by key, as returned by Portable; the map is left unchanged.
+//
+// Returns:
+// - []string: The keys of the skipped unknown settings, sorted.
+// - error: An error if a setting has an invalid value.
+func (c *Config) Merge(settings map[string]json.RawMessage) ([]string, error) {
+	known := jsonKeys(reflect.TypeOf(*c))
+	var unknown []string
+	kept := make(map[string]json.RawMessage, len(settings))
+	for key, value := range settings {
+		switch {
+		case slices.Contains(machineKeys, key), slices.Contains(secretKeys, key):
+		case !slices.Contains(known, key):
+			unknown = append(unknown, key)
+		default:
+			kept[key] = value
+		}
+	}
+	slices.Sort(unknown)
+
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	merged := *c
+	if err = json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	*c = merged
+	return unknown, nil
+}
+
+// Redacted returns a copy of the configuration with the secrets replaced, e.g. for a crash report the player
+// may attach to a public issue. Empty secrets stay empty, so the copy still shows which secrets are set.
+func (c *Config) Redacted() Config {
+	r := *c
+	v := reflect.ValueOf(&r).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if field := v.Field(i); slices.Contains(secretKeys, name) && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+	return r
+}
+
+// jsonKeys returns the JSON keys of the fields of the struct type.
+func jsonKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
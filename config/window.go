@@ -0,0 +1,57 @@
+// Package config contains the user settings of the Snake game and the functions for loading and saving them.
+package config
+
+import "path/filepath"
+
+const windowFileName = "window.json"
+
+// Window holds the geometry of the game window in windowed mode, remembered between game sessions.
+// The fullscreen state is stored in the main configuration.
+// Fields:
+// - X, Y: the position of the window's top-left corner on the desktop.
+// - Width, Height: the size of the window.
+type Window struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// WindowPath returns the location of the window geometry file, which is stored alongside the configuration file.
+//
+// Parameters:
+// - dir (string): The data directory.
+//
+// Returns:
+// - string: The full path to the window geometry file.
+func WindowPath(dir string) string {
+	return filepath.Join(dir, windowFileName)
+}
+
+// LoadWindow reads the window geometry from the file at the given path.
+//
+// Parameters:
+// - path (string): The path to the window geometry file.
+//
+// Returns:
+// - *Window: The loaded geometry, or nil if the file doesn't exist.
+// - error: An error if the file exists but cannot be read or parsed.
+func LoadWindow(path string) (*Window, error) {
+	w := &Window{}
+	exists, err := readJSON(path, w)
+	if !exists || err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Save writes the window geometry to the file at the given path.
+//
+// Parameters:
+// - path (string): The path to the window geometry file.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (w *Window) Save(path string) error {
+	return writeJSON(path, w)
+}
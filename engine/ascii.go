@@ -0,0 +1,86 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The characters of the ASCII board drawn by State.String.
+const (
+	asciiEmpty  = '.'
+	asciiBody   = 'o'
+	asciiFood   = '*'
+	asciiDead   = 'X' // the head of a snake that has hit a wall
+	asciiCorner = '+'
+	asciiHWall  = '-'
+	asciiVWall  = '|'
+)
+
+// asciiHeads are the characters of the head, pointing where the snake moves on the screen; the engine's y axis
+// grows in its Up direction, which is down on the screen.
+var asciiHeads = map[Dir]byte{Up: 'v', Right: '>', Down: '^', Left: '<'}
+
+// screenNames are the names of the directions as seen on the screen.
+var screenNames = map[Dir]string{Up: "down", Right: "right", Down: "up", Left: "left"}
+
+// String draws the board as ASCII art, for tests, bug reports and debugging without a window: a line
+// with the tick, the score, the length, the food eaten, the speed, the direction and the next gust of wind
+// once it's announced, and the board
+// framed by walls, with the top row of the screen first. The head is an arrow pointing where the snake moves,
+// or X once it has hit a wall, the body is o, the food * and the empty cells are dots, or the characters
+// of their tiles (see Tile).
+//
+// Returns:
+// - string: The board, without a final newline.
+func (s State) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tick %d  score %d  length %d  food %d  speed %dms  heading %s",
+		s.Tick, s.Score, len(s.Snake), s.AteFood, s.Speed, screenNames[s.Direction])
+	if gust, ok := s.NextGust(); ok {
+		fmt.Fprintf(&b, "  gust %s in %d", screenNames[gust.Dir], gust.Tick-s.Tick)
+	}
+	if s.GameOver {
+		b.WriteString("  GAME OVER")
+	}
+	b.WriteByte('\n')
+
+	rows := make([][]byte, s.Cells)
+	for y := range rows {
+		rows[y] = []byte(strings.Repeat(string(asciiEmpty), s.Cells))
+		for x := range rows[y] {
+			if tile := s.Terrain.At(Point{X: float64(x), Y: float64(y)}); tile != Plain {
+				rows[y][x] = byte(tile)
+			}
+		}
+	}
+	set := func(p Point, c byte) {
+		if !CollidesWithWall(p, s.Cells) {
+			rows[int(p.Y)][int(p.X)] = c
+		}
+	}
+	set(s.Food, asciiFood)
+	for i := len(s.Snake) - 1; i > 0; i-- {
+		set(s.Snake[i], asciiBody)
+	}
+	if len(s.Snake) > 0 {
+		head, ok := asciiHeads[s.Direction]
+		if s.GameOver || !ok {
+			head = asciiDead
+		}
+		set(s.Snake[0], head)
+	}
+
+	border := string(asciiCorner) + strings.Repeat(string(asciiHWall), s.Cells) + string(asciiCorner)
+	b.WriteString(border)
+	for _, row := range rows {
+		b.WriteByte('\n')
+		b.WriteByte(asciiVWall)
+		b.Write(row)
+		b.WriteByte(asciiVWall)
+	}
+	b.WriteByte('\n')
+	b.WriteString(border)
+	return b.String()
+}
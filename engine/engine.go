@@ -0,0 +1,386 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	Cells      = 20  // the default number of cells along each side of the board
+	MinCells   = 5   // the smallest supported board
+	StartSpeed = 300 // the initial interval between two snake steps, in milliseconds
+	MinSpeed   = 60  // the speed cap: the shortest possible interval between two snake steps
+	SpeedStep  = 5   // how much the interval shrinks every time the snake eats food
+
+	AssistSpeed    = 200 // the speed cap with the beginner assist: the snake never gets faster than this
+	AssistSlowdown = 3   // how many times longer the tick lasts with the beginner assist when the snake is about to hit a wall
+)
+
+// Engine holds the complete state of a single game and advances it tick by tick.
+//
+// The engine doesn't depend on time or input devices: the caller decides when the next tick happens
+// and which directions are chosen, and all randomness comes from a generator seeded with a known seed,
+// so the same seed and the same inputs always lead to the same game.
+//
+// Engines don't share any state: every engine owns its snake and its random generator, so any number
+// of engines can run concurrently, e.g. in a tournament runner or a server. A single engine isn't safe
+// for concurrent use.
+// Fields:
+// - Snake: the snake controlled by the player.
+// - Food: the position of the food on the board.
+// - Score: the current score.
+// - AteFood: the number of food items eaten.
+// - Speed: the current interval between two snake steps, in milliseconds.
+// - GameOver: whether the game has ended.
+// - Tick: the number of steps played since the start of the game.
+// - Elapsed: the game time played since the start of the game: the sum of the intervals of all ticks played.
+// It's measured in game time, so it's exact and doesn't depend on the frame rate, the pauses or the load of the machine.
+// - Assist: whether the beginner assist is on: the speed is capped at AssistSpeed, and time slows down
+// when the snake is one cell away from a wall. A game played with the assist, even partly, is marked as assisted.
+// - Wind: whether gusts of wind blow every GustEvery ticks, pushing the snake one cell sideways; it should be set
+// before a game starts, since it's recorded in the replays only then.
+// - terrain: the special tiles of the board, see SetTerrain.
+// - stuck: the number of ticks the snake stays stuck in mud.
+type Engine struct {
+	Snake    *Snake
+	Food     Point
+	Score    int
+	AteFood  int
+	Speed    int
+	GameOver bool
+	Tick     int
+	Elapsed  time.Duration
+	Assist   bool
+	Wind     bool
+
+	seed     int64
+	cells    int
+	rng      *rand.Rand
+	draws    int
+	turned   bool
+	assisted bool
+	rewind   *rewind
+	terrain  Terrain
+	stuck    int
+}
+
+// StepResult describes what happened during a single tick.
+// Fields:
+// - Moved: the snake moved to a free cell.
+// - Ate: the snake ate food and grew.
+// - Cut: the snake bit itself and was shortened.
+// - Died: the snake hit a wall and the game ended during this tick.
+// - Stuck: the snake stayed stuck in mud during this tick.
+// - Trimmed: the snake lost its last segment on spikes.
+// - Pushed: a gust of wind pushed the snake one cell sideways instead of ahead.
+// - Won: the snake has filled the whole board, so there is no cell left for the food, and the game ended
+// with a win during this tick.
+type StepResult struct {
+	Moved   bool
+	Ate     bool
+	Cut     bool
+	Died    bool
+	Stuck   bool
+	Trimmed bool
+	Pushed  bool
+	Won     bool
+}
+
+// New creates a new engine with a game started from the given seed on a board of the default size.
+//
+// Parameters:
+// - seed (int64): The seed of the random generator that places the food.
+func New(seed int64) *Engine {
+	return NewSized(seed, Cells)
+}
+
+// NewSized creates a new engine with a game started from the given seed on a board of the given size.
+//
+// Parameters:
+// - seed (int64): The seed of the random generator that places the food.
+// - cells (int): The number of cells along each side of the board; boards smaller than MinCells are enlarged to it.
+func NewSized(seed int64, cells int) *Engine {
+	e := &Engine{Snake: NewSnake()}
+	e.ResetSized(seed, cells)
+	return e
+}
+
+// Reset starts a new game from the given seed on the board of the same size: the snake returns to its
+// starting position, the score, the food counter and the speed are reset, and the first food is placed.
+//
+// Parameters:
+// - seed (int64): The seed of the random generator that places the food.
+func (e *Engine) Reset(seed int64) {
+	e.ResetSized(seed, e.cells)
+}
+
+// ResetSized starts a new game from the given seed, like Reset, on a board of the given size.
+//
+// Parameters:
+// - seed (int64): The seed of the random generator that places the food.
+// - cells (int): The number of cells along each side of the board; boards smaller than MinCells are enlarged to it.
+func (e *Engine) ResetSized(seed int64, cells int) {
+	e.cells = max(cells, MinCells)
+	e.seed = seed
+	e.rng = rand.New(rand.NewSource(seed))
+	e.draws = 0
+	e.Snake.Reset()
+	e.Score = 0
+	e.AteFood = 0
+	e.Speed = StartSpeed
+	e.GameOver = false
+	e.Tick = 0
+	e.Elapsed = 0
+	e.turned = false
+	e.stuck = 0
+	e.assisted = e.Assist
+	e.clearRewind()
+	e.placeFood()
+}
+
+// Seed returns the seed the current game was started from.
+func (e *Engine) Seed() int64 {
+	return e.seed
+}
+
+// BoardSize returns the number of cells along each side of the board.
+func (e *Engine) BoardSize() int {
+	return e.cells
+}
+
+// Assisted reports whether the beginner assist has been on at any time during the current game.
+func (e *Engine) Assisted() bool {
+	return e.assisted || e.Assist
+}
+
+// Interval returns the time the current tick lasts at the current speed.
+//
+// With the beginner assist, the tick lasts AssistSlowdown times longer if the snake will hit a wall
+// on the next step, giving the player time to turn.
+func (e *Engine) Interval() time.Duration {
+	interval := time.Millisecond * time.Duration(e.Speed)
+	if e.Assist && !e.GameOver && CollidesWithWall(e.Snake.Direction.Exec(e.Snake.Head()), e.cells) {
+		interval *= AssistSlowdown
+	}
+	return interval
+}
+
+// Turn changes the direction of the snake for the next tick.
+//
+// The snake can't reverse (the new direction can't be opposite to the current one), and it can
+// turn only once per tick, so two quick key presses can't make it reverse either. It can't turn on ice.
+//
+// Parameters:
+// - dir (Dir): The new direction.
+//
+// Returns:
+// - bool: True if the direction has been changed, false if the turn has been rejected.
+func (e *Engine) Turn(dir Dir) bool {
+	if e.GameOver || e.turned || e.Snake.Direction.CheckParallel(dir) || !e.terrain.Turnable(e.Snake.Head()) {
+		return false
+	}
+	e.Snake.Direction = dir
+	e.turned = true
+	return true
+}
+
+// Step advances the game by one tick.
+//
+// The method performs the following tasks:
+// - Takes the snapshot of the rewind buffer, if one is due (see SetRewind).
+// - Adds the interval of the tick to the elapsed game time.
+// - Checks for collisions with walls, ending the game if necessary.
+// - Cuts off the snake's body if the snake bites itself, correcting the score according to the new size.
+// - Grows the snake, speeds the game up and places new food if the snake eats the food, or ends the game
+// with a win if the snake has filled the board.
+// - Otherwise moves the snake in its current direction, or sideways if a gust of wind blows (see NextGust).
+// - Applies the effect of the tile the head has entered (see Terrain); the snake doesn't move during the tick
+// after it has entered mud.
+//
+// Returns:
+// - StepResult: What happened during the tick. Nothing happens if the game is already over.
+func (e *Engine) Step() StepResult {
+	var res StepResult
+	if e.GameOver {
+		return res
+	}
+	e.autoSave()
+	e.Elapsed += e.Interval()
+	e.Tick++
+	e.turned = false
+	if e.Assist {
+		e.assisted = true
+		e.Speed = max(e.Speed, AssistSpeed)
+	}
+	if e.stuck > 0 {
+		e.stuck--
+		res.Stuck = true
+		return res
+	}
+	size := e.Snake.Size
+	heading := e.Snake.Direction
+	push, pushed := e.gust()
+	if pushed {
+		e.Snake.Direction = push
+	}
+	res = e.Snake.advance(e.Food, e.cells)
+	e.Snake.Direction = heading
+	res.Pushed = pushed
+	if res.Died {
+		e.GameOver = true
+		return res
+	}
+	if res.Cut {
+		e.Score = e.Score / size * e.Snake.Size //correct score according new snake size
+	}
+	if res.Ate {
+		e.AteFood += 1
+		e.Speed = max(e.Speed-SpeedStep, e.minSpeed())
+		e.Score += e.calculateScore(e.Snake.Head())
+		if !e.placeFood() {
+			e.GameOver = true
+			res.Won = true
+			return res
+		}
+	}
+	e.enterTile(&res)
+	return res
+}
+
+// advance moves the snake by one tick by the rules of the game: it dies on a wall, it's cut if it bites its body,
+// and it grows if it eats the food. The game itself, the score and the next food, is up to the caller.
+//
+// Parameters:
+// - food (Point): The position of the food.
+// - cells (int): The number of cells along each side of the board.
+//
+// Returns:
+// - StepResult: What happened to the snake; it doesn't move if it dies.
+func (s *Snake) advance(food Point, cells int) StepResult {
+	var res StepResult
+	newPos := s.Direction.Exec(s.Head())
+	if CollidesWithWall(newPos, cells) {
+		res.Died = true
+		return res
+	}
+	//we cut off the snake if there is a new position on its body
+	if s.CutIfSnake(newPos) {
+		s.Size = len(s.Parts)
+		res.Cut = true
+	}
+	//snakes move and eat food
+	if newPos == food {
+		s.Add(newPos)
+		s.Size++
+		res.Ate = true
+	} else {
+		s.Move(s.Direction)
+		res.Moved = true
+	}
+	return res
+}
+
+// minSpeed returns the shortest interval between two snake steps: the speed cap.
+func (e *Engine) minSpeed() int {
+	if e.Assist {
+		return AssistSpeed
+	}
+	return MinSpeed
+}
+
+// placeFood generates a new food position on the board.
+//
+// It randomly selects coordinates within the board and ensures
+// the position does not overlap with the snake's body. The new position is
+// stored in e.Food.
+//
+// Returns:
+// - bool: False if the snake fills the whole board, so there is no cell for the food; the food is left unchanged then.
+func (e *Engine) placeFood() bool {
+	if !hasFreeCell(e.cells, e.Snake.Parts) {
+		return false
+	}
+	for {
+		newPoint := Point{float64(e.intn(e.cells)), float64(e.intn(e.cells))}
+		if !e.Snake.IsSnake(newPoint) {
+			e.Food = newPoint
+			return true
+		}
+	}
+}
+
+// hasFreeCell reports whether any cell of the board is not taken by the snakes, so the food can be placed
+// without looping forever.
+//
+// Parameters:
+// - cells (int): The number of cells along each side of the board.
+// - bodies ([]Point): The parts of the snakes; the parts outside the board are ignored.
+func hasFreeCell(cells int, bodies ...[]Point) bool {
+	taken := 0
+	for _, parts := range bodies {
+		taken += len(parts)
+	}
+	if taken < cells*cells {
+		return true
+	}
+	// the parts may overlap, e.g. at the start of a game, so the taken cells are counted one by one
+	board := make([]bool, cells*cells)
+	free := len(board)
+	for _, parts := range bodies {
+		for _, p := range parts {
+			x, y := int(p.X), int(p.Y)
+			if x >= 0 && x < cells && y >= 0 && y < cells && !board[y*cells+x] {
+				board[y*cells+x] = true
+				free--
+			}
+		}
+	}
+	return free > 0
+}
+
+// intn draws a random number in the range [0, n) and counts the draw, so the state of the generator
+// can be restored from a snapshot.
+func (e *Engine) intn(n int) int {
+	e.draws++
+	return e.rng.Intn(n)
+}
+
+// calculateScore calculates the score based on the position of the food consumed by the snake.
+// The score is determined by the proximity of the food to the edges or corners of the game field,
+// with higher rewards for food closer to the corners and edges.
+//
+// Parameters:
+// - pos (Point): The position of the food that was consumed.
+//
+// Returns:
+// - int: The calculated score based on the food's position and the current game speed.
+//
+// Scoring logic:
+// - Food in the corners of the game field yields the highest score (multiplied by 4).
+// - Food on the edges but not in the corners yields a moderate score (multiplied by 2).
+// - Food elsewhere yields the base score (no multiplier).
+func (e *Engine) calculateScore(pos Point) int {
+	switch {
+	case pos.IsCorner(e.cells):
+		return 1000 / e.Speed * 4
+	case pos.IsEdge(e.cells):
+		return 1000 / e.Speed * 2
+	default:
+		return 1000 / e.Speed
+	}
+}
+
+// CollidesWithWall checks if the given position causes a collision with the game field boundaries.
+//
+// Parameters:
+// - pos (Point): The position to check for a boundary collision.
+// - cells (int): The number of cells along each side of the board.
+//
+// Returns:
+// - bool: True if the position is outside the game field boundaries, otherwise false.
+func CollidesWithWall(pos Point, cells int) bool {
+	size := float64(cells)
+	return pos.X < 0 || pos.X >= size || pos.Y < 0 || pos.Y >= size
+}
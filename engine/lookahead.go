@@ -0,0 +1,125 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"slices"
+)
+
+// EnterableAt returns the tick from which the head can enter the cell of a segment without biting the snake,
+// counting the next tick as 1, e.g. for searching paths around the body. The body moves on every tick,
+// but the bite is checked before it moves, so the head can follow the tail only into the cell the tail has just
+// left, never into the cell it's leaving.
+//
+// Parameters:
+// - i (int): The index of the segment, from 0 for the head.
+//
+// Returns:
+// - int: The first tick the head can move into the cell on, if the snake doesn't grow meanwhile.
+func (s State) EnterableAt(i int) int {
+	return len(s.Snake) - i + 1
+}
+
+// WillCollide reports whether the snake would hit a wall or bite its body within the next n ticks if it turned
+// in the given direction now and then went straight, e.g. for a bot or an assist checking a move.
+//
+// The ticks are played by the rules of Step on a copy of the snake: a turn the engine would reject, such as
+// reversing or turning on ice, keeps the current direction, and eating the food makes the snake grow. Where the next
+// food appears isn't known in advance, so the lookahead ignores it; it also ignores mud and spikes, which only delay
+// the snake or shorten it, and the gusts of wind.
+//
+// Parameters:
+// - dir (Dir): The direction to turn in.
+// - n (int): The number of ticks to look ahead.
+//
+// Returns:
+// - bool: True if the snake hits a wall or bites itself within n ticks; always true once the game is over.
+func (s State) WillCollide(dir Dir, n int) bool {
+	if s.GameOver {
+		return true
+	}
+	if len(s.Snake) == 0 {
+		return false
+	}
+	snake := Snake{Direction: s.Direction, Parts: slices.Clone(s.Snake), Size: s.Size}
+	if !s.Turned && !snake.Direction.CheckParallel(dir) && s.Terrain.Turnable(s.Snake[0]) {
+		snake.Direction = dir
+	}
+	food := s.Food
+	for range n {
+		res := snake.advance(food, s.Cells)
+		if res.Died || res.Cut {
+			return true
+		}
+		if res.Ate {
+			food = Point{X: -1, Y: -1}
+		}
+	}
+	return false
+}
+
+// ReachableCells returns the cells the snake could get to from a cell by the shortest safe way, starting
+// on the next tick: moving one cell per tick, never through a wall and never into a part of the body before
+// the body has left it (see EnterableAt). The number of cells reachable from the cell ahead of the head
+// tells, for example, whether a move leads into a dead end too small for the snake.
+//
+// Parameters:
+// - from (Point): The cell to start from, usually the head of the snake or a cell next to it.
+//
+// Returns:
+// - []Point: The reachable cells in the order of their distance from from, without from itself.
+func (s State) ReachableCells(from Point) []Point {
+	if CollidesWithWall(from, s.Cells) {
+		return nil
+	}
+	enterable := make(map[Point]int, len(s.Snake))
+	for i, p := range s.Snake {
+		if p != from {
+			enterable[p] = max(enterable[p], s.EnterableAt(i))
+		}
+	}
+	seen := map[Point]bool{from: true}
+	var cells []Point
+	frontier := []Point{from}
+	for tick := 1; len(frontier) > 0; tick++ {
+		var next []Point
+		for _, p := range frontier {
+			for _, dir := range []Dir{Up, Right, Down, Left} {
+				n := dir.Exec(p)
+				if seen[n] || CollidesWithWall(n, s.Cells) || enterable[n] > tick {
+					continue
+				}
+				seen[n] = true
+				next = append(next, n)
+			}
+		}
+		cells = append(cells, next...)
+		frontier = next
+	}
+	return cells
+}
+
+// WillCollide reports whether the snake would hit a wall or bite its body within the next n ticks if it turned
+// in the given direction now and then went straight; see State.WillCollide.
+//
+// Parameters:
+// - dir (Dir): The direction to turn in.
+// - n (int): The number of ticks to look ahead.
+//
+// Returns:
+// - bool: True if the snake hits a wall or bites itself within n ticks.
+func (e *Engine) WillCollide(dir Dir, n int) bool {
+	return e.Snapshot().WillCollide(dir, n)
+}
+
+// ReachableCells returns the cells the snake could get to from a cell, starting on the next tick;
+// see State.ReachableCells.
+//
+// Parameters:
+// - from (Point): The cell to start from.
+//
+// Returns:
+// - []Point: The reachable cells in the order of their distance from from.
+func (e *Engine) ReachableCells(from Point) []Point {
+	return e.Snapshot().ReachableCells(from)
+}
@@ -0,0 +1,126 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"errors"
+)
+
+// rewind is a ring of snapshots of the game, the newest last, for going back in time.
+// Fields:
+// - every: how many ticks apart the automatic snapshots are taken.
+// - states: the ring; its length is the capacity of the buffer.
+// - next: the index the next snapshot is stored at.
+// - count: the number of snapshots stored.
+type rewind struct {
+	every  int
+	states []State
+	next   int
+	count  int
+}
+
+// push stores a snapshot, replacing the oldest one once the ring is full.
+func (r *rewind) push(s State) {
+	r.states[r.next] = s
+	r.next = (r.next + 1) % len(r.states)
+	r.count = min(r.count+1, len(r.states))
+}
+
+// index returns the index in the ring of the snapshot taken back snapshots ago, from 1 for the newest one.
+func (r *rewind) index(back int) int {
+	return (r.next - back + len(r.states)) % len(r.states)
+}
+
+// SetRewind configures the rewind buffer: a ring of snapshots of the game, taken automatically before a tick is
+// played, every tick or every few ticks, which the game can be rewound to with LoadState. Snapshots can also be
+// added with SaveState. The buffer is off until it's configured; configuring it again empties it, and so does
+// starting a new game.
+//
+// Parameters:
+// - size (int): The number of snapshots kept; the oldest ones are dropped. 0 or less turns the buffer off.
+// - every (int): How many ticks apart the automatic snapshots are taken: 1 for every tick; 0 or less to take
+// them only with SaveState.
+func (e *Engine) SetRewind(size, every int) {
+	if size <= 0 {
+		e.rewind = nil
+		return
+	}
+	e.rewind = &rewind{every: every, states: make([]State, size)}
+}
+
+// SaveState adds a snapshot of the current state to the rewind buffer, e.g. when the player reaches a moment
+// worth returning to.
+//
+// Returns:
+// - State: The snapshot; it's returned even if the rewind buffer is off, but not stored then.
+func (e *Engine) SaveState() State {
+	s := e.Snapshot()
+	if e.rewind != nil {
+		e.rewind.push(s)
+	}
+	return s
+}
+
+// LoadState rewinds the game to a snapshot of the rewind buffer. The snapshot and all newer ones are removed
+// from the buffer, so rewinding by 1 again goes further back.
+//
+// Parameters:
+// - back (int): Which snapshot to rewind to, counted from 1 for the newest one.
+//
+// Returns:
+// - State: The state the game has been rewound to.
+// - error: An error if the buffer is off or doesn't hold that many snapshots; the game is left unchanged then.
+func (e *Engine) LoadState(back int) (State, error) {
+	s, ok := e.StateAt(back)
+	if !ok {
+		return State{}, errors.New("no such snapshot in the rewind buffer")
+	}
+	if err := e.restore(s); err != nil {
+		return State{}, err
+	}
+	r := e.rewind
+	for range back {
+		r.next = r.index(1)
+		r.states[r.next] = State{}
+		r.count--
+	}
+	return s, nil
+}
+
+// StateAt returns a snapshot of the rewind buffer without rewinding to it, e.g. for a debugger stepping
+// through the latest ticks.
+//
+// Parameters:
+// - back (int): Which snapshot to return, counted from 1 for the newest one.
+//
+// Returns:
+// - State: The snapshot.
+// - bool: False if the buffer is off or doesn't hold that many snapshots.
+func (e *Engine) StateAt(back int) (State, bool) {
+	if e.rewind == nil || back < 1 || back > e.rewind.count {
+		return State{}, false
+	}
+	return e.rewind.states[e.rewind.index(back)], true
+}
+
+// SavedStates returns the number of snapshots in the rewind buffer.
+func (e *Engine) SavedStates() int {
+	if e.rewind == nil {
+		return 0
+	}
+	return e.rewind.count
+}
+
+// autoSave takes the automatic snapshot of the rewind buffer before a tick is played, if one is due.
+func (e *Engine) autoSave() {
+	if r := e.rewind; r != nil && r.every > 0 && e.Tick%r.every == 0 {
+		r.push(e.Snapshot())
+	}
+}
+
+// clearRewind empties the rewind buffer, keeping its configuration.
+func (e *Engine) clearRewind() {
+	if e.rewind != nil {
+		e.SetRewind(len(e.rewind.states), e.rewind.every)
+	}
+}
@@ -1,5 +1,6 @@
-// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
-package game
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
 
 import (
 	"slices"
@@ -121,12 +122,13 @@ func (s *Snake) Tail() Point {
 // The snake's size is updated as the parts are added to the snake's body.
 //
 // Side Effects:
-//   - Resets the snake's parts to a new slice of length 0.
+//   - Resets the snake's parts to a new slice of length 0 and its size to 0.
 //   - Sets the snake's direction to "right".
 //   - Initializes the snake's body at a starting position with a default length of 3.
 func (s *Snake) Reset() {
 	s.Parts = []Point{}
-	s.Direction = right
+	s.Size = 0
+	s.Direction = Right
 	x, y, length := 1, 1, 3 //snake position and length
 	for i := length - 1; i >= 0; i-- {
 		s.Parts = append(s.Parts, Point{float64(x + i), float64(y)})
@@ -145,7 +147,7 @@ func (s *Snake) Reset() {
 //
 // Parameters:
 //   - directional (Dir): The direction in which the snake should move. This can be one of
-//     the constants up, down, left, or right.
+//     the constants Up, Down, Left, or Right.
 func (s *Snake) Move(directional Dir) {
 	lastPoint := s.Parts[0]
 	s.Parts[0] = directional.Exec(s.Parts[0])
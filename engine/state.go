@@ -0,0 +1,129 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// maxDraws is the largest number of random values a snapshot may have drawn: far more than placing the food
+// of a whole game on the largest board takes, but few enough for restoring a crafted snapshot to be quick.
+const maxDraws = 1 << 22
+
+// State is a snapshot of the complete state of a game, which can be saved and restored later.
+//
+// The random generator can't be copied, so its state is described by the seed and the number of values
+// drawn from it: restoring the state draws the same values again, and the restored game continues
+// exactly like the original one would.
+// Fields:
+// - Seed: the seed the game was started from.
+// - Draws: the number of values drawn from the random generator.
+// - Cells: the number of cells along each side of the board.
+// - Snake: the positions of the snake's segments, the head first.
+// - Size: the size of the snake.
+// - Direction: the direction the snake moves in.
+// - Food: the position of the food.
+// - Score, AteFood, Speed, Tick, Elapsed, GameOver: the same as in Engine.
+// - Turned: whether the snake has already turned during the current tick.
+// - Assist, Assisted: whether the beginner assist is on, and whether it has been on during the game.
+// - Wind: whether gusts of wind blow, the same as in Engine.
+// - Terrain: the special tiles of the board, shared with the engine.
+// - Stuck: the number of ticks the snake stays stuck in mud.
+type State struct {
+	Seed      int64         `json:"seed"`
+	Draws     int           `json:"draws"`
+	Cells     int           `json:"cells"`
+	Snake     []Point       `json:"snake"`
+	Size      int           `json:"size"`
+	Direction Dir           `json:"direction"`
+	Food      Point         `json:"food"`
+	Score     int           `json:"score"`
+	AteFood   int           `json:"ate_food"`
+	Speed     int           `json:"speed"`
+	Tick      int           `json:"tick"`
+	Elapsed   time.Duration `json:"elapsed,omitempty"`
+	GameOver  bool          `json:"game_over"`
+	Turned    bool          `json:"turned"`
+	Assist    bool          `json:"assist"`
+	Assisted  bool          `json:"assisted"`
+	Wind      bool          `json:"wind,omitempty"`
+	Terrain   Terrain       `json:"terrain,omitempty"`
+	Stuck     int           `json:"stuck,omitempty"`
+}
+
+// Snapshot returns the current state of the game.
+func (e *Engine) Snapshot() State {
+	return State{
+		Seed:      e.seed,
+		Draws:     e.draws,
+		Cells:     e.cells,
+		Snake:     slices.Clone(e.Snake.Parts),
+		Size:      e.Snake.Size,
+		Direction: e.Snake.Direction,
+		Food:      e.Food,
+		Score:     e.Score,
+		AteFood:   e.AteFood,
+		Speed:     e.Speed,
+		Tick:      e.Tick,
+		Elapsed:   e.Elapsed,
+		GameOver:  e.GameOver,
+		Turned:    e.turned,
+		Assist:    e.Assist,
+		Assisted:  e.assisted,
+		Wind:      e.Wind,
+		Terrain:   e.terrain,
+		Stuck:     e.stuck,
+	}
+}
+
+// Restore replaces the state of the game with a snapshot taken by Snapshot. The game continues from another
+// moment, so the rewind buffer is emptied.
+//
+// Parameters:
+// - s (State): The snapshot.
+//
+// Returns:
+// - error: An error if the snapshot is invalid; the game is left unchanged then.
+func (e *Engine) Restore(s State) error {
+	if err := e.restore(s); err != nil {
+		return err
+	}
+	e.clearRewind()
+	return nil
+}
+
+// restore replaces the state of the game with a snapshot, like Restore, keeping the rewind buffer.
+func (e *Engine) restore(s State) error {
+	//the size divides the score when the snake is cut, so a state without one would crash the next step
+	if s.Cells < MinCells || len(s.Snake) == 0 || s.Size < 1 || s.Size < len(s.Snake) || s.Draws < 0 ||
+		s.Draws > maxDraws || s.Speed <= 0 || s.Stuck < 0 || s.Terrain.Validate() != nil {
+		return errors.New("invalid game state")
+	}
+	e.seed = s.Seed
+	e.cells = s.Cells
+	e.rng = rand.New(rand.NewSource(s.Seed))
+	e.draws = 0
+	for e.draws < s.Draws {
+		e.intn(e.cells)
+	}
+	e.Snake.Parts = slices.Clone(s.Snake)
+	e.Snake.Size = s.Size
+	e.Snake.Direction = s.Direction
+	e.Food = s.Food
+	e.Score = s.Score
+	e.AteFood = s.AteFood
+	e.Speed = s.Speed
+	e.Tick = s.Tick
+	e.Elapsed = s.Elapsed
+	e.GameOver = s.GameOver
+	e.turned = s.Turned
+	e.Assist = s.Assist
+	e.assisted = s.Assisted
+	e.Wind = s.Wind
+	e.terrain = s.Terrain
+	e.stuck = s.Stuck
+	return nil
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	valid := NewSized(7, 10).Snapshot()
+	valid.Snake = []Point{{X: 5, Y: 5}, {X: 5, Y: 4}, {X: 5, Y: 3}}
+	valid.Size = 3
+	tests := []struct {
+		name   string
+		change func(s *State)
+		valid  bool
+	}{
+		{name: "valid", change: func(s *State) {}, valid: true},
+		{name: "terrain", change: func(s *State) { s.Terrain = Terrain{"..~", "%!"} }, valid: true},
+		{name: "board too small", change: func(s *State) { s.Cells = MinCells - 1 }},
+		{name: "no snake", change: func(s *State) { s.Snake = nil }},
+		{name: "no size", change: func(s *State) { s.Size = 0 }},
+		{name: "size below the length", change: func(s *State) { s.Size = 2 }},
+		{name: "negative draws", change: func(s *State) { s.Draws = -1 }},
+		{name: "too many draws", change: func(s *State) { s.Draws = maxDraws + 1 }},
+		{name: "no speed", change: func(s *State) { s.Speed = 0 }},
+		{name: "negative stuck", change: func(s *State) { s.Stuck = -1 }},
+		{name: "unknown tile", change: func(s *State) { s.Terrain = Terrain{"..x"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			s.Snake = append([]Point(nil), valid.Snake...)
+			tt.change(&s)
+			e := NewSized(1, 20)
+			before := e.Snapshot()
+			err := e.Restore(s)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Restore() error = %v, want valid %v", err, tt.valid)
+			}
+			want := s
+			if err != nil {
+				want = before
+			}
+			if got := e.Snapshot(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Snapshot() after Restore() = %+v, want %+v", got, want)
+			}
+			e.Step() //a restored game must go on without crashing
+		})
+	}
+}
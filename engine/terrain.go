@@ -0,0 +1,148 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tile is the kind of a cell of the board, which may affect the snake moving over it.
+// Its value is the character of the tile in the rows of a Terrain.
+type Tile byte
+
+// The kinds of tiles.
+const (
+	Plain  Tile = '.' // an ordinary cell
+	Ice    Tile = '~' // the snake can't turn while its head is on ice
+	Mud    Tile = '%' // the snake is stuck for one tick after its head enters mud
+	Spikes Tile = '!' // the snake loses its last segment when its head enters spikes
+)
+
+// TileNames are the kinds of tiles by name, e.g. for the commands laying them.
+var TileNames = map[string]Tile{"plain": Plain, "ice": Ice, "mud": Mud, "spikes": Spikes}
+
+// tileHook is the effect of a kind of tile on the snake moving over it.
+// Fields:
+// - noTurn: the snake can't turn while its head is on the tile.
+// - enter: called when the head of the snake has entered the tile during a tick, to apply the effect of the tile;
+// nil if entering the tile has no effect.
+type tileHook struct {
+	noTurn bool
+	enter  func(e *Engine, res *StepResult)
+}
+
+// tileHooks are the effects of the tiles, by kind; plain cells have none.
+var tileHooks = map[Tile]tileHook{
+	Ice: {noTurn: true},
+	Mud: {enter: func(e *Engine, res *StepResult) {
+		e.stuck = 1
+	}},
+	Spikes: {enter: func(e *Engine, res *StepResult) {
+		if len(e.Snake.Parts) < 2 {
+			return
+		}
+		size := e.Snake.Size
+		e.Snake.Parts = e.Snake.Parts[:len(e.Snake.Parts)-1]
+		e.Snake.Size = len(e.Snake.Parts)
+		e.Score = e.Score / size * e.Snake.Size //correct score according new snake size
+		res.Trimmed = true
+	}},
+}
+
+// Terrain is the layout of the special tiles of a board, e.g. of a level: the rows of the board from the top one
+// of the screen, with a character of a Tile for every cell. Missing rows and the cells past the end of a short row
+// are plain, so an empty Terrain is an ordinary board.
+//
+// A Terrain doesn't change during a game, so snapshots of the game share it.
+type Terrain []string
+
+// At returns the tile of a cell.
+//
+// Parameters:
+// - p (Point): The cell.
+//
+// Returns:
+// - Tile: The tile; Plain for the cells the terrain doesn't cover.
+func (t Terrain) At(p Point) Tile {
+	x, y := int(p.X), int(p.Y)
+	if y < 0 || y >= len(t) || x < 0 || x >= len(t[y]) {
+		return Plain
+	}
+	return Tile(t[y][x])
+}
+
+// Turnable reports whether the snake can turn while its head is in the cell.
+//
+// Parameters:
+// - p (Point): The cell of the head.
+func (t Terrain) Turnable(p Point) bool {
+	return !tileHooks[t.At(p)].noTurn
+}
+
+// Validate checks that the terrain has only known tiles.
+//
+// Returns:
+// - error: An error naming the first unknown tile; otherwise, nil.
+func (t Terrain) Validate() error {
+	for y, row := range t {
+		for x := range len(row) {
+			if _, ok := tileHooks[Tile(row[x])]; !ok && Tile(row[x]) != Plain {
+				return fmt.Errorf("unknown tile %q at (%d, %d), expected one of %q", row[x], x, y, ".~%!")
+			}
+		}
+	}
+	return nil
+}
+
+// With returns a copy of the terrain with a cell changed, e.g. for building a level.
+//
+// Parameters:
+// - p (Point): The cell; it must not be negative.
+// - tile (Tile): The new tile of the cell.
+//
+// Returns:
+// - Terrain: The new terrain; the terrain itself is left unchanged, since snapshots may share it.
+func (t Terrain) With(p Point, tile Tile) Terrain {
+	x, y := int(p.X), int(p.Y)
+	rows := make(Terrain, max(len(t), y+1))
+	copy(rows, t)
+	row := []byte(rows[y])
+	if len(row) <= x {
+		row = append(row, []byte(strings.Repeat(string(Plain), x+1-len(row)))...)
+	}
+	row[x] = byte(tile)
+	rows[y] = string(row)
+	return rows
+}
+
+// SetTerrain lays the special tiles of a level on the board. The terrain is kept for the following games
+// until it's changed; nil makes the board plain.
+//
+// Parameters:
+// - t (Terrain): The terrain; the cells outside the board are ignored.
+//
+// Returns:
+// - error: An error if the terrain has unknown tiles; the terrain is left unchanged then.
+func (e *Engine) SetTerrain(t Terrain) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("error setting terrain: %w", err)
+	}
+	e.terrain = t
+	return nil
+}
+
+// Terrain returns the special tiles of the board.
+func (e *Engine) Terrain() Terrain {
+	return e.terrain
+}
+
+// enterTile applies the effect of the tile the head of the snake has entered during the tick.
+//
+// Parameters:
+// - res (*StepResult): What has happened during the tick, updated with the effect.
+func (e *Engine) enterTile(res *StepResult) {
+	if hook := tileHooks[e.terrain.At(e.Snake.Head())]; hook.enter != nil {
+		hook.enter(e, res)
+	}
+}
@@ -0,0 +1,210 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	Players       = 2               // the number of snakes in a versus game
+	VersusSpeed   = 150             // the default interval between two steps of a versus game, in milliseconds
+	VersusTimeout = 3 * time.Minute // the longest versus game; the longer snake wins when the time is up
+	versusLength  = 3               // the starting length of the snakes in a versus game
+)
+
+// Draw is the Winner of a versus game that no snake has survived.
+const Draw = -1
+
+// Versus holds the state of a head-to-head game: two snakes on a shared board, racing for the same food.
+//
+// A snake dies when it hits a wall or the other snake; two heads meeting in the same cell kill both snakes.
+// A snake biting itself is shortened, like in the single-player game. The last snake alive wins; if both snakes
+// die in the same tick, or the time is up with snakes of the same length, the game is a draw.
+// The game runs at a constant speed, so both players have the same time to react.
+//
+// Like Engine, Versus doesn't depend on time or input devices, and it isn't safe for concurrent use.
+// Fields:
+// - Snakes: the snakes of the players, indexed by player.
+// - Alive: whether the snakes are alive.
+// - Food: the position of the food on the board.
+// - Tick: the number of steps played since the start of the game.
+// - Over: whether the game has ended.
+// - Winner: the index of the winning player, or Draw; valid once the game is over.
+// - Speed: the interval between two steps, in milliseconds.
+type Versus struct {
+	Snakes [Players]*Snake
+	Alive  [Players]bool
+	Food   Point
+	Tick   int
+	Over   bool
+	Winner int
+	Speed  int
+
+	cells  int
+	rng    *rand.Rand
+	turned [Players]bool
+}
+
+// NewVersus creates a versus game started from the given seed on a board of the given size.
+// The snakes start in the opposite corners of the board, heading towards each other.
+//
+// Parameters:
+// - seed (int64): The seed of the random generator that places the food.
+// - cells (int): The number of cells along each side of the board; boards smaller than MinCells are enlarged to it.
+// - speed (int): The interval between two steps in milliseconds, clamped between MinSpeed and StartSpeed.
+func NewVersus(seed int64, cells, speed int) *Versus {
+	cells = max(cells, MinCells)
+	last := cells - 2
+	v := &Versus{
+		Snakes: [Players]*Snake{
+			newSnakeAt(Point{versusLength, 1}, Right),
+			newSnakeAt(Point{float64(last - versusLength + 1), float64(last)}, Left),
+		},
+		Alive: [Players]bool{true, true},
+		Speed: min(max(speed, MinSpeed), StartSpeed),
+		cells: cells,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+	v.placeFood()
+	return v
+}
+
+// newSnakeAt creates a snake of the starting length with the head at the given position, moving in the given direction.
+func newSnakeAt(head Point, dir Dir) *Snake {
+	s := &Snake{Direction: dir}
+	back := Right
+	if dir == Right {
+		back = Left
+	}
+	for p, i := head, 0; i < versusLength; p, i = back.Exec(p), i+1 {
+		s.Parts = append(s.Parts, p)
+	}
+	s.Size = len(s.Parts)
+	return s
+}
+
+// BoardSize returns the number of cells along each side of the board.
+func (v *Versus) BoardSize() int {
+	return v.cells
+}
+
+// Interval returns the time a tick lasts.
+func (v *Versus) Interval() time.Duration {
+	return time.Duration(v.Speed) * time.Millisecond
+}
+
+// Turn changes the direction of a player's snake for the next tick. Like in the single-player game,
+// a snake can't reverse and can turn only once per tick.
+//
+// Parameters:
+// - player (int): The index of the player.
+// - dir (Dir): The new direction.
+//
+// Returns:
+// - bool: True if the direction has been changed, false if the turn has been rejected.
+func (v *Versus) Turn(player int, dir Dir) bool {
+	if player < 0 || player >= Players || dir < Up || dir > Left {
+		return false
+	}
+	s := v.Snakes[player]
+	if v.Over || !v.Alive[player] || v.turned[player] || s.Direction.CheckParallel(dir) {
+		return false
+	}
+	s.Direction = dir
+	v.turned[player] = true
+	return true
+}
+
+// Step advances the game by one tick: all snakes move at the same time, then the collisions are resolved.
+//
+// Returns:
+// - bool: Whether the game has ended during this tick.
+func (v *Versus) Step() bool {
+	if v.Over {
+		return false
+	}
+	v.Tick++
+	v.turned = [Players]bool{}
+	ate := false
+	for i, s := range v.Snakes {
+		if !v.Alive[i] {
+			continue
+		}
+		res := s.advance(v.Food, v.cells)
+		if res.Died {
+			v.Alive[i] = false
+			continue
+		}
+		ate = ate || res.Ate
+	}
+	//a head in the other snake, including its head, kills the snake; both are checked before anyone is removed
+	var hit [Players]bool
+	for i, s := range v.Snakes {
+		other := v.Snakes[1-i]
+		hit[i] = v.Alive[i] && v.Alive[1-i] && other.IsSnake(s.Head())
+	}
+	for i := range v.Snakes {
+		if hit[i] {
+			v.Alive[i] = false
+		}
+	}
+	full := ate && !v.placeFood()
+	v.finish(full)
+	return v.Over
+}
+
+// finish ends the game once at most one snake is alive, the time is up or the board is full, and decides
+// the winner.
+//
+// Parameters:
+// - full (bool): Whether the snakes fill the whole board, leaving no cell for the food.
+func (v *Versus) finish(full bool) {
+	switch {
+	case v.Alive[0] && v.Alive[1] && !full && v.Tick < int(VersusTimeout/v.Interval()):
+		return
+	case v.Alive[0] && v.Alive[1] && v.Snakes[0].Len() > v.Snakes[1].Len():
+		v.Winner = 0
+	case v.Alive[0] && v.Alive[1] && v.Snakes[0].Len() < v.Snakes[1].Len():
+		v.Winner = 1
+	case v.Alive[0] && v.Alive[1]:
+		v.Winner = Draw
+	case v.Alive[0]:
+		v.Winner = 0
+	case v.Alive[1]:
+		v.Winner = 1
+	default:
+		v.Winner = Draw
+	}
+	v.Over = true
+}
+
+// placeFood places new food on a random cell that isn't taken by a living snake.
+//
+// Returns:
+// - bool: False if the living snakes fill the whole board; the food is left unchanged then.
+func (v *Versus) placeFood() bool {
+	var bodies [][]Point
+	for i, s := range v.Snakes {
+		if v.Alive[i] {
+			bodies = append(bodies, s.Parts)
+		}
+	}
+	if !hasFreeCell(v.cells, bodies...) {
+		return false
+	}
+	for {
+		p := Point{float64(v.rng.Intn(v.cells)), float64(v.rng.Intn(v.cells))}
+		free := true
+		for i, s := range v.Snakes {
+			if v.Alive[i] && s.IsSnake(p) {
+				free = false
+			}
+		}
+		if free {
+			v.Food = p
+			return true
+		}
+	}
+}
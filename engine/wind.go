@@ -0,0 +1,89 @@
+// Package engine contains the rules of the Snake game: the board geometry, the snake and the game state,
+// independent of rendering and input, so the game can be simulated headlessly.
+package engine
+
+const (
+	GustEvery   = 40 // the number of ticks between two gusts of wind
+	GustWarning = 8  // the number of ticks a gust is announced in advance, see NextGust
+)
+
+// Gust is a gust of wind announced by NextGust.
+// Fields:
+// - Dir: the direction the wind blows in.
+// - Tick: the tick the gust blows on, counted like Engine.Tick: it pushes the snake during the step that makes
+// the tick count reach it.
+type Gust struct {
+	Dir  Dir
+	Tick int
+}
+
+// gustAt returns the gust blowing on a tick of a game with wind, if any. The gusts blow every GustEvery ticks,
+// and their directions are derived from the seed of the game, so they're the same in a replay and unknown
+// in advance beyond the next one.
+//
+// Parameters:
+// - seed (int64): The seed of the game.
+// - tick (int): The tick.
+//
+// Returns:
+// - Dir: The direction of the gust.
+// - bool: Whether a gust blows on the tick.
+func gustAt(seed int64, tick int) (Dir, bool) {
+	if tick <= 0 || tick%GustEvery != 0 {
+		return 0, false
+	}
+	// splitmix64 of the seed and the number of the gust
+	z := uint64(seed) + uint64(tick/GustEvery)*0x9E3779B97F4A7C15
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return Dir(z % 4), true
+}
+
+// NextGust returns the next gust of wind once it's announced: during the last GustWarning ticks before it blows,
+// so the player can see it coming, e.g. as an arrow on the screen.
+//
+// Returns:
+// - Gust: The gust.
+// - bool: False if the wind is off, the game is over or no gust is announced yet.
+func (s State) NextGust() (Gust, bool) {
+	if !s.Wind || s.GameOver {
+		return Gust{}, false
+	}
+	for tick := s.Tick + 1; tick <= s.Tick+GustWarning; tick++ {
+		if dir, ok := gustAt(s.Seed, tick); ok {
+			return Gust{Dir: dir, Tick: tick}, true
+		}
+	}
+	return Gust{}, false
+}
+
+// NextGust returns the next gust of wind once it's announced; see State.NextGust.
+//
+// Returns:
+// - Gust: The gust.
+// - bool: False if no gust is announced.
+func (e *Engine) NextGust() (Gust, bool) {
+	if !e.Wind || e.GameOver {
+		return Gust{}, false
+	}
+	return State{Seed: e.seed, Tick: e.Tick, Wind: true}.NextGust()
+}
+
+// gust returns the direction the snake is pushed in during the current tick by a gust of wind, if any.
+// A gust pushes the snake only sideways, not along its way, and not into a wall: the snake then goes on as usual.
+//
+// Returns:
+// - Dir: The direction of the push.
+// - bool: Whether the snake is pushed.
+func (e *Engine) gust() (Dir, bool) {
+	if !e.Wind {
+		return 0, false
+	}
+	dir, ok := gustAt(e.seed, e.Tick)
+	if !ok || dir == e.Snake.Direction || dir.CheckParallel(e.Snake.Direction) ||
+		CollidesWithWall(dir.Exec(e.Snake.Head()), e.cells) {
+		return 0, false
+	}
+	return dir, true
+}
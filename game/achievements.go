@@ -0,0 +1,184 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/achievements"
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	toastTime  = 3 * time.Second        // how long an achievement toast stays on the screen
+	toastSlide = 300 * time.Millisecond // how long a toast takes to slide in and out
+	toastW     = 320.0                  // the width of a toast
+	toastH     = 56.0                   // the height of a toast
+	badgeR     = 14.0                   // the radius of a badge on the score screens
+)
+
+// checkAchievements unlocks the achievements reached during the tick, saves them and publishes
+// an event for every newly unlocked one. Assisted games don't unlock achievements.
+//
+// It's called by the game logic goroutine after every tick.
+//
+// Parameters:
+// - res (engine.StepResult): What happened during the tick.
+func (g *Game) checkAchievements(res engine.StepResult) {
+	if g.eng.Assisted() {
+		return
+	}
+	head := g.eng.Snake.Head()
+	unlocked := g.achievements.Check(achievements.Progress{
+		Score:       g.eng.Score,
+		Length:      len(g.eng.Snake.Parts),
+		Food:        g.eng.AteFood,
+		Ticks:       g.eng.Tick,
+		Cut:         res.Cut,
+		AteInCorner: res.Ate && head.IsCorner(g.eng.BoardSize()),
+	})
+	if len(unlocked) == 0 {
+		return
+	}
+	if g.dataDir != "" {
+		if err := g.achievements.Save(g.dataDir); err != nil {
+			log.Println(err)
+		}
+	}
+	for i := range unlocked {
+		g.events.publish(event{kind: eventUnlocked, pos: head, speed: g.eng.Speed, cells: g.eng.BoardSize(),
+			achievement: &unlocked[i]})
+	}
+}
+
+// toastOverlay is a HUD widget that announces the achievements unlocked during the game with toasts
+// sliding in at the top of the game area, one at a time.
+// Fields:
+// - mu: guards queue, which is filled by the game logic goroutine.
+// - queue: the achievements waiting to be shown, the oldest first.
+// - age: the time the first achievement of the queue has been shown for.
+type toastOverlay struct {
+	mu    sync.Mutex
+	queue []*achievements.Achievement
+	age   time.Duration
+}
+
+// newToastOverlay creates an empty toast overlay.
+func newToastOverlay() *toastOverlay {
+	return &toastOverlay{}
+}
+
+// handleEvent queues the toast of an unlocked achievement.
+func (t *toastOverlay) handleEvent(e event) {
+	if e.kind != eventUnlocked {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queue = append(t.queue, e.achievement)
+}
+
+// layout does nothing: the toasts are placed relative to the game area when they're drawn.
+func (t *toastOverlay) layout(g *Game) {}
+
+// update advances the shown toast and moves on to the next one when its time is over.
+func (t *toastOverlay) update(g *Game, dt time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) == 0 {
+		return
+	}
+	t.age += dt
+	if t.age >= toastTime {
+		t.queue = t.queue[1:]
+		t.age = 0
+	}
+}
+
+// draw renders the shown toast: the badge of the achievement, its name and its description.
+// The toast slides in and out, unless the reduced motion mode is on.
+func (t *toastOverlay) draw(g *Game) {
+	t.mu.Lock()
+	if len(t.queue) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	a, age := t.queue[0], t.age
+	t.mu.Unlock()
+
+	centerX := g.gameAreaSP.X + g.param.gameW/2
+	g.beginUI(centerX, g.gameAreaSP.Y)
+	defer g.endUI()
+	y := g.gameAreaSP.Y + 12
+	if !g.cfg.ReducedMotion {
+		shown := math.Min(float64(age), float64(toastTime-age)) / float64(toastSlide)
+		y -= (toastH + 12) * (1 - math.Min(shown, 1))
+	}
+	x := centerX - toastW/2
+	g.cv.SetFillStyle(0, 0, 0, 0.8)
+	g.cv.FillRect(x, y, toastW, toastH)
+	g.cv.SetStrokeStyle(a.Color)
+	g.cv.SetLineWidth(2)
+	g.cv.StrokeRect(x, y, toastW, toastH)
+	g.drawBadge(x+toastH/2, y+toastH/2, badgeR+4, a.Color, true)
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.middle, 15)
+	g.cv.FillText(g.tr.T("achievement.unlocked", g.tr.T("achievement."+a.ID)), x+toastH, y+24)
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 13)
+	g.cv.FillText(g.tr.T("achievement."+a.ID+".desc"), x+toastH, y+44)
+}
+
+// drawBadge draws the badge of an achievement: a star on a disc of the achievement's color,
+// or a gray outline for an achievement that hasn't been unlocked yet.
+//
+// Parameters:
+// - cx, cy (float64): The center of the badge.
+// - r (float64): The radius of the badge.
+// - color (string): The color of the achievement.
+// - earned (bool): Whether the achievement has been unlocked.
+func (g *Game) drawBadge(cx, cy, r float64, color string, earned bool) {
+	g.cv.BeginPath()
+	g.cv.Arc(cx, cy, r, 0, 2*math.Pi, false)
+	if earned {
+		g.cv.SetFillStyle(color)
+		g.cv.Fill()
+	} else {
+		g.cv.SetStrokeStyle("#607D8B")
+		g.cv.SetLineWidth(1.5)
+		g.cv.Stroke()
+	}
+	g.cv.BeginPath()
+	for i := 0; i < 10; i++ {
+		rr := r * 0.6
+		if i%2 == 1 {
+			rr = r * 0.25
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/5
+		g.cv.LineTo(cx+rr*math.Cos(angle), cy+rr*math.Sin(angle))
+	}
+	g.cv.ClosePath()
+	if earned {
+		g.cv.SetFillStyle("#FFFFFF")
+	} else {
+		g.cv.SetFillStyle("#607D8B")
+	}
+	g.cv.Fill()
+}
+
+// drawBadges draws the badges of all achievements in a row, the unlocked ones in color, followed by
+// the number of unlocked achievements.
+//
+// Parameters:
+// - x, y (float64): The center of the first badge.
+func (g *Game) drawBadges(x, y float64) {
+	for i, a := range achievements.All {
+		g.drawBadge(x+float64(i)*(badgeR*2+6), y, badgeR, a.Color, g.achievements.Unlocked(a.ID))
+	}
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("achievement.count", g.achievements.Count(), len(achievements.All)),
+		x+float64(len(achievements.All))*(badgeR*2+6), y+5)
+}
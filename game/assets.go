@@ -0,0 +1,87 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Names of the asset files. The same names are used for the embedded assets and for the files
+// in the asset override directory.
+const (
+	mainFontFile   = "samuraiterrapingradital.ttf"
+	middleFontFile = "Dejavusansmono.ttf"
+	smallFontFile  = "Righteous-Regular.ttf"
+	logoFile       = "SnakeGO.png"
+)
+
+// assets provides the game's fonts and images.
+//
+// The assets embedded into the executable are used by default; if an override directory is set,
+// the files found in it replace the embedded ones with the same name, so players can customize
+// the look of the game without rebuilding it.
+// Fields:
+// - dir: the asset override directory; empty means that only the embedded assets are used.
+type assets struct {
+	dir string
+}
+
+// devAssetsDir is the location of the embedded assets in the source tree, relative to the repository root.
+const devAssetsDir = "game/assets"
+
+// newAssets creates the asset provider with the override directory chosen in the following order:
+// the directory passed with the -assets flag, the `assets` directory in the resource directory
+// (next to the executable or inside the application bundle), if it exists, and, in development mode,
+// the assets in the source tree when the game is started from the repository root.
+//
+// Parameters:
+// - dir (string): The directory from the command line; empty means "not set".
+// - dev (bool): Whether the game runs in development mode.
+func newAssets(dir string, dev bool) assets {
+	if dir != "" {
+		return assets{dir: dir}
+	}
+	if dev && isDir(devAssetsDir) {
+		return assets{dir: devAssetsDir}
+	}
+	resDir, err := ResourceDir()
+	if err != nil {
+		log.Println(err)
+		return assets{}
+	}
+	dir = filepath.Join(resDir, "assets")
+	if !isDir(dir) {
+		return assets{}
+	}
+	return assets{dir: dir}
+}
+
+// isDir reports whether the path exists and is a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// read returns the content of the asset with the given name.
+//
+// Parameters:
+// - name (string): The file name of the asset.
+// - embedded ([]byte): The embedded asset used when there is no override.
+//
+// Returns:
+// - []byte: The content of the file from the override directory if it exists, otherwise the embedded asset.
+func (a assets) read(name string, embedded []byte) []byte {
+	if a.dir == "" {
+		return embedded
+	}
+	data, err := os.ReadFile(filepath.Join(a.dir, name))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Println("error reading asset, using the embedded one:", err)
+		}
+		return embedded
+	}
+	return data
+}
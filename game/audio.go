@@ -0,0 +1,503 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"embed"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/veandco/go-sdl2/mix"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	musicDir   = "music" // directory in the data directory with the background music
+	stemsDir   = "stems" // directory in the music directory with the layered music stems
+	stemEasing = 1.5     // how fast the stems fade in and out, per second
+
+	duckLevel      = 0.3                    // the music volume while ducked, relative to the normal volume
+	duckFadeOut    = 250 * time.Millisecond // how long the music takes to be lowered
+	duckFadeIn     = time.Second            // how long the music takes to be restored
+	audioFrequency = 44100                  // the sample rate of the audio device
+	audioChunkSize = 2048                   // the size of the audio buffer in samples
+)
+
+// musicExtensions are the file extensions of the music files added to the playlist.
+var musicExtensions = []string{".ogg", ".mp3"}
+
+// soundFiles are the embedded sound effects.
+//
+//go:embed assets/sounds
+var soundFiles embed.FS
+
+const (
+	soundsDir     = "assets/sounds" // the location of the sound effects in soundFiles
+	soundPack     = "sounds"        // directory with a sound pack in the data directory or the asset override directory
+	soundEat      = "eat.wav"       // played when the snake eats food
+	soundGameOver = "gameover.wav"  // the jingle played when the game ends
+	soundClick    = "click.wav"     // the soft click played when a turn is rejected
+	panWidth      = 0.6             // how far the panned sounds move from the center, 1 is fully left or right
+	soundsMax     = 16              // the number of sound effects waiting to be played
+)
+
+// soundRequest is a sound effect waiting to be played by the render loop.
+// Fields:
+// - name: the file name of the sound effect.
+// - pan: the stereo position from -1 (left) to 1 (right).
+type soundRequest struct {
+	name string
+	pan  float64
+}
+
+// audio plays the background music and the sound effects.
+//
+// The background music is a playlist of the OGG and MP3 files found in the music directory
+// of the data directory. The playlist is shuffled and played in a loop during the gameplay.
+//
+// Alternatively, the music can be made of layered stems: the files of the music/stems directory are played
+// together in sync, ordered by name from the calmest to the most intense. The first stem is always
+// audible, and the others fade in one by one as the snake gets faster, so the soundtrack becomes more
+// intense with the speed and calms down again when a new game starts. If there are stems, the playlist isn't used.
+//
+// If the audio device cannot be opened, the game runs silently.
+// Fields:
+// - ok: whether the audio device has been opened.
+// - tracks: the shuffled playlist.
+// - next: the index of the next track in the playlist.
+// - music: the track currently loaded.
+// - rng: the random generator used to shuffle the playlist.
+// - stems: the loaded music stems, each playing on its own reserved channel.
+// - stemGain: the current volume of every stem in the range [0, 1].
+// - duck: the envelope lowering the music while the game is paused or over.
+// - musicVolume: the effective music volume in the mixer range.
+// - intensity: the target intensity of the music in the range [0, 1], set from the game events.
+// - sounds: the loaded sound effects by file name.
+// - queue: the sound effects requested by the game logic goroutine, played by the render loop.
+type audio struct {
+	ok     bool
+	tracks []string
+	next   int
+	music  *mix.Music
+	rng    *rand.Rand
+
+	stems       []*mix.Chunk
+	stemGain    []float64
+	duck        envelope
+	musicVolume int
+	intensity   atomic.Uint64
+
+	sounds map[string]*mix.Chunk
+	queue  chan soundRequest
+}
+
+// newAudio opens the audio device and builds the music playlist.
+//
+// Parameters:
+// - dataDir (string): The data directory containing the music directory and, optionally, a sound pack.
+// - packs (assets): The asset override directory, which may contain a sound pack.
+//
+// Returns:
+// - *audio: The audio player; it stays silent if the audio device is unavailable.
+func newAudio(dataDir string, packs assets) *audio {
+	a := &audio{
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		sounds: make(map[string]*mix.Chunk),
+		queue:  make(chan soundRequest, soundsMax),
+		duck:   envelope{value: 1, target: 1},
+	}
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		log.Println("error initializing audio:", err)
+		return a
+	}
+	if err := mix.Init(mix.INIT_OGG | mix.INIT_MP3); err != nil {
+		// the formats that failed to initialize are skipped when the tracks are loaded
+		log.Println("error initializing audio decoders:", err)
+	}
+	if err := mix.OpenAudio(audioFrequency, mix.DEFAULT_FORMAT, mix.DEFAULT_CHANNELS, audioChunkSize); err != nil {
+		log.Println("error opening audio device:", err)
+		return a
+	}
+	a.ok = true
+	a.loadSounds(packs, dataDir)
+	if dataDir != "" {
+		a.loadStems(filepath.Join(dataDir, musicDir, stemsDir))
+		if len(a.stems) == 0 {
+			a.tracks = findTracks(filepath.Join(dataDir, musicDir))
+			a.shuffle()
+		}
+	}
+	return a
+}
+
+// loadStems loads the music stems from the directory and reserves a mixer channel for each of them,
+// so the sound effects never interrupt the music.
+//
+// Parameters:
+// - dir (string): The stems directory.
+func (a *audio) loadStems(dir string) {
+	paths := findTracks(dir)
+	slices.Sort(paths)
+	for _, path := range paths {
+		chunk, err := mix.LoadWAV(path)
+		if err != nil {
+			log.Printf("error loading stem %s: %v", path, err)
+			continue
+		}
+		a.stems = append(a.stems, chunk)
+	}
+	if len(a.stems) == 0 {
+		return
+	}
+	mix.AllocateChannels(len(a.stems) + 8)
+	mix.ReserveChannels(len(a.stems))
+	a.stemGain = make([]float64, len(a.stems))
+	for i, stem := range a.stems {
+		if _, err := stem.Play(i, -1); err != nil {
+			log.Println("error playing stem:", err)
+		}
+		mix.Volume(i, 0)
+	}
+}
+
+// loadSounds loads the sound effects.
+//
+// Every embedded sound effect can be replaced by a file with the same name from a sound pack: the `sounds`
+// directory of the data directory takes precedence over the `sounds` directory of the asset override
+// directory, which takes precedence over the embedded sounds. A file that cannot be decoded is ignored.
+//
+// Parameters:
+// - packs (assets): The asset override directory.
+// - dataDir (string): The data directory; empty if unknown.
+func (a *audio) loadSounds(packs assets, dataDir string) {
+	entries, err := soundFiles.ReadDir(soundsDir)
+	if err != nil {
+		log.Println("error reading sound effects:", err)
+		return
+	}
+	for _, e := range entries {
+		embedded, err := soundFiles.ReadFile(soundsDir + "/" + e.Name())
+		if err != nil {
+			log.Println("error reading sound effect:", err)
+			continue
+		}
+		candidates := [][]byte{embedded}
+		if data := packs.read(filepath.Join(soundPack, e.Name()), nil); data != nil {
+			candidates = append(candidates, data)
+		}
+		if dataDir != "" {
+			if data, err := os.ReadFile(filepath.Join(dataDir, soundPack, e.Name())); err == nil {
+				candidates = append(candidates, data)
+			}
+		}
+		// the last candidate has the highest priority
+		for i := len(candidates) - 1; i >= 0; i-- {
+			chunk, err := loadChunk(candidates[i])
+			if err != nil {
+				log.Printf("error loading sound effect %s: %v", e.Name(), err)
+				continue
+			}
+			a.sounds[e.Name()] = chunk
+			break
+		}
+	}
+}
+
+// loadChunk decodes a sound effect from memory.
+func loadChunk(data []byte) (*mix.Chunk, error) {
+	rw, err := sdl.RWFromMem(data)
+	if err != nil {
+		return nil, err
+	}
+	return mix.LoadWAVRW(rw, true)
+}
+
+// play requests the sound effect to be played. It never blocks: if too many sounds are waiting,
+// the sound is dropped.
+//
+// Parameters:
+// - name (string): The file name of the sound effect.
+// - pan (float64): The stereo position from -1 (left) to 1 (right).
+func (a *audio) play(name string, pan float64) {
+	if !a.ok {
+		return
+	}
+	select {
+	case a.queue <- soundRequest{name: name, pan: pan}:
+	default:
+	}
+}
+
+// playQueued plays the sound effects waiting in the queue.
+func (a *audio) playQueued() {
+	for {
+		select {
+		case req := <-a.queue:
+			chunk := a.sounds[req.name]
+			if chunk == nil {
+				continue
+			}
+			channel, err := chunk.Play(-1, 0)
+			if err != nil {
+				// all channels are busy, the sound is skipped
+				continue
+			}
+			left, right := panLevels(req.pan)
+			if err = mix.SetPanning(channel, left, right); err != nil {
+				log.Println("error panning sound:", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// panLevels converts the stereo position to the volumes of the left and right channels.
+//
+// Parameters:
+// - pan (float64): The stereo position from -1 (left) to 1 (right).
+//
+// Returns:
+// - uint8, uint8: The volumes of the left and right channels; both are 255 in the center.
+func panLevels(pan float64) (uint8, uint8) {
+	pan = math.Max(-1, math.Min(pan, 1))
+	return uint8(255 * math.Min(1, 1-pan)), uint8(255 * math.Min(1, 1+pan))
+}
+
+// handleEvent plays the sound effects of the game events and adjusts the intensity of the music to them:
+// it follows the speed of the snake and returns to calm when a new game starts.
+//
+// The sound of eaten food is panned left or right according to the position of the food on the board.
+// It's called by the game logic goroutine.
+func (a *audio) handleEvent(e event) {
+	switch e.kind {
+	case eventAte:
+		a.play(soundEat, (e.pos.X/float64(e.cells-1)*2-1)*panWidth)
+	case eventDied:
+		a.play(soundGameOver, 0)
+	case eventRejected:
+		a.play(soundClick, 0)
+	case eventSpeed, eventRestart:
+		a.intensity.Store(math.Float64bits(speedLevelOf(e.speed)))
+	}
+}
+
+// stemTarget returns the volume of the stem for the current intensity, in the range [0, 1].
+//
+// The first stem is always audible; stem i fades in while the intensity grows
+// from (i-1)/(n-1) to i/(n-1) of the way to the speed cap.
+func (a *audio) stemTarget(i int) float64 {
+	if i == 0 {
+		return 1
+	}
+	n := float64(len(a.stems) - 1)
+	level := math.Float64frombits(a.intensity.Load())
+	return math.Max(0, math.Min((level-float64(i-1)/n)*n, 1))
+}
+
+// updateStems fades the stems towards the current intensity, scaled by the ducking envelope.
+func (a *audio) updateStems(dt time.Duration) {
+	k := 1 - math.Exp(-stemEasing*dt.Seconds())
+	for i := range a.stems {
+		a.stemGain[i] += (a.stemTarget(i) - a.stemGain[i]) * k
+		mix.Volume(i, int(a.stemGain[i]*a.duck.value*float64(a.musicVolume)))
+	}
+}
+
+// findTracks lists the music files in the directory.
+//
+// Parameters:
+// - dir (string): The music directory.
+//
+// Returns:
+// - []string: The paths of the music files; empty if the directory doesn't exist.
+func findTracks(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("error reading music directory:", err)
+		}
+		return nil
+	}
+	var tracks []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		for _, want := range musicExtensions {
+			if !e.IsDir() && ext == want {
+				tracks = append(tracks, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	return tracks
+}
+
+// shuffle shuffles the playlist and starts it from the beginning.
+func (a *audio) shuffle() {
+	a.rng.Shuffle(len(a.tracks), func(i, j int) {
+		a.tracks[i], a.tracks[j] = a.tracks[j], a.tracks[i]
+	})
+	a.next = 0
+}
+
+// applyVolumes sets the volumes of the music and the sound effects from the configuration:
+// both are scaled by the master volume, and everything is silent while the game is muted.
+func (g *Game) applyVolumes() {
+	master := g.cfg.MasterVolume
+	if g.cfg.Muted {
+		master = 0
+	}
+	g.audio.setVolumes(g.cfg.MusicVolume*master/100, g.cfg.SFXVolume*master/100)
+}
+
+// toggleMute mutes or unmutes all sounds instantly and remembers the choice in the configuration.
+func (g *Game) toggleMute() {
+	g.cfg.Muted = !g.cfg.Muted
+	g.applyVolumes()
+	g.saveConfig()
+}
+
+// setVolumes sets the volumes of the music and the sound effects.
+//
+// Parameters:
+// - music, sfx (int): The volumes in percent.
+func (a *audio) setVolumes(music, sfx int) {
+	if !a.ok {
+		return
+	}
+	a.musicVolume = percentVolume(music)
+	mix.VolumeMusic(a.musicVolume)
+	mix.Volume(-1, percentVolume(sfx))
+}
+
+// percentVolume converts the volume in percent to the volume range of the mixer.
+func percentVolume(p int) int {
+	return max(0, min(p, 100)) * mix.MAX_VOLUME / 100
+}
+
+// update keeps the background music in sync with the game; it's called on every frame.
+//
+// The sound effects requested since the previous frame are played. The music is ducked (lowered,
+// so the game over jingle can be heard) while the game is paused or over, and smoothly restored
+// when the gameplay resumes. When a track ends, the next one of the playlist starts, and the stems
+// are faded towards the current intensity.
+//
+// Parameters:
+// - ducked (bool): Whether the music should be lowered.
+// - dt (time.Duration): The time elapsed since the previous frame.
+func (a *audio) update(ducked bool, dt time.Duration) {
+	if !a.ok {
+		return
+	}
+	a.playQueued()
+	if ducked {
+		a.duck.fadeTo(duckLevel, duckFadeOut)
+	} else {
+		a.duck.fadeTo(1, duckFadeIn)
+	}
+	a.duck.update(dt)
+	if len(a.stems) > 0 {
+		a.updateStems(dt)
+		return
+	}
+	if len(a.tracks) == 0 {
+		return
+	}
+	mix.VolumeMusic(int(a.duck.value * float64(a.musicVolume)))
+	if !mix.PlayingMusic() {
+		a.playNext()
+	}
+}
+
+// playNext starts the next track of the playlist, reshuffling it after the last track.
+// Tracks that cannot be loaded are removed from the playlist.
+func (a *audio) playNext() {
+	if a.music != nil {
+		a.music.Free()
+		a.music = nil
+	}
+	for len(a.tracks) > 0 {
+		if a.next >= len(a.tracks) {
+			a.shuffle()
+		}
+		path := a.tracks[a.next]
+		music, err := mix.LoadMUS(path)
+		if err == nil {
+			err = music.Play(1)
+		}
+		if err != nil {
+			log.Printf("error playing %s: %v", path, err)
+			if music != nil {
+				music.Free()
+			}
+			a.tracks = append(a.tracks[:a.next], a.tracks[a.next+1:]...)
+			continue
+		}
+		a.music = music
+		a.next++
+		return
+	}
+}
+
+// close stops the playback and closes the audio device.
+func (a *audio) close() {
+	if !a.ok {
+		return
+	}
+	mix.HaltMusic()
+	mix.HaltChannel(-1)
+	if a.music != nil {
+		a.music.Free()
+	}
+	for _, stem := range a.stems {
+		stem.Free()
+	}
+	for _, sound := range a.sounds {
+		sound.Free()
+	}
+	mix.CloseAudio()
+	mix.Quit()
+	a.ok = false
+}
+
+// envelope is a gain that fades linearly towards its target.
+// Fields:
+// - value: the current gain.
+// - target: the gain the envelope is fading to.
+// - step: the change of the gain per second.
+type envelope struct {
+	value  float64
+	target float64
+	step   float64
+}
+
+// fadeTo starts fading to the target gain.
+//
+// Parameters:
+// - target (float64): The new gain.
+// - d (time.Duration): The time the fade takes.
+func (e *envelope) fadeTo(target float64, d time.Duration) {
+	if target == e.target {
+		return
+	}
+	e.target = target
+	e.step = math.Abs(target-e.value) / d.Seconds()
+}
+
+// update advances the fade by the time elapsed since the previous frame.
+func (e *envelope) update(dt time.Duration) {
+	delta := e.step * dt.Seconds()
+	switch {
+	case math.Abs(e.target-e.value) <= delta:
+		e.value = e.target
+	case e.target > e.value:
+		e.value += delta
+	default:
+		e.value -= delta
+	}
+}
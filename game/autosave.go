@@ -0,0 +1,101 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/saves"
+)
+
+const autosaveInterval = 10 * time.Second // how often the game in progress is autosaved
+
+// autosave saves a snapshot of the game in progress into the autosave every autosaveInterval,
+// and removes the autosave when the game ends, so only an interrupted game can be resumed.
+//
+// It's called by the game logic goroutine after every tick, so the snapshot is always consistent.
+//
+// Parameters:
+// - res (engine.StepResult): What happened during the tick.
+func (g *Game) autosave(res engine.StepResult) {
+	if g.dataDir == "" {
+		return
+	}
+	if res.Died || res.Won {
+		g.lastAutosave = time.Time{}
+		if err := saves.RemoveAutosave(g.dataDir); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	if time.Since(g.lastAutosave) < autosaveInterval {
+		return
+	}
+	g.lastAutosave = time.Now()
+	if err := saves.Autosave(g.dataDir, g.newSlot()); err != nil {
+		log.Println(err)
+	}
+}
+
+// offerResume checks for a game interrupted by a crash or a power loss and, if there is one,
+// pauses the game and asks the player whether to resume it.
+func (g *Game) offerResume() {
+	if g.dataDir == "" {
+		return
+	}
+	slot, err := saves.LoadAutosave(g.dataDir)
+	if err != nil {
+		log.Println(err)
+	}
+	if slot == nil || slot.State.GameOver {
+		return
+	}
+	g.resume = slot
+	g.paused = true
+}
+
+// handleResumeKey processes a key press while the player is asked whether to resume the interrupted game:
+// ENTER loads the autosave, ESC discards it and starts a new game.
+//
+// Parameters:
+// - name (string): The name of the released key.
+func (g *Game) handleResumeKey(name string) {
+	switch name {
+	case "Enter":
+		g.loadSlot(g.resume)
+	case "Escape":
+		if err := saves.RemoveAutosave(g.dataDir); err != nil {
+			log.Println(err)
+		}
+		g.paused = false
+	default:
+		return
+	}
+	g.resume = nil
+}
+
+// drawResume displays the question whether to resume the interrupted game.
+//
+// Parameters:
+// - x, y (float64): The starting position for rendering the question.
+func (g *Game) drawResume(x, y float64) {
+	g.cv.BeginPath()
+	g.cv.SetFillStyle(0, 0, 0, 0.6)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.cv.Stroke()
+	g.beginUI(g.param.gameW/2, g.param.gameH/2)
+	defer g.endUI()
+
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 30)
+	g.cv.FillText(g.tr.T("resume.title"), x, y)
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 15)
+	state := g.resume.State
+	g.cv.FillText(g.tr.T("resume.info", state.Score, len(state.Snake),
+		g.resume.SavedAt.Local().Format("2006-01-02 15:04")), x, y+35)
+	g.cv.FillText(g.tr.T("resume.hint"), x, y+60)
+	g.cv.Stroke()
+}
@@ -0,0 +1,50 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	minBoardCells = 10 // the smallest board that can be chosen in the settings
+	maxBoardCells = 60 // the largest board that can be chosen in the settings
+	boardCellStep = 5  // how much the board grows or shrinks with a single key press on the settings screen
+)
+
+// boardSizeFor returns the size of the board chosen in the configuration: the size of the board setting,
+// clamped to the supported range, or the small board of the large cell mode.
+//
+// Parameters:
+// - cfg (*config.Config): The configuration.
+//
+// Returns:
+// - int: The number of cells along each side of the board.
+func boardSizeFor(cfg *config.Config) int {
+	switch {
+	case cfg.LargeCells:
+		return largeBoardCells
+	case cfg.BoardSize == 0:
+		return engine.Cells
+	default:
+		return max(minBoardCells, min(cfg.BoardSize, maxBoardCells))
+	}
+}
+
+// setBoardSize changes the size of the board in the configuration.
+//
+// The board changes its size, so a new game is started, and the camera is zoomed out to show the whole board,
+// like when the large cell mode is switched.
+//
+// Parameters:
+// - cells (int): The new number of cells along each side of the board; it's clamped to the supported range.
+func (g *Game) setBoardSize(cells int) {
+	cells = max(minBoardCells, min(cells, maxBoardCells))
+	if cells == boardSizeFor(g.cfg) && !g.cfg.LargeCells {
+		return
+	}
+	g.cfg.BoardSize = cells
+	g.cfg.LargeCells = false
+	g.restartGame()
+	g.setZoom(g.boardCells())
+}
@@ -0,0 +1,44 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+
+	"github.com/DenisKhanov/Snake/botapi"
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// serveBots serves the bot API in the background if the player has asked for it on the command line,
+// so bots can watch the game and steer the snake over gRPC.
+func (g *Game) serveBots() {
+	if g.botAddr == "" {
+		return
+	}
+	g.bots = botapi.NewLive()
+	log.Println("serving the bot API on", g.botAddr)
+	go func() {
+		log.Println("the bot API has stopped:", botapi.ListenAndServe(g.botAddr, g.bots))
+	}()
+}
+
+// botTurn returns the next turn the bots have asked for, if any.
+//
+// It's called by the game logic goroutine before every tick.
+func (g *Game) botTurn() (engine.Dir, bool) {
+	if g.bots == nil {
+		return 0, false
+	}
+	return g.bots.Turn()
+}
+
+// observe sends the state of the game after the tick to the bots.
+//
+// It's called by the game logic goroutine after every tick.
+//
+// Parameters:
+// - res (engine.StepResult): What has happened during the tick.
+func (g *Game) observe(res engine.StepResult) {
+	if g.bots != nil {
+		g.bots.Publish(botapi.Observe(g.eng, res))
+	}
+}
@@ -0,0 +1,113 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	minZoomCells   = 8.0  // the smallest number of cells visible along one axis (maximum zoom in)
+	zoomStep       = 2.0  // number of cells added or removed by a single zoom key press
+	cameraSmooth   = 10.0 // how fast the camera catches up with the snake head, per second
+	cameraSnapDist = 0.01 // distance in cells below which the camera snaps to its target
+)
+
+// camera describes the part of the board that is visible in the game area.
+// Fields:
+// - x, y: the top-left corner of the viewport in board cells.
+// - cells: the number of cells visible along each axis.
+type camera struct {
+	x, y  float64
+	cells float64
+}
+
+// setZoom changes the number of cells visible in the game area and recalculates the cell sizes.
+//
+// The value is clamped between minZoomCells and the board size, so the camera never shows
+// anything outside the board.
+//
+// Parameters:
+// - cells (float64): The desired number of visible cells along each axis.
+func (g *Game) setZoom(cells float64) {
+	g.cam.cells = math.Max(minZoomCells, math.Min(cells, g.boardCells()))
+	g.cellW = g.param.gameW / g.cam.cells
+	g.cellH = g.param.gameH / g.cam.cells
+	g.side = math.Min(g.cellW-1*2, g.cellH-1*2)
+	g.cam.x, g.cam.y = g.cameraTarget()
+}
+
+// boardCells returns the number of cells along each side of the board, or of the shared board of an online game.
+func (g *Game) boardCells() float64 {
+	if cells := g.versusCells(); cells > 0 {
+		return float64(cells)
+	}
+	if g.eng == nil {
+		return engine.Cells
+	}
+	return float64(g.eng.BoardSize())
+}
+
+// cameraTarget calculates the viewport position that centers the snake's head (the player's snake on the shared
+// board of an online game), clamped to the board boundaries.
+//
+// Returns:
+// - float64, float64: The desired top-left corner of the viewport in cells.
+func (g *Game) cameraTarget() (float64, float64) {
+	limit := g.boardCells() - g.cam.cells
+	if g.eng == nil || limit <= 0 {
+		return 0, 0
+	}
+	head := g.eng.Snake.Head()
+	if m := g.versus.Load(); m != nil {
+		head, _ = m.head()
+	}
+	x := head.X + 0.5 - g.cam.cells/2
+	y := head.Y + 0.5 - g.cam.cells/2
+	return math.Max(0, math.Min(x, limit)), math.Max(0, math.Min(y, limit))
+}
+
+// updateCamera moves the viewport smoothly towards the snake's head.
+// In the reduced motion mode the viewport jumps to the head right away.
+//
+// Parameters:
+// - dt (time.Duration): The time elapsed since the previous frame.
+func (g *Game) updateCamera(dt time.Duration) {
+	targetX, targetY := g.cameraTarget()
+	if g.cfg.ReducedMotion {
+		g.cam.x, g.cam.y = targetX, targetY
+		return
+	}
+	k := 1 - math.Exp(-cameraSmooth*dt.Seconds())
+	g.cam.x += (targetX - g.cam.x) * k
+	g.cam.y += (targetY - g.cam.y) * k
+	if math.Abs(targetX-g.cam.x) < cameraSnapDist {
+		g.cam.x = targetX
+	}
+	if math.Abs(targetY-g.cam.y) < cameraSnapDist {
+		g.cam.y = targetY
+	}
+}
+
+// toScreen transforms a board position into canvas coordinates using the current camera.
+//
+// Parameters:
+// - p (Point): The position on the board, in cells.
+//
+// Returns:
+// - float64, float64: The canvas coordinates of the top-left corner of the cell.
+func (g *Game) toScreen(p Point) (float64, float64) {
+	return g.gameAreaSP.X + (p.X-g.cam.x)*g.cellW, g.gameAreaSP.Y + (p.Y-g.cam.y)*g.cellH
+}
+
+// clipGameArea restricts all following drawing operations to the game area.
+//
+// Every call must be paired with a call of g.cv.Restore().
+func (g *Game) clipGameArea() {
+	g.cv.Save()
+	g.cv.BeginPath()
+	g.cv.Rect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.cv.Clip()
+}
@@ -0,0 +1,115 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	captionTime   = 2 * time.Second        // how long a caption stays on the screen
+	captionFade   = 500 * time.Millisecond // how long a caption takes to fade out at the end
+	captionsMax   = 3                      // the largest number of captions shown at once
+	captionLineH  = 24.0                   // the distance between two captions
+	captionMargin = 12.0                   // the distance between the captions and the bottom of the game area
+)
+
+// captionKeys maps the events that play sound effects to the message keys of their captions.
+var captionKeys = map[eventKind]string{
+	eventAte:      "caption.eat",
+	eventDied:     "caption.gameover",
+	eventRejected: "caption.click",
+}
+
+// caption is a description of a sound effect shown on the screen.
+// Fields:
+// - key: the message key of the text.
+// - age: the time the caption has been shown for.
+type caption struct {
+	key string
+	age time.Duration
+}
+
+// captionOverlay is a HUD widget that shows small captions such as "*crunch*" at the bottom of the game area
+// when sound effects are played, for deaf and hard-of-hearing players.
+//
+// It receives the same events as the audio, so every sound effect has a caption. The captions are shown
+// only if they are enabled in the settings.
+// Fields:
+// - mu: guards pending, which is filled by the game logic goroutine.
+// - pending: the captions of the events received since the previous frame.
+// - shown: the captions on the screen, the newest last.
+type captionOverlay struct {
+	mu      sync.Mutex
+	pending []string
+	shown   []caption
+}
+
+// newCaptionOverlay creates an empty caption overlay.
+func newCaptionOverlay() *captionOverlay {
+	return &captionOverlay{}
+}
+
+// handleEvent queues the caption of the event's sound effect, if it has one.
+// It's called by the game logic goroutine and by the input handlers.
+func (c *captionOverlay) handleEvent(e event) {
+	key, ok := captionKeys[e.kind]
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) < captionsMax {
+		c.pending = append(c.pending, key)
+	}
+}
+
+// layout does nothing: the captions are placed relative to the game area when they're drawn.
+func (c *captionOverlay) layout(g *Game) {}
+
+// update shows the queued captions and removes the ones that have been shown long enough.
+func (c *captionOverlay) update(g *Game, dt time.Duration) {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	for _, key := range pending {
+		c.shown = append(c.shown, caption{key: key})
+	}
+	kept := c.shown[:0]
+	for _, cp := range c.shown {
+		cp.age += dt
+		if cp.age < captionTime {
+			kept = append(kept, cp)
+		}
+	}
+	c.shown = kept[max(0, len(kept)-captionsMax):]
+}
+
+// draw renders the captions centered at the bottom of the game area, on a dark background,
+// fading them out at the end of their time.
+func (c *captionOverlay) draw(g *Game) {
+	if !g.cfg.Captions || len(c.shown) == 0 {
+		return
+	}
+	centerX := g.gameAreaSP.X + g.param.gameW/2
+	bottom := g.gameAreaEP.Y - captionMargin
+	g.beginUI(centerX, bottom)
+	defer g.endUI()
+
+	g.cv.SetFont(g.fonts.small, 16)
+	for i, cp := range c.shown {
+		alpha := math.Min(1, float64(captionTime-cp.age)/float64(captionFade))
+		text := g.tr.T(cp.key)
+		w := g.cv.MeasureText(text).Width
+		y := bottom - float64(len(c.shown)-1-i)*captionLineH
+		g.cv.SetGlobalAlpha(alpha)
+		g.cv.SetFillStyle(0, 0, 0, 0.6)
+		g.cv.FillRect(centerX-w/2-6, y-16, w+12, 22)
+		g.cv.SetFillStyle("#FFFFFF")
+		g.cv.FillText(text, centerX-w/2, y)
+	}
+	g.cv.SetGlobalAlpha(1)
+}
@@ -0,0 +1,143 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	captureSeconds  = 10                     // length of the recorded clip
+	captureInterval = 100 * time.Millisecond // time between two captured frames
+	captureScale    = 3                      // the board is downscaled by this factor
+	captureDir      = "captures"             // directory in the data directory the clips are saved to
+)
+
+// frameRecorder keeps a rolling buffer of downscaled board frames covering the last captureSeconds seconds.
+// Fields:
+// - frames: the ring buffer of captured frames.
+// - next: the index in frames the next frame will be written to.
+// - count: the number of frames in the buffer.
+// - elapsed: the time elapsed since the last captured frame.
+type frameRecorder struct {
+	frames  []*image.RGBA
+	next    int
+	count   int
+	elapsed time.Duration
+}
+
+// newFrameRecorder creates an empty frame recorder.
+func newFrameRecorder() *frameRecorder {
+	return &frameRecorder{frames: make([]*image.RGBA, int(captureSeconds*time.Second/captureInterval))}
+}
+
+// reset drops all captured frames.
+func (r *frameRecorder) reset() {
+	clear(r.frames)
+	r.next, r.count, r.elapsed = 0, 0, 0
+}
+
+// record captures the board into the ring buffer if enough time has passed since the previous frame.
+//
+// Parameters:
+// - g (*Game): The game whose board is captured.
+// - dt (time.Duration): The time elapsed since the previous rendered frame.
+func (r *frameRecorder) record(g *Game, dt time.Duration) {
+	r.elapsed += dt
+	if r.elapsed < captureInterval {
+		return
+	}
+	r.elapsed = 0
+	// image data is read in window pixels, so the layout offset is applied manually
+	img := g.cv.GetImageData(int(g.gameAreaSP.X+g.offset.X), int(g.gameAreaSP.Y+g.offset.Y),
+		int(g.param.gameW), int(g.param.gameH))
+	if img == nil {
+		return
+	}
+	r.frames[r.next] = downscale(img, captureScale)
+	r.next = (r.next + 1) % len(r.frames)
+	r.count = min(r.count+1, len(r.frames))
+}
+
+// snapshot returns the captured frames in chronological order.
+func (r *frameRecorder) snapshot() []*image.RGBA {
+	frames := make([]*image.RGBA, 0, r.count)
+	start := (r.next - r.count + len(r.frames)) % len(r.frames)
+	for i := 0; i < r.count; i++ {
+		frames = append(frames, r.frames[(start+i)%len(r.frames)])
+	}
+	return frames
+}
+
+// downscale shrinks the image by the given factor using the nearest-neighbor method.
+func downscale(src *image.RGBA, factor int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/factor, b.Dy()/factor))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			dst.SetRGBA(x, y, src.RGBAAt(b.Min.X+x*factor, b.Min.Y+y*factor))
+		}
+	}
+	return dst
+}
+
+// saveCapture encodes the frames currently in the buffer into an animated GIF in the captures
+// directory of the data directory.
+//
+// The encoding runs in a separate goroutine, so the game doesn't freeze while the clip is being saved.
+func (g *Game) saveCapture() {
+	frames := g.recorder.snapshot()
+	if len(frames) == 0 || g.dataDir == "" {
+		return
+	}
+	path := filepath.Join(g.dataDir, captureDir, time.Now().Format("snake-20060102-150405.gif"))
+	go func() {
+		delays := make([]time.Duration, len(frames))
+		for i := range delays {
+			delays[i] = captureInterval
+		}
+		if err := writeGIF(path, frames, delays); err != nil {
+			log.Println(err)
+			return
+		}
+		log.Println("clip saved to", path)
+	}()
+}
+
+// writeGIF encodes the frames into an animated GIF file.
+//
+// Parameters:
+// - path (string): The path of the GIF file; the directory is created if needed.
+// - frames ([]*image.RGBA): The frames of the animation.
+// - delays ([]time.Duration): The time each frame is shown, one value per frame.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func writeGIF(path string, frames []*image.RGBA, delays []time.Duration) error {
+	anim := &gif.GIF{}
+	for i, frame := range frames {
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, image.Point{})
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, int(delays[i]/(10*time.Millisecond)))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+	if err = gif.EncodeAll(file, anim); err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	return nil
+}
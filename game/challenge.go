@@ -0,0 +1,94 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/DenisKhanov/Snake/challenges"
+)
+
+// challengeResult is the outcome of a finished game of the weekly challenge.
+// Fields:
+// - place: the place of the score in the challenge's score table, starting from 1; 0 if it didn't make it.
+// - best: the best score of the challenge.
+type challengeResult struct {
+	place int
+	best  int
+}
+
+// toggleChallenge starts a game of this week's challenge, or, during a challenge, goes back to normal games.
+// Either way a new game is started.
+func (g *Game) toggleChallenge() {
+	if g.challenge.Load() != nil {
+		g.challenge.Store(nil)
+	} else {
+		schedule, err := challenges.LoadSchedule(g.dataDir)
+		if err != nil {
+			log.Println(err)
+		}
+		c := challenges.ForWeek(time.Now(), schedule)
+		g.challenge.Store(&c)
+	}
+	g.restartGame()
+	g.setZoom(g.boardCells())
+}
+
+// finishChallenge adds the score of the finished game to the score table of the weekly challenge,
+// if the game was played in one.
+//
+// It's called by the game logic goroutine when the snake dies.
+func (g *Game) finishChallenge() {
+	c := g.challenge.Load()
+	if c == nil || g.dataDir == "" {
+		return
+	}
+	entry := challenges.Entry{Score: g.eng.Score, Length: len(g.eng.Snake.Parts), At: time.Now()}
+	table, place, err := challenges.AddScore(g.dataDir, c.Week, entry)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	res := &challengeResult{place: place}
+	if len(table) > 0 {
+		res.best = table[0].Score
+	}
+	g.challengeRes.Store(res)
+}
+
+// challengeWeek returns the week of the weekly challenge being played, or an empty string for a normal game.
+func (g *Game) challengeWeek() string {
+	if c := g.challenge.Load(); c != nil {
+		return c.Week
+	}
+	return ""
+}
+
+// drawChallenge displays the weekly challenge being played on the side panel.
+func (g *Game) drawChallenge() {
+	c := g.challenge.Load()
+	if c == nil {
+		return
+	}
+	g.cv.SetFillStyle("#29B6F6")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("challenge.title", c.Week, c.Rules.Name), g.param.gameW+50, 20)
+}
+
+// drawChallengeResult displays the place of the finished game in the score table of the weekly challenge.
+//
+// Parameters:
+// - x, y (float64): The starting position for rendering the result.
+func (g *Game) drawChallengeResult(x, y float64) {
+	res := g.challengeRes.Load()
+	if res == nil {
+		return
+	}
+	g.cv.SetFillStyle("#29B6F6")
+	g.cv.SetFont(g.fonts.small, 15)
+	if res.place > 0 {
+		g.cv.FillText(g.tr.T("challenge.place", res.place, res.best), x, y)
+	} else {
+		g.cv.FillText(g.tr.T("challenge.no_place", res.best), x, y)
+	}
+}
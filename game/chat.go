@@ -0,0 +1,154 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/DenisKhanov/Snake/netplay"
+)
+
+const (
+	chatLines = 6                // the number of chat messages kept and shown
+	chatShow  = 10 * time.Second // how long a chat message stays on the screen, unless the player is typing
+)
+
+// chatLine is a chat message received in an online room or game.
+// Fields:
+// - name: the name of the sender.
+// - own: whether the player has sent the message.
+// - text: the text of the message.
+// - emote: the emote sent instead of a text, if any.
+// - at: when the message has been received.
+type chatLine struct {
+	name  string
+	own   bool
+	text  string
+	emote string
+	at    time.Time
+}
+
+// handleChatKey processes a key press for the chat of an online room or game: TAB starts typing a message,
+// ENTER sends it and ESC cancels it, and the digit keys send the quick emotes.
+//
+// Parameters:
+// - m (*versusMatch): The current match.
+// - rn (rune): The character of the released key.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled; all keys are while typing.
+func (g *Game) handleChatKey(m *versusMatch, rn rune, name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client := m.client
+	if client == nil || (m.room == nil && m.cells == 0) || m.you == netplay.Spectator || m.ended() {
+		m.typing = false
+		return false
+	}
+	if !m.typing {
+		n, err := strconv.Atoi(strings.TrimPrefix(name, "Digit"))
+		switch {
+		case name == "Tab":
+			m.typing = true
+		case strings.HasPrefix(name, "Digit") && err == nil && n >= 1 && n <= len(netplay.Emotes):
+			emote := netplay.Emotes[n-1]
+			go func() {
+				if err := client.Emote(emote); err != nil {
+					log.Println(err)
+				}
+			}()
+		default:
+			return false
+		}
+		return true
+	}
+	switch name {
+	case "Escape":
+		m.typing, m.draft = false, ""
+	case "Enter":
+		text := strings.TrimSpace(m.draft)
+		m.typing, m.draft = false, ""
+		if text == "" {
+			break
+		}
+		go func() {
+			if err := client.Chat(text); err != nil {
+				log.Println(err)
+			}
+		}()
+	case "Backspace":
+		if _, size := utf8.DecodeLastRuneInString(m.draft); size > 0 {
+			m.draft = m.draft[:len(m.draft)-size]
+		}
+	default:
+		if unicode.IsPrint(rn) && utf8.RuneCountInString(m.draft) < netplay.ChatMax {
+			m.draft += string(rn)
+		}
+	}
+	return true
+}
+
+// addChat keeps a received chat message, dropping the oldest one if there are too many; the caller must hold m.mu.
+func (m *versusMatch) addChat(msg netplay.Message) {
+	you := m.you
+	if m.cells == 0 && m.room != nil {
+		you = m.room.You
+	}
+	m.chat = append(m.chat, chatLine{name: msg.Name, own: msg.From == you, text: msg.Text, emote: msg.Emote, at: time.Now()})
+	if len(m.chat) > chatLines {
+		m.chat = m.chat[len(m.chat)-chatLines:]
+	}
+}
+
+// drawChat draws the chat at the bottom of the game area: the message being typed and the latest messages above it,
+// the player's name in the color of the palette and the opponent's in red. The messages fade out after chatShow,
+// but all of them are shown while typing.
+//
+// Parameters:
+// - s (*versusMatch): A snapshot of the current match.
+func (g *Game) drawChat(s *versusMatch) {
+	now := time.Now()
+	x := g.gameAreaSP.X + 10
+	y := g.gameAreaSP.Y + g.param.gameH - 12
+	g.cv.SetFont(g.fonts.small, 14)
+	if s.typing {
+		g.cv.SetFillStyle(0, 0, 0, 0.7)
+		g.cv.FillRect(g.gameAreaSP.X, y-17, g.param.gameW, 24)
+		g.cv.SetFillStyle("#FFFFFF")
+		g.cv.FillText("> "+s.draft+"_", x, y)
+		y -= 24
+	}
+	for i := len(s.chat) - 1; i >= 0; i-- {
+		line := s.chat[i]
+		age := now.Sub(line.at)
+		if !s.typing && age > chatShow {
+			break
+		}
+		alpha := 1.0
+		if !s.typing && age > chatShow-time.Second {
+			alpha = float64(chatShow-age) / float64(time.Second)
+		}
+		text := line.text
+		if line.emote != "" {
+			text = g.tr.T("chat.emote_" + line.emote)
+		}
+		color := "#E53935"
+		if line.own {
+			color = g.pal().head
+		}
+		g.cv.SetGlobalAlpha(alpha)
+		g.cv.SetFillStyle(0, 0, 0, 0.45)
+		g.cv.FillRect(g.gameAreaSP.X, y-17, g.param.gameW, 22)
+		g.cv.SetFillStyle(color)
+		g.cv.FillText(line.name+":", x, y)
+		g.cv.SetFillStyle("#ECEFF1")
+		g.cv.FillText(text, x+g.cv.MeasureText(line.name+": ").Width, y)
+		y -= 22
+	}
+	g.cv.SetGlobalAlpha(1)
+}
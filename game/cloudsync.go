@@ -0,0 +1,262 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/achievements"
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/leaderboard"
+	"github.com/DenisKhanov/Snake/stats"
+)
+
+const syncStateFile = "sync_state.json" // the name of the file with the state of the profile sync in the data directory
+
+// syncedSection is what the game remembers of a section of the profile after a sync.
+// Fields:
+// - Hash: the SHA-256 hash of the content of the section on this machine right after the sync.
+// - UpdatedAt: the update time of the section kept by the server.
+type syncedSection struct {
+	Hash      string    `json:"hash"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// profileSection is a section of the profile synced through the leaderboard server.
+// Fields:
+// - name: the name of the section in the profile.
+// - path: returns the file the section is stored in on this machine, whose modification time is the update time
+// of the section when it has changed since the last sync.
+// - read: returns the content of the section on this machine.
+// - apply: replaces the section on this machine with the content synced from another machine.
+type profileSection struct {
+	name  string
+	path  func(dataDir string) string
+	read  func(g *Game) (any, error)
+	apply func(g *Game, data json.RawMessage) error
+}
+
+// profileSections are the sections of the profile: the portable settings, the unlocked achievements and the history
+// of the games, from which the statistics, the records and the XP are computed.
+var profileSections = []profileSection{
+	{
+		name: "settings",
+		path: config.Path,
+		read: func(g *Game) (any, error) { return g.cfg.Portable() },
+		apply: func(g *Game, data json.RawMessage) error {
+			var settings map[string]json.RawMessage
+			if err := json.Unmarshal(data, &settings); err != nil {
+				return fmt.Errorf("error parsing synced settings: %w", err)
+			}
+			old := *g.cfg
+			unknown, err := g.cfg.Merge(settings)
+			if err != nil {
+				return err
+			}
+			if len(unknown) > 0 {
+				log.Println("skipped unknown synced settings:", unknown)
+			}
+			g.applyConfig(old)
+			g.saveConfig()
+			return nil
+		},
+	},
+	{
+		name: "achievements",
+		path: achievements.Path,
+		read: func(g *Game) (any, error) { return g.achievements.Unlocks(), nil },
+		apply: func(g *Game, data json.RawMessage) error {
+			var unlocked map[string]time.Time
+			if err := json.Unmarshal(data, &unlocked); err != nil {
+				return fmt.Errorf("error parsing synced achievements: %w", err)
+			}
+			g.achievements.Replace(unlocked)
+			return g.achievements.Save(g.dataDir)
+		},
+	},
+	{
+		name: "history",
+		path: stats.Path,
+		read: func(g *Game) (any, error) { return stats.Load(g.dataDir) },
+		apply: func(g *Game, data json.RawMessage) error {
+			var runs []stats.Run
+			if err := json.Unmarshal(data, &runs); err != nil {
+				return fmt.Errorf("error parsing synced history: %w", err)
+			}
+			if err := stats.Replace(g.dataDir, runs); err != nil {
+				return err
+			}
+			g.loadHistory()
+			return nil
+		},
+	},
+}
+
+// profileSync is the result of a sync of the profile, handed from the goroutine talking to the server
+// to the render loop, which applies it.
+// Fields:
+// - profile: the profile kept by the server, or nil if the sync has failed.
+// - local: the hashes of the sections on this machine when the sync started, by name.
+type profileSync struct {
+	profile *leaderboard.Profile
+	local   map[string]string
+}
+
+// syncProfile starts syncing the profile with the leaderboard server the player's token belongs to:
+// the sections changed since the last sync are sent, and the sections another machine has written later
+// are applied when the server answers.
+//
+// Returns:
+// - string: The message describing the state of the sync.
+func (g *Game) syncProfile() string {
+	if g.leaderboard == nil || g.cfg.LeaderboardToken == "" {
+		return g.tr.T("settings.sync_unlinked")
+	}
+	if !g.syncing.CompareAndSwap(false, true) {
+		return g.tr.T("settings.syncing")
+	}
+	synced := loadSyncState(g.dataDir)
+	req := leaderboard.Profile{Sections: make(map[string]leaderboard.Section)}
+	local := make(map[string]string)
+	for _, sec := range profileSections {
+		v, err := sec.read(g)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			log.Println("error encoding profile section", sec.name+":", err)
+			continue
+		}
+		hash := hashSection(data)
+		local[sec.name] = hash
+		if hash == synced[sec.name].Hash {
+			continue
+		}
+		//the section has changed since the last sync; it has been written when its file was
+		info, err := os.Stat(sec.path(g.dataDir))
+		if err != nil {
+			//nothing has been stored on this machine yet, so there's nothing to win over another machine
+			continue
+		}
+		req.Sections[sec.name] = leaderboard.Section{UpdatedAt: info.ModTime().UTC(), Data: data}
+	}
+	client := g.leaderboard
+	go func() {
+		p, err := client.SyncProfile(context.Background(), req)
+		if err != nil {
+			log.Println(err)
+		}
+		g.profileSync.Store(&profileSync{profile: p, local: local})
+	}()
+	return g.tr.T("settings.syncing")
+}
+
+// applyProfileSync applies the result of a sync of the profile, if one has arrived: every section written
+// on another machine after the changes of this one replaces the local section. It's called by the render loop,
+// since applying the settings touches the window.
+func (g *Game) applyProfileSync() {
+	res := g.profileSync.Swap(nil)
+	if res == nil {
+		return
+	}
+	defer g.syncing.Store(false)
+	if res.profile == nil {
+		g.settings.message = g.tr.T("settings.sync_failed")
+		return
+	}
+	synced := loadSyncState(g.dataDir)
+	applied, failed := 0, 0
+	for _, sec := range profileSections {
+		remote, ok := res.profile.Sections[sec.name]
+		if !ok {
+			continue
+		}
+		hash := hashSection(remote.Data)
+		if hash != res.local[sec.name] {
+			if err := sec.apply(g, remote.Data); err != nil {
+				log.Println(err)
+				failed++
+				continue
+			}
+			applied++
+			//the section may be stored a little differently on this machine, so it's compared with what is stored
+			if v, err := sec.read(g); err == nil {
+				if data, err := json.Marshal(v); err == nil {
+					hash = hashSection(data)
+				}
+			}
+		}
+		synced[sec.name] = syncedSection{Hash: hash, UpdatedAt: remote.UpdatedAt}
+	}
+	if err := saveSyncState(g.dataDir, synced); err != nil {
+		log.Println(err)
+	}
+	switch {
+	case failed > 0:
+		g.settings.message = g.tr.T("settings.sync_failed")
+	case applied > 0:
+		g.settings.message = g.tr.T("settings.synced_applied", applied)
+	default:
+		g.settings.message = g.tr.T("settings.synced")
+	}
+}
+
+// hashSection returns the hex-encoded SHA-256 hash of the content of a section of the profile.
+func hashSection(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadSyncState reads the state of the profile sync saved after the last sync.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - map[string]syncedSection: The synced sections by name; empty if the profile has never been synced.
+func loadSyncState(dataDir string) map[string]syncedSection {
+	synced := make(map[string]syncedSection)
+	data, err := os.ReadFile(filepath.Join(dataDir, syncStateFile))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("error reading the state of the profile sync:", err)
+		}
+		return synced
+	}
+	if err = json.Unmarshal(data, &synced); err != nil {
+		log.Println("error parsing the state of the profile sync:", err)
+		return make(map[string]syncedSection)
+	}
+	return synced
+}
+
+// saveSyncState saves the state of the profile sync.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - synced (map[string]syncedSection): The synced sections by name.
+//
+// Returns:
+// - error: An error if the state cannot be written; otherwise, nil.
+func saveSyncState(dataDir string, synced map[string]syncedSection) error {
+	data, err := json.MarshalIndent(synced, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding the state of the profile sync: %w", err)
+	}
+	path := filepath.Join(dataDir, syncStateFile)
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
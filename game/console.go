@@ -0,0 +1,392 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	consoleLines   = 10   // the number of output lines kept and shown
+	consoleHistory = 32   // the number of commands kept for recalling them with ↑ and ↓
+	consoleLineH   = 16.0 // the height of a line of the console
+	consoleMaxLen  = 80   // the longest command that can be typed
+	consoleMaxMS   = 2000 // the longest tick interval that can be set with the speed command, in milliseconds
+)
+
+// consoleLine is a line of the console output.
+// Fields:
+// - text: the text of the line.
+// - err: whether the line reports an error.
+type consoleLine struct {
+	text string
+	err  bool
+}
+
+// consoleCommand is a command of the developer console.
+// Fields:
+// - usage: the arguments of the command, for the help.
+// - help: what the command does.
+// - run: executes the command with its arguments and returns its output.
+type consoleCommand struct {
+	usage string
+	help  string
+	run   func(g *Game, args []string) (string, error)
+}
+
+// consoleCommands are the commands of the developer console, by name, besides help, which lists them.
+var consoleCommands = map[string]consoleCommand{
+	"speed":    {usage: "MS", help: "set the interval between two ticks", run: consoleSpeed},
+	"spawn":    {usage: "food|ice|mud|spikes|plain X Y", help: "move the food to a cell or lay a tile", run: consoleSpawn},
+	"teleport": {usage: "X Y", help: "move the snake so its head is in a cell", run: consoleTeleport},
+	"rules":    {usage: "[NAME on|off]", help: "list the rules or switch one", run: consoleRules},
+	"seed":     {usage: "N", help: "start a new game from a seed", run: consoleSeed},
+	"clear":    {usage: "", help: "clear the output", run: func(g *Game, args []string) (string, error) { g.console.lines = nil; return "", nil }},
+}
+
+// switchableRules are the rules the rules command switches, by name.
+var switchableRules = map[string]struct {
+	get func(g *Game) bool
+	set func(g *Game, on bool)
+}{
+	"assist": {get: func(g *Game) bool { return g.eng.Assist }, set: (*Game).setAssist},
+	"hints":  {get: func(g *Game) bool { return g.cfg.Hints }, set: func(g *Game, on bool) { g.cfg.Hints = on }},
+}
+
+// devConsole is a HUD widget dropping down over the top of the game area, for testing new features and levels
+// quickly: the developer types commands which change the running game, such as `speed 150` or `teleport 10 10`.
+//
+// It's available only when the game is started with the -debug flag, and it's opened and closed with ~.
+// The game is paused while the console is open. A game changed from the console isn't recorded in the statistics,
+// the replays or the leaderboards, since it hasn't been played by the rules.
+// Fields:
+// - enabled: whether the console is available.
+// - open: whether the console is shown and gets the keys.
+// - wasPaused: whether the game was paused before the console was opened.
+// - draft: the command being typed.
+// - lines: the latest output lines, the newest last.
+// - history: the latest commands, the newest last.
+// - recall: the index in history of the command recalled with ↑ and ↓; len(history) while typing a new one.
+type devConsole struct {
+	enabled   bool
+	open      bool
+	wasPaused bool
+	draft     string
+	lines     []consoleLine
+	history   []string
+	recall    int
+}
+
+// newDevConsole creates a closed developer console.
+//
+// Parameters:
+// - enabled (bool): Whether the console is available, i.e. the game runs with the -debug flag.
+func newDevConsole(enabled bool) *devConsole {
+	return &devConsole{enabled: enabled}
+}
+
+// handleKey processes a key press for the console: ~ opens and closes it, ENTER runs the command, ESC closes
+// the console, and ↑ and ↓ recall the previous commands.
+//
+// Parameters:
+// - rn (rune): The character of the released key.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled; all keys are while the console is open.
+func (c *devConsole) handleKey(g *Game, rn rune, name string) bool {
+	if !c.enabled {
+		return false
+	}
+	if name == "Backquote" || name == "Escape" && c.open {
+		c.toggle(g)
+		return true
+	}
+	if !c.open {
+		return false
+	}
+	switch name {
+	case "Enter":
+		c.exec(g, c.draft)
+		c.draft = ""
+	case "Backspace":
+		if _, size := utf8.DecodeLastRuneInString(c.draft); size > 0 {
+			c.draft = c.draft[:len(c.draft)-size]
+		}
+	case "ArrowUp":
+		if c.recall > 0 {
+			c.recall--
+			c.draft = c.history[c.recall]
+		}
+	case "ArrowDown":
+		if c.recall < len(c.history) {
+			c.recall++
+			c.draft = ""
+			if c.recall < len(c.history) {
+				c.draft = c.history[c.recall]
+			}
+		}
+	default:
+		if unicode.IsPrint(rn) && utf8.RuneCountInString(c.draft) < consoleMaxLen {
+			c.draft += string(rn)
+		}
+	}
+	return true
+}
+
+// toggle opens or closes the console, pausing the game while it's open.
+func (c *devConsole) toggle(g *Game) {
+	c.open = !c.open
+	c.draft = ""
+	c.recall = len(c.history)
+	if c.open {
+		c.wasPaused = g.paused
+		g.paused = !g.eng.GameOver
+		return
+	}
+	g.paused = c.wasPaused && !g.eng.GameOver
+}
+
+// exec runs a command and prints it with its output.
+//
+// Parameters:
+// - line (string): The command with its arguments, separated by spaces.
+func (c *devConsole) exec(g *Game, line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	c.history = append(c.history, strings.Join(fields, " "))
+	if len(c.history) > consoleHistory {
+		c.history = c.history[len(c.history)-consoleHistory:]
+	}
+	c.recall = len(c.history)
+	c.print(consoleLine{text: "> " + strings.Join(fields, " ")})
+
+	name := strings.ToLower(fields[0])
+	cmd, ok := consoleCommands[name]
+	if name == "help" {
+		cmd, ok = consoleCommand{run: consoleHelp}, true
+	}
+	if !ok {
+		c.print(consoleLine{text: fmt.Sprintf("unknown command %q, type help for the list", fields[0]), err: true})
+		return
+	}
+	out, err := cmd.run(g, fields[1:])
+	switch {
+	case err != nil:
+		c.print(consoleLine{text: err.Error(), err: true})
+	case out != "":
+		for _, text := range strings.Split(out, "\n") {
+			c.print(consoleLine{text: text})
+		}
+	}
+	g.needUpdateInfo = true
+}
+
+// print adds a line to the output, dropping the oldest one if there are too many.
+func (c *devConsole) print(line consoleLine) {
+	c.lines = append(c.lines, line)
+	if len(c.lines) > consoleLines {
+		c.lines = c.lines[len(c.lines)-consoleLines:]
+	}
+}
+
+// layout does nothing: the console is placed relative to the game area when it's drawn.
+func (c *devConsole) layout(g *Game) {}
+
+// update does nothing: the console changes only with the keys.
+func (c *devConsole) update(g *Game, dt time.Duration) {}
+
+// draw renders the output and the command being typed at the top of the game area.
+func (c *devConsole) draw(g *Game) {
+	if !c.open {
+		return
+	}
+	x, y := g.gameAreaSP.X, g.gameAreaSP.Y
+	g.cv.SetFillStyle(0, 0, 0, 0.8)
+	g.cv.FillRect(x, y, g.param.gameW, (consoleLines+1)*consoleLineH+12)
+	g.cv.SetFont(g.fonts.small, 13)
+	for i, line := range c.lines {
+		g.cv.SetFillStyle("#CFD8DC")
+		if line.err {
+			g.cv.SetFillStyle("#E57373")
+		}
+		g.cv.FillText(line.text, x+10, y+float64(i+1)*consoleLineH)
+	}
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.FillText("> "+c.draft+"_", x+10, y+(consoleLines+1)*consoleLineH+4)
+}
+
+// tamper marks the current game as changed from the console, so it isn't recorded; the replay recorded so far
+// is dropped, since it can't reproduce the game anymore.
+func (g *Game) tamper() {
+	g.tampered.Store(true)
+	g.rec.Store(nil)
+}
+
+// consoleHelp lists the commands.
+func consoleHelp(g *Game, args []string) (string, error) {
+	lines := []string{"help - list the commands"}
+	for _, name := range slices.Sorted(maps.Keys(consoleCommands)) {
+		cmd := consoleCommands[name]
+		lines = append(lines, strings.TrimSpace(name+" "+cmd.usage)+" - "+cmd.help)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// consoleSpeed sets the interval between two ticks, e.g. `speed 150`. The snake speeds up from it as usual
+// when it eats.
+func consoleSpeed(g *Game, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: speed MS")
+	}
+	ms, err := strconv.Atoi(args[0])
+	if err != nil || ms < engine.MinSpeed || ms > consoleMaxMS {
+		return "", fmt.Errorf("the interval must be from %d to %d ms", engine.MinSpeed, consoleMaxMS)
+	}
+	g.eng.Speed = ms
+	g.tamper()
+	return fmt.Sprintf("speed %dms", ms), nil
+}
+
+// consoleSpawn moves the food to a free cell, e.g. `spawn food 3 7`, or lays a tile on a cell, e.g. `spawn ice 3 7`,
+// for trying out the terrain of a level.
+func consoleSpawn(g *Game, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", errors.New("usage: spawn food|ice|mud|spikes|plain X Y")
+	}
+	p, err := consoleCell(g, args[1:])
+	if err != nil {
+		return "", err
+	}
+	what := strings.ToLower(args[0])
+	if tile, ok := engine.TileNames[what]; ok {
+		if err := g.eng.SetTerrain(g.eng.Terrain().With(p, tile)); err != nil {
+			return "", err
+		}
+		g.tamper()
+		return what + " at " + debugCell(p), nil
+	}
+	if what != "food" {
+		return "", fmt.Errorf("can't spawn %q, expected food, ice, mud, spikes or plain", args[0])
+	}
+	if g.eng.Snake.IsSnake(p) {
+		return "", fmt.Errorf("the cell %s is taken by the snake", debugCell(p))
+	}
+	g.eng.Food = p
+	g.tamper()
+	return "food at " + debugCell(p), nil
+}
+
+// consoleTeleport moves the whole snake so its head is in a cell, keeping its shape and direction,
+// e.g. `teleport 10 10`.
+func consoleTeleport(g *Game, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errors.New("usage: teleport X Y")
+	}
+	p, err := consoleCell(g, args)
+	if err != nil {
+		return "", err
+	}
+	if g.eng.GameOver {
+		return "", errors.New("the game is over")
+	}
+	head := g.eng.Snake.Head()
+	parts := make([]Point, len(g.eng.Snake.Parts))
+	for i, part := range g.eng.Snake.Parts {
+		parts[i] = Point{X: part.X + p.X - head.X, Y: part.Y + p.Y - head.Y}
+		if engine.CollidesWithWall(parts[i], g.eng.BoardSize()) {
+			return "", fmt.Errorf("the snake doesn't fit on the board with its head at %s", debugCell(p))
+		}
+	}
+	g.eng.Snake.Parts = parts
+	if g.eng.Snake.IsSnake(g.eng.Food) {
+		g.eng.Food = head
+	}
+	g.tamper()
+	return "head at " + debugCell(p), nil
+}
+
+// consoleRules lists the rules that can be switched with their state, or switches one, e.g. `rules assist on`.
+func consoleRules(g *Game, args []string) (string, error) {
+	names := slices.Sorted(maps.Keys(switchableRules))
+	if len(args) == 0 {
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = name + " " + onOffText(switchableRules[name].get(g))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	if len(args) != 2 || args[1] != "on" && args[1] != "off" {
+		return "", errors.New("usage: rules [NAME on|off]")
+	}
+	rule, ok := switchableRules[strings.ToLower(args[0])]
+	if !ok {
+		return "", fmt.Errorf("unknown rule %q, expected one of %v", args[0], names)
+	}
+	on := args[1] == "on"
+	if rule.get(g) != on {
+		rule.set(g, on)
+	}
+	return strings.ToLower(args[0]) + " " + onOffText(on), nil
+}
+
+// consoleSeed starts a new game from a seed, e.g. `seed 42`, to replay the same food. The game is recorded
+// as usual, since it's played by the rules.
+func consoleSeed(g *Game, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: seed N")
+	}
+	seed, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid seed %q", args[0])
+	}
+	if g.challenge.Load() != nil {
+		return "", errors.New("the seed can't be changed in the weekly challenge")
+	}
+	g.restartGame()
+	g.eng.ResetSized(seed, g.eng.BoardSize())
+	g.setGhost()
+	g.startRecording()
+	g.console.wasPaused = false
+	return fmt.Sprintf("new game from the seed %d", seed), nil
+}
+
+// consoleCell parses the coordinates of a cell on the board.
+//
+// Parameters:
+// - args ([]string): The x and the y of the cell.
+//
+// Returns:
+// - Point: The cell.
+// - error: An error if the coordinates aren't numbers or the cell is off the board.
+func consoleCell(g *Game, args []string) (Point, error) {
+	x, errX := strconv.Atoi(args[0])
+	y, errY := strconv.Atoi(args[1])
+	if errX != nil || errY != nil {
+		return Point{}, fmt.Errorf("invalid cell %s %s", args[0], args[1])
+	}
+	p := Point{X: float64(x), Y: float64(y)}
+	if engine.CollidesWithWall(p, g.eng.BoardSize()) {
+		return Point{}, fmt.Errorf("the cell %s is off the %dx%d board", debugCell(p), g.eng.BoardSize(), g.eng.BoardSize())
+	}
+	return p, nil
+}
+
+// onOffText returns "on" or "off".
+func onOffText(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
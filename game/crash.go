@@ -0,0 +1,143 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/version"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	inputLogSize = 100       // the number of the most recent input events kept for crash reports
+	crashDir     = "crashes" // directory in the data directory the crash reports are written to
+)
+
+// inputEvent is a single input event remembered for crash reports.
+type inputEvent struct {
+	at   time.Time
+	kind string
+	name string
+}
+
+// inputLog keeps the most recent input events in a ring buffer.
+// It is safe for concurrent use, since a crash may happen in any goroutine.
+type inputLog struct {
+	mu     sync.Mutex
+	events [inputLogSize]inputEvent
+	next   int
+	count  int
+}
+
+// add remembers an input event.
+//
+// Parameters:
+// - kind (string): The kind of the event, e.g. "key" or "mouse".
+// - name (string): The name of the key or the button.
+func (l *inputLog) add(kind, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[l.next] = inputEvent{at: time.Now(), kind: kind, name: name}
+	l.next = (l.next + 1) % len(l.events)
+	l.count = min(l.count+1, len(l.events))
+}
+
+// String formats the remembered events one per line, the oldest first.
+func (l *inputLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	start := (l.next - l.count + len(l.events)) % len(l.events)
+	for i := 0; i < l.count; i++ {
+		e := l.events[(start+i)%len(l.events)]
+		fmt.Fprintf(&b, "%s %-5s %s\n", e.at.Format("15:04:05.000"), e.kind, e.name)
+	}
+	return b.String()
+}
+
+// recoverCrash handles a panic of one of the game loops. It must be deferred at the top of every
+// long-running goroutine.
+//
+// Instead of dying silently to the console, the game writes a crash report (the panic, the stack trace,
+// the version, the configuration and the last input events) to the data directory, shows a dialog
+// telling the player where the report is, and exits.
+func (g *Game) recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	log.Printf("panic: %v\n%s", r, stack)
+	message := "The game has crashed unexpectedly."
+	if path, err := g.writeCrashReport(r, stack); err != nil {
+		log.Println(err)
+	} else {
+		message += "\n\nA crash report has been saved to:\n" + path +
+			"\n\nPlease attach it to an issue at https://github.com/DenisKhanov/Snake/issues"
+	}
+	var window *sdl.Window
+	if g.wnd != nil {
+		window = g.wnd.Window
+	}
+	if err := sdl.ShowSimpleMessageBox(sdl.MESSAGEBOX_ERROR, "SnakeGO crashed", message, window); err != nil {
+		log.Println(err)
+	}
+	os.Exit(2)
+}
+
+// writeCrashReport writes the crash report to the crashes directory of the data directory.
+//
+// Parameters:
+// - reason (any): The value the game panicked with.
+// - stack ([]byte): The stack trace of the panicking goroutine.
+//
+// Returns:
+// - string: The path of the written report.
+// - error: An error if the report cannot be written; otherwise, nil.
+func (g *Game) writeCrashReport(reason any, stack []byte) (string, error) {
+	if g.dataDir == "" {
+		return "", fmt.Errorf("error writing crash report: no data directory")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SnakeGO crash report\n\n")
+	fmt.Fprintf(&b, "Time:    %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version: %s\n", version.Get())
+	fmt.Fprintf(&b, "System:  %s/%s, %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintf(&b, "Panic:   %v\n\nStack:\n%s\n", reason, stack)
+	if cfg, err := json.MarshalIndent(g.cfg.Redacted(), "", "  "); err == nil {
+		fmt.Fprintf(&b, "Config:\n%s\n\n", cfg)
+	}
+	fmt.Fprintf(&b, "Last input events (oldest first):\n%s", g.inputs.String())
+
+	path := filepath.Join(g.dataDir, crashDir, time.Now().Format("crash-20060102-150405.txt"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("error writing crash report %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// showFatalError tells the player that the game cannot start, showing the error in a dialog
+// instead of a panic in the console, and exits.
+//
+// Parameters:
+// - err (error): The error that prevents the game from running.
+func showFatalError(err error) {
+	log.Println(err)
+	message := fmt.Sprintf("The game cannot start:\n\n%v", err)
+	if err := sdl.ShowSimpleMessageBox(sdl.MESSAGEBOX_ERROR, "SnakeGO", message, nil); err != nil {
+		log.Println(err)
+	}
+	os.Exit(1)
+}
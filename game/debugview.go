@@ -0,0 +1,142 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	debugW, debugLineH = 230.0, 16.0 // the width of the state panel and the height of its lines
+	debugMargin        = 8.0         // the distance between the panel and the corner of the game area
+	debugLabelMinW     = 18.0        // the smallest cell width with a coordinate label on every cell; narrower cells are labeled every 5th
+)
+
+// debugDirs are the names of the directions as seen on the screen; the engine's y axis grows in its Up direction,
+// which is down on the screen.
+var debugDirs = map[engine.Dir]string{engine.Up: "down", engine.Right: "right", engine.Down: "up", engine.Left: "left"}
+
+// debugOverlay is a HUD widget for inspecting the game while developing it: it labels the columns and rows
+// of the board with their coordinates, outlines the cells of the head and the food, and lists the state
+// of the game in the top-right corner of the game area: the head's cell, the direction, the tick,
+// the food's cell and the input waiting for the next tick.
+//
+// It is toggled with F4, next to the profiling overlay on F3.
+// Fields:
+// - visible: whether the overlay is shown.
+type debugOverlay struct {
+	visible atomic.Bool
+}
+
+// toggle shows or hides the overlay.
+func (d *debugOverlay) toggle() {
+	d.visible.Store(!d.visible.Load())
+}
+
+// layout does nothing: the overlay is placed relative to the game area when it's drawn.
+func (d *debugOverlay) layout(g *Game) {}
+
+// update does nothing: the overlay reads the state of the game when it's drawn.
+func (d *debugOverlay) update(g *Game, dt time.Duration) {}
+
+// draw renders the coordinates of the cells and the state panel.
+func (d *debugOverlay) draw(g *Game) {
+	if !d.visible.Load() {
+		return
+	}
+	s := g.eng.Snapshot()
+	g.clipGameArea()
+	d.drawCoords(g, s)
+	g.cv.Restore()
+	d.drawPanel(g, s)
+}
+
+// drawCoords labels the visible columns along the top edge of the game area and the visible rows along its left
+// edge, and outlines the cells of the head and the food.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+func (d *debugOverlay) drawCoords(g *Game, s engine.State) {
+	step := 1
+	if g.cellW < debugLabelMinW {
+		step = 5
+	}
+	g.cv.SetFont(g.fonts.small, math.Min(10, g.cellH*0.6))
+	g.cv.SetFillStyle(0, 0, 0, 0.5)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, 12)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, 16, g.param.gameH)
+	g.cv.SetFillStyle("#FFFFFF")
+	first := int(math.Floor(math.Min(g.cam.x, g.cam.y)))
+	for i := max(first, 0); i <= min(first+int(g.cam.cells)+1, s.Cells-1); i++ {
+		if i%step != 0 {
+			continue
+		}
+		x, y := g.toScreen(Point{X: float64(i), Y: float64(i)})
+		g.cv.FillText(fmt.Sprint(i), x+2, g.gameAreaSP.Y+10)
+		g.cv.FillText(fmt.Sprint(i), g.gameAreaSP.X+2, y+g.cellH/2+4)
+	}
+
+	g.cv.SetLineWidth(2)
+	g.cv.SetStrokeStyle("#FFEE58")
+	fx, fy := g.toScreen(s.Food)
+	g.cv.StrokeRect(fx, fy, g.cellW, g.cellH)
+	if len(s.Snake) > 0 {
+		g.cv.SetStrokeStyle("#4DD0E1")
+		hx, hy := g.toScreen(s.Snake[0])
+		g.cv.StrokeRect(hx, hy, g.cellW, g.cellH)
+	}
+}
+
+// drawPanel lists the state of the game in the top-right corner of the game area.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+func (d *debugOverlay) drawPanel(g *Game, s engine.State) {
+	lines := []struct {
+		text  string
+		color string
+	}{
+		{fmt.Sprintf("tick %d  %s  speed %dms", s.Tick, s.Elapsed.Truncate(time.Second/10), s.Speed), "#CFD8DC"},
+		{fmt.Sprintf("heading %s (engine %d)  turned %t", debugDirs[s.Direction], s.Direction, s.Turned), "#CFD8DC"},
+		{"food " + debugCell(s.Food), "#FFEE58"},
+		{fmt.Sprintf("length %d  size %d  snapshots %d", len(s.Snake), s.Size, g.eng.SavedStates()), "#CFD8DC"},
+		{fmt.Sprintf("tile %c  stuck %d", s.Terrain.At(g.eng.Snake.Head()), s.Stuck), "#CFD8DC"},
+		{"input " + d.pendingInput(g), "#CFD8DC"},
+	}
+	if len(s.Snake) > 0 {
+		lines[2].text = "head " + debugCell(s.Snake[0]) + "  " + lines[2].text
+	}
+	x := g.gameAreaEP.X - debugMargin - debugW
+	y := g.gameAreaSP.Y + debugMargin + 12
+	g.cv.SetFillStyle(0, 0, 0, 0.65)
+	g.cv.FillRect(x, y, debugW, float64(len(lines))*debugLineH+8)
+	g.cv.SetFont(g.fonts.small, 12)
+	for i, line := range lines {
+		g.cv.SetFillStyle(line.color)
+		g.cv.FillText(line.text, x+5, y+debugLineH*float64(i+1))
+	}
+}
+
+// pendingInput describes the turns waiting for the next ticks: the direction pressed on the keyboard
+// and the number of turns queued by the bots.
+func (d *debugOverlay) pendingInput(g *Game) string {
+	text := "none"
+	if g.keyboard != nil {
+		if dir, ok := g.keyboard.Pending(); ok {
+			text = debugDirs[dir]
+		}
+	}
+	if g.bots != nil {
+		text += fmt.Sprintf("  bots %d queued", g.bots.Queued())
+	}
+	return text
+}
+
+// debugCell formats the coordinates of a cell.
+func debugCell(p Point) string {
+	return fmt.Sprintf("(%d, %d)", int(p.X), int(p.Y))
+}
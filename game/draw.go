@@ -5,34 +5,73 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"time"
+
+	"github.com/DenisKhanov/Snake/version"
 )
 
 // drawWorld renders the background of the game area.
 //
-// This method fills a rectangular region representing the game world with a specific color.
+// This method fills a rectangular region representing the game world with the current world color.
 func (g *Game) drawWorld() {
 	g.cv.BeginPath()
-	g.cv.SetFillStyle("#78909C")
-	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.gameAreaEP.X-15, g.gameAreaEP.Y-15)
+	g.cv.SetFillStyle(g.worldColor())
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
 	g.cv.Stroke()
 }
 
 // drawGridGameArea renders a grid within the game area.
 //
-// This method draws evenly spaced vertical and horizontal lines to create a grid.
+// This method draws evenly spaced vertical and horizontal lines for every cell boundary visible through the camera.
 func (g *Game) drawGridGameArea() {
 	g.cv.BeginPath()
-	g.cv.SetStrokeStyle("#5D4037")
+	g.cv.SetStrokeStyle(g.pal().grid)
 	g.cv.SetLineWidth(0.5)
-	for i := 0; i < 20+1; i++ {
-		g.cv.MoveTo(g.gameAreaSP.X+float64(i)*g.cellH, g.gameAreaSP.Y)
-		g.cv.LineTo(g.gameAreaSP.X+float64(i)*g.cellH, g.gameAreaEP.Y)
-		g.cv.MoveTo(g.gameAreaSP.X, g.gameAreaSP.Y+float64(i)*g.cellW)
-		g.cv.LineTo(g.gameAreaEP.X, g.gameAreaSP.Y+float64(i)*g.cellW)
+	//the camera may be off the diagonal of the board, so the columns and the rows start from their own cells
+	for i := math.Floor(g.cam.x); i <= g.cam.x+g.cam.cells+1; i++ {
+		x, _ := g.toScreen(Point{X: i, Y: g.cam.y})
+		g.cv.MoveTo(x, g.gameAreaSP.Y)
+		g.cv.LineTo(x, g.gameAreaEP.Y)
+	}
+	for i := math.Floor(g.cam.y); i <= g.cam.y+g.cam.cells+1; i++ {
+		_, y := g.toScreen(Point{X: g.cam.x, Y: i})
+		g.cv.MoveTo(g.gameAreaSP.X, y)
+		g.cv.LineTo(g.gameAreaEP.X, y)
 	}
 	g.cv.Stroke()
 }
 
+// drawBoard draws the game area: the world background, the grid, the snake and the food,
+// as seen through the camera.
+func (g *Game) drawBoard() {
+	//draw world
+	g.drawWorld()
+	//draw board content through the camera
+	g.clipGameArea()
+	//draw grid within the game area
+	g.drawGridGameArea()
+	//draw the special tiles of the board
+	g.drawTerrain()
+	//draw the ghost of the best game under the snake
+	g.drawGhost()
+	//draw snake
+	g.drawSnake()
+	//draw the safe-move hints over the snake
+	if g.cfg.Hints {
+		g.drawHints()
+	}
+	//draw food
+	foodX, foodY := g.toScreen(g.eng.Food)
+	g.drawApple(foodX+1, foodY+1, g.side)
+	//outline the snake and the food in the large cell mode
+	if g.cfg.LargeCells {
+		g.drawOutlines()
+	}
+	//announce the next gust of wind
+	g.drawGust()
+	g.cv.Restore()
+}
+
 // drawSnakeHead renders the snake's head on the game canvas at the specified position.
 //
 // The snake's head is drawn as an ellipse with eyes, nostrils, and a tongue to create a more detailed visual representation.
@@ -48,7 +87,7 @@ func (g *Game) drawSnakeHead(x, y, side float64) {
 	radiusX := side / 2
 	radiusY := side * 0.6 / 2
 
-	g.cv.SetFillStyle("#039BE5")
+	g.cv.SetFillStyle(g.pal().head)
 	g.cv.BeginPath()
 	g.cv.Ellipse(centerX, centerY, radiusX, radiusY, 0, 0, 2*math.Pi, false)
 	g.cv.Fill()
@@ -95,31 +134,93 @@ func (g *Game) drawSnakeHead(x, y, side float64) {
 	g.cv.Fill()
 }
 
-// drawSnake renders the snake on the game canvas.
+// drawHeadMarker draws a bright outline around the snake's head and a short arrow showing where the snake moves,
+// so the head is easy to find on a small window. The marker also flashes briefly in red when a turn is rejected.
 //
-// The snake is drawn part by part, with the first part being the head and the rest of the body alternating between two different colors for visual distinction.
+// Parameters:
+// - x, y (float64): The position of the snake's head.
+// - side (float64): The size of the square cell the head fits into.
+// - color (string): The color of the marker.
+func (g *Game) drawHeadMarker(x, y, side float64, color string) {
+	centerX := x + side/2
+	centerY := y + side/2
+	// the direction in board cells is the same as on the screen; a turn pressed during the tick is shown at once
+	heading := g.eng.Snake.Direction
+	if g.keyboard != nil {
+		if pending, ok := g.keyboard.Pending(); ok {
+			heading = pending
+		}
+	}
+	dir := heading.Exec(Point{})
+	tipX, tipY := centerX+dir.X*side*0.9, centerY+dir.Y*side*0.9
+	baseX, baseY := centerX+dir.X*side*0.6, centerY+dir.Y*side*0.6
+	wing := side * 0.15
+
+	for _, style := range []struct {
+		color string
+		width float64
+	}{{"#000000", 5}, {color, 3}} {
+		g.cv.SetStrokeStyle(style.color)
+		g.cv.SetLineWidth(style.width)
+		g.cv.BeginPath()
+		g.cv.Ellipse(centerX, centerY, side/2, side*0.3, 0, 0, 2*math.Pi, false)
+		g.cv.MoveTo(centerX+dir.X*side/2, centerY+dir.Y*side*0.3)
+		g.cv.LineTo(tipX, tipY)
+		g.cv.MoveTo(baseX-dir.Y*wing, baseY-dir.X*wing)
+		g.cv.LineTo(tipX, tipY)
+		g.cv.LineTo(baseX+dir.Y*wing, baseY+dir.X*wing)
+		g.cv.Stroke()
+	}
+}
+
+// rejectFlashTime is how long the direction arrow stays red after a rejected turn.
+const rejectFlashTime = 250 * time.Millisecond
+
+// markRejected turns the marker of the head red for rejectFlashTime after a rejected turn. However fast the turns
+// are rejected, a new flash starts at most maxFlashRate times a second; when flashing is turned off in the settings,
+// the marker stays steadily red instead while the turns keep being rejected.
+func (g *Game) markRejected() {
+	since := g.renderClock - g.rejectedAt
+	if g.rejectedAt == 0 || g.cfg.NoFlashing || since.Seconds()*maxFlashRate >= 1 {
+		g.rejectedAt = g.renderClock
+	}
+}
+
+// drawSnake renders the snake on the game canvas, with the marker of its head if it's enabled
+// or a turn has just been rejected.
 func (g *Game) drawSnake() {
+	g.drawSnakeParts(g.eng.Snake.Parts)
+	if len(g.eng.Snake.Parts) == 0 {
+		return
+	}
+	x, y := g.toScreen(g.eng.Snake.Head())
+	switch {
+	case g.renderClock-g.rejectedAt < rejectFlashTime && g.rejectedAt > 0:
+		g.drawHeadMarker(x+1, y+1, g.side, "#E53935")
+	case g.cfg.HeadOutline:
+		g.drawHeadMarker(x+1, y+1, g.side, "#FFEB3B")
+	}
+}
+
+// drawSnakeParts renders a snake with the given segments in the colors of the current palette.
+//
+// The snake is drawn part by part, with the first part being the head and the rest of the body alternating between two different colors for visual distinction.
+//
+// Parameters:
+// - parts ([]Point): The positions of the snake's segments, the head first.
+func (g *Game) drawSnakeParts(parts []Point) {
 	g.cv.BeginPath()
-	for i, point := range g.snake.Parts {
+	for i, point := range parts {
+		x, y := g.toScreen(point)
 		switch {
 		case i == 0: //draw head
-			g.drawSnakeHead(g.gameAreaSP.X+point.X*g.cellW+1, g.gameAreaSP.Y+point.Y*g.cellH+1, g.side)
+			g.drawSnakeHead(x+1, y+1, g.side)
 		case i%2 == 0:
-			g.cv.SetFillStyle("#00BCD4")
-			g.cv.FillRect(
-				g.gameAreaSP.X+point.X*g.cellW+1,
-				g.gameAreaSP.Y+point.Y*g.cellH+1,
-				g.cellW-1*2,
-				g.cellH-1*2,
-			)
+			g.cv.SetFillStyle(g.pal().body)
+			g.cv.FillRect(x+1, y+1, g.cellW-1*2, g.cellH-1*2)
 		default:
-			g.cv.SetFillStyle("#4DD0E1")
-			g.cv.FillRect(
-				g.gameAreaSP.X+point.X*g.cellW+1,
-				g.gameAreaSP.Y+point.Y*g.cellH+1,
-				g.cellW-1*2,
-				g.cellH-1*2,
-			)
+			g.cv.SetFillStyle(g.pal().bodyAlt)
+			g.cv.FillRect(x+1, y+1, g.cellW-1*2, g.cellH-1*2)
 		}
 	}
 	g.cv.Stroke()
@@ -140,13 +241,13 @@ func (g *Game) drawApple(x, y, sizeCell float64) {
 	centerX := x + radius
 	centerY := y + radius
 
-	g.cv.SetFillStyle("#7CB342")
+	g.cv.SetFillStyle(g.pal().apple)
 	g.cv.BeginPath()
 	g.cv.Arc(centerX, centerY, radius, 0, 2*math.Pi, false)
 	g.cv.Fill()
 
 	// Draw an apple leaf
-	g.cv.SetFillStyle("#1B5E20")
+	g.cv.SetFillStyle(g.pal().leaf)
 	g.cv.BeginPath()
 	g.cv.MoveTo(centerX-5, centerY-radius*0.1)
 	g.cv.BezierCurveTo(
@@ -160,30 +261,42 @@ func (g *Game) drawApple(x, y, sizeCell float64) {
 	// Draw an apple stalk
 	stemWidth := sizeCell * 0.1
 	stemHeight := sizeCell * 0.2
-	g.cv.SetFillStyle("#8B4513")
+	g.cv.SetFillStyle(g.pal().stalk)
 	g.cv.FillRect(centerX-stemWidth/2, centerY-radius, stemWidth, -stemHeight)
 	g.cv.Stroke()
 }
 
 // drawGameInfo displays the current game statistics on the screen.
 //
-// This method shows the current score, the number of food items eaten, the current speed of the snake, and the FPS.
+// This method shows the current score, the number of food items eaten and the best score of the mode being played, marks the game as assisted
+// if the beginner assist has been used in it, with the number of moves undone, and shows the score of the ghost if the best game is raced.
+// The current speed of the snake is shown by the animated speed gauge widget.
 func (g *Game) drawGameInfo() {
 	g.cv.SetFillStyle("#4CAF50")
 	g.cv.BeginPath()
 	g.cv.SetFont(g.fonts.main, 25)
 
 	//draw score
-	text := fmt.Sprintf("Your score: %d", g.score)
+	text := g.tr.T("info.score", g.eng.Score)
 	g.cv.FillText(text, g.param.gameW+50, 50)
 
 	// food
-	text = fmt.Sprintf("You ate food: %d", g.ateFood)
+	text = g.tr.T("info.food", g.eng.AteFood)
 	g.cv.FillText(text, g.param.gameW+50, 85)
 
-	// speed
-	text = fmt.Sprintf("Your speed: %d", startSpeed-g.param.speed+5)
-	g.cv.FillText(text, g.param.gameW+50, 120)
+	// assisted game, with the number of undone moves
+	if g.eng.Assisted() {
+		g.cv.SetFillStyle("#FFA726")
+		g.cv.SetFont(g.fonts.small, 15)
+		text = g.tr.T("info.assisted")
+		if n := g.undos.Load(); n > 0 {
+			text = g.tr.T("info.assisted_undos", n)
+		}
+		g.cv.FillText(text, g.param.gameW+50, 170)
+	}
+	g.drawBest()
+	g.drawGhostScore()
+	g.drawChallenge()
 
 	g.cv.Stroke()
 }
@@ -195,22 +308,22 @@ func (g *Game) drawInstructions() {
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#FFEE58")
 	g.cv.SetFont(g.fonts.main, 20)
-	text := fmt.Sprint("Game Instructions:")
+	text := g.tr.T("help.title")
 	g.cv.FillText(text, g.param.gameW+50, 215)
 	g.cv.Stroke()
 
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#CFD8DC")
 	g.cv.SetFont(g.fonts.middle, 15)
-	text = fmt.Sprint("Use keys ← ↑ → ↓ to move snake")
+	text = g.tr.T("help.move")
 	g.cv.FillText(text, g.param.gameW+30, 245)
 
-	text = fmt.Sprint("Raise     to grow +++")
+	text = g.tr.T("help.grow")
 	g.cv.FillText(text, g.param.gameW+30, 275)
 
-	text = fmt.Sprint("If you eat your tail, ")
+	text = g.tr.T("help.tail1")
 	g.cv.FillText(text, g.param.gameW+30, 305)
-	text = fmt.Sprint(" the snake will shorten---")
+	text = g.tr.T("help.tail2")
 	g.cv.FillText(text, g.param.gameW+70, 325)
 	g.cv.Stroke()
 
@@ -219,25 +332,33 @@ func (g *Game) drawInstructions() {
 
 // drawAboutCreator displays information about the game's creator on the screen.
 //
-// This method renders a brief description of the game and credits the creator.
+// This method renders the version of the game, a brief description of the game and credits the creator.
 // The text is displayed at the specified coordinates.
 func (g *Game) drawAboutCreator(x, y float64) {
 	g.cv.BeginPath()
+	g.cv.SetFillStyle("#78909C")
+	g.cv.SetFont(g.fonts.small, 13)
+	g.cv.FillText(g.tr.T("about.version", version.Get()), x, y-25)
 	g.cv.SetFillStyle("#00897B")
 	g.cv.SetFont(g.fonts.small, 15)
-	text := fmt.Sprint("This game  was created in the Golang")
+	text := g.tr.T("about.created")
 	g.cv.FillText(text, x, y)
-	text = fmt.Sprint("by Denis Khanov")
+	text = g.tr.T("about.author")
 	g.cv.FillText(text, x, y+20)
 	g.cv.Stroke()
 }
 
-// drawFPS displays information about FPS
+// drawFPS displays information about FPS and whether the sound is muted
 func (g *Game) drawFPS() {
+	g.beginUI(0, 0)
+	defer g.endUI()
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#FFEE58")
 	g.cv.SetFont(g.fonts.small, 15)
-	text := fmt.Sprintf("FPS: %.1f", g.wnd.FPS())
+	text := g.tr.T("hud.fps", g.wnd.FPS())
+	if g.cfg.Muted {
+		text += "   " + g.tr.T("hud.muted")
+	}
 	g.cv.FillText(text, 5, 14)
 	g.cv.Stroke()
 }
@@ -249,32 +370,41 @@ func (g *Game) drawContacts() {
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#00897B")
 	g.cv.SetFont(g.fonts.small, 15)
-	text := fmt.Sprint("Game's repo:")
-	g.cv.FillText(text, g.param.gameW+130, g.param.gameH-10)
-	text = fmt.Sprint("Telegram:")
-	g.cv.FillText(text, g.param.gameW+130, g.param.gameH+10)
+	text := g.tr.T("contacts.repo")
+	bottom := g.panelH()
+	g.cv.FillText(text, g.param.gameW+130, bottom-10)
+	text = g.tr.T("contacts.telegram")
+	g.cv.FillText(text, g.param.gameW+130, bottom+10)
 
 	g.cv.SetFillStyle("#1A237E")
 	text = fmt.Sprint("@DenKhan")
-	g.cv.FillText(text, g.param.gameW+200, g.param.gameH+10)
+	g.cv.FillText(text, g.param.gameW+200, bottom+10)
 	text = fmt.Sprint("@GitHub")
-	g.cv.FillText(text, g.param.gameW+225, g.param.gameH-10)
+	g.cv.FillText(text, g.param.gameW+225, bottom-10)
 
 	onTheLinc := func(x, x1, x2 float64, y, y1, y2 float64) bool {
 		return x >= x1 && x <= x2 && y <= y1 && y >= y2
 	}
 
-	g.wnd.MouseUp = func(button, x, y int) {
-		if button == 1 && onTheLinc(float64(x), g.param.gameW+200, g.param.gameW+300,
-			float64(y), g.param.gameH+10, g.param.gameH-5) {
+	g.wnd.MouseUp = func(button, wx, wy int) {
+		g.inputs.add("mouse", fmt.Sprintf("button %d at %d,%d", button, wx, wy))
+		x, y := g.toPanel(g.toLayout(wx, wy))
+		bottom := g.panelH()
+		if button == 1 && onTheLinc(x, g.param.gameW+200, g.param.gameW+300,
+			y, bottom+10, bottom-5) {
 			if err := openURL("https://t.me/DenKhan"); err != nil {
 				log.Println(err)
 			}
-		} else if button == 1 && onTheLinc(float64(x), g.param.gameW+225, g.param.gameW+300,
-			float64(y), g.param.gameH-10, g.param.gameH-20) {
+		} else if button == 1 && onTheLinc(x, g.param.gameW+225, g.param.gameW+300,
+			y, bottom-10, bottom-20) {
 			if err := openURL("https://github.com/DenisKhanov/Snake"); err != nil {
 				log.Println(err)
 			}
+		} else if rel := g.newRelease.Load(); button == 1 && rel != nil && onTheLinc(x, g.param.gameW+50, g.param.gameW+300,
+			y, 22, 6) {
+			if err := openURL(rel.URL); err != nil {
+				log.Println(err)
+			}
 		}
 	}
 	g.cv.Stroke()
@@ -283,25 +413,83 @@ func (g *Game) drawContacts() {
 // drawGameOver displays the "Game Over" message and instructions on the screen.
 //
 // This method renders a prominent "Game Over" text and provides instructions to restart or exit the game.
-// The text is displayed at the specified coordinates.
+// The text is displayed at the specified coordinates, with a note if the game has set a new best score for its mode,
+// the offer to undo the fatal move of a practice game while it's open, or the number of moves undone,
+// followed by the badges of the unlocked achievements
+// and the player level with the XP awarded for the game, and, for a game of the weekly challenge, its place in the challenge's score table.
+// At the bottom, it offers exporting a share image of the game, and then shows the result of the export,
+// followed by the global rank of the game if the scores are submitted to an online leaderboard.
 //
 // Parameters:
 // - x, y (float64): The starting position for rendering the "Game Over" text.
 func (g *Game) drawGameOver(x, y float64) {
+	g.beginUI(g.param.gameW/2, g.param.gameH/2)
+	defer g.endUI()
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#C2185B")
 	g.cv.SetFont(g.fonts.main, 60)
-	text := fmt.Sprintf("Game over")
+	text := g.tr.T("gameover.title")
 	g.cv.FillText(text, x, y)
 	g.cv.Stroke()
 
 	g.cv.BeginPath()
 	g.cv.SetFillStyle("#1B5E20")
 	g.cv.SetFont(g.fonts.small, 15)
-	text = fmt.Sprintf("Press 'ENTER' for start new game")
+	text = g.tr.T("gameover.restart")
 	g.cv.FillText(text, x-60, y+40)
-	text = fmt.Sprintf("Press 'ESC' for close game")
+	text = g.tr.T("gameover.close")
 	g.cv.FillText(text, x+225, y+40)
+	switch left := g.undoLeft(); {
+	case left > 0:
+		g.cv.SetFillStyle("#FFA726")
+		g.cv.FillText(g.tr.T("gameover.undo", maxUndos-g.undos.Load(), int(left.Seconds())+1), x-60, y+65)
+	case g.eng.Assisted() && g.undos.Load() > 0:
+		g.cv.SetFillStyle("#FFA726")
+		g.cv.FillText(g.tr.T("gameover.assisted_undos", g.undos.Load()), x-60, y+65)
+	case g.eng.Assisted():
+		g.cv.SetFillStyle("#FFA726")
+		g.cv.FillText(g.tr.T("gameover.assisted"), x-60, y+65)
+	}
+	if g.newBest.Load() {
+		g.cv.SetFillStyle("#FFEE58")
+		g.cv.FillText(g.tr.T("gameover.new_best"), x+225, y+65)
+	}
+	g.cv.Stroke()
+	g.drawBadges(x-60+badgeR, y+100)
+	g.drawLevel(x-60, y+140, true)
+	g.drawChallengeResult(x-60, y+190)
+
+	//share image export
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 15)
+	text = g.tr.T("share.hint")
+	if g.telegram != nil {
+		text = g.tr.T("share.hint_telegram")
+	}
+	if msg := g.shareMsg.Load(); msg != nil && *msg != "" {
+		text = *msg
+	}
+	g.cv.FillText(text, x-60, y+220)
+	g.drawRank(x-60, y+245)
+
+}
+
+// drawPause displays the "Pause" message and the instruction to continue the game.
+//
+// Parameters:
+// - x, y (float64): The starting position for rendering the "Pause" text.
+func (g *Game) drawPause(x, y float64) {
+	g.beginUI(g.param.gameW/2, g.param.gameH/2)
+	defer g.endUI()
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 60)
+	g.cv.FillText(g.tr.T("pause.title"), x, y)
 	g.cv.Stroke()
 
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#1B5E20")
+	g.cv.SetFont(g.fonts.small, 15)
+	g.cv.FillText(g.tr.T("pause.continue"), x+5, y+40)
+	g.cv.Stroke()
 }
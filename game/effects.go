@@ -0,0 +1,126 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	crtPixelSize   = 3   // size of the square "pixel" block used by the pixelation pass
+	crtScanlineGap = 3   // distance in pixels between two scanlines
+	crtScanAlpha   = 0.2 // opacity of a single scanline
+)
+
+// drawCRTFilter applies the retro CRT post-processing effect over the game area.
+//
+// The effect is an overlay pass made of three steps, executed after the board has been drawn:
+// - pixelation: the board is read back and redrawn in coarse square blocks;
+// - scanlines: thin dark horizontal lines are drawn across the board;
+// - vignette: the corners of the board are slightly darkened with a radial gradient.
+func (g *Game) drawCRTFilter() {
+	x, y := g.gameAreaSP.X, g.gameAreaSP.Y
+	w, h := g.gameAreaEP.X-g.gameAreaSP.X, g.gameAreaEP.Y-g.gameAreaSP.Y
+
+	// image data is read in window pixels, so the layout offset is applied manually
+	g.pixelate(int(x+g.offset.X), int(y+g.offset.Y), int(w), int(h), crtPixelSize)
+
+	// Draw scanlines
+	g.cv.SetStrokeStyle(0, 0, 0, crtScanAlpha)
+	g.cv.SetLineWidth(1)
+	g.cv.BeginPath()
+	for ly := y; ly < y+h; ly += crtScanlineGap {
+		g.cv.MoveTo(x, ly+0.5)
+		g.cv.LineTo(x+w, ly+0.5)
+	}
+	g.cv.Stroke()
+
+	// Draw vignette
+	centerX, centerY := x+w/2, y+h/2
+	vignette := g.cv.CreateRadialGradient(centerX, centerY, w*0.35, centerX, centerY, w*0.75)
+	vignette.AddColorStop(0, 0, 0, 0, 0.0)
+	vignette.AddColorStop(1, 0, 0, 0, 0.55)
+	g.cv.SetFillStyle(vignette)
+	g.cv.FillRect(x, y, w, h)
+}
+
+// pixelate redraws the given canvas region in square blocks of the given size.
+//
+// Each block takes the color of its top-left pixel, which gives the board a low-resolution look.
+//
+// Parameters:
+// - x, y (int): The top-left corner of the region.
+// - w, h (int): The size of the region.
+// - size (int): The side of a single block in pixels.
+func (g *Game) pixelate(x, y, w, h, size int) {
+	img := g.cv.GetImageData(x, y, w, h)
+	if img == nil {
+		return
+	}
+	bounds := img.Bounds()
+	for by := bounds.Min.Y; by < bounds.Max.Y; by += size {
+		for bx := bounds.Min.X; bx < bounds.Max.X; bx += size {
+			c := img.RGBAAt(bx, by)
+			for py := by; py < by+size && py < bounds.Max.Y; py++ {
+				for px := bx; px < bx+size && px < bounds.Max.X; px++ {
+					img.SetRGBA(px, py, c)
+				}
+			}
+		}
+	}
+	g.cv.PutImageData(img, x, y)
+}
+
+// maxFlashRate is the largest number of flashes per second any effect may show.
+// Faster flashing can trigger seizures in photosensitive players.
+const maxFlashRate = 3.0
+
+// flash returns the brightness of a flashing effect at the given time, in the range [0, 1].
+//
+// The frequency is capped at maxFlashRate. When flashing is turned off in the settings,
+// the brightness is always 1, so the effect becomes a steady color change.
+//
+// Parameters:
+// - freq (float64): The flashes per second.
+// - clock (time.Duration): The time elapsed since the effect has started.
+//
+// Returns:
+// - float64: The brightness of the effect.
+func (g *Game) flash(freq float64, clock time.Duration) float64 {
+	if g.cfg.NoFlashing {
+		return 1
+	}
+	freq = math.Min(freq, maxFlashRate)
+	return (1 + math.Sin(2*math.Pi*freq*clock.Seconds())) / 2
+}
+
+const dayNightCycle = 4 * time.Minute // duration of a full day → night → day cycle
+
+// worldColor returns the background color of the game area.
+//
+// When the day/night cycle is enabled, the color is smoothly blended between the day and night tints of the palette
+// depending on the time elapsed on the render clock; otherwise, and in the reduced motion mode,
+// the day tint is returned.
+//
+// Returns:
+// - string: The color in the "#RRGGBB" format.
+func (g *Game) worldColor() string {
+	p := g.pal()
+	if !g.dayNight || g.cfg.ReducedMotion {
+		return p.day
+	}
+	day, night := parseHex(p.day), parseHex(p.night)
+	cycle := float64(g.renderClock%dayNightCycle) / float64(dayNightCycle)
+	phase := (1 - math.Cos(2*math.Pi*cycle)) / 2 // 0 - day, 1 - night
+	var tint [3]float64
+	for i := range tint {
+		tint[i] = day[i] + (night[i]-day[i])*phase
+	}
+	return colorHex(tint)
+}
+
+// colorHex formats the RGB components as a "#RRGGBB" color string.
+func colorHex(c [3]float64) string {
+	return fmt.Sprintf("#%02X%02X%02X", uint8(c[0]), uint8(c[1]), uint8(c[2]))
+}
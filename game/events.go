@@ -0,0 +1,82 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"sync"
+
+	"github.com/DenisKhanov/Snake/achievements"
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// eventKind is the kind of a game event.
+type eventKind int
+
+const (
+	eventAte      eventKind = iota // the snake ate food
+	eventCut                       // the snake bit itself or ran over spikes and was shortened
+	eventDied                      // the snake hit a wall and the game ended
+	eventRestart                   // a new game has started
+	eventSpeed                     // the tick interval has changed
+	eventRejected                  // a turn has been rejected: the snake can't reverse or has already turned during the tick
+	eventUnlocked                  // an achievement has been unlocked
+)
+
+// event describes something that happened in the game, for the modules reacting to it,
+// such as the audio.
+// Fields:
+// - kind: the kind of the event.
+// - pos: the board position the event happened at, if any.
+// - speed: the tick interval after the event, in milliseconds.
+// - cells: the number of cells along each side of the board.
+// - achievement: the unlocked achievement, for eventUnlocked.
+type event struct {
+	kind        eventKind
+	pos         Point
+	speed       int
+	cells       int
+	achievement *achievements.Achievement
+}
+
+// eventBus delivers the game events to the subscribed handlers.
+//
+// Events are published by the game logic goroutine and by the input handlers, so the handlers must be safe for concurrent use
+// and should return quickly, leaving heavy work to the render loop.
+type eventBus struct {
+	mu       sync.Mutex
+	handlers []func(e event)
+}
+
+// subscribe registers a handler called for every published event.
+func (b *eventBus) subscribe(h func(e event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// publish delivers the event to all handlers in the order they subscribed.
+func (b *eventBus) publish(e event) {
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// publishStep publishes the events of a single tick of the engine.
+//
+// Parameters:
+// - res (engine.StepResult): What happened during the tick.
+func (g *Game) publishStep(res engine.StepResult) {
+	head := g.eng.Snake.Head()
+	if res.Cut || res.Trimmed {
+		g.events.publish(event{kind: eventCut, pos: head, speed: g.eng.Speed, cells: g.eng.BoardSize()})
+	}
+	if res.Ate {
+		g.events.publish(event{kind: eventAte, pos: head, speed: g.eng.Speed, cells: g.eng.BoardSize()})
+		g.events.publish(event{kind: eventSpeed, pos: head, speed: g.eng.Speed, cells: g.eng.BoardSize()})
+	}
+	if res.Died {
+		g.events.publish(event{kind: eventDied, pos: head, speed: g.eng.Speed, cells: g.eng.BoardSize()})
+	}
+}
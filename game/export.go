@@ -0,0 +1,91 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/replay"
+	"github.com/DenisKhanov/Snake/version"
+	"github.com/tfriedel6/canvas"
+	"github.com/tfriedel6/canvas/backend/softwarebackend"
+)
+
+const (
+	exportMinSize  = 100             // the smallest board size of an exported animation, in pixels
+	exportLastHold = 2 * time.Second // how long the final frame of an exported animation is shown
+)
+
+// ExportReplay re-simulates a recorded game and saves it as an animated GIF.
+//
+// The game is rendered headlessly: no window is created, the board is drawn by the pure Go
+// software backend of the canvas, so the export works without a display or a GPU.
+// Every tick becomes a frame shown for as long as the tick lasted in the original game.
+//
+// Parameters:
+// - in (string): The path to the replay file.
+// - out (string): The path of the GIF file to create.
+// - size (int): The side of the exported board in pixels.
+//
+// Returns:
+// - error: An error if the replay cannot be loaded or played, or the GIF cannot be written; otherwise, nil.
+func ExportReplay(in, out string, size int) error {
+	if size < exportMinSize {
+		return fmt.Errorf("export size must be at least %d pixels", exportMinSize)
+	}
+	r, err := replay.Load(in)
+	if err != nil {
+		return err
+	}
+	g, backend := newHeadlessGame(size, r.Cells)
+
+	var frames []*image.RGBA
+	var delays []time.Duration
+	final, err := r.Play(func(e *engine.Engine) {
+		g.eng = e
+		g.drawBoard()
+		frame := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(frame, frame.Rect, backend.Image, image.Point{}, draw.Src)
+		frames = append(frames, frame)
+		delays = append(delays, e.Interval())
+	})
+	if err != nil {
+		return err
+	}
+	delays[len(delays)-1] = exportLastHold
+	if err = r.Check(final); err != nil {
+		return fmt.Errorf("replay %s is out of sync (recorded with version %q, exported with %q): %w",
+			in, r.GameVersion, version.Get().Version, err)
+	}
+	return writeGIF(out, frames, delays)
+}
+
+// newHeadlessGame creates a game that renders only the board into an offscreen software canvas.
+//
+// The game area fills the whole canvas and the camera shows the whole board.
+//
+// Parameters:
+// - size (int): The side of the canvas in pixels.
+// - cells (int): The number of cells along each side of the board.
+//
+// Returns:
+// - *Game: The game ready for drawing the board once its engine is set.
+// - *softwarebackend.SoftwareBackend: The backend holding the rendered image.
+func newHeadlessGame(size, cells int) (*Game, *softwarebackend.SoftwareBackend) {
+	backend := softwarebackend.New(size, size)
+	side := float64(size)
+	g := &Game{
+		cv:         canvas.New(backend),
+		param:      &GameParam{windowW: size, windowH: size, gameW: side, gameH: side},
+		gameAreaSP: Point{X: 0, Y: 0},
+		gameAreaEP: Point{X: side, Y: side},
+		cfg:        config.Default(),
+		eng:        engine.NewSized(0, cells),
+	}
+	g.setZoom(float64(cells))
+	return g, backend
+}
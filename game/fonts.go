@@ -0,0 +1,147 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"log"
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/tfriedel6/canvas"
+)
+
+// Fonts holds the font styles used in the game for different text stile.
+//
+// Each style has a chain of fonts in the order of preference: the decorative fonts only have Latin letters,
+// so for a language written in another script the style falls back to the first font of the chain
+// that can draw all letters of the language's messages.
+// Fields:
+// - main, middle, small: the fonts chosen for the current language.
+// - faces: all loaded fonts by file name.
+type Fonts struct {
+	main   *canvas.Font
+	middle *canvas.Font
+	small  *canvas.Font
+	faces  map[string]*fontFace
+}
+
+// fontFace is a loaded font together with its parsed outlines, which tell what characters it has.
+type fontFace struct {
+	font *canvas.Font
+	ttf  *truetype.Font
+}
+
+// fontFiles lists the embedded fonts by file name.
+var fontFiles = map[string][]byte{
+	mainFontFile:   samuraiFont,
+	middleFontFile: dejavuFont,
+	smallFontFile:  righteousFont,
+}
+
+// covers reports whether the font has glyphs for all letters of the text.
+// Digits and punctuation are not checked: every font has them, even if they are drawn as boxes in some.
+func (f *fontFace) covers(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) && f.ttf.Index(r) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// choose picks the font of every text style for the given text.
+//
+// Every style uses the first loaded font of its chain that covers the text; if none covers it,
+// the first loaded font of the chain is used, so the text is still drawn as well as possible.
+//
+// Parameters:
+// - text (string): All messages of the current language.
+func (f *Fonts) choose(text string) {
+	pick := func(chain ...string) *canvas.Font {
+		var first *canvas.Font
+		for _, name := range chain {
+			face, ok := f.faces[name]
+			if !ok {
+				continue
+			}
+			if face.covers(text) {
+				return face.font
+			}
+			if first == nil {
+				first = face.font
+			}
+		}
+		return first
+	}
+	if len(f.faces) == 0 {
+		return
+	}
+	f.main = pick(mainFontFile, smallFontFile, middleFontFile)
+	f.middle = pick(middleFontFile, smallFontFile, mainFontFile)
+	f.small = pick(smallFontFile, middleFontFile, mainFontFile)
+}
+
+// loadFonts loads the fonts used for different text styles and chooses them for the current language.
+//
+// The renderer degrades gracefully: a font that fails to load is replaced with one of the other fonts,
+// so the game stays playable, although it may look different.
+//
+// Returns:
+// - Fonts: The loaded fonts.
+// - error: An error if none of the fonts can be loaded.
+func (g *Game) loadFonts() (Fonts, error) {
+	fonts := Fonts{faces: make(map[string]*fontFace)}
+	var firstErr error
+	for name, embedded := range fontFiles {
+		face, err := g.loadFont(name, embedded)
+		if err != nil {
+			log.Println(err)
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		fonts.faces[name] = face
+	}
+	if len(fonts.faces) == 0 {
+		return Fonts{}, firstErr
+	}
+	fonts.choose(g.tr.Text())
+	return fonts, nil
+}
+
+// loadFont loads a single font, falling back to the embedded font if the file from the asset
+// override directory cannot be loaded.
+//
+// Parameters:
+// - name (string): The name of the font file.
+// - embedded ([]byte): The embedded font.
+//
+// Returns:
+// - *fontFace: The loaded font.
+// - error: An error if neither the override nor the embedded font can be loaded.
+func (g *Game) loadFont(name string, embedded []byte) (*fontFace, error) {
+	data := g.assets.read(name, embedded)
+	face, err := g.parseFont(data)
+	if err != nil && !bytes.Equal(data, embedded) {
+		log.Printf("error loading font %s, using the embedded one: %v", name, err)
+		face, err = g.parseFont(embedded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading font %s: %w", name, err)
+	}
+	return face, nil
+}
+
+// parseFont parses a TrueType font and loads it into the canvas.
+func (g *Game) parseFont(data []byte) (*fontFace, error) {
+	ttf, err := truetype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	font, err := g.cv.LoadFont(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return &fontFace{font: font, ttf: ttf}, nil
+}
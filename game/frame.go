@@ -0,0 +1,104 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// fpsCaps lists the frame rate caps the player can switch between; 0 means "no cap".
+var fpsCaps = []int{0, 30, 60, 120}
+
+// backgroundFPS is the frame rate used while the window is unfocused or minimized.
+const backgroundFPS = 5
+
+// frameLimiter keeps the render loop from running faster than the frame rate cap.
+// Fields:
+// - next: the earliest moment the next frame may start.
+type frameLimiter struct {
+	next time.Time
+}
+
+// wait blocks until the next frame may start according to the given frame rate cap.
+//
+// Parameters:
+// - fps (int): The maximum number of frames per second; 0 or less disables the cap.
+func (l *frameLimiter) wait(fps int) {
+	now := time.Now()
+	if fps <= 0 {
+		l.next = now
+		return
+	}
+	if d := l.next.Sub(now); d > 0 {
+		time.Sleep(d)
+		now = l.next
+	}
+	l.next = now.Add(time.Second / time.Duration(fps))
+}
+
+// applyVSync enables or disables vertical synchronization according to the configuration.
+func (g *Game) applyVSync() {
+	interval := 0
+	if g.cfg.VSync {
+		interval = 1
+	}
+	if err := sdl.GLSetSwapInterval(interval); err != nil {
+		log.Println("error setting vsync:", err)
+	}
+}
+
+// toggleVSync switches vertical synchronization on or off and remembers the choice in the configuration.
+func (g *Game) toggleVSync() {
+	g.cfg.VSync = !g.cfg.VSync
+	g.applyVSync()
+	g.saveConfig()
+}
+
+// cycleFPSCap switches to the next frame rate cap from fpsCaps and remembers the choice in the configuration.
+func (g *Game) cycleFPSCap() {
+	next := 0
+	for i, c := range fpsCaps {
+		if c == g.cfg.FPSCap {
+			next = (i + 1) % len(fpsCaps)
+			break
+		}
+	}
+	g.cfg.FPSCap = fpsCaps[next]
+	g.saveConfig()
+}
+
+// fpsCap returns the frame rate cap for the next frame.
+//
+// While the window is in the background, the rendering is throttled to backgroundFPS to save battery;
+// otherwise the cap from the configuration is used.
+func (g *Game) fpsCap() int {
+	if g.background {
+		return backgroundFPS
+	}
+	return g.cfg.FPSCap
+}
+
+// handleWindowEvent reacts to the window losing or gaining focus and to the window being minimized or restored.
+//
+// When the window goes to the background, the rendering is throttled and a running game is paused
+// automatically; the player resumes it manually when coming back.
+//
+// Parameters:
+// - event (sdl.Event): An SDL event that wasn't handled by the window itself.
+func (g *Game) handleWindowEvent(event sdl.Event) {
+	e, ok := event.(*sdl.WindowEvent)
+	if !ok {
+		return
+	}
+	switch e.Event {
+	case sdl.WINDOWEVENT_FOCUS_LOST, sdl.WINDOWEVENT_MINIMIZED:
+		g.background = true
+		if !g.eng.GameOver {
+			g.paused = true
+		}
+	case sdl.WINDOWEVENT_FOCUS_GAINED, sdl.WINDOWEVENT_RESTORED:
+		g.background = false
+	}
+}
@@ -2,17 +2,34 @@
 package game
 
 import (
+	"bytes"
 	_ "embed"
 	"fmt"
+	"github.com/DenisKhanov/Snake/achievements"
+	"github.com/DenisKhanov/Snake/botapi"
+	"github.com/DenisKhanov/Snake/challenges"
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/i18n"
+	"github.com/DenisKhanov/Snake/leaderboard"
+	"github.com/DenisKhanov/Snake/livecast"
+	"github.com/DenisKhanov/Snake/player"
+	"github.com/DenisKhanov/Snake/replay"
+	"github.com/DenisKhanov/Snake/saves"
+	"github.com/DenisKhanov/Snake/speedrun"
+	"github.com/DenisKhanov/Snake/stats"
+	"github.com/DenisKhanov/Snake/telegram"
+	"github.com/DenisKhanov/Snake/update"
 	"github.com/tfriedel6/canvas"
+	"github.com/tfriedel6/canvas/backend/goglbackend"
 	"github.com/tfriedel6/canvas/sdlcanvas"
 	"github.com/veandco/go-sdl2/sdl"
 	"log"
-	"math"
-	"math/rand"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,30 +45,17 @@ var righteousFont []byte
 //go:embed assets/SnakeGO.png
 var backgroundImage []byte
 
-const (
-	cellsCount = 20
-	startSpeed = 300
-)
-
-// Fonts holds the font styles used in the game for different text stile.
-type Fonts struct {
-	main   *canvas.Font
-	middle *canvas.Font
-	small  *canvas.Font
-}
-
 // GameParam holds the configuration parameters for the game window and game area.
-// It includes the dimensions of the window and game area, as well as the speed of the game.
+// It includes the dimensions of the window and game area.
 type GameParam struct {
 	windowW int
 	windowH int
 	gameW   float64
 	gameH   float64
-	speed   int
 }
 
 // NewGameParam creates and returns a new instance of GameParam with default values.
-// These values include the window size, game area size, and the initial speed of the game.
+// These values include the window size and game area size.
 // The returned GameParam is used to configure the game environment when creating a new game.
 func NewGameParam() *GameParam {
 	return &GameParam{
@@ -59,214 +63,346 @@ func NewGameParam() *GameParam {
 		windowH: 730,
 		gameW:   700.0,
 		gameH:   700.0,
-		speed:   startSpeed,
 	}
 }
 
 // Game represents the state and behavior of the Snake game. It holds the
-// game configuration, game area properties, and manages the snake, food,
-// score, and game state.
+// game configuration and game area properties, and drives the engine that manages
+// the snake, food, score, and game state.
 type Game struct {
 	cv  *canvas.Canvas
 	wnd *sdlcanvas.Window
 
+	mainCv       *canvas.Canvas
+	boardCv      *canvas.Canvas
+	boardBackend *goglbackend.GoGLBackendOffscreen
+
+	cfg           *config.Config
+	dataDir       string
+	geometry      config.Window
+	assets        assets
+	assetsChanged atomic.Bool
+	newRelease    atomic.Pointer[update.Release]
+
 	param *GameParam
-	snake *Snake
-	food  Point
+	eng   *engine.Engine
 	fonts Fonts
+	logo  *canvas.Image
+	tr    *i18n.Catalog
 
+	offset     Point
 	gameAreaSP Point
 	gameAreaEP Point
+	cam        camera
 	cellW      float64
 	cellH      float64
 	side       float64
 
-	score          int
-	ateFood        int
-	gameOver       bool
-	needMove       bool
-	needUpdateInfo bool
+	paused           bool
+	background       bool
+	needUpdateInfo   bool
+	needRedrawStatic bool
+
+	hud  []widget
+	perf *perfOverlay
+	dbg  *debugOverlay
+
+	console  *devConsole
+	tampered atomic.Bool
+
+	limiter      frameLimiter
+	audio        *audio
+	settings     *settingsScreen
+	slots        slotsScreen
+	stats        statsScreen
+	history      historyScreen
+	achievements *achievements.Store
+	xp           atomic.Int64
+	challenge    atomic.Pointer[challenges.Challenge]
+	challengeRes atomic.Pointer[challengeResult]
+	lastXP       atomic.Int64
+	records      atomic.Pointer[stats.Records]
+	newBest      atomic.Bool
+	pbs          *speedrun.PBs
+	splits       splitTracker
+	shareMsg     atomic.Pointer[string]
+	rankMsg      atomic.Pointer[string]
+	syncing      atomic.Bool
+	profileSync  atomic.Pointer[profileSync]
+	versus       atomic.Pointer[versusMatch]
+	leaderboard  *leaderboard.Client
+	telegram     *telegram.Client
+	player       player.Player
+	keyboard     *player.Keyboard
+	bots         *botapi.Live
+	botAddr      string
+	cast         *livecast.Caster
+	castAddr     string
+	resume       *saves.Slot
+	events       eventBus
+	recorder     *frameRecorder
+	rec          atomic.Pointer[replay.Recorder]
+	ghost        atomic.Pointer[replay.Player]
+	inputs       inputLog
+	lastTick     atomic.Int64
+	logicGen     atomic.Int64
+	stalled      bool
+	done         chan struct{}
+	devMode      bool
+	ascii        bool
+	undos        atomic.Int32
+	undoUntil    atomic.Int64
+
+	// lastAutosave is used only by the game logic goroutine
+	lastAutosave time.Time
+
+	crtFilter   bool
+	dayNight    bool
+	renderClock time.Duration
+	rejectedAt  time.Duration
+	previewPal  *colorPalette
+	hints       moveHints
 }
 
 // NewGame creates a new instance of the Game struct.
 // It initializes the game window and canvas with specified window size
-// and other game parameters, such as the game area dimensions and cell sizes.
+// and other game parameters, such as the game area dimensions and cell sizes,
+// and attaches the engine that runs the game.
 //
-// The function creates the window with a title and calculates the width and height
-// of each cell in the grid based on the game area dimensions and a predefined constant
-// `cellsCount` (which determines the number of cells in the grid).
-// If the window creation fails, the function will panic.
-func NewGame(param *GameParam) *Game {
-	wnd, cv, err := sdlcanvas.CreateWindow(param.windowW, param.windowH, "Welcome to the Snake game written in Golang")
+// The function creates the window with a title and an icon and calculates the width and height
+// of each cell in the grid based on the game area dimensions and the camera zoom, which
+// initially shows the whole board.
+// The window size and position saved in the previous session are restored (unless another monitor
+// is chosen in the options), and the window is switched to fullscreen mode if it's enabled in the configuration.
+//
+// Returns:
+// - *Game: The created game.
+// - error: An error if the window cannot be created.
+func NewGame(param *GameParam, eng *engine.Engine, cfg *config.Config, dataDir string, opts Options) (*Game, error) {
+	geom := loadWindowGeometry(dataDir)
+	if geom != nil {
+		param.windowW = max(geom.Width, minWindowW)
+		param.windowH = max(geom.Height, minWindowH)
+	}
+	wnd, cv, err := sdlcanvas.CreateWindow(param.windowW, param.windowH, opts.title(cfg))
 	if err != nil {
-		panic(err)
-	}
-
-	cellW := param.gameW / cellsCount
-	cellH := param.gameH / cellsCount
-	return &Game{
-		cv:         cv,
-		wnd:        wnd,
-		param:      param,
-		gameAreaSP: Point{15, 15},
-		gameAreaEP: Point{15 + param.gameW, 15 + param.gameH},
-		cellW:      cellW,
-		cellH:      cellH,
-		side:       math.Min(cellW-1*2, cellH-1*2),
-		gameOver:   false,
+		return nil, fmt.Errorf("error creating window: %w", err)
 	}
-}
 
-// initFonts initializes the fonts used in the game.
-// It loads three different font files for different text styles
-// and assigns them to the game's `fonts` field.
-//
-// The function will panic if any font fails to load.
-func (g *Game) initFonts() {
-	mainFont, err := g.cv.LoadFont(samuraiFont)
-	if err != nil {
-		panic(err)
+	g := &Game{
+		cv:       cv,
+		wnd:      wnd,
+		cfg:      cfg,
+		dataDir:  dataDir,
+		assets:   newAssets(opts.Assets, opts.Dev),
+		param:    param,
+		eng:      eng,
+		devMode:  opts.Dev,
+		botAddr:  opts.BotAPI,
+		castAddr: opts.Live,
+		player:   opts.Player,
+		ascii:    opts.ASCII,
+		recorder: newFrameRecorder(),
+		done:     make(chan struct{}),
 	}
-	instructionFont, err := g.cv.LoadFont(dejavuFont)
-	if err != nil {
-		panic(err)
+	if g.player == nil {
+		g.keyboard = player.NewKeyboard()
+		g.player = g.keyboard
 	}
-	easyFont, err := g.cv.LoadFont(righteousFont)
-	if err != nil {
-		panic(err)
+	g.cam.cells = g.boardCells()
+	g.achievements = achievements.New()
+	if dataDir != "" {
+		if g.achievements, err = achievements.Load(dataDir); err != nil {
+			log.Println(err)
+		}
 	}
+	g.loadHistory()
+	g.pbs = speedrun.New()
+	if dataDir != "" {
+		if g.pbs, err = speedrun.Load(dataDir); err != nil {
+			log.Println(err)
+		}
+	}
+	if cfg.Ghost {
+		eng.ResetSized(g.newGameSeed(), eng.BoardSize())
+	}
+	g.setGhost()
+	g.startRecording()
+	if cfg.Language == "" {
+		cfg.Language = i18n.Choose(i18n.Detect())
+		g.saveConfig()
+	}
+	g.loadLanguage()
+	g.audio = newAudio(dataDir, g.assets)
+	g.events.subscribe(g.audio.handleEvent)
+	g.applyVolumes()
+	g.settings = newSettingsScreen()
+	g.perf = newPerfOverlay()
+	g.dbg = &debugOverlay{}
+	g.console = newDevConsole(opts.Debug)
+	captions := newCaptionOverlay()
+	g.events.subscribe(captions.handleEvent)
+	toasts := newToastOverlay()
+	g.events.subscribe(toasts.handleEvent)
+	g.hud = []widget{newSpeedGauge(125, 180, 14), g.perf, g.dbg, captions, toasts, &speedrunTimer{}, g.console}
+	eng.SetRewind(undoSnapshot, 0)
+	g.layout(param.windowW, param.windowH)
+	wnd.Window.SetResizable(true)
+	wnd.Window.SetMinimumSize(minWindowW, minWindowH)
+	wnd.SizeChange = g.handleResize
+	wnd.Event = g.handleWindowEvent
+	if err = g.setWindowIcon(); err != nil {
+		log.Println(err)
+	}
+	g.placeWindow(geom, opts.Display)
+	g.rememberWindowGeometry()
+	g.applyVSync()
+	if cfg.Fullscreen {
+		g.setFullscreen(true)
+	}
+	return g, nil
+}
 
-	fonts := Fonts{
-		main:   mainFont,
-		middle: instructionFont,
-		small:  easyFont,
+// initFonts initializes the fonts used in the game.
+// It loads three different font files for different text styles (from the asset override
+// directory, if they are present there, or the embedded ones), chooses the ones that can draw
+// the current language and assigns them to the game's `fonts` field.
+//
+// Returns:
+// - error: An error if no font can be loaded at all; otherwise, nil.
+func (g *Game) initFonts() error {
+	fonts, err := g.loadFonts()
+	if err != nil {
+		return err
 	}
 	g.fonts = fonts
+	return nil
 }
 
-// setSnake sets the provided snake instance to the game object.
-// It assigns the passed *Snake object to the `g.snake` field,
-// allowing the game to track and update the snake's state.
-func (g *Game) setSnake(snake *Snake) {
-	g.snake = snake
+// loadLogo loads the logo image, falling back to the embedded image if the file from the asset
+// override directory cannot be loaded.
+//
+// Returns:
+// - *canvas.Image: The loaded logo.
+// - error: An error if neither the override nor the embedded image can be loaded.
+func (g *Game) loadLogo() (*canvas.Image, error) {
+	data := g.assets.read(logoFile, backgroundImage)
+	logo, err := g.cv.LoadImage(data)
+	if err != nil && !bytes.Equal(data, backgroundImage) {
+		log.Println("error loading logo, using the embedded one:", err)
+		logo, err = g.cv.LoadImage(backgroundImage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading logo: %w", err)
+	}
+	return logo, nil
 }
 
 // run starts the main game loop for the Snake game.
-// It initializes the game logic handling, food generation, and rendering loop.
+// It sets up the input handling and starts the game logic handling and rendering loop.
+// In development mode, it also starts watching the asset files for changes.
+// A game interrupted by a crash or a power loss is offered to be resumed first.
+// When the window is closed, the background goroutines of the game are stopped, the window
+// geometry is saved for the next session and the autosave is removed, since the game wasn't interrupted.
+// If any of the loops panics, a crash report is written (see recoverCrash).
 func (g *Game) run() {
-	go g.handleGameLogic()
-	g.foodGeneration()
+	defer g.recoverCrash()
+	if g.devMode {
+		go g.watchAssets()
+	}
+	g.checkForUpdates()
+	g.connectLeaderboard()
+	g.connectTelegram()
+	g.serveBots()
+	g.serveLive()
+	g.offerResume()
+	//keyboard scan
+	g.processInput()
+	g.heartbeat()
+	go g.handleGameLogic(g.logicGen.Load())
 	g.renderLoop()
+	close(g.done)
+	g.settleRun(true)
+	g.audio.close()
+	g.saveWindowGeometry()
+	if g.dataDir != "" {
+		if err := saves.RemoveAutosave(g.dataDir); err != nil {
+			log.Println(err)
+		}
+	}
 }
 
-// handleGameLogic manages the core game loop, including snake movement, collision detection,
-// food consumption, and scoring updates. It uses a timer to control the snake's speed
-// and processes game logic in each iteration.
+// handleGameLogic manages the core game loop. It uses a timer to control the snake's speed
+// and advances the engine by one tick in each iteration.
 //
 // The method performs the following tasks:
-// - Processes player input to update the snake's Direction.
-// - Checks for collisions with walls or the snake's own body, setting the gameOver flag if necessary.
-// - Updates the snake's size and score if it eats food.
-// - Adjusts the game's speed dynamically based on the snake's progress.
+// - Skips the snake's steps while the game is paused.
+// - Saves the state before the tick in practice games, so a fatal move can be undone.
+// - Advances the engine, which moves the snake, detects collisions, and updates the score and speed.
+// - Schedules the game information for redrawing when the score or the snake's size changes.
+// - Records the finished game, once the time to undo its fatal move has passed.
+// - Reports to the watchdog that the logic is alive.
 // - Resets the timer at the end of each loop iteration to maintain consistent movement intervals.
 //
-// This method runs continuously until the game is over or the application is exited.
-func (g *Game) handleGameLogic() {
-	var snakeTimer = time.NewTimer(time.Millisecond * time.Duration(g.param.speed))
-	//keyboard scan
-	g.processInput()
-	//loop
-	for {
-		<-snakeTimer.C
-		newPos := g.snake.Direction.Exec(g.snake.Parts[0])
-		if g.collidesWithWall(newPos) {
-			g.gameOver = true
-		}
-		//we cut off the snake if there is a new position on its body
-		if g.snake.CutIfSnake(newPos) {
-			newSize := len(g.snake.Parts)
-			g.score = g.score / g.snake.Size * newSize //correct score according new snake size
-			g.snake.Size = newSize
-			g.needUpdateInfo = true
-		}
-
-		//snakes move and eat food
-		if newPos == g.food {
-			g.snake.Add(newPos)
-			g.foodGeneration()
-			g.ateFood += 1
-			g.snake.Size++
-			g.param.speed -= 5
-			g.score += g.calculateScore(newPos)
-			g.needUpdateInfo = true
-		} else if !g.gameOver {
-			g.snake.Move(g.snake.Direction)
-			g.needMove = true
-		}
-		snakeTimer.Reset(time.Millisecond * time.Duration(g.param.speed))
-	}
-}
-
-// foodGeneration generates a new food position on the grid.
+// This method runs until the window is closed, or until the watchdog replaces it with a new
+// logic goroutine of another generation.
+// If the tick interval becomes non-positive, the loop stops, and the watchdog reports the problem.
 //
-// It randomly selects coordinates within the grid (cellsCount) and ensures
-// the position does not overlap with the snake's body. The new position is
-// stored in g.food.
-func (g *Game) foodGeneration() {
+// Parameters:
+// - gen (int64): The generation of the logic goroutine.
+func (g *Game) handleGameLogic(gen int64) {
+	defer g.recoverCrash()
+	var snakeTimer = time.NewTimer(g.eng.Interval())
+	//loop
+	defer snakeTimer.Stop()
 	for {
-		randX := rand.Intn(cellsCount)
-		randY := rand.Intn(cellsCount)
-		newPoint := Point{float64(randX), float64(randY)}
-		check := true
-		if g.snake.IsSnake(newPoint) {
-			check = false
-		}
-		if check {
-			g.food = newPoint
+		select {
+		case <-snakeTimer.C:
+		case <-g.done:
 			return
 		}
+		if g.logicGen.Load() != gen {
+			return
+		}
+		if !g.paused {
+			g.saveUndoPoint()
+			if dir := g.player.NextMove(g.eng.Snapshot()); dir != g.eng.Snake.Direction {
+				g.turn(dir)
+			}
+			if dir, ok := g.botTurn(); ok {
+				g.turn(dir)
+			}
+			start := time.Now()
+			res := g.eng.Step()
+			g.stepGhost()
+			g.perf.addTick(time.Since(start))
+			if g.ascii {
+				fmt.Println(g.eng.Snapshot())
+			}
+			g.publishStep(res)
+			g.observe(res)
+			g.broadcast()
+			g.checkAchievements(res)
+			g.takeSplit(res.Ate)
+			if res.Died || res.Won {
+				g.endRun()
+			}
+			g.autosave(res)
+			if res.Ate || res.Cut || res.Trimmed {
+				g.needUpdateInfo = true
+			}
+		}
+		g.settleRun(false)
+		interval := g.eng.Interval()
+		if interval <= 0 {
+			log.Println("game logic stopped: invalid tick interval", interval)
+			return
+		}
+		g.heartbeat()
+		snakeTimer.Reset(interval)
 	}
 }
 
-// calculateScore calculates the score based on the position of the food consumed by the snake.
-// The score is determined by the proximity of the food to the edges or corners of the game field,
-// with higher rewards for food closer to the corners and edges.
-//
-// Parameters:
-// - pos (Point): The position of the food that was consumed.
-//
-// Returns:
-// - int: The calculated score based on the food's position and the current game speed.
-//
-// Scoring logic:
-// - Food in the corners of the game field yields the highest score (multiplied by 4).
-// - Food on the edges but not in the corners yields a moderate score (multiplied by 2).
-// - Food elsewhere yields the base score (no multiplier).
-func (g *Game) calculateScore(pos Point) int {
-	switch {
-	case pos.IsCorner():
-		return 1000 / g.param.speed * 4
-	case pos.IsEdge():
-		return 1000 / g.param.speed * 2
-	default:
-		return 1000 / g.param.speed
-	}
-}
-
-// collidesWithWall checks if the given position causes a collision with the game field boundaries.
-//
-// Parameters:
-// - newPos (Point): The position to check for a boundary collision.
-//
-// Returns:
-// - bool: True if the position is outside the game field boundaries, otherwise false.
-//
-// The method verifies if the X or Y coordinates of the position are less than 0
-// or exceed the maximum number of cells in the game field (`cellsCount`).
-func (g *Game) collidesWithWall(newPos Point) bool {
-	return newPos.X < 0 || newPos.X >= cellsCount || newPos.Y < 0 || newPos.Y >= cellsCount
-}
-
 // processInput handles keyboard input during the game.
 //
 // This method assigns a function to the `KeyUp` event of the game window.
@@ -274,88 +410,338 @@ func (g *Game) collidesWithWall(newPos Point) bool {
 // This method dynamically updates the behavior of the game in response to player input.
 func (g *Game) processInput() {
 	g.wnd.KeyUp = func(code int, rn rune, name string) {
+		g.inputs.add("key", name)
+		//fullscreen keys: F11 or Alt+Enter
+		if name == "F11" || name == "Enter" && sdl.GetModState()&sdl.KMOD_ALT != 0 {
+			g.toggleFullscreen()
+			return
+		}
+		//developer console keys
+		if g.console.handleKey(g, rn, name) {
+			return
+		}
+		//settings screen keys
+		if g.settings.open {
+			g.settings.handleKey(g, name)
+			return
+		}
+		//interrupted game keys
+		if g.resume != nil {
+			g.handleResumeKey(name)
+			return
+		}
+		//save slot screen keys
+		if g.slots.open {
+			g.slots.handleKey(g, name)
+			return
+		}
+		//statistics screen keys
+		if g.stats.open {
+			g.stats.handleKey(g, name)
+			return
+		}
+		//history screen keys
+		if g.history.open {
+			g.history.handleKey(g, name)
+			return
+		}
+		//online versus game keys
+		if m := g.versus.Load(); m != nil {
+			g.handleVersusKey(m, code, rn, name)
+			return
+		}
+		//stalled game keys
+		if g.stalled {
+			switch name {
+			case "Enter":
+				g.restartLogic()
+			case "Escape":
+				g.wnd.Close()
+			}
+			return
+		}
 		//game over keys
-		if g.gameOver {
+		if g.eng.GameOver {
 			switch name {
 			case "Enter":
 				g.restartGame()
-				g.gameOver = false
 				return
 			case "Escape":
-				sdl.Quit()
-				os.Exit(1)
+				g.wnd.Close()
+			case "KeyE":
+				g.exportShareCard()
+				return
+			case "KeyU":
+				g.undo()
+				return
+			case "KeyB":
+				if g.telegram != nil {
+					g.sendToTelegram()
+					return
+				}
 			}
 		}
-		//Direction's keys  ← ↑ → ↓
-		if 79 <= code && code <= 82 && g.needMove {
-			newDir := g.snake.Direction.FromKey(code)
-			if !g.snake.Direction.CheckParallel(newDir) {
-				g.snake.Direction = newDir
-				g.needMove = false
+		//visual effect keys
+		switch name {
+		case "KeyP":
+			if !g.eng.GameOver {
+				g.paused = !g.paused
 			}
+			return
+		case "KeyC":
+			g.crtFilter = !g.crtFilter
+			return
+		//settings and sound keys
+		case "KeyS":
+			g.settings.toggle(g)
+			return
+		case "KeyM":
+			g.toggleMute()
+			return
+		//save slot keys
+		case "F5":
+			g.slots.show(g, true)
+			return
+		case "F9":
+			g.slots.show(g, false)
+			return
+		//weekly challenge
+		case "KeyW":
+			g.toggleChallenge()
+			return
+		//statistics screen
+		case "KeyT":
+			g.stats.toggle(g)
+			return
+		//history of the latest games
+		case "KeyH":
+			g.history.toggle(g)
+			return
+		//online versus game
+		case "KeyO":
+			g.toggleVersus()
+			return
+		//profiling overlay
+		case "F3":
+			g.perf.toggle()
+			return
+		//debug overlay
+		case "F4":
+			g.dbg.toggle()
+			return
+		case "KeyN":
+			g.dayNight = !g.dayNight
+			return
+		//frame rate keys
+		case "KeyV":
+			g.toggleVSync()
+			return
+		case "KeyF":
+			g.cycleFPSCap()
+			return
+		case "KeyR":
+			g.cycleRenderScale()
+			return
+		//save the last seconds of the game as a GIF
+		case "KeyG":
+			g.saveCapture()
+			return
+		//zoom keys
+		case "Equal", "NumpadAdd":
+			g.setZoom(g.cam.cells - zoomStep)
+			return
+		case "Minus", "NumpadSubtract":
+			g.setZoom(g.cam.cells + zoomStep)
+			return
+		}
+		//Direction's keys  ← ↑ → ↓
+		if 79 <= code && code <= 82 {
+			g.press(g.eng.Snake.Direction.FromKey(code))
 		}
 	}
 }
 
+// press passes a direction key to the keyboard player, which turns the snake on the next tick. The key is ignored
+// while another player controls the snake.
+//
+// If the keyboard rejects the turn, because the snake can't reverse or has already turned during this tick,
+// the player is told right away, like for a turn rejected by the engine.
+//
+// Parameters:
+// - dir (engine.Dir): The direction pressed.
+func (g *Game) press(dir engine.Dir) {
+	if g.keyboard == nil || g.eng.GameOver {
+		return
+	}
+	if !g.keyboard.Press(dir, g.eng.Snake.Direction) {
+		g.rejectTurn()
+	}
+}
+
+// turn changes the direction of the snake.
+//
+// It's called by the game logic goroutine with the moves of the player and of the bots.
+// If the engine rejects the turn, because the snake can't reverse or has already turned during this tick,
+// a rejection event is published, so the player hears a click and sees the direction arrow flash.
+//
+// Parameters:
+// - dir (engine.Dir): The new direction.
+func (g *Game) turn(dir engine.Dir) {
+	if g.eng.GameOver {
+		return
+	}
+	tick := g.eng.Tick
+	if g.eng.Turn(dir) {
+		if rec := g.rec.Load(); rec != nil {
+			rec.Turn(tick, dir)
+		}
+		return
+	}
+	g.rejectTurn()
+}
+
+// rejectTurn publishes a rejection event, so the player hears a click and sees the direction arrow flash.
+func (g *Game) rejectTurn() {
+	g.markRejected()
+	g.events.publish(event{kind: eventRejected, pos: g.eng.Snake.Head(), speed: g.eng.Speed, cells: g.eng.BoardSize()})
+}
+
 // renderLoop manages the rendering process and continuously updates the game window.
 //
 // This method uses the `MainLoop` function to handle the rendering cycle, drawing the game's visual elements on each frame.
 //
 // This loop ensures that the game visuals are consistently updated based on the game's current state.
 func (g *Game) renderLoop() {
-	logo, err := g.cv.LoadImage(backgroundImage)
+	//the logo is optional: without it the side panel is drawn without the image
+	logo, err := g.loadLogo()
 	if err != nil {
 		log.Println(err)
 	}
-	g.drawGameInfo()
-	//draw game instructions for the player
-	g.drawInstructions()
-	// draw creator information
-	g.drawAboutCreator(g.param.gameW+20, g.param.gameH-50)
-	//draw contact details
-	g.drawContacts()
-	//draw logo
-	g.cv.DrawImage(logo, g.param.gameW+40, g.param.gameH-350, 250, 250)
+	g.logo = logo
 
 	//start loop
+	lastFrame := time.Now()
+	wasGameOver := false
 	g.wnd.MainLoop(func() {
+		//respect the frame rate cap
+		g.limiter.wait(g.fpsCap())
+		//advance render clock
+		now := time.Now()
+		dt := now.Sub(lastFrame)
+		lastFrame = now
+		g.renderClock += dt
+		g.updateCamera(dt)
+		g.updateHUD(dt)
+		//make sure the game logic is still ticking
+		g.checkWatchdog()
+		//play the sound effects, and lower the music while the game is paused or over
+		g.audio.update(g.paused || g.eng.GameOver || g.stalled, dt)
+		//reload assets changed on disk in development mode
+		if g.assetsChanged.Swap(false) {
+			g.reloadAssets()
+		}
+		//apply the profile synced through the leaderboard server
+		g.applyProfileSync()
+		//center the content in the window
+		g.cv.Save()
+		g.cv.Translate(g.offset.X, g.offset.Y)
+		//redraw the side panel for the first frame and after the layout has changed
+		if g.needRedrawStatic {
+			g.drawStatic()
+		}
 		//clear game world
 		g.cv.ClearRect(0, 0, g.param.gameW, g.param.gameH+30) // update game area
-		//draw world
-		g.drawWorld()
-		//draw grid within the game area
-		g.drawGridGameArea()
-
 		g.drawFPS()
-		//draw snake
-		g.drawSnake()
-		//draw food
-		g.drawApple(g.gameAreaSP.X+g.food.X*g.cellW+1, g.gameAreaSP.Y+g.food.Y*g.cellH+1, g.side)
+		//draw the board, possibly at a lower internal resolution, or the shared board of an online game
+		versus := g.versus.Load()
+		g.beginBoard()
+		if versus != nil {
+			g.drawVersus(versus)
+		} else {
+			g.drawBoard()
+		}
+		g.endBoard()
+		//record the board for GIF clips; save a clip automatically when the game ends, if enabled
+		if !g.eng.GameOver && !g.paused {
+			g.recorder.record(g, dt)
+		}
+		if g.eng.GameOver && !wasGameOver && g.cfg.GIFOnGameOver {
+			g.saveCapture()
+		}
+		wasGameOver = g.eng.GameOver
 		// draw "Game Over" screen, if the game has ended
-		if g.gameOver {
+		if g.eng.GameOver && versus == nil {
 			g.drawGameOver(g.param.gameW/2-160, g.param.gameH/2)
 		}
+		// draw "Pause" screen, if the game is paused
+		if g.paused && versus == nil {
+			g.drawPause(g.param.gameW/2-80, g.param.gameH/2)
+		}
+		// ask whether to resume the interrupted game, if there is one
+		if g.resume != nil {
+			g.drawResume(g.param.gameW/2-220, g.param.gameH/2)
+		}
+		// draw the error screen, if the game logic has stopped responding
+		if g.stalled {
+			g.drawStalled(g.param.gameW/2-200, g.param.gameH/2)
+		}
+		// apply retro CRT effect over the board, if it's enabled
+		if g.crtFilter {
+			g.drawCRTFilter()
+		}
+		// draw the settings screen, the save slot screen and the statistics screen, if they're open
+		g.settings.draw(g)
+		g.slots.draw(g)
+		g.stats.draw(g)
+		g.history.draw(g)
 		// this is an optimization to avoid drawing relatively static information every frame
 		if g.needUpdateInfo {
+			g.beginPanel()
 			//clear game world
-			g.cv.ClearRect(750, 0, 280, 200) //update only GameInfo area
+			g.cv.ClearRect(g.param.gameW+50, 0, sidePanelW-50, 200) //update only GameInfo area
 			//draw game information, such as score and speed
 			g.drawGameInfo()
+			g.drawUpdateBanner()
+			g.endUI()
 			g.needUpdateInfo = false
 		}
+		//draw animated HUD widgets
+		g.drawHUD()
+		g.cv.Restore()
 	})
 }
 
 // restartGame resets the game state to its initial values, effectively restarting the game.
 //
-// This method resets the snake's position and state, sets the score and food count to zero,
-// restores the default game speed, and flags the game as not over.
+// This method starts a new game in the engine from a new seed (or from the seed of the best game, which is
+// raced by the ghost, if the ghost is on, or from the seed and with the rules of the weekly challenge
+// during a challenge), which resets the snake's position and state,
+// the score and food count, and the game speed, clears the recorded GIF frames and starts recording the replay.
 func (g *Game) restartGame() {
-	g.snake.Reset()
-	g.score = 0
-	g.ateFood = 0
-	g.param.speed = 300
-	g.gameOver = false
+	g.settleRun(true)
+	g.undos.Store(0)
+	g.tampered.Store(false)
+	var terrain engine.Terrain
+	g.eng.Wind = g.cfg.Wind && g.challenge.Load() == nil
+	if c := g.challenge.Load(); c != nil {
+		g.eng.ResetSized(c.Seed, c.Rules.Cells)
+		terrain = c.Rules.Terrain
+	} else {
+		g.eng.ResetSized(g.newGameSeed(), boardSizeFor(g.cfg))
+	}
+	if err := g.eng.SetTerrain(terrain); err != nil {
+		log.Println(err)
+	}
+	g.challengeRes.Store(nil)
+	g.newBest.Store(false)
+	g.splits.reset()
+	g.setShareMessage("")
+	g.setRankMessage("")
+	g.setGhost()
+	g.needUpdateInfo = true
+	g.recorder.reset()
+	g.startRecording()
+	g.events.publish(event{kind: eventRestart, speed: g.eng.Speed})
 }
 
 // openURL opens the specified URL in the default web browser based on the operating system.
@@ -391,20 +777,49 @@ func openURL(url string) error {
 }
 
 // RunGame initializes and starts a new game of Snake.
-// It creates a new Snake object, resets it, initializes game parameters, and runs the game.
+// The SNAKE_* environment variables take precedence over the configuration file, and the options passed
+// on the command line take precedence over both.
+// It creates a new engine, initializes game parameters, and runs the game.
 //
 // The function does the following:
-// 1. Creates a new Snake instance using NewSnake() and resets it.
-// 2. Initializes the game parameters with NewGameParam().
-// 3. Creates a new game instance with NewGame(gameParam) and sets up the game environment.
-// 4. Initializes fonts for rendering and sets the Snake for the game.
-// 5. Starts the game loop with the run method.
-func RunGame() {
-	snake := NewSnake()
-	snake.Reset()
+// 1. Finds the data directory (next to the executable in portable mode) and loads the user configuration;
+// if it cannot be loaded, the defaults are used. Then the environment overrides are applied.
+// 2. Creates a new engine with a game started from a random seed.
+// 3. Initializes the game parameters with NewGameParam().
+// 4. Creates a new game instance with NewGame(gameParam, eng, cfg, dataDir, opts) and sets up the game environment.
+// 5. Initializes fonts for rendering.
+// 6. Starts the game loop with the run method.
+//
+// If the window or the fonts cannot be created, an error dialog is shown instead of a panic.
+func RunGame(opts Options) {
+	dataDir, err := config.Dir(opts.Portable)
+	if err != nil {
+		log.Println(err)
+	}
+	cfg := config.Default()
+	if dataDir != "" {
+		if cfg, err = config.Load(config.Path(dataDir)); err != nil {
+			log.Println(err)
+		}
+	}
+	overridden, err := cfg.ApplyEnv(os.Environ())
+	if err != nil {
+		log.Println(err)
+	}
+	if len(overridden) > 0 {
+		log.Println("settings overridden by the environment:", strings.Join(overridden, ", "))
+	}
+	eng := engine.NewSized(time.Now().UnixNano(), boardSizeFor(cfg))
+	eng.Assist = cfg.Assist
+	eng.Wind = cfg.Wind
 	gameParam := NewGameParam()
-	game := NewGame(gameParam)
-	game.initFonts()
-	game.setSnake(snake)
+	game, err := NewGame(gameParam, eng, cfg, dataDir, opts)
+	if err != nil {
+		showFatalError(err)
+	}
+	if err = game.initFonts(); err != nil {
+		showFatalError(err)
+	}
 	game.run()
+	game.wnd.Destroy()
 }
@@ -0,0 +1,116 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/DenisKhanov/Snake/replay"
+)
+
+const ghostAlpha = 0.35 // the opacity of the ghost snake
+
+// loadBestReplay reads the replay of the best unassisted game on a board of the given size.
+//
+// The replay is verified by re-simulating it, so a corrupted or tampered file is neither raced nor
+// kept as the best game: it's replaced by the next finished game.
+//
+// Parameters:
+// - cells (int): The side of the board in cells.
+//
+// Returns:
+// - *replay.Replay: The best game, or nil if there is none or it can't be read.
+func (g *Game) loadBestReplay(cells int) *replay.Replay {
+	if g.dataDir == "" {
+		return nil
+	}
+	r, err := replay.Load(bestReplayPath(g.dataDir, cells))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println(err)
+		}
+		return nil
+	}
+	if err = r.Verify(); err != nil {
+		log.Printf("best game %s is rejected: %v", bestReplayPath(g.dataDir, cells), err)
+		return nil
+	}
+	return r
+}
+
+// newGameSeed returns the seed for a new game: the seed of the best game on the board of the current size
+// when the ghost is on, so the player can race their best run with the same food, otherwise a new random seed.
+func (g *Game) newGameSeed() int64 {
+	if g.cfg.Ghost {
+		if best := g.loadBestReplay(boardSizeFor(g.cfg)); best != nil {
+			return best.Seed
+		}
+	}
+	return time.Now().UnixNano()
+}
+
+// setGhost starts the ghost of the best game, a translucent snake that follows the recorded inputs of the best run,
+// if the ghost is on and the current game is played from the seed of the best one. The ghost catches up with
+// the current tick, so it also runs in lockstep with a loaded game.
+func (g *Game) setGhost() {
+	g.ghost.Store(nil)
+	if !g.cfg.Ghost {
+		return
+	}
+	best := g.loadBestReplay(g.eng.BoardSize())
+	if best == nil || best.Seed != g.eng.Seed() {
+		return
+	}
+	p, err := replay.NewPlayer(best)
+	if err != nil {
+		log.Println("error starting ghost:", err)
+		return
+	}
+	for !p.Done() && p.Engine().Tick < g.eng.Tick {
+		p.Step()
+	}
+	g.ghost.Store(p)
+}
+
+// stepGhost advances the ghost by a single tick. It's called by the game logic goroutine after every tick
+// of the game, so both engines run in lockstep.
+func (g *Game) stepGhost() {
+	if p := g.ghost.Load(); p != nil {
+		p.Step()
+	}
+}
+
+// drawGhost renders the ghost snake under the player's snake: the body as translucent cells and the head
+// as a translucent outline. The ghost disappears when the best run has ended.
+func (g *Game) drawGhost() {
+	p := g.ghost.Load()
+	if p == nil || p.Done() {
+		return
+	}
+	g.cv.SetGlobalAlpha(ghostAlpha)
+	g.cv.SetFillStyle("#FFFFFF")
+	g.cv.SetStrokeStyle("#FFFFFF")
+	g.cv.SetLineWidth(2)
+	for i, point := range p.Engine().Snake.Parts {
+		x, y := g.toScreen(point)
+		if i == 0 {
+			g.cv.StrokeRect(x+2, y+2, g.cellW-4, g.cellH-4)
+			continue
+		}
+		g.cv.FillRect(x+1, y+1, g.cellW-2, g.cellH-2)
+	}
+	g.cv.SetGlobalAlpha(1)
+}
+
+// drawGhostScore displays the score of the ghost on the side panel, so the player can see whether they are ahead.
+func (g *Game) drawGhostScore() {
+	p := g.ghost.Load()
+	if p == nil {
+		return
+	}
+	g.cv.SetFillStyle("#ECEFF1")
+	g.cv.SetFont(g.fonts.small, 15)
+	g.cv.FillText(g.tr.T("info.ghost", p.Engine().Score), g.param.gameW+50, 195)
+}
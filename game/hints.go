@@ -0,0 +1,127 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/path"
+)
+
+const hintAlpha = 0.4 // the opacity of the safe-move hints
+
+// hintKey identifies the tick the safe-move hints have been computed for: the tick alone repeats after a restart.
+type hintKey struct {
+	tick   int
+	head   Point
+	length int
+}
+
+// moveHints are the safe-move hints of a tick, shown for practicing with the "hints" setting: what each move
+// the snake can make on the next tick leads to.
+// Fields:
+// - key: the tick the hints have been computed for.
+// - walls: the directions leading into a wall, which ends the game.
+// - bites: the cells of the body the snake would bite, losing everything behind them.
+// - open: the cells the snake can move to and still follow its tail afterwards, so it can't get trapped.
+type moveHints struct {
+	key   hintKey
+	walls []engine.Dir
+	bites []Point
+	open  []Point
+}
+
+// computeHints finds the safe-move hints of a state of the game with the path package.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+//
+// Returns:
+// - moveHints: The hints; empty when the game is over.
+func computeHints(s engine.State) moveHints {
+	var h moveHints
+	if len(s.Snake) == 0 || s.GameOver {
+		return h
+	}
+	h.key = hintKey{tick: s.Tick, head: s.Snake[0], length: len(s.Snake)}
+	grid := path.FromState(s)
+	for _, dir := range []engine.Dir{engine.Up, engine.Right, engine.Down, engine.Left} {
+		if s.Direction.CheckParallel(dir) || dir != s.Direction && !s.Terrain.Turnable(s.Snake[0]) {
+			continue
+		}
+		next := dir.Exec(s.Snake[0])
+		switch {
+		case engine.CollidesWithWall(next, s.Cells):
+			h.walls = append(h.walls, dir)
+		case !grid.Free(next, 1):
+			h.bites = append(h.bites, next)
+		case tailReachable(s, next):
+			h.open = append(h.open, next)
+		}
+	}
+	return h
+}
+
+// tailReachable reports whether the snake can still reach its tail after moving to a cell next to its head.
+// A snake that can follow its tail can't get trapped, however long it gets.
+//
+// Parameters:
+// - s (engine.State): The state of the game before the move.
+// - next (Point): The cell the head moves to.
+func tailReachable(s engine.State, next Point) bool {
+	body := append([]Point{next}, s.Snake...)
+	if next != s.Food {
+		body = body[:len(body)-1]
+	}
+	_, ok := path.Find(path.FromState(engine.State{Cells: s.Cells, Snake: body}), next, body[len(body)-1])
+	return ok
+}
+
+// drawHints draws the safe-move hints around the snake's head: a red bar along the walls the snake would hit,
+// a white cross on red over the cells of the body it would bite, and a white ring on green in the cells that keep
+// a way to its tail open. The marks differ in shape as well as in color, for colorblind players. The hints are
+// recomputed once per tick.
+func (g *Game) drawHints() {
+	s := g.eng.Snapshot()
+	if len(s.Snake) == 0 || s.GameOver {
+		return
+	}
+	if key := (hintKey{tick: s.Tick, head: s.Snake[0], length: len(s.Snake)}); key != g.hints.key {
+		g.hints = computeHints(s)
+	}
+	g.cv.SetGlobalAlpha(hintAlpha)
+	g.cv.SetLineWidth(3)
+
+	g.cv.SetStrokeStyle("#E53935")
+	x, y := g.toScreen(s.Snake[0])
+	for _, dir := range g.hints.walls {
+		d := dir.Exec(Point{})
+		cx, cy := x+g.cellW/2+d.X*g.cellW/2, y+g.cellH/2+d.Y*g.cellH/2
+		g.cv.BeginPath()
+		g.cv.MoveTo(cx-d.Y*g.cellW/2, cy-d.X*g.cellH/2)
+		g.cv.LineTo(cx+d.Y*g.cellW/2, cy+d.X*g.cellH/2)
+		g.cv.Stroke()
+	}
+	g.cv.SetStrokeStyle("#FFFFFF")
+	g.cv.SetFillStyle("#E53935")
+	for _, p := range g.hints.bites {
+		x, y := g.toScreen(p)
+		g.cv.FillRect(x+1, y+1, g.cellW-2, g.cellH-2)
+		g.cv.BeginPath()
+		g.cv.MoveTo(x+g.cellW*0.25, y+g.cellH*0.25)
+		g.cv.LineTo(x+g.cellW*0.75, y+g.cellH*0.75)
+		g.cv.MoveTo(x+g.cellW*0.75, y+g.cellH*0.25)
+		g.cv.LineTo(x+g.cellW*0.25, y+g.cellH*0.75)
+		g.cv.Stroke()
+	}
+
+	g.cv.SetFillStyle("#43A047")
+	for _, p := range g.hints.open {
+		x, y := g.toScreen(p)
+		g.cv.FillRect(x+1, y+1, g.cellW-2, g.cellH-2)
+		g.cv.BeginPath()
+		g.cv.Ellipse(x+g.cellW/2, y+g.cellH/2, g.cellW*0.25, g.cellH*0.25, 0, 0, 2*math.Pi, false)
+		g.cv.Stroke()
+	}
+	g.cv.SetGlobalAlpha(1)
+}
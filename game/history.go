@@ -0,0 +1,208 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/stats"
+)
+
+const (
+	historyMax   = 50   // the number of the latest games listed on the history screen
+	historyRowH  = 26.0 // the height of a row of the history screen
+	historyGIFPx = 400  // the side of the GIFs exported from the history screen, in pixels
+)
+
+// historyScreen is the overlay listing the latest historyMax games, opened with H.
+//
+// Every row shows when the game ended, its mode, score and duration, and how it ended. The arrow keys ↑ ↓ select
+// a game, and Enter exports the replay of the selected game, if it was saved, as a GIF into the captures
+// directory. H or ESC close the screen. The game is paused while the screen is open.
+// Fields:
+// - open: whether the screen is shown.
+// - runs: the latest games, the newest first, read from the history when the screen is opened.
+// - selected: the index of the selected game.
+// - top: the index of the first game shown, when the list doesn't fit on the screen.
+// - mu: guards message, which is set by the goroutine exporting a replay.
+// - message: the result of the last replay export, shown under the list.
+type historyScreen struct {
+	open     bool
+	runs     []stats.Run
+	selected int
+	top      int
+	mu       sync.Mutex
+	message  string
+}
+
+// toggle opens the screen, reading the history, or closes it.
+//
+// Parameters:
+// - g (*Game): The game.
+func (s *historyScreen) toggle(g *Game) {
+	s.open = !s.open
+	if !s.open {
+		return
+	}
+	if !g.eng.GameOver {
+		g.paused = true
+	}
+	s.runs = nil
+	s.selected, s.top = 0, 0
+	s.setMessage("")
+	if g.dataDir == "" {
+		return
+	}
+	runs, err := stats.Load(g.dataDir)
+	if err != nil {
+		log.Println(err)
+	}
+	s.runs = stats.Latest(runs, historyMax)
+}
+
+// handleKey processes a key press while the screen is open.
+//
+// Parameters:
+// - g (*Game): The game.
+// - name (string): The name of the released key.
+func (s *historyScreen) handleKey(g *Game, name string) {
+	if len(s.runs) == 0 && name != "KeyH" && name != "Escape" {
+		return
+	}
+	switch name {
+	case "KeyH", "Escape":
+		s.toggle(g)
+	case "ArrowUp":
+		s.selected = (s.selected - 1 + len(s.runs)) % len(s.runs)
+	case "ArrowDown":
+		s.selected = (s.selected + 1) % len(s.runs)
+	case "Enter":
+		s.exportReplay(g, s.runs[s.selected])
+	}
+}
+
+// exportReplay exports the replay of the game as a GIF into the captures directory of the data directory.
+//
+// The export runs in a separate goroutine, so the game doesn't freeze while the replay is being rendered.
+//
+// Parameters:
+// - g (*Game): The game.
+// - run (stats.Run): The game whose replay is exported.
+func (s *historyScreen) exportReplay(g *Game, run stats.Run) {
+	in := g.runReplayPath(run)
+	if in == "" {
+		s.setMessage(g.tr.T("history.no_replay"))
+		return
+	}
+	out := filepath.Join(g.dataDir, captureDir, strings.TrimSuffix(run.Replay, filepath.Ext(run.Replay))+".gif")
+	s.setMessage(g.tr.T("history.exporting"))
+	go func() {
+		if err := ExportReplay(in, out, historyGIFPx); err != nil {
+			log.Println(err)
+			s.setMessage(g.tr.T("history.export_failed"))
+			return
+		}
+		log.Println("replay exported to", out)
+		s.setMessage(g.tr.T("history.exported", out))
+	}()
+}
+
+// setMessage sets the message shown under the list.
+func (s *historyScreen) setMessage(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.message = text
+}
+
+// runReplayPath returns the location of the replay of the game, or an empty string if it wasn't saved
+// or has since been removed.
+func (g *Game) runReplayPath(run stats.Run) string {
+	if run.Replay == "" || g.dataDir == "" {
+		return ""
+	}
+	path := filepath.Join(g.dataDir, replaysDir, filepath.Base(run.Replay))
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// formatDuration formats the duration of a game as minutes and seconds, such as "3:07".
+func formatDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// causeName returns the description of how the game ended, marked with the number of undos if any were used.
+func (g *Game) causeName(run stats.Run) string {
+	if run.Cause == "" {
+		return "-"
+	}
+	if run.Undos > 0 {
+		return g.tr.T("history.cause."+run.Cause) + " " + g.tr.T("history.undos", run.Undos)
+	}
+	return g.tr.T("history.cause." + run.Cause)
+}
+
+// draw renders the screen over the game area.
+func (s *historyScreen) draw(g *Game) {
+	if !s.open {
+		return
+	}
+	g.cv.SetFillStyle(0, 0, 0, 0.8)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+
+	x := g.gameAreaSP.X + 40
+	y := g.gameAreaSP.Y + 70
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("history.title"), x, y)
+
+	g.cv.SetFont(g.fonts.small, 14)
+	if len(s.runs) == 0 {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(g.tr.T("stats.empty"), x, y+50)
+	}
+
+	//scroll the list so the selected game is always visible
+	visible := max(1, int((g.param.gameH-200)/historyRowH))
+	s.top = min(s.top, s.selected)
+	s.top = max(s.top, s.selected-visible+1)
+	shown := s.runs[s.top:min(len(s.runs), s.top+visible)]
+	for i, run := range shown {
+		rowY := y + 50 + float64(i)*historyRowH
+		color := "#CFD8DC"
+		if s.top+i == s.selected {
+			color = "#FFEE58"
+			g.cv.SetFillStyle(color)
+			g.cv.FillText("›", x-18, rowY)
+		}
+		g.cv.SetFillStyle(color)
+		g.cv.FillText(run.EndedAt.Local().Format("01-02 15:04"), x, rowY)
+		g.cv.FillText(g.modeName(run.Mode()), x+100, rowY)
+		g.cv.FillText(g.tr.T("history.score", run.Score), x+320, rowY)
+		g.cv.FillText(formatDuration(run.Duration), x+430, rowY)
+		g.cv.FillText(g.causeName(run), x+490, rowY)
+		if run.Replay != "" {
+			g.cv.FillText(g.tr.T("history.replay"), x+620, rowY)
+		}
+	}
+
+	hintY := y + 50 + float64(max(len(shown), 1))*historyRowH
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.FillText(g.tr.T("history.hint"), x, hintY)
+	s.mu.Lock()
+	message := s.message
+	s.mu.Unlock()
+	if message != "" {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(message, x, hintY+24)
+	}
+}
@@ -0,0 +1,91 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const watchInterval = 500 * time.Millisecond // how often the asset directory is checked for changes
+
+// watchAssets polls the asset override directory and flags the assets for reloading
+// whenever a file is added, removed or modified.
+//
+// Polling is used instead of file system notifications to avoid platform-specific dependencies;
+// the asset directory is small, so checking it twice a second is cheap.
+// The reloading itself happens in the render loop, since fonts and images must be created
+// on the rendering thread.
+func (g *Game) watchAssets() {
+	defer g.recoverCrash()
+	if g.assets.dir == "" {
+		log.Println("development mode: no asset directory to watch")
+		return
+	}
+	log.Println("development mode: watching", g.assets.dir)
+	last := g.assets.snapshot()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-g.done:
+			return
+		}
+		current := g.assets.snapshot()
+		if !sameSnapshot(last, current) {
+			g.assetsChanged.Store(true)
+			last = current
+		}
+	}
+}
+
+// snapshot returns the modification times of all files in the asset override directory.
+func (a assets) snapshot() map[string]time.Time {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil
+	}
+	files := make(map[string]time.Time, len(entries))
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil {
+			files[e.Name()] = info.ModTime()
+		}
+	}
+	return files
+}
+
+// sameSnapshot reports whether two snapshots of the asset directory are equal.
+func sameSnapshot(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, mod := range a {
+		if other, ok := b[name]; !ok || !other.Equal(mod) {
+			return false
+		}
+	}
+	return true
+}
+
+// reloadAssets loads the fonts and images again and redraws everything that uses them.
+//
+// Unlike the initial loading, errors don't stop the game: the assets that fail to load
+// are kept unchanged, so a half-saved file doesn't crash the development session.
+func (g *Game) reloadAssets() {
+	if fonts, err := g.loadFonts(); err != nil {
+		log.Println("error reloading fonts:", err)
+	} else {
+		g.fonts = fonts
+	}
+	if logo, err := g.loadLogo(); err != nil {
+		log.Println("error reloading logo:", err)
+	} else {
+		g.logo = logo
+	}
+	if err := g.setWindowIcon(); err != nil {
+		log.Println(err)
+	}
+	g.needRedrawStatic = true
+	log.Println("development mode: assets reloaded")
+}
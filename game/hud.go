@@ -0,0 +1,127 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// widget is an animated element of the HUD.
+//
+// Widgets are updated and drawn on every frame, independently of the static information
+// on the side panel, so they are responsible for clearing their own area before drawing.
+type widget interface {
+	// update advances the widget's animation by the time elapsed since the previous frame.
+	update(g *Game, dt time.Duration)
+	// draw renders the widget on the game canvas.
+	draw(g *Game)
+	// layout places the widget according to the current layout of the game screen.
+	layout(g *Game)
+}
+
+// updateHUD advances the animations of all HUD widgets.
+//
+// Parameters:
+// - dt (time.Duration): The time elapsed since the previous frame.
+func (g *Game) updateHUD(dt time.Duration) {
+	for _, w := range g.hud {
+		w.update(g, dt)
+	}
+}
+
+// drawHUD renders all HUD widgets.
+func (g *Game) drawHUD() {
+	for _, w := range g.hud {
+		w.draw(g)
+	}
+}
+
+const (
+	gaugeEasing     = 6.0  // how fast the gauge fill catches up with the actual speed, per second
+	gaugePulseLevel = 0.85 // the fill level from which the gauge starts pulsing
+	gaugePulseFreq  = 2.0  // pulses per second near the speed cap
+)
+
+// speedGauge is a HUD widget that shows the snake's speed as a horizontal bar.
+//
+// The bar fills as the tick interval shrinks from engine.StartSpeed down to the speed cap (engine.MinSpeed)
+// and pulses when the snake gets close to the cap, unless the reduced motion mode is on.
+// Fields:
+// - x, y, w, h: the position and size of the widget on the canvas.
+// - fill: the currently displayed fill level in the range [0, 1].
+// - clock: the time used to animate the pulse.
+type speedGauge struct {
+	x, y, w, h float64
+	fill       float64
+	clock      time.Duration
+}
+
+// newSpeedGauge creates a speed gauge widget of the given size placed on the side panel at the given height.
+func newSpeedGauge(y, w, h float64) *speedGauge {
+	return &speedGauge{y: y, w: w, h: h}
+}
+
+// layout places the gauge on the side panel, next to the game area.
+func (s *speedGauge) layout(g *Game) {
+	s.x = g.param.gameW + 50
+}
+
+// speedLevel returns how close the current tick interval is to the speed cap, in the range [0, 1].
+func (g *Game) speedLevel() float64 {
+	return speedLevelOf(g.eng.Speed)
+}
+
+// speedLevelOf returns how close the given tick interval is to the speed cap, in the range [0, 1].
+func speedLevelOf(speed int) float64 {
+	level := float64(engine.StartSpeed-speed) / float64(engine.StartSpeed-engine.MinSpeed)
+	return math.Max(0, math.Min(level, 1))
+}
+
+// update eases the displayed fill level towards the current speed level.
+// In the reduced motion mode the fill level follows the speed without easing.
+func (s *speedGauge) update(g *Game, dt time.Duration) {
+	s.clock += dt
+	if g.cfg.ReducedMotion {
+		s.fill = g.speedLevel()
+		return
+	}
+	s.fill += (g.speedLevel() - s.fill) * (1 - math.Exp(-gaugeEasing*dt.Seconds()))
+}
+
+// draw renders the caption, the frame and the filled part of the gauge.
+func (s *speedGauge) draw(g *Game) {
+	g.beginPanel()
+	defer g.endUI()
+	g.cv.ClearRect(s.x, s.y-30, s.w+80, s.h+35)
+
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#4CAF50")
+	g.cv.SetFont(g.fonts.main, 25)
+	g.cv.FillText(g.tr.T("info.speed"), s.x, s.y-8)
+	g.cv.Stroke()
+
+	color := "#4CAF50"
+	switch {
+	case s.fill >= gaugePulseLevel:
+		color = "#E53935"
+	case s.fill >= 0.5:
+		color = "#FFB300"
+	}
+	g.cv.SetFillStyle(color)
+	if s.fill >= gaugePulseLevel && !g.cfg.ReducedMotion {
+		g.cv.SetGlobalAlpha(0.5 + g.flash(gaugePulseFreq, s.clock)*0.5)
+	}
+	g.cv.FillRect(s.x, s.y, s.w*s.fill, s.h)
+	g.cv.SetGlobalAlpha(1)
+
+	g.cv.SetStrokeStyle("#CFD8DC")
+	g.cv.SetLineWidth(1)
+	g.cv.StrokeRect(s.x, s.y, s.w, s.h)
+
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 15)
+	g.cv.FillText(fmt.Sprintf("%d", engine.StartSpeed-g.eng.Speed+5), s.x+s.w+10, s.y+s.h-2)
+}
@@ -0,0 +1,39 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"runtime"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// setWindowIcon sets the application icon of the game window from the logo image.
+//
+// Returns:
+// - error: An error if the image cannot be decoded or the SDL surface cannot be created; otherwise, nil.
+func (g *Game) setWindowIcon() error {
+	img, err := png.Decode(bytes.NewReader(g.assets.read(logoFile, backgroundImage)))
+	if err != nil {
+		return fmt.Errorf("error decoding window icon: %w", err)
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	// image.RGBA keeps the bytes in R, G, B, A order, which is ABGR8888 on little-endian machines
+	surface, err := sdl.CreateRGBSurfaceWithFormatFrom(unsafe.Pointer(&rgba.Pix[0]),
+		int32(w), int32(h), 32, int32(rgba.Stride), sdl.PIXELFORMAT_ABGR8888)
+	if err != nil {
+		return fmt.Errorf("error creating window icon: %w", err)
+	}
+	defer surface.Free()
+	g.wnd.Window.SetIcon(surface)
+	runtime.KeepAlive(rgba)
+	return nil
+}
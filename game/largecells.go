@@ -0,0 +1,50 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+)
+
+const (
+	largeBoardCells = 10  // the number of cells along each side of the board in the large cell mode
+	outlineWidth    = 6.0 // the width of the dark outer outline in the large cell mode
+	outlineInner    = 2.0 // the width of the light inner outline in the large cell mode
+)
+
+// toggleLargeCells switches the large cell mode on or off.
+//
+// The board changes its size, so a new game is started, and the camera is zoomed out to show the whole board.
+func (g *Game) toggleLargeCells() {
+	g.cfg.LargeCells = !g.cfg.LargeCells
+	g.restartGame()
+	g.setZoom(g.boardCells())
+}
+
+// drawOutlines draws thick high-contrast outlines around the snake and the food,
+// so they stand out against the board for low-vision players.
+//
+// Every outline is a dark wide line with a light thin line on top of it, which contrasts both
+// with light and dark colors, including the night tint of the board.
+func (g *Game) drawOutlines() {
+	for _, style := range []struct {
+		color string
+		width float64
+	}{{"#000000", outlineWidth}, {"#FFFFFF", outlineInner}} {
+		g.cv.SetStrokeStyle(style.color)
+		g.cv.SetLineWidth(style.width)
+		g.cv.BeginPath()
+		for i, point := range g.eng.Snake.Parts {
+			x, y := g.toScreen(point)
+			if i == 0 {
+				g.cv.MoveTo(x+1+g.side, y+1+g.side/2)
+				g.cv.Ellipse(x+1+g.side/2, y+1+g.side/2, g.side/2, g.side*0.3, 0, 0, 2*math.Pi, false)
+				continue
+			}
+			g.cv.Rect(x+1, y+1, g.cellW-1*2, g.cellH-1*2)
+		}
+		foodX, foodY := g.toScreen(g.eng.Food)
+		g.cv.MoveTo(foodX+1+g.side, foodY+1+g.side/2)
+		g.cv.Arc(foodX+1+g.side/2, foodY+1+g.side/2, g.side/2, 0, 2*math.Pi, false)
+		g.cv.Stroke()
+	}
+}
@@ -0,0 +1,91 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import "math"
+
+const (
+	areaMargin  = 15.0  // margin between the window border and the game area
+	sidePanelW  = 315.0 // width of the side panel with the game information
+	minGameSide = 200.0 // the smallest side of the game area
+	minWindowW  = 640   // the smallest window width allowed by the layout
+	minWindowH  = 480   // the smallest window height allowed by the layout
+	logoMinY    = 330   // the highest position of the logo on the side panel, below the instructions
+)
+
+// layout recalculates the positions and sizes of all screen elements for the given window size.
+//
+// The game area is kept square and takes as much space as possible, leaving room for the side panel,
+// whose width grows with the UI scale.
+// If the window proportions differ from the proportions of the content, the content is centered
+// and the rest of the window is left empty (letterboxing).
+// Cell sizes, the camera and the HUD widgets are updated accordingly, and the static layers
+// are scheduled for redrawing on the next frame.
+//
+// Parameters:
+// - w, h (int): The new size of the window in pixels.
+func (g *Game) layout(w, h int) {
+	g.param.windowW = w
+	g.param.windowH = h
+	panelW := sidePanelW * g.uiScale()
+	side := math.Min(float64(w)-panelW-areaMargin, float64(h)-2*areaMargin)
+	side = math.Max(side, minGameSide)
+	g.param.gameW = side
+	g.param.gameH = side
+	g.gameAreaSP = Point{X: areaMargin, Y: areaMargin}
+	g.gameAreaEP = Point{X: areaMargin + side, Y: areaMargin + side}
+	contentW := areaMargin + side + panelW
+	contentH := side + 2*areaMargin
+	g.offset = Point{X: math.Max(0, (float64(w)-contentW)/2), Y: math.Max(0, (float64(h)-contentH)/2)}
+	g.setZoom(g.cam.cells)
+	for _, wg := range g.hud {
+		wg.layout(g)
+	}
+	g.needRedrawStatic = true
+}
+
+// toLayout transforms window coordinates, such as the mouse position, into layout coordinates.
+//
+// Parameters:
+// - x, y (int): The position in the window in pixels.
+//
+// Returns:
+// - float64, float64: The position relative to the letterboxed content.
+func (g *Game) toLayout(x, y int) (float64, float64) {
+	return float64(x) - g.offset.X, float64(y) - g.offset.Y
+}
+
+// handleResize is called when the size of the window has changed.
+//
+// It updates the bounds of the rendering backend and recalculates the layout.
+//
+// Parameters:
+// - w, h (int): The new size of the window in pixels.
+func (g *Game) handleResize(w, h int) {
+	g.wnd.Backend.SetBounds(0, 0, w, h)
+	g.layout(w, h)
+}
+
+// drawStatic renders the relatively static information of the side panel: the game information,
+// instructions, creator information, contacts and the logo.
+//
+// The whole window is cleared first, so this method is used both for the first frame
+// and after the layout has changed. It must be called with the layout offset applied to the canvas.
+func (g *Game) drawStatic() {
+	g.cv.ClearRect(-g.offset.X, -g.offset.Y, float64(g.param.windowW), float64(g.param.windowH))
+	g.beginPanel()
+	defer g.endUI()
+	g.drawGameInfo()
+	//draw the update banner, if a newer release is available
+	g.drawUpdateBanner()
+	//draw game instructions for the player
+	g.drawInstructions()
+	// draw creator information
+	g.drawAboutCreator(g.param.gameW+20, g.panelH()-50)
+	//draw contact details
+	g.drawContacts()
+	//draw logo, unless the enlarged text leaves no room for it below the instructions
+	if g.logo != nil && g.panelH()-350 >= logoMinY {
+		g.cv.DrawImage(g.logo, g.param.gameW+40, g.panelH()-350, 250, 250)
+	}
+	g.needRedrawStatic = false
+}
@@ -0,0 +1,95 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"time"
+
+	"github.com/DenisKhanov/Snake/leaderboard"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+const defaultPlayerName = "Player" // the name the scores are submitted under if the player hasn't set one
+
+// connectLeaderboard creates the client of the online leaderboard, if the player has opted in by setting its URL,
+// and sends the scores queued while the computer was offline in the background.
+func (g *Game) connectLeaderboard() {
+	if g.cfg.LeaderboardURL == "" || g.dataDir == "" {
+		return
+	}
+	client, err := leaderboard.New(g.cfg.LeaderboardURL, g.cfg.LeaderboardToken, g.dataDir)
+	if err != nil {
+		log.Println("the leaderboard is disabled:", err)
+		return
+	}
+	g.leaderboard = client
+	go func() {
+		if err := client.Flush(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+// submitScore submits the score of the finished game to the online leaderboard in the background.
+// The game over screen shows the global rank of the game once the server has answered, or that the score
+// has been queued if the server can't be reached.
+//
+// It's called by the game logic goroutine when the snake dies.
+func (g *Game) submitScore() {
+	client := g.leaderboard
+	if client == nil {
+		return
+	}
+	replay := g.encodeReplay()
+	entry := leaderboard.Entry{
+		ID:          leaderboard.NewID(),
+		Name:        cmp.Or(g.cfg.PlayerName, defaultPlayerName),
+		Score:       g.eng.Score,
+		Cells:       g.eng.BoardSize(),
+		Assisted:    g.eng.Assisted(),
+		Seed:        g.eng.Seed(),
+		ReplayHash:  leaderboard.HashReplay(replay),
+		Replay:      replay,
+		GameVersion: version.Get().Version,
+		EndedAt:     time.Now(),
+	}
+	submitting := g.tr.T("leaderboard.submitting")
+	pending := &submitting
+	g.rankMsg.Store(pending)
+	go func() {
+		res, err := client.Submit(context.Background(), entry)
+		if err != nil {
+			log.Println(err)
+		}
+		var text string
+		switch {
+		case res != nil:
+			text = g.tr.T("leaderboard.rank", res.Rank, res.Total)
+		case err != nil:
+			text = g.tr.T("leaderboard.queued")
+		}
+		//a new game may have started in the meantime; its game over screen mustn't show this result
+		g.rankMsg.CompareAndSwap(pending, &text)
+	}()
+}
+
+// setRankMessage sets the leaderboard result shown on the game over screen; an empty text hides it.
+func (g *Game) setRankMessage(text string) {
+	g.rankMsg.Store(&text)
+}
+
+// drawRank displays the leaderboard result of the finished game.
+//
+// Parameters:
+// - x, y (float64): The starting position for rendering the result.
+func (g *Game) drawRank(x, y float64) {
+	msg := g.rankMsg.Load()
+	if msg == nil || *msg == "" {
+		return
+	}
+	g.cv.SetFillStyle("#29B6F6")
+	g.cv.SetFont(g.fonts.small, 15)
+	g.cv.FillText(*msg, x, y)
+}
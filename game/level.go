@@ -0,0 +1,47 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import "github.com/DenisKhanov/Snake/stats"
+
+const levelBarW = 240.0 // the width of the player level progress bar
+
+// awardXP adds the XP awarded for the finished game to the total.
+//
+// Parameters:
+// - run (stats.Run): The finished game.
+func (g *Game) awardXP(run stats.Run) {
+	xp := int64(stats.XP(run))
+	g.lastXP.Store(xp)
+	g.xp.Add(xp)
+}
+
+// level returns the current player level.
+func (g *Game) level() int {
+	return stats.Level(int(g.xp.Load()))
+}
+
+// drawLevel draws the player level with a progress bar towards the next level.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the bar.
+// - gained (bool): Whether the XP awarded for the last game is shown.
+func (g *Game) drawLevel(x, y float64, gained bool) {
+	xp := int(g.xp.Load())
+	level := stats.Level(xp)
+	from, to := stats.LevelXP(level), stats.LevelXP(level+1)
+
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.middle, 15)
+	text := g.tr.T("level.title", level)
+	if gained {
+		text += "   " + g.tr.T("level.gained", g.lastXP.Load())
+	}
+	g.cv.FillText(text, x, y)
+	g.cv.SetFillStyle("#37474F")
+	g.cv.FillRect(x, y+8, levelBarW, 10)
+	g.cv.SetFillStyle("#29B6F6")
+	g.cv.FillRect(x, y+8, levelBarW*float64(xp-from)/float64(to-from), 10)
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 13)
+	g.cv.FillText(g.tr.T("level.progress", xp-from, to-from), x+levelBarW+10, y+18)
+}
@@ -0,0 +1,30 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+
+	"github.com/DenisKhanov/Snake/livecast"
+)
+
+// serveLive serves the live view in the background if the player has asked for it on the command line,
+// so the game can be watched in a browser.
+func (g *Game) serveLive() {
+	if g.castAddr == "" {
+		return
+	}
+	g.cast = livecast.NewCaster()
+	log.Printf("serving the live view on http://%s/", g.castAddr)
+	go func() {
+		log.Println("the live view has stopped:", livecast.ListenAndServe(g.castAddr, g.cast))
+	}()
+}
+
+// broadcast sends the state of the game after the tick to the live view.
+//
+// It's called by the game logic goroutine after every tick.
+func (g *Game) broadcast() {
+	if g.cast != nil {
+		g.cast.Publish(livecast.FrameOf(g.eng))
+	}
+}
@@ -0,0 +1,441 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/netplay"
+)
+
+// the entries of the menu of the online versus mode
+const (
+	lobbyQuick   = iota // join the quick-match queue
+	lobbyCreate         // create a room
+	lobbyEnter          // join a room by its code
+	lobbyWatch          // watch a running game
+	lobbyRatings        // show the rating ladders
+	lobbyChoices
+)
+
+const browseRows = 8 // the number of running games listed at once
+
+// versusBoards are the board sizes the host of a room chooses from with ← →.
+var versusBoards = []int{15, 20, 30, 40}
+
+// handleMenuKey processes a key press in the menu of the online versus mode: the arrow keys ↑ ↓ select an entry,
+// the letters and BACKSPACE edit the code of the room to join, and ENTER connects.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the menu.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled.
+func (g *Game) handleMenuKey(m *versusMatch, name string) bool {
+	if name == "Enter" {
+		m.mu.Lock()
+		choice, incomplete := m.choice, m.choice == lobbyEnter && len(m.code) != netplay.CodeLength
+		m.mu.Unlock()
+		switch {
+		case choice == lobbyWatch:
+			g.browseVersus(m)
+		case choice == lobbyRatings:
+			g.loadRatings(m)
+		case !incomplete:
+			g.joinVersus(m)
+		}
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	letter := strings.TrimPrefix(name, "Key")
+	switch {
+	case name == "ArrowUp":
+		m.choice = (m.choice - 1 + lobbyChoices) % lobbyChoices
+	case name == "ArrowDown":
+		m.choice = (m.choice + 1) % lobbyChoices
+	case name == "Backspace" && m.choice == lobbyEnter && m.code != "":
+		m.code = m.code[:len(m.code)-1]
+	case m.choice == lobbyEnter && len(letter) == 1 && strings.Contains(netplay.CodeLetters, letter):
+		if len(m.code) < netplay.CodeLength {
+			m.code += letter
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// browseVersus loads the list of the running games to watch in the background.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the menu or the list.
+func (g *Game) browseVersus(m *versusMatch) {
+	m.mu.Lock()
+	m.status = "versus.loading"
+	m.mu.Unlock()
+	url := g.cfg.VersusURL
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), versusConnectTimeout)
+		defer cancel()
+		list, err := netplay.ListMatches(ctx, url)
+		if err != nil {
+			log.Println("error listing versus games:", err)
+			m.fail(err)
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.status == "versus.loading" {
+			m.status, m.matches, m.pick = "versus.browse", list, 0
+		}
+	}()
+}
+
+// handleBrowseKey processes a key press in the list of the running games: the arrow keys ↑ ↓ select a game,
+// ENTER watches it, R loads the list again, and ESC goes back to the menu.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the list.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled.
+func (g *Game) handleBrowseKey(m *versusMatch, name string) bool {
+	m.mu.Lock()
+	n := min(len(m.matches), browseRows)
+	watch, refresh := false, false
+	switch name {
+	case "ArrowUp":
+		m.pick = (m.pick - 1 + max(n, 1)) % max(n, 1)
+	case "ArrowDown":
+		m.pick = (m.pick + 1) % max(n, 1)
+	case "Escape":
+		m.status = "versus.menu"
+	case "Enter":
+		if watch = m.pick < n; watch {
+			m.watch = m.matches[m.pick].ID
+		}
+	case "KeyR":
+		refresh = true
+	default:
+		m.mu.Unlock()
+		return false
+	}
+	m.mu.Unlock()
+	switch {
+	case watch:
+		g.joinVersus(m)
+	case refresh:
+		g.browseVersus(m)
+	}
+	return true
+}
+
+// loadRatings loads the ladder of the selected ranked mode in the background.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the menu or the ladders.
+func (g *Game) loadRatings(m *versusMatch) {
+	m.mu.Lock()
+	m.status = "versus.loading_ratings"
+	mode := netplay.Modes[m.ladderMode].Name
+	m.mu.Unlock()
+	url := g.cfg.VersusURL
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), versusConnectTimeout)
+		defer cancel()
+		ladder, err := netplay.TopRatings(ctx, url, mode)
+		if err != nil {
+			log.Println("error loading versus ratings:", err)
+			m.fail(err)
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.status == "versus.loading_ratings" && netplay.Modes[m.ladderMode].Name == mode {
+			m.status, m.ladder = "versus.ratings", ladder
+		}
+	}()
+}
+
+// handleRatingsKey processes a key press on the rating ladders: the arrow keys ← → switch the mode,
+// R loads the ladder again, and ESC goes back to the menu.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the ladders.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled.
+func (g *Game) handleRatingsKey(m *versusMatch, name string) bool {
+	m.mu.Lock()
+	switch name {
+	case "ArrowLeft":
+		m.ladderMode = (m.ladderMode - 1 + len(netplay.Modes)) % len(netplay.Modes)
+	case "ArrowRight":
+		m.ladderMode = (m.ladderMode + 1) % len(netplay.Modes)
+	case "KeyR":
+	case "Escape":
+		m.status = "versus.menu"
+		m.mu.Unlock()
+		return true
+	default:
+		m.mu.Unlock()
+		return false
+	}
+	m.mu.Unlock()
+	g.loadRatings(m)
+	return true
+}
+
+// handleRoomKey processes a key press in a room: ENTER or SPACE toggle whether the player is ready,
+// and the host changes the board with the arrow keys ← → and the mode with ↑ ↓.
+//
+// Parameters:
+// - m (*versusMatch): The current match, in a room.
+// - name (string): The name of the released key.
+//
+// Returns:
+// - bool: Whether the key has been handled.
+func (g *Game) handleRoomKey(m *versusMatch, name string) bool {
+	m.mu.Lock()
+	client, room := m.client, m.room
+	m.mu.Unlock()
+	if client == nil || room == nil || room.You >= len(room.Readies) {
+		return false
+	}
+	cells, speed := room.Cells, room.Speed
+	switch name {
+	case "Enter", "Space":
+		ready := !room.Readies[room.You]
+		go func() {
+			if err := client.Ready(ready); err != nil {
+				log.Println(err)
+			}
+		}()
+		return true
+	case "ArrowLeft":
+		cells = cycle(versusBoards, cells, -1)
+	case "ArrowRight":
+		cells = cycle(versusBoards, cells, 1)
+	case "ArrowDown":
+		speed = nextMode(speed, -1)
+	case "ArrowUp":
+		speed = nextMode(speed, 1)
+	default:
+		return false
+	}
+	if room.You != 0 {
+		return true
+	}
+	go func() {
+		if err := client.Settings(cells, speed); err != nil {
+			log.Println(err)
+		}
+	}()
+	return true
+}
+
+// cycle returns the value next to the current one in the list, wrapping around;
+// a value not in the list is replaced with the first one.
+//
+// Parameters:
+// - values ([]int): The list.
+// - current (int): The current value.
+// - step (int): 1 for the next value, -1 for the previous one.
+func cycle(values []int, current, step int) int {
+	i := slices.Index(values, current)
+	if i < 0 {
+		return values[0]
+	}
+	return values[(i+step+len(values))%len(values)]
+}
+
+// nextMode returns the speed of the ranked mode next to the one of the current speed, wrapping around;
+// a speed that isn't one of the modes is replaced with the classic one.
+func nextMode(speed, step int) int {
+	i := modeIndex(speed)
+	if i < 0 {
+		return engine.VersusSpeed
+	}
+	return netplay.Modes[(i+step+len(netplay.Modes))%len(netplay.Modes)].Speed
+}
+
+// modeIndex returns the index of the mode of the speed in netplay.Modes, or -1 if the speed isn't one of the modes.
+func modeIndex(speed int) int {
+	return slices.IndexFunc(netplay.Modes, func(mode netplay.Mode) bool { return mode.Speed == speed })
+}
+
+// versusModeName returns the name of the mode of the speed, or the speed itself if it isn't one of the modes.
+func (g *Game) versusModeName(speed int) string {
+	if mode := netplay.ModeOf(speed); mode != "" {
+		return g.tr.T("versus.mode_" + mode)
+	}
+	return fmt.Sprintf("%d ms", speed)
+}
+
+// ratedName returns the name of the player with the index followed by the rating, if the message has the ratings.
+//
+// Parameters:
+// - m (*netplay.Message): The room or start message with the names and the ratings of the players.
+// - i (int): The index of the player.
+func ratedName(m *netplay.Message, i int) string {
+	if i >= len(m.Ratings) {
+		return m.Names[i]
+	}
+	return fmt.Sprintf("%s (%d)", m.Names[i], m.Ratings[i])
+}
+
+// drawLobby draws the menu of the online versus mode or the room the player is in over the game area.
+//
+// Parameters:
+// - s (*versusMatch): A snapshot of the current match.
+func (g *Game) drawLobby(s *versusMatch) {
+	g.cv.SetFillStyle(0, 0, 0, 0.75)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+
+	x := g.gameAreaSP.X + 40
+	y := g.gameAreaSP.Y + 70
+	g.cv.SetFillStyle("#FFEE58")
+	switch s.status {
+	case "versus.browse":
+		g.drawBrowse(s, x, y)
+		return
+	case "versus.ratings":
+		g.drawRatings(s, x, y)
+		return
+	}
+	if s.room == nil {
+		g.cv.SetFont(g.fonts.main, 40)
+		g.cv.FillText(g.tr.T("versus.title"), x, y)
+		g.cv.SetFont(g.fonts.middle, 16)
+		code := s.code + strings.Repeat("_", netplay.CodeLength-len(s.code))
+		entries := [lobbyChoices]string{
+			g.tr.T("versus.quick"),
+			g.tr.T("versus.create"),
+			g.tr.T("versus.enter", code),
+			g.tr.T("versus.watch"),
+			g.tr.T("versus.ratings"),
+		}
+		for i, text := range entries {
+			rowY := y + 50 + float64(i)*settingsRowH
+			color := "#CFD8DC"
+			if i == s.choice {
+				color = "#FFEE58"
+				g.cv.SetFillStyle(color)
+				g.cv.FillText("›", x-18, rowY)
+			}
+			g.cv.SetFillStyle(color)
+			g.cv.FillText(text, x, rowY)
+		}
+		g.cv.SetFillStyle("#90A4AE")
+		g.cv.SetFont(g.fonts.small, 14)
+		g.cv.FillText(g.tr.T("versus.menu_hint"), x, y+50+lobbyChoices*settingsRowH+20)
+		return
+	}
+
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("versus.room_code", s.room.Room), x, y)
+	g.cv.SetFont(g.fonts.middle, 16)
+	for i := range engine.Players {
+		rowY := y + 50 + float64(i)*settingsRowH
+		text, color := g.tr.T("versus.empty_seat"), "#90A4AE"
+		if i < len(s.room.Names) && i < len(s.room.Readies) {
+			text, color = ratedName(s.room, i)+" - "+g.tr.T("versus.not_ready"), "#CFD8DC"
+			if s.room.Readies[i] {
+				text, color = ratedName(s.room, i)+" - "+g.tr.T("versus.ready"), "#66BB6A"
+			}
+			if i == 0 {
+				text += " " + g.tr.T("versus.host")
+			}
+		}
+		if i == s.room.You {
+			g.cv.SetFillStyle("#FFEE58")
+			g.cv.FillText("›", x-18, rowY)
+		}
+		g.cv.SetFillStyle(color)
+		g.cv.FillText(text, x, rowY)
+	}
+	rowY := y + 50 + engine.Players*settingsRowH + 10
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.FillText(g.tr.T("versus.board", s.room.Cells, s.room.Cells), x, rowY)
+	mode := "versus.mode"
+	if netplay.ModeOf(s.room.Speed) != "" {
+		mode = "versus.mode_ranked"
+	}
+	g.cv.FillText(g.tr.T(mode, g.versusModeName(s.room.Speed)), x, rowY+settingsRowH)
+
+	hint := "versus.room_hint"
+	if s.room.You == 0 {
+		hint = "versus.host_hint"
+	}
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T(hint), x, rowY+2*settingsRowH+20)
+	g.drawChat(s)
+}
+
+// drawBrowse draws the list of the running games to watch: the players, the board, the mode, how long
+// the game has been running and how many spectators it has.
+//
+// Parameters:
+// - s (*versusMatch): A snapshot of the current match.
+// - x, y (float64): The position of the title.
+func (g *Game) drawBrowse(s *versusMatch, x, y float64) {
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("versus.browse_title"), x, y)
+	g.cv.SetFont(g.fonts.middle, 16)
+	n := min(len(s.matches), browseRows)
+	if n == 0 {
+		g.cv.SetFillStyle("#90A4AE")
+		g.cv.FillText(g.tr.T("versus.no_games"), x, y+50)
+	}
+	for i, info := range s.matches[:n] {
+		rowY := y + 50 + float64(i)*settingsRowH
+		color := "#CFD8DC"
+		if i == s.pick {
+			color = "#FFEE58"
+			g.cv.SetFillStyle(color)
+			g.cv.FillText("›", x-18, rowY)
+		}
+		secs := info.Tick * info.Speed / 1000
+		text := g.tr.T("versus.game_entry", strings.Join(info.Names, " - "), info.Cells, info.Cells,
+			g.versusModeName(info.Speed), secs/60, secs%60, info.Spectators)
+		g.cv.SetFillStyle(color)
+		g.cv.FillText(text, x, rowY)
+	}
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("versus.browse_hint"), x, y+50+float64(max(n, 1))*settingsRowH+20)
+}
+
+// drawRatings draws the ladder of a ranked mode: the rank, name, rating and record of its best players.
+//
+// Parameters:
+// - s (*versusMatch): A snapshot of the current match.
+// - x, y (float64): The position of the title.
+func (g *Game) drawRatings(s *versusMatch, x, y float64) {
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("versus.ratings_title", g.tr.T("versus.mode_"+netplay.Modes[s.ladderMode].Name)), x, y)
+	g.cv.SetFont(g.fonts.middle, 16)
+	if len(s.ladder) == 0 {
+		g.cv.SetFillStyle("#90A4AE")
+		g.cv.FillText(g.tr.T("versus.no_ratings"), x, y+50)
+	}
+	g.cv.SetFillStyle("#CFD8DC")
+	for i, e := range s.ladder {
+		rowY := y + 50 + float64(i)*settingsRowH
+		g.cv.FillText(g.tr.T("versus.rating_entry", i+1, e.Name, e.Rating, e.Wins, e.Games), x, rowY)
+	}
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("versus.ratings_hint"), x, y+50+float64(max(len(s.ladder), 1))*settingsRowH+20)
+}
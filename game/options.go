@@ -0,0 +1,52 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/player"
+)
+
+const defaultTitle = "Welcome to the Snake game written in Golang"
+
+// Options holds the settings passed to the game on the command line.
+// Options take precedence over the configuration file for the current session only
+// and are never saved to it; zero values mean "not set" unless stated otherwise.
+// Fields:
+// - Title: the title of the game window.
+// - Display: the index of the monitor to open the window on; a negative value means "not set".
+// - Assets: the directory with the fonts and images overriding the embedded ones.
+// - Dev: whether the game runs in development mode, reloading the assets when they change on disk.
+// - Portable: whether the game data is stored next to the executable instead of the user directories.
+// - BotAPI: the address the bot API is served on, so bots can watch and steer the game over gRPC; empty disables it.
+// - Live: the address the live view is served on, so the game can be watched in a browser; empty disables it.
+// - Player: the player controlling the snake instead of the keyboard, e.g. a built-in AI; nil for the keyboard.
+// - ASCII: whether the board is printed to the standard output as ASCII art after every tick, for debugging.
+// - Debug: whether the developer console is available, opened with ~ for changing the running game.
+type Options struct {
+	Title    string
+	Display  int
+	Assets   string
+	Dev      bool
+	Portable bool
+	BotAPI   string
+	Live     string
+	Player   player.Player
+	ASCII    bool
+	Debug    bool
+}
+
+// title returns the title of the game window: the one from the command line, the one
+// from the configuration, or the default one, in that order of precedence.
+//
+// Parameters:
+// - cfg (*config.Config): The configuration loaded from the configuration file.
+func (o Options) title(cfg *config.Config) string {
+	switch {
+	case o.Title != "":
+		return o.Title
+	case cfg.Title != "":
+		return cfg.Title
+	default:
+		return defaultTitle
+	}
+}
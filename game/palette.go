@@ -0,0 +1,224 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// colorPalette is a set of colors the board is drawn with.
+// All colors are in the "#RRGGBB" format.
+// Fields:
+// - name: the name of the palette, used in the configuration and for the message key of its title.
+// - day, night: the background of the board; the night tint is used by the day/night cycle.
+// - grid: the lines between the cells.
+// - head, body, bodyAlt: the snake's head and the two alternating colors of its body.
+// - apple, leaf, stalk: the parts of the food.
+// - level: the player level that unlocks the palette; 0 for the palettes available from the start,
+// which include all accessibility palettes.
+type colorPalette struct {
+	name          string
+	level         int
+	day, night    string
+	grid          string
+	head          string
+	body, bodyAlt string
+	apple, leaf   string
+	stalk         string
+}
+
+// palettes lists the palettes the player can choose from; the first one is the default.
+var palettes = []*colorPalette{
+	{
+		name: "classic",
+		day:  "#78909C", night: "#263238", grid: "#5D4037",
+		head: "#039BE5", body: "#00BCD4", bodyAlt: "#4DD0E1",
+		apple: "#7CB342", leaf: "#1B5E20", stalk: "#8B4513",
+	},
+	{
+		// the Okabe-Ito colors, which stay distinct for all common color-vision deficiencies
+		name: "okabe_ito",
+		day:  "#5A5A5A", night: "#1E1E1E", grid: "#3A3A3A",
+		head: "#0072B2", body: "#56B4E9", bodyAlt: "#8ECBEF",
+		apple: "#E69F00", leaf: "#009E73", stalk: "#D55E00",
+	},
+	{
+		name: "high_contrast",
+		day:  "#101010", night: "#000000", grid: "#505050",
+		head: "#FFFFFF", body: "#FFEB3B", bodyAlt: "#FDD835",
+		apple: "#FF1744", leaf: "#00E676", stalk: "#FFFFFF",
+	},
+	{
+		name: "forest", level: 3,
+		day: "#556B2F", night: "#1C2410", grid: "#3E4F22",
+		head: "#F4E04D", body: "#C5A572", bodyAlt: "#D9BE8C",
+		apple: "#E63946", leaf: "#2D6A4F", stalk: "#6B4226",
+	},
+	{
+		name: "neon", level: 5,
+		day: "#1A1A2E", night: "#0B0B16", grid: "#2E2E55",
+		head: "#F72585", body: "#4CC9F0", bodyAlt: "#7209B7",
+		apple: "#B8F500", leaf: "#39FF14", stalk: "#FF9E00",
+	},
+}
+
+// pal returns the palette the board is drawn with: the preview palette while a preview is drawn,
+// otherwise the one chosen in the configuration, if it's unlocked, or the default one.
+func (g *Game) pal() *colorPalette {
+	if g.previewPal != nil {
+		return g.previewPal
+	}
+	if p := paletteByName(g.cfg.Palette); g.unlocked(p) {
+		return p
+	}
+	return palettes[0]
+}
+
+// unlocked reports whether the player level is high enough for the palette.
+func (g *Game) unlocked(p *colorPalette) bool {
+	return p.level <= g.level()
+}
+
+// paletteByName returns the palette with the given name, or the default palette if there is none.
+func paletteByName(name string) *colorPalette {
+	for _, p := range palettes {
+		if p.name == name {
+			return p
+		}
+	}
+	return palettes[0]
+}
+
+// cyclePalette switches to the previous or the next unlocked palette.
+//
+// Parameters:
+// - delta (int): The direction of the switch, -1 or 1.
+func (g *Game) cyclePalette(delta int) {
+	i := slices.Index(palettes, g.pal())
+	for {
+		i = (i + delta + len(palettes)) % len(palettes)
+		if g.unlocked(palettes[i]) {
+			break
+		}
+	}
+	g.cfg.Palette = palettes[i].name
+}
+
+// colorVision is a common color-vision deficiency, simulated with a color transformation matrix.
+// Fields:
+// - key: the message key of the name of the deficiency.
+// - m: the matrix applied to the RGB components (Machado et al., 2009, full severity).
+type colorVision struct {
+	key string
+	m   [3][3]float64
+}
+
+// colorVisions lists the deficiencies shown by the palette preview.
+var colorVisions = []colorVision{
+	{"vision.protanopia", [3][3]float64{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}},
+	{"vision.deuteranopia", [3][3]float64{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}},
+	{"vision.tritanopia", [3][3]float64{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}},
+}
+
+// simulate returns the palette as seen with the color-vision deficiency.
+func (v colorVision) simulate(p *colorPalette) *colorPalette {
+	sim := func(hex string) string {
+		c := parseHex(hex)
+		var out [3]float64
+		for i := range out {
+			x := v.m[i][0]*c[0] + v.m[i][1]*c[1] + v.m[i][2]*c[2]
+			out[i] = math.Max(0, math.Min(x, 255))
+		}
+		return colorHex(out)
+	}
+	return &colorPalette{
+		name: p.name, level: p.level,
+		day: sim(p.day), night: sim(p.night), grid: sim(p.grid),
+		head: sim(p.head), body: sim(p.body), bodyAlt: sim(p.bodyAlt),
+		apple: sim(p.apple), leaf: sim(p.leaf), stalk: sim(p.stalk),
+	}
+}
+
+// parseHex parses a color in the "#RRGGBB" format into its RGB components; invalid colors are black.
+func parseHex(hex string) [3]float64 {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return [3]float64{}
+	}
+	return [3]float64{float64(r), float64(g), float64(b)}
+}
+
+const (
+	previewTile = 64.0 // the side of a single palette preview
+	previewGap  = 16.0 // the distance between two palette previews
+)
+
+// drawPalettePreview draws the chosen palette on small boards as seen with normal vision and with every
+// color-vision deficiency from colorVisions, so the player can pick a palette that works for them.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the first preview.
+func (g *Game) drawPalettePreview(x, y float64) {
+	p := g.pal()
+	views := []struct {
+		key string
+		pal *colorPalette
+	}{{"vision.normal", p}}
+	for _, vision := range colorVisions {
+		views = append(views, struct {
+			key string
+			pal *colorPalette
+		}{vision.key, vision.simulate(p)})
+	}
+	for i, v := range views {
+		tx := x + float64(i)*(previewTile+previewGap)
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.SetFont(g.fonts.small, 12)
+		g.cv.FillText(g.tr.T(v.key), tx, y-6)
+		g.previewPal = v.pal
+		g.drawPreviewBoard(tx, y, previewTile)
+		g.previewPal = nil
+	}
+}
+
+// drawPreviewBoard draws a tiny board with a snake and food, using the current palette.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the board.
+// - size (float64): The side of the board.
+func (g *Game) drawPreviewBoard(x, y, size float64) {
+	const cells = 4
+	cell := size / cells
+	p := g.pal()
+	g.cv.SetFillStyle(p.day)
+	g.cv.FillRect(x, y, size, size)
+	g.cv.SetStrokeStyle(p.grid)
+	g.cv.SetLineWidth(0.5)
+	g.cv.BeginPath()
+	for i := 0; i <= cells; i++ {
+		g.cv.MoveTo(x+float64(i)*cell, y)
+		g.cv.LineTo(x+float64(i)*cell, y+size)
+		g.cv.MoveTo(x, y+float64(i)*cell)
+		g.cv.LineTo(x+size, y+float64(i)*cell)
+	}
+	g.cv.Stroke()
+	for i, color := range []string{p.body, p.bodyAlt} {
+		g.cv.SetFillStyle(color)
+		g.cv.FillRect(x+float64(i)*cell+1, y+cell+1, cell-2, cell-2)
+	}
+	g.drawSnakeHead(x+2*cell+1, y+cell+1, cell-2)
+	g.drawApple(x+2*cell+1, y+3*cell+1, cell-2)
+}
@@ -0,0 +1,198 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	perfWindow   = 5 * time.Second        // the time span shown by the profiling graphs
+	perfGCPoll   = 500 * time.Millisecond // how often the GC statistics are read
+	perfW, perfH = 240.0, 110.0           // the size of the overlay
+	perfMargin   = 8.0                    // the distance between the overlay and the corner of the game area
+	perfMinScale = 33 * time.Millisecond  // the smallest value at the top of the graph (two frames at 60 FPS)
+)
+
+// perfSample is a single measurement of a profiling graph.
+type perfSample struct {
+	at time.Time
+	v  time.Duration
+}
+
+// perfSeries keeps the measurements of the last perfWindow.
+type perfSeries []perfSample
+
+// add appends a measurement and drops the ones that have left the window.
+func (s *perfSeries) add(at time.Time, v time.Duration) {
+	*s = append(*s, perfSample{at: at, v: v})
+	i := 0
+	for i < len(*s) && at.Sub((*s)[i].at) > perfWindow {
+		i++
+	}
+	*s = (*s)[i:]
+}
+
+// last returns the latest measurement, or zero if there is none.
+func (s perfSeries) last() time.Duration {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].v
+}
+
+// peak returns the largest measurement.
+func (s perfSeries) peak() time.Duration {
+	var p time.Duration
+	for _, sample := range s {
+		p = max(p, sample.v)
+	}
+	return p
+}
+
+// perfOverlay is a HUD widget that graphs the frame time, the tick time of the game logic and
+// the GC pauses over the last few seconds, in the bottom-left corner of the game area.
+//
+// It is toggled with F3 and collects measurements only while it's visible.
+// Fields:
+// - visible: whether the overlay is shown.
+// - mu: guards ticks, which are measured by the game logic goroutine.
+// - frames, ticks, gc: the measurements of the graphs.
+// - lastGC: the end time of the latest GC pause already recorded.
+// - gcPoll: the time elapsed since the GC statistics were read.
+type perfOverlay struct {
+	visible atomic.Bool
+
+	mu     sync.Mutex
+	frames perfSeries
+	ticks  perfSeries
+	gc     perfSeries
+	lastGC time.Time
+	gcPoll time.Duration
+}
+
+// newPerfOverlay creates a hidden profiling overlay.
+func newPerfOverlay() *perfOverlay {
+	return &perfOverlay{}
+}
+
+// toggle shows or hides the overlay. The old measurements are dropped when it's shown again.
+func (p *perfOverlay) toggle() {
+	if p.visible.Load() {
+		p.visible.Store(false)
+		return
+	}
+	p.mu.Lock()
+	p.frames, p.ticks, p.gc = nil, nil, nil
+	p.lastGC = time.Now()
+	p.mu.Unlock()
+	p.visible.Store(true)
+}
+
+// addTick records the time the game logic has spent on a single tick.
+//
+// Parameters:
+// - d (time.Duration): The duration of the tick.
+func (p *perfOverlay) addTick(d time.Duration) {
+	if !p.visible.Load() {
+		return
+	}
+	p.mu.Lock()
+	p.ticks.add(time.Now(), d)
+	p.mu.Unlock()
+}
+
+// layout does nothing: the overlay is placed relative to the game area when it's drawn.
+func (p *perfOverlay) layout(g *Game) {}
+
+// update records the frame time and, from time to time, the GC pauses that have happened since the last poll.
+func (p *perfOverlay) update(g *Game, dt time.Duration) {
+	if !p.visible.Load() {
+		return
+	}
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frames.add(now, dt)
+	p.gcPoll += dt
+	if p.gcPoll < perfGCPoll {
+		return
+	}
+	p.gcPoll = 0
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	// the pauses are listed from the most recent one
+	for i := len(stats.PauseEnd) - 1; i >= 0; i-- {
+		if end := stats.PauseEnd[i]; end.After(p.lastGC) {
+			p.gc.add(end, stats.Pause[i])
+			p.lastGC = end
+		}
+	}
+	p.gc.add(now, 0)
+}
+
+// draw renders the graphs, the scale and the legend with the latest values.
+func (p *perfOverlay) draw(g *Game) {
+	if !p.visible.Load() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	x := g.gameAreaSP.X + perfMargin
+	y := g.gameAreaEP.Y - perfMargin - perfH
+	graphY, graphH := y+30, perfH-38
+
+	g.cv.SetFillStyle(0, 0, 0, 0.65)
+	g.cv.FillRect(x, y, perfW, perfH)
+
+	scale := max(perfMinScale, p.frames.peak(), p.ticks.peak(), p.gc.peak())
+	now := time.Now()
+	toX := func(at time.Time) float64 {
+		return x + perfW*(1-float64(now.Sub(at))/float64(perfWindow))
+	}
+	toY := func(v time.Duration) float64 {
+		return graphY + graphH*(1-float64(v)/float64(scale))
+	}
+
+	// GC pauses are drawn as bars, the frame and tick times as lines
+	g.cv.SetFillStyle("#E53935")
+	for _, s := range p.gc {
+		if s.v > 0 {
+			g.cv.FillRect(toX(s.at)-1, toY(s.v), 2, graphY+graphH-toY(s.v))
+		}
+	}
+	for _, graph := range []struct {
+		series perfSeries
+		color  string
+	}{{p.frames, "#FFEE58"}, {p.ticks, "#4DD0E1"}} {
+		if len(graph.series) < 2 {
+			continue
+		}
+		g.cv.BeginPath()
+		g.cv.SetStrokeStyle(graph.color)
+		g.cv.SetLineWidth(1)
+		g.cv.MoveTo(toX(graph.series[0].at), toY(graph.series[0].v))
+		for _, s := range graph.series[1:] {
+			g.cv.LineTo(toX(s.at), toY(s.v))
+		}
+		g.cv.Stroke()
+	}
+
+	g.cv.SetFont(g.fonts.small, 12)
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.FillText(fmt.Sprintf("frame %.1fms", ms(p.frames.last())), x+5, y+13)
+	g.cv.SetFillStyle("#4DD0E1")
+	g.cv.FillText(fmt.Sprintf("tick %.2fms", ms(p.ticks.last())), x+95, y+13)
+	g.cv.SetFillStyle("#E53935")
+	g.cv.FillText(fmt.Sprintf("gc %.2fms", ms(p.gc.peak())), x+175, y+13)
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.FillText(fmt.Sprintf("%.0fms", ms(scale)), x+5, y+26)
+}
+
+// ms converts the duration to milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,68 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+
+	"github.com/DenisKhanov/Snake/stats"
+)
+
+// loadHistory reads the history of the games played and computes the player's XP and the best results
+// of every mode from it.
+func (g *Game) loadHistory() {
+	if g.dataDir == "" {
+		return
+	}
+	runs, err := stats.Load(g.dataDir)
+	if err != nil {
+		log.Println(err)
+	}
+	g.xp.Store(int64(stats.TotalXP(runs)))
+	records := stats.NewRecords(runs)
+	g.records.Store(&records)
+}
+
+// updateRecords adds the finished game to the best results of its mode and, if it has set the best score,
+// saves a thumbnail of its final board.
+//
+// Parameters:
+// - run (stats.Run): The finished game.
+func (g *Game) updateRecords(run stats.Run) {
+	var records stats.Records
+	if r := g.records.Load(); r != nil {
+		records = *r
+	}
+	records, beaten := records.With(run)
+	g.records.Store(&records)
+	g.newBest.Store(beaten)
+	if beaten {
+		g.saveThumbnail(run.Mode())
+	}
+}
+
+// record returns the best result of the mode being played: the board size and whether the beginner assist
+// has been used.
+func (g *Game) record() stats.Record {
+	r := g.records.Load()
+	if r == nil {
+		return stats.Record{}
+	}
+	return (*r)[g.mode()]
+}
+
+// mode returns the mode being played.
+func (g *Game) mode() stats.Mode {
+	return stats.Mode{Cells: g.eng.BoardSize(), Assisted: g.eng.Assisted()}
+}
+
+// drawBest displays the best score of the mode being played on the side panel,
+// highlighted once the current game has beaten it.
+func (g *Game) drawBest() {
+	best := g.record().Score
+	g.cv.SetFillStyle("#90A4AE")
+	if g.eng.Score > best {
+		g.cv.SetFillStyle("#FFEE58")
+	}
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("info.best", best), g.param.gameW+50, 155)
+}
@@ -0,0 +1,80 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+
+	"github.com/tfriedel6/canvas"
+	"github.com/tfriedel6/canvas/backend/goglbackend"
+)
+
+// renderScales lists the internal resolutions of the board the player can switch between, in percent.
+var renderScales = []int{100, 75, 50}
+
+// renderScale returns the internal resolution of the board as a fraction of the game area size.
+func (g *Game) renderScale() float64 {
+	if g.cfg.RenderScale <= 0 || g.cfg.RenderScale >= 100 {
+		return 1
+	}
+	return float64(g.cfg.RenderScale) / 100
+}
+
+// cycleRenderScale switches to the next internal resolution from renderScales and remembers the choice
+// in the configuration.
+func (g *Game) cycleRenderScale() {
+	next := 0
+	for i, s := range renderScales {
+		if s == g.cfg.RenderScale {
+			next = (i + 1) % len(renderScales)
+			break
+		}
+	}
+	g.cfg.RenderScale = renderScales[next]
+	g.saveConfig()
+}
+
+// beginBoard prepares the canvas for drawing the board.
+//
+// When the render scale is below 100%, the board is drawn on an offscreen canvas with a lower
+// resolution: g.cv is temporarily replaced by the offscreen canvas, scaled so that all drawing
+// methods can keep using the game area coordinates. Every call must be paired with endBoard.
+func (g *Game) beginBoard() {
+	scale := g.renderScale()
+	if scale == 1 {
+		return
+	}
+	w := int(g.param.gameW * scale)
+	h := int(g.param.gameH * scale)
+	if g.boardBackend == nil {
+		backend, err := goglbackend.NewOffscreen(w, h, false, nil)
+		if err != nil {
+			log.Println("error creating offscreen canvas:", err)
+			g.cfg.RenderScale = 100
+			return
+		}
+		g.boardBackend = backend
+		g.boardCv = canvas.New(backend)
+	} else if bw, bh := g.boardBackend.Size(); bw != w || bh != h {
+		g.boardBackend.SetSize(w, h)
+	}
+	g.mainCv = g.cv
+	g.cv = g.boardCv
+	g.cv.Save()
+	g.cv.ClearRect(0, 0, float64(w), float64(h))
+	g.cv.Scale(scale, scale)
+	g.cv.Translate(-g.gameAreaSP.X, -g.gameAreaSP.Y)
+}
+
+// endBoard finishes drawing the board.
+//
+// If the board has been drawn on the offscreen canvas, the main canvas is restored and the board
+// is upscaled onto the game area.
+func (g *Game) endBoard() {
+	if g.mainCv == nil {
+		return
+	}
+	g.cv.Restore()
+	g.cv = g.mainCv
+	g.mainCv = nil
+	g.cv.DrawImage(g.boardCv, g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+}
@@ -0,0 +1,140 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/DenisKhanov/Snake/replay"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+const (
+	replaysDir     = "replays"      // the directory with the recorded games in the data directory
+	lastReplayFile = "last.replay"  // the name of the replay of the last finished game
+	runReplayGlob  = "run-*.replay" // the names of the replays of the games in the history
+	runReplaysMax  = historyMax     // the number of the latest games whose replays are kept
+)
+
+// bestReplayPath returns the location of the replay of the best unassisted game on a board of the given size.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - cells (int): The side of the board in cells.
+//
+// Returns:
+// - string: The path to the replay file.
+func bestReplayPath(dataDir string, cells int) string {
+	return filepath.Join(dataDir, replaysDir, fmt.Sprintf("best-%d.replay", cells))
+}
+
+// startRecording starts recording the game that has just been started in the engine.
+func (g *Game) startRecording() {
+	g.rec.Store(replay.NewRecorder(g.eng, version.Get().Version))
+}
+
+// continueRecording continues recording a loaded game from its replay recorded so far.
+// Games saved without a replay, or with one that can't be decoded, are not recorded.
+//
+// Parameters:
+// - data ([]byte): The encoded replay of the game so far.
+func (g *Game) continueRecording(data []byte) {
+	if len(data) == 0 {
+		g.rec.Store(nil)
+		return
+	}
+	r, err := replay.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Println("error decoding replay of saved game, the game won't be recorded:", err)
+		g.rec.Store(nil)
+		return
+	}
+	g.rec.Store(replay.Continue(r))
+}
+
+// encodeReplay returns the replay of the game so far in the binary replay format,
+// or nil if the game isn't recorded.
+func (g *Game) encodeReplay() []byte {
+	rec := g.rec.Load()
+	if rec == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := rec.Replay(g.eng).Encode(&buf); err != nil {
+		log.Println("error encoding replay:", err)
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// saveReplays saves the replay of the finished game into the replays directory of the data directory
+// as the last game and as a game of the history, and also as the best one if it has beaten the best score
+// on the board of this size.
+// Assisted games never replace the best replay, and neither do games whose replay fails the verification.
+// Only the replays of the latest runReplaysMax games of the history are kept.
+//
+// It's called by the game logic goroutine when the snake dies.
+//
+// Returns:
+// - string: The name of the replay file of the game in the history, or an empty string if it wasn't saved.
+func (g *Game) saveReplays() string {
+	rec := g.rec.Load()
+	if rec == nil || g.dataDir == "" {
+		return ""
+	}
+	r := rec.Replay(g.eng)
+	if err := r.Save(filepath.Join(g.dataDir, replaysDir, lastReplayFile)); err != nil {
+		log.Println(err)
+	}
+	runFile := time.Now().Format("run-20060102-150405.000.replay")
+	if err := r.Save(filepath.Join(g.dataDir, replaysDir, runFile)); err != nil {
+		log.Println(err)
+		runFile = ""
+	}
+	g.pruneRunReplays()
+	if g.cfg.SaveBestReplay && !g.eng.Assisted() {
+		g.saveBestReplay(r)
+	}
+	return runFile
+}
+
+// saveBestReplay saves the replay of the finished game as the best one on the board of its size
+// if it has beaten the best score and passes the verification.
+//
+// Parameters:
+// - r (*replay.Replay): The replay of the finished game.
+func (g *Game) saveBestReplay(r *replay.Replay) {
+	if err := r.Verify(); err != nil {
+		log.Println("the game isn't saved as the best one:", err)
+		return
+	}
+	path := bestReplayPath(g.dataDir, r.Cells)
+	if best := g.loadBestReplay(r.Cells); best != nil && best.Score >= r.Score {
+		return
+	}
+	if err := r.Save(path); err != nil {
+		log.Println(err)
+		return
+	}
+	log.Println("best game saved to", path)
+}
+
+// pruneRunReplays removes the replays of the games that have dropped out of the latest runReplaysMax games.
+// The file names start with the time the game ended, so they sort from the oldest to the newest.
+func (g *Game) pruneRunReplays() {
+	files, err := filepath.Glob(filepath.Join(g.dataDir, replaysDir, runReplayGlob))
+	if err != nil || len(files) <= runReplaysMax {
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files[:len(files)-runReplaysMax] {
+		if err = os.Remove(file); err != nil {
+			log.Println("error removing old replay:", err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResourceDir returns the directory where the game looks for its external resources.
+//
+// When the game is packaged as a macOS application bundle, the executable lives in
+// `SnakeGO.app/Contents/MacOS` and the resources are in `SnakeGO.app/Contents/Resources`.
+// In every other case the resources are looked up next to the executable.
+//
+// Returns:
+// - string: The resource directory.
+// - error: An error if the location of the executable cannot be determined.
+func ResourceDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("error finding executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	if bundle := filepath.Dir(dir); filepath.Base(dir) == "MacOS" && filepath.Base(bundle) == "Contents" &&
+		strings.HasSuffix(filepath.Dir(bundle), ".app") {
+		return filepath.Join(bundle, "Resources"), nil
+	}
+	return dir, nil
+}
@@ -0,0 +1,313 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/DenisKhanov/Snake/i18n"
+)
+
+const (
+	settingsRowH    = 34.0 // the height of a row of the settings screen
+	settingsBarW    = 160.0
+	settingsSpare   = 220.0 // the height of the game area taken by the title and the hints under the rows
+	settingsMinRows = 5     // the fewest rows shown at once, however small the game area
+	volumeStep      = 10    // how much a volume changes with a single key press, in percent
+)
+
+// setting is a single row of the settings screen.
+// Fields:
+// - label: the message key of the name of the setting.
+// - value: returns the current value formatted for the screen.
+// - level: returns the current value in the range [0, 1] for settings shown as sliders; nil for the others.
+// - change: changes the value by one step in the given direction (-1 or 1).
+// - preview: draws an illustration of the value below the list while the row is selected; nil for most settings.
+type setting struct {
+	label   string
+	value   func(g *Game) string
+	level   func(g *Game) float64
+	change  func(g *Game, delta int)
+	preview func(g *Game, x, y float64)
+}
+
+// settingsScreen is the overlay listing the settings that can be changed while playing.
+//
+// It's opened with the S key: the arrow keys ↑ ↓ select a setting, ← → change it, and S or ESC
+// close the screen. Every change is applied and saved to the configuration file immediately.
+// Fields:
+// - open: whether the screen is shown.
+// - items: the rows of the screen.
+// - selected: the index of the selected row.
+// - message: the result of the last export or import of the settings, shown under the rows.
+type settingsScreen struct {
+	open     bool
+	items    []setting
+	selected int
+	message  string
+}
+
+// newSettingsScreen creates the settings screen with all its rows.
+func newSettingsScreen() *settingsScreen {
+	return &settingsScreen{items: []setting{
+		volumeSetting("settings.master_volume", func(g *Game) *int { return &g.cfg.MasterVolume }),
+		volumeSetting("settings.music_volume", func(g *Game) *int { return &g.cfg.MusicVolume }),
+		volumeSetting("settings.sfx_volume", func(g *Game) *int { return &g.cfg.SFXVolume }),
+		{
+			label:  "settings.language",
+			value:  func(g *Game) string { return g.tr.T("language.name") },
+			change: func(g *Game, delta int) { g.cycleLanguage(delta) },
+		},
+		{
+			label: "settings.ui_scale",
+			value: func(g *Game) string { return fmt.Sprintf("%d%%", g.cfg.UIScale) },
+			level: func(g *Game) float64 {
+				return float64(g.cfg.UIScale-minUIScale) / float64(maxUIScale-minUIScale)
+			},
+			change: func(g *Game, delta int) { g.setUIScale(g.cfg.UIScale + delta*uiScaleStep) },
+		},
+		{
+			label:   "settings.palette",
+			value:   func(g *Game) string { return g.tr.T("palette." + g.pal().name) },
+			change:  func(g *Game, delta int) { g.cyclePalette(delta) },
+			preview: func(g *Game, x, y float64) { g.drawPalettePreview(x, y) },
+		},
+		{
+			label:  "settings.reduced_motion",
+			value:  func(g *Game) string { return g.onOff(g.cfg.ReducedMotion) },
+			change: func(g *Game, _ int) { g.cfg.ReducedMotion = !g.cfg.ReducedMotion },
+		},
+		{
+			label:  "settings.no_flashing",
+			value:  func(g *Game) string { return g.onOff(g.cfg.NoFlashing) },
+			change: func(g *Game, _ int) { g.cfg.NoFlashing = !g.cfg.NoFlashing },
+		},
+		{
+			label: "settings.board_size",
+			value: func(g *Game) string { return g.tr.T("settings.board_cells", boardSizeFor(g.cfg)) },
+			level: func(g *Game) float64 {
+				return float64(boardSizeFor(g.cfg)-minBoardCells) / float64(maxBoardCells-minBoardCells)
+			},
+			change: func(g *Game, delta int) { g.setBoardSize(boardSizeFor(g.cfg) + delta*boardCellStep) },
+		},
+		{
+			label:  "settings.large_cells",
+			value:  func(g *Game) string { return g.onOff(g.cfg.LargeCells) },
+			change: func(g *Game, _ int) { g.toggleLargeCells() },
+		},
+		{
+			label:  "settings.head_outline",
+			value:  func(g *Game) string { return g.onOff(g.cfg.HeadOutline) },
+			change: func(g *Game, _ int) { g.cfg.HeadOutline = !g.cfg.HeadOutline },
+		},
+		{
+			label:  "settings.captions",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Captions) },
+			change: func(g *Game, _ int) { g.cfg.Captions = !g.cfg.Captions },
+		},
+		{
+			label:  "settings.assist",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Assist) },
+			change: func(g *Game, _ int) { g.setAssist(!g.cfg.Assist) },
+		},
+		{
+			label:  "settings.hints",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Hints) },
+			change: func(g *Game, _ int) { g.cfg.Hints = !g.cfg.Hints },
+		},
+		{
+			label: "settings.ghost",
+			value: func(g *Game) string { return g.onOff(g.cfg.Ghost) },
+			change: func(g *Game, _ int) {
+				g.cfg.Ghost = !g.cfg.Ghost
+				g.setGhost()
+			},
+		},
+		{
+			label:  "settings.speedrun",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Speedrun) },
+			change: func(g *Game, _ int) { g.cfg.Speedrun = !g.cfg.Speedrun },
+		},
+		{
+			label:  "settings.wind",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Wind) },
+			change: func(g *Game, _ int) { g.cfg.Wind = !g.cfg.Wind },
+		},
+		{
+			label:  "settings.mute",
+			value:  func(g *Game) string { return g.onOff(g.cfg.Muted) },
+			change: func(g *Game, _ int) { g.toggleMute() },
+		},
+		{
+			label:  "settings.export",
+			value:  func(g *Game) string { return g.tr.T("settings.action") },
+			change: func(g *Game, _ int) { g.settings.message = g.exportSettings() },
+		},
+		{
+			label:  "settings.import",
+			value:  func(g *Game) string { return g.tr.T("settings.action") },
+			change: func(g *Game, _ int) { g.settings.message = g.importSettings() },
+		},
+		{
+			label:  "settings.sync",
+			value:  func(g *Game) string { return g.tr.T("settings.action") },
+			change: func(g *Game, _ int) { g.settings.message = g.syncProfile() },
+		},
+	}}
+}
+
+// setAssist switches the beginner assist on or off, both in the configuration and in the running game,
+// and records the switch in the replay.
+//
+// Parameters:
+// - on (bool): Whether the assist is on.
+func (g *Game) setAssist(on bool) {
+	g.cfg.Assist = on
+	g.eng.Assist = on
+	if rec := g.rec.Load(); rec != nil {
+		rec.ToggleAssist(g.eng.Tick)
+	}
+	g.needUpdateInfo = true
+}
+
+// volumeSetting creates a slider row for the volume stored in the configuration field.
+//
+// Parameters:
+// - label (string): The message key of the name of the setting.
+// - field (func(*Game) *int): Returns the configuration field with the volume in percent.
+//
+// Returns:
+// - setting: The row of the settings screen.
+func volumeSetting(label string, field func(g *Game) *int) setting {
+	return setting{
+		label: label,
+		value: func(g *Game) string { return fmt.Sprintf("%d%%", *field(g)) },
+		level: func(g *Game) float64 { return float64(*field(g)) / 100 },
+		change: func(g *Game, delta int) {
+			v := field(g)
+			*v = max(0, min(*v+delta*volumeStep, 100))
+			g.applyVolumes()
+		},
+	}
+}
+
+// onOff formats a boolean setting.
+func (g *Game) onOff(v bool) string {
+	if v {
+		return g.tr.T("settings.on")
+	}
+	return g.tr.T("settings.off")
+}
+
+// toggle opens or closes the settings screen. The game is paused while the screen is open.
+func (s *settingsScreen) toggle(g *Game) {
+	s.open = !s.open
+	s.message = ""
+	if s.open && !g.eng.GameOver {
+		g.paused = true
+	}
+}
+
+// handleKey processes a key press while the settings screen is open.
+//
+// Parameters:
+// - g (*Game): The game whose settings are changed.
+// - name (string): The name of the released key.
+func (s *settingsScreen) handleKey(g *Game, name string) {
+	switch name {
+	case "KeyS", "Escape":
+		s.toggle(g)
+	case "ArrowUp":
+		s.selected = (s.selected - 1 + len(s.items)) % len(s.items)
+	case "ArrowDown":
+		s.selected = (s.selected + 1) % len(s.items)
+	case "ArrowLeft", "ArrowRight", "Enter":
+		delta := 1
+		if name == "ArrowLeft" {
+			delta = -1
+		}
+		s.items[s.selected].change(g, delta)
+		g.saveConfig()
+	}
+}
+
+// draw renders the settings screen over the game area.
+func (s *settingsScreen) draw(g *Game) {
+	if !s.open {
+		return
+	}
+	g.cv.SetFillStyle(0, 0, 0, 0.75)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+
+	x := g.gameAreaSP.X + 40
+	y := g.gameAreaSP.Y + 70
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("settings.title"), x, y)
+
+	// the rows that don't fit in the game area scroll with the selection
+	shown := min(len(s.items), max(settingsMinRows, int((g.param.gameH/g.uiScale()-settingsSpare)/settingsRowH)))
+	first := min(max(s.selected-shown/2, 0), len(s.items)-shown)
+	g.cv.SetFont(g.fonts.middle, 16)
+	for i := first; i < first+shown; i++ {
+		item := s.items[i]
+		rowY := y + 50 + float64(i-first)*settingsRowH
+		color := "#CFD8DC"
+		if i == s.selected {
+			color = "#FFEE58"
+		}
+		g.cv.SetFillStyle(color)
+		if i == s.selected {
+			g.cv.FillText("›", x-18, rowY)
+		}
+		g.cv.FillText(g.tr.T(item.label), x, rowY)
+		valueX := x + g.param.gameW*0.45/g.uiScale()
+		if item.level != nil {
+			g.cv.SetStrokeStyle(color)
+			g.cv.SetLineWidth(1)
+			g.cv.StrokeRect(valueX, rowY-12, settingsBarW, 12)
+			g.cv.FillRect(valueX, rowY-12, settingsBarW*item.level(g), 12)
+			valueX += settingsBarW + 10
+		}
+		g.cv.FillText(item.value(g), valueX, rowY)
+	}
+
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	hintY := y + 50 + float64(shown)*settingsRowH + 20
+	g.cv.FillText(g.tr.T("settings.hint"), x, hintY)
+	if s.message != "" {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(s.message, x, hintY+20)
+	}
+
+	if preview := s.items[s.selected].preview; preview != nil {
+		preview(g, x, hintY+50)
+	}
+}
+
+// cycleLanguage switches to the previous or the next available language.
+//
+// Parameters:
+// - delta (int): The direction of the switch, -1 or 1.
+func (g *Game) cycleLanguage(delta int) {
+	langs := i18n.Available()
+	i := slices.Index(langs, g.cfg.Language)
+	g.cfg.Language = langs[(i+delta+len(langs))%len(langs)]
+	g.loadLanguage()
+}
+
+// loadLanguage loads the message catalog of the language chosen in the configuration,
+// picks the fonts that can draw it and schedules the whole screen for redrawing.
+func (g *Game) loadLanguage() {
+	tr, err := i18n.Load(g.cfg.Language)
+	if err != nil {
+		log.Println(err)
+	}
+	g.tr = tr
+	g.fonts.choose(tr.Text())
+	g.needRedrawStatic = true
+}
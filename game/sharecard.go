@@ -0,0 +1,175 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/version"
+	"github.com/tfriedel6/canvas"
+	"github.com/tfriedel6/canvas/backend/softwarebackend"
+)
+
+const (
+	shareCardW   = 600   // the width of a share card, in pixels
+	shareCardH   = 780   // the height of a share card, in pixels
+	shareBoardPx = 520.0 // the side of the board on a share card, in pixels
+	shareBoardY  = 90.0  // the distance between the top of a share card and the board
+)
+
+// shareCard is the content of a share card: an image summing up a finished game, for posting on social media.
+// Fields:
+// - state: the final state of the game.
+// - mode: the name of the mode the game was played in.
+// - pal: the palette the board is drawn with.
+// - endedAt: the time the game ended.
+type shareCard struct {
+	state   engine.State
+	mode    string
+	pal     *colorPalette
+	endedAt time.Time
+}
+
+// exportShareCard renders a share card of the finished game into a PNG file in the captures directory
+// of the data directory. The result is shown on the game over screen.
+//
+// The card is rendered by the software backend of the canvas in a separate goroutine,
+// so the game doesn't freeze while the image is being rendered and encoded.
+func (g *Game) exportShareCard() {
+	if !g.eng.GameOver || g.dataDir == "" {
+		return
+	}
+	card := g.newShareCard()
+	path := filepath.Join(g.dataDir, captureDir, card.endedAt.Format("share-20060102-150405.png"))
+	g.setShareMessage(g.tr.T("share.exporting"))
+	go func() {
+		if err := g.writeShareCard(path, card); err != nil {
+			log.Println(err)
+			g.setShareMessage(g.tr.T("share.failed"))
+			return
+		}
+		log.Println("share image saved to", path)
+		g.setShareMessage(g.tr.T("share.saved", path))
+	}()
+}
+
+// newShareCard returns the share card of the finished game.
+func (g *Game) newShareCard() shareCard {
+	return shareCard{
+		state:   g.eng.Snapshot(),
+		mode:    g.modeName(g.mode()),
+		pal:     g.pal(),
+		endedAt: time.Now(),
+	}
+}
+
+// setShareMessage sets the result of the share card export shown on the game over screen;
+// an empty text hides it.
+func (g *Game) setShareMessage(text string) {
+	g.shareMsg.Store(&text)
+}
+
+// writeShareCard renders the share card and saves it as a PNG file.
+//
+// Parameters:
+// - path (string): The path of the PNG file; the directory is created if needed.
+// - card (shareCard): The content of the card.
+//
+// Returns:
+// - error: An error if the card cannot be rendered or the file cannot be written; otherwise, nil.
+func (g *Game) writeShareCard(path string, card shareCard) error {
+	img, err := g.renderShareCard(card)
+	if err != nil {
+		return err
+	}
+	return writePNG(path, img)
+}
+
+// renderShareCard renders the share card.
+//
+// The card has the title of the game, the final board, the score, the length of the snake, the mode,
+// the date and the version of the game.
+//
+// Parameters:
+// - card (shareCard): The content of the card.
+//
+// Returns:
+// - image.Image: The card.
+// - error: An error if the card cannot be rendered; otherwise, nil.
+func (g *Game) renderShareCard(card shareCard) (image.Image, error) {
+	eng := engine.NewSized(card.state.Seed, card.state.Cells)
+	if err := eng.Restore(card.state); err != nil {
+		return nil, fmt.Errorf("error rendering share image: %w", err)
+	}
+	backend := softwarebackend.New(shareCardW, shareCardH)
+	boardX := (shareCardW - shareBoardPx) / 2
+	c := &Game{
+		cv:         canvas.New(backend),
+		param:      &GameParam{windowW: shareCardW, windowH: shareCardH, gameW: shareBoardPx, gameH: shareBoardPx},
+		gameAreaSP: Point{X: boardX, Y: shareBoardY},
+		gameAreaEP: Point{X: boardX + shareBoardPx, Y: shareBoardY + shareBoardPx},
+		cfg:        g.cfg,
+		assets:     g.assets,
+		tr:         g.tr,
+		eng:        eng,
+		previewPal: card.pal,
+	}
+	fonts, err := c.loadFonts()
+	if err != nil {
+		return nil, fmt.Errorf("error rendering share image: %w", err)
+	}
+	c.fonts = fonts
+	c.setZoom(float64(eng.BoardSize()))
+
+	c.cv.SetFillStyle("#263238")
+	c.cv.FillRect(0, 0, shareCardW, shareCardH)
+	c.cv.SetFillStyle("#4CAF50")
+	c.cv.SetFont(c.fonts.main, 44)
+	c.cv.FillText("SnakeGO", boardX, shareBoardY-25)
+	c.drawBoard()
+
+	y := shareBoardY + shareBoardPx
+	c.cv.SetFillStyle("#FFEE58")
+	c.cv.SetFont(c.fonts.main, 32)
+	c.cv.FillText(c.tr.T("info.score", card.state.Score), boardX, y+50)
+	c.cv.SetFillStyle("#CFD8DC")
+	c.cv.SetFont(c.fonts.small, 18)
+	c.cv.FillText(c.tr.T("share.length", len(card.state.Snake))+"   "+card.mode, boardX, y+85)
+	c.cv.SetFillStyle("#90A4AE")
+	c.cv.SetFont(c.fonts.small, 14)
+	c.cv.FillText(card.endedAt.Format("2006-01-02")+"   "+c.tr.T("about.version", version.Get()), boardX, y+125)
+
+	img := image.NewRGBA(image.Rect(0, 0, shareCardW, shareCardH))
+	draw.Draw(img, img.Rect, backend.Image, image.Point{}, draw.Src)
+	return img, nil
+}
+
+// writePNG encodes the image into a PNG file.
+//
+// Parameters:
+// - path (string): The path of the PNG file; the directory is created if needed.
+// - img (image.Image): The image.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+	if err = png.Encode(file, img); err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/DenisKhanov/Snake/saves"
+	"github.com/DenisKhanov/Snake/version"
+	"github.com/DenisKhanov/Snake/wire"
+)
+
+// slotsScreen is the overlay for saving the game into a save slot and loading it back.
+//
+// It's opened with F5 for saving, which is possible only while the game is paused, and with F9 for loading.
+// The arrow keys ↑ ↓ select a slot, Enter saves or loads, and ESC closes the screen.
+// A loaded game stays paused until the player continues it.
+// Fields:
+// - open: whether the screen is shown.
+// - saving: whether the screen saves the game; otherwise, it loads one.
+// - selected: the index of the selected slot.
+// - slots: the games in the slots; nil for empty slots.
+type slotsScreen struct {
+	open     bool
+	saving   bool
+	selected int
+	slots    [saves.Count]*saves.Slot
+}
+
+// show opens the screen for saving or loading and reads the save slots.
+//
+// Parameters:
+// - g (*Game): The game.
+// - saving (bool): Whether the game is saved; otherwise, it's loaded.
+func (s *slotsScreen) show(g *Game, saving bool) {
+	if saving && (!g.paused || g.eng.GameOver) {
+		return
+	}
+	s.open = true
+	s.saving = saving
+	for i := range s.slots {
+		slot, err := saves.Load(g.dataDir, i+1)
+		if err != nil {
+			log.Println(err)
+		}
+		s.slots[i] = slot
+	}
+	if !g.eng.GameOver {
+		g.paused = true
+	}
+}
+
+// handleKey processes a key press while the screen is open.
+//
+// Parameters:
+// - g (*Game): The game.
+// - name (string): The name of the released key.
+func (s *slotsScreen) handleKey(g *Game, name string) {
+	switch name {
+	case "Escape", "F5", "F9":
+		s.open = false
+	case "ArrowUp":
+		s.selected = (s.selected - 1 + len(s.slots)) % len(s.slots)
+	case "ArrowDown":
+		s.selected = (s.selected + 1) % len(s.slots)
+	case "Enter":
+		if s.saving {
+			g.saveSlot(s.selected + 1)
+		} else if s.slots[s.selected] != nil {
+			g.loadSlot(s.slots[s.selected])
+		}
+		s.open = false
+	}
+}
+
+// saveSlot saves the current game into the save slot.
+//
+// Parameters:
+// - n (int): The number of the slot.
+func (g *Game) saveSlot(n int) {
+	if err := saves.Save(g.dataDir, n, g.newSlot()); err != nil {
+		log.Println(err)
+	}
+}
+
+// newSlot returns a save slot with the current game and its replay recorded so far.
+func (g *Game) newSlot() *saves.Slot {
+	return &saves.Slot{
+		SavedAt:     time.Now(),
+		GameVersion: version.Get().Version,
+		State:       wire.NewState(g.eng.Snapshot()),
+		Replay:      g.encodeReplay(),
+	}
+}
+
+// loadSlot replaces the current game with the saved one, which stays paused.
+//
+// Parameters:
+// - slot (*saves.Slot): The saved game.
+func (g *Game) loadSlot(slot *saves.Slot) {
+	if slot.State.Newer() {
+		log.Println("the game has been saved by a newer version, some of its state may be lost")
+	}
+	g.settleRun(true)
+	if err := g.eng.Restore(slot.State.State); err != nil {
+		log.Println("error loading saved game:", err)
+		return
+	}
+	g.undos.Store(0)
+	g.tampered.Store(false)
+	g.paused = !g.eng.GameOver
+	g.recorder.reset()
+	g.challenge.Store(nil)
+	g.challengeRes.Store(nil)
+	g.newBest.Store(false)
+	g.splits.reset()
+	g.setRankMessage("")
+	g.continueRecording(slot.Replay)
+	g.setGhost()
+	g.setZoom(g.cam.cells)
+	g.needUpdateInfo = true
+	g.events.publish(event{kind: eventRestart, speed: g.eng.Speed})
+}
+
+// draw renders the screen over the game area.
+func (s *slotsScreen) draw(g *Game) {
+	if !s.open {
+		return
+	}
+	g.cv.SetFillStyle(0, 0, 0, 0.75)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+
+	x := g.gameAreaSP.X + 40
+	y := g.gameAreaSP.Y + 70
+	title := "slots.load"
+	if s.saving {
+		title = "slots.save"
+	}
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T(title), x, y)
+
+	g.cv.SetFont(g.fonts.middle, 16)
+	for i, slot := range s.slots {
+		rowY := y + 50 + float64(i)*settingsRowH
+		color := "#CFD8DC"
+		if i == s.selected {
+			color = "#FFEE58"
+			g.cv.SetFillStyle(color)
+			g.cv.FillText("›", x-18, rowY)
+		}
+		g.cv.SetFillStyle(color)
+		text := g.tr.T("slots.empty", i+1)
+		if slot != nil {
+			text = g.tr.T("slots.slot", i+1, slot.State.Score, len(slot.State.Snake),
+				slot.SavedAt.Local().Format("2006-01-02 15:04"))
+		}
+		g.cv.FillText(text, x, rowY)
+	}
+
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("slots.hint"), x, y+50+float64(len(s.slots))*settingsRowH+20)
+}
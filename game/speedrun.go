@@ -0,0 +1,145 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/speedrun"
+)
+
+const (
+	speedrunSplitsShown = 3     // the number of the latest splits shown by the speedrun timer
+	speedrunLineH       = 20.0  // the distance between two splits of the speedrun timer
+	speedrunW           = 210.0 // the width of the speedrun timer
+)
+
+// splitTracker collects the split times of the game being played.
+//
+// Splits are taken by the game logic goroutine and read by the render loop.
+// Fields:
+// - mu: guards splits.
+// - splits: the elapsed game time at every split, the first split first.
+type splitTracker struct {
+	mu     sync.Mutex
+	splits []time.Duration
+}
+
+// reset forgets the splits, when a new game starts.
+func (t *splitTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.splits = nil
+}
+
+// add takes a split at the elapsed game time.
+func (t *splitTracker) add(elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.splits = append(t.splits, elapsed)
+}
+
+// get returns a copy of the splits taken so far.
+func (t *splitTracker) get() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]time.Duration(nil), t.splits...)
+}
+
+// speedrunKey returns the key of the personal best of the mode being played.
+func (g *Game) speedrunKey() string {
+	return speedrun.Key(g.eng.BoardSize(), g.eng.Assisted())
+}
+
+// takeSplit takes a split every speedrun.SplitEvery food items eaten.
+//
+// It's called by the game logic goroutine after every tick, whether the speedrun timer is shown or not,
+// so the personal bests are kept up to date.
+//
+// Parameters:
+// - ate (bool): Whether the snake ate food during the tick.
+func (g *Game) takeSplit(ate bool) {
+	if ate && g.eng.AteFood%speedrun.SplitEvery == 0 {
+		g.splits.add(g.eng.Elapsed)
+	}
+}
+
+// finishSpeedrun replaces the personal best of the mode with the splits of the finished game if they are better.
+// Games loaded from a save slot, whose earlier splits are unknown, can't set a personal best.
+//
+// It's called by the game logic goroutine when the snake dies.
+func (g *Game) finishSpeedrun() {
+	splits := g.splits.get()
+	if len(splits) != g.eng.AteFood/speedrun.SplitEvery || !g.pbs.Update(g.speedrunKey(), splits) {
+		return
+	}
+	if g.dataDir == "" {
+		return
+	}
+	if err := g.pbs.Save(g.dataDir); err != nil {
+		log.Println(err)
+	}
+}
+
+// formatSplit formats a game time as minutes, seconds and hundredths of a second, such as "1:07.25".
+func formatSplit(d time.Duration) string {
+	cs := int(d.Round(10*time.Millisecond) / (10 * time.Millisecond))
+	return fmt.Sprintf("%d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// formatDelta formats the difference between a split and the personal best, such as "-1.20" or "+0.45".
+func formatDelta(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%.2f", sign, d.Seconds())
+}
+
+// speedrunTimer is a HUD widget that shows the elapsed game time in the top-left corner of the game area,
+// with the latest splits and their difference from the personal best splits of the mode:
+// green when the run is ahead, red when it's behind. It's shown only if it's enabled in the settings.
+type speedrunTimer struct{}
+
+// layout does nothing: the timer is placed relative to the game area when it's drawn.
+func (t *speedrunTimer) layout(g *Game) {}
+
+// update does nothing: the timer shows the game time of the engine.
+func (t *speedrunTimer) update(g *Game, dt time.Duration) {}
+
+// draw renders the timer and the latest splits on a dark background.
+func (t *speedrunTimer) draw(g *Game) {
+	if !g.cfg.Speedrun {
+		return
+	}
+	splits := g.splits.get()
+	pb := g.pbs.Get(g.speedrunKey())
+	first := max(0, len(splits)-speedrunSplitsShown)
+	x, y := g.gameAreaSP.X+10, g.gameAreaSP.Y+10
+	g.beginUI(x, y)
+	defer g.endUI()
+
+	g.cv.SetFillStyle(0, 0, 0, 0.6)
+	g.cv.FillRect(x, y, speedrunW, 40+float64(len(splits)-first)*speedrunLineH)
+	g.cv.SetFillStyle("#FFFFFF")
+	g.cv.SetFont(g.fonts.middle, 24)
+	g.cv.FillText(formatSplit(g.eng.Elapsed), x+10, y+30)
+
+	g.cv.SetFont(g.fonts.small, 14)
+	for i := first; i < len(splits); i++ {
+		rowY := y + 30 + float64(i-first+1)*speedrunLineH
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(g.tr.T("speedrun.split", (i+1)*speedrun.SplitEvery), x+10, rowY)
+		g.cv.FillText(formatSplit(splits[i]), x+80, rowY)
+		if i < len(pb) {
+			delta := splits[i] - pb[i]
+			g.cv.SetFillStyle("#66BB6A")
+			if delta > 0 {
+				g.cv.SetFillStyle("#EF5350")
+			}
+			g.cv.FillText(formatDelta(delta), x+150, rowY)
+		}
+	}
+}
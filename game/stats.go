@@ -0,0 +1,283 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/stats"
+	"github.com/tfriedel6/canvas"
+)
+
+const (
+	statsRowH   = 96.0  // the height of the statistics of a single mode
+	statsBarW   = 220.0 // the width of the best score bar
+	statsSparkW = 180.0 // the width of the sparkline of the latest scores
+	statsSparkH = 34.0  // the height of the sparkline of the latest scores
+	heatmapSize = 200.0 // the side of the death heatmap
+
+	exportDir = "exports" // the directory with the exported statistics in the data directory
+)
+
+// statsScreen is the overlay with the lifetime statistics, opened with T.
+//
+// The statistics are shown per mode (the board size) and per difficulty (with or without the beginner assist):
+// the number of games, the best and the average score, the food eaten, the longest snake and the longest game,
+// with a bar comparing the best scores of the modes and a sparkline of the latest scores. The game is paused while the screen is open.
+// A heatmap of the cells where the games on the current board size ended shows the player's bad habits
+// near the walls and in the corners.
+// Under it, a thumbnail of the final board of the best game of the mode being played shows what the record-setting
+// snake looked like.
+// The badges of the achievements and the player level are shown at the bottom.
+// E exports the history of all games to CSV and JSON files.
+// Fields:
+// - open: whether the screen is shown.
+// - summaries: the statistics of every mode played, read from the history when the screen is opened.
+// - deaths, deathsTop: the number of games that ended in every cell of the current board and the largest of them.
+// - thumb: the thumbnail of the best game of the mode being played; nil if there is none.
+// - message: the result of the last export, shown under the statistics.
+type statsScreen struct {
+	open      bool
+	summaries []stats.Summary
+	deaths    [][]int
+	deathsTop int
+	thumb     *canvas.Image
+	message   string
+}
+
+// toggle opens the screen, reading the history, or closes it.
+//
+// Parameters:
+// - g (*Game): The game.
+func (s *statsScreen) toggle(g *Game) {
+	s.open = !s.open
+	if !s.open {
+		return
+	}
+	if !g.eng.GameOver {
+		g.paused = true
+	}
+	s.summaries = nil
+	s.deaths, s.deathsTop = nil, 0
+	s.message = ""
+	if s.thumb != nil {
+		s.thumb.Delete()
+	}
+	s.thumb = g.loadThumbnail(g.mode())
+	if g.dataDir == "" {
+		return
+	}
+	runs, err := stats.Load(g.dataDir)
+	if err != nil {
+		log.Println(err)
+	}
+	s.summaries = stats.Summarize(runs)
+	s.deaths, s.deathsTop = stats.Deaths(runs, g.eng.BoardSize())
+}
+
+// handleKey processes a key press while the screen is open.
+//
+// Parameters:
+// - g (*Game): The game.
+// - name (string): The name of the released key.
+func (s *statsScreen) handleKey(g *Game, name string) {
+	switch name {
+	case "KeyT", "Escape":
+		s.toggle(g)
+	case "KeyE":
+		s.message = g.exportStats()
+	}
+}
+
+// exportStats exports the history of all games into CSV and JSON files in the exports directory
+// of the data directory.
+//
+// Returns:
+// - string: The message describing the result of the export.
+func (g *Game) exportStats() string {
+	if g.dataDir == "" {
+		return g.tr.T("stats.export_failed")
+	}
+	base := filepath.Join(g.dataDir, exportDir, time.Now().Format("stats-20060102-150405"))
+	for _, ext := range []string{".csv", ".json"} {
+		if _, err := stats.Export(g.dataDir, base+ext); err != nil {
+			log.Println(err)
+			return g.tr.T("stats.export_failed")
+		}
+	}
+	log.Println("statistics exported to", base+".csv", "and", base+".json")
+	return g.tr.T("stats.exported", filepath.Dir(base))
+}
+
+// recordRun adds the finished game to the history of the games played and to the best results of its mode,
+// and awards XP for it.
+//
+// It's called by the game logic goroutine when the snake dies.
+//
+// Parameters:
+// - replayFile (string): The name of the file with the replay of the game, or an empty string if it wasn't saved.
+func (g *Game) recordRun(replayFile string) {
+	if g.dataDir == "" {
+		return
+	}
+	head := g.eng.Snake.Head()
+	run := stats.Run{
+		EndedAt:   time.Now(),
+		Seed:      g.eng.Seed(),
+		Cells:     g.eng.BoardSize(),
+		Assisted:  g.eng.Assisted(),
+		Score:     g.eng.Score,
+		Length:    len(g.eng.Snake.Parts),
+		Food:      g.eng.AteFood,
+		Ticks:     g.eng.Tick,
+		Death:     &stats.Cell{X: int(head.X), Y: int(head.Y)},
+		Challenge: g.challengeWeek(),
+		Duration:  g.eng.Elapsed,
+		Cause:     stats.CauseWall,
+		Replay:    replayFile,
+		Undos:     int(g.undos.Load()),
+	}
+	g.awardXP(run)
+	g.updateRecords(run)
+	if err := stats.Append(g.dataDir, run); err != nil {
+		log.Println(err)
+	}
+}
+
+// modeName returns the name of the mode of the statistics, such as "20x20 board, assisted".
+func (g *Game) modeName(m stats.Mode) string {
+	difficulty := g.tr.T("stats.normal")
+	if m.Assisted {
+		difficulty = g.tr.T("stats.assisted")
+	}
+	return g.tr.T("stats.mode", m.Cells, m.Cells, difficulty)
+}
+
+// draw renders the screen over the game area.
+func (s *statsScreen) draw(g *Game) {
+	if !s.open {
+		return
+	}
+	g.cv.SetFillStyle(0, 0, 0, 0.8)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+
+	x := g.gameAreaSP.X + 40
+	y := g.gameAreaSP.Y + 70
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 40)
+	g.cv.FillText(g.tr.T("stats.title"), x, y)
+
+	if len(s.summaries) == 0 {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.SetFont(g.fonts.middle, 16)
+		g.cv.FillText(g.tr.T("stats.empty"), x, y+50)
+	}
+	bestMax := 1
+	for _, sum := range s.summaries {
+		bestMax = max(bestMax, sum.Best)
+	}
+	for i, sum := range s.summaries {
+		rowY := y + 50 + float64(i)*statsRowH
+		g.cv.SetFillStyle("#FFEE58")
+		g.cv.SetFont(g.fonts.middle, 16)
+		g.cv.FillText(g.modeName(sum.Mode), x, rowY)
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.SetFont(g.fonts.small, 14)
+		g.cv.FillText(g.tr.T("stats.summary", sum.Games, sum.Best, sum.Average(), sum.Food), x, rowY+22)
+
+		//the best score compared with the best scores of the other modes
+		g.cv.SetFillStyle("#37474F")
+		g.cv.FillRect(x, rowY+34, statsBarW, 12)
+		g.cv.SetFillStyle("#4CAF50")
+		g.cv.FillRect(x, rowY+34, statsBarW*float64(sum.Best)/float64(bestMax), 12)
+
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(g.tr.T("stats.records", sum.Longest, sum.MostTicks), x, rowY+66)
+
+		g.drawSparkline(sum.Recent, x+statsBarW+30, rowY+12, statsSparkW, statsSparkH)
+	}
+
+	g.drawHeatmap(s.deaths, s.deathsTop, g.gameAreaSP.X+g.param.gameW-40-heatmapSize, y+40)
+	if s.thumb != nil {
+		thumbX, thumbY := g.gameAreaSP.X+g.param.gameW-40-heatmapSize, y+40+heatmapSize+40
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.SetFont(g.fonts.small, 14)
+		g.cv.FillText(g.tr.T("stats.best_board", g.record().Score), thumbX, thumbY-8)
+		g.cv.DrawImage(s.thumb, thumbX, thumbY, thumbnailSize, thumbnailSize)
+	}
+
+	hintY := y + 50 + float64(max(len(s.summaries), 1))*statsRowH
+	g.cv.SetFillStyle("#90A4AE")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("stats.hint"), x, hintY)
+	if s.message != "" {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.FillText(s.message, x, hintY+24)
+	}
+	g.drawBadges(x+badgeR, hintY+64)
+	g.drawLevel(x, hintY+110, false)
+}
+
+// drawSparkline draws the values as a line chart without axes, scaled to fit the given box.
+//
+// Parameters:
+// - values ([]int): The values, drawn from left to right.
+// - x, y (float64): The top-left corner of the box.
+// - w, h (float64): The size of the box.
+func (g *Game) drawSparkline(values []int, x, y, w, h float64) {
+	if len(values) == 0 {
+		return
+	}
+	top := 1
+	for _, v := range values {
+		top = max(top, v)
+	}
+	step := w / float64(max(len(values)-1, 1))
+	g.cv.SetStrokeStyle("#29B6F6")
+	g.cv.SetLineWidth(1.5)
+	g.cv.BeginPath()
+	for i, v := range values {
+		px := x + float64(i)*step
+		py := y + h - h*float64(v)/float64(top)
+		if i == 0 {
+			g.cv.MoveTo(px, py)
+		} else {
+			g.cv.LineTo(px, py)
+		}
+	}
+	g.cv.Stroke()
+}
+
+// drawHeatmap draws the board with every cell tinted by the number of games that ended in it:
+// the more games, the brighter the red.
+//
+// Parameters:
+// - deaths ([][]int): The number of games that ended in every cell, indexed by the row and then by the column.
+// - top (int): The largest number of games that ended in a single cell.
+// - x, y (float64): The top-left corner of the heatmap.
+func (g *Game) drawHeatmap(deaths [][]int, top int, x, y float64) {
+	if len(deaths) == 0 {
+		return
+	}
+	cell := heatmapSize / float64(len(deaths))
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 14)
+	g.cv.FillText(g.tr.T("stats.heatmap"), x, y-8)
+	g.cv.SetFillStyle("#263238")
+	g.cv.FillRect(x, y, heatmapSize, heatmapSize)
+	for row, counts := range deaths {
+		for col, n := range counts {
+			if n == 0 {
+				continue
+			}
+			g.cv.SetFillStyle(229, 57, 53, 0.2+0.8*float64(n)/float64(top))
+			g.cv.FillRect(x+float64(col)*cell, y+float64(row)*cell, cell, cell)
+		}
+	}
+	g.cv.SetStrokeStyle("#78909C")
+	g.cv.SetLineWidth(1)
+	g.cv.StrokeRect(x, y, heatmapSize, heatmapSize)
+}
@@ -0,0 +1,55 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"log"
+
+	"github.com/DenisKhanov/Snake/telegram"
+)
+
+// connectTelegram creates the client of the Telegram bot, if the player has opted in by setting the bot token
+// and the chat in the configuration.
+func (g *Game) connectTelegram() {
+	if g.cfg.TelegramBotToken == "" {
+		return
+	}
+	client, err := telegram.New(g.cfg.TelegramBotToken, g.cfg.TelegramChatID)
+	if err != nil {
+		log.Println("sharing to Telegram is disabled:", err)
+		return
+	}
+	g.telegram = client
+}
+
+// sendToTelegram sends the share card of the finished game, with the score and the seed in the caption,
+// to the configured Telegram chat. The result is shown on the game over screen.
+//
+// The card is rendered and sent in a separate goroutine, so the game doesn't freeze meanwhile.
+func (g *Game) sendToTelegram() {
+	if !g.eng.GameOver {
+		return
+	}
+	card := g.newShareCard()
+	caption := g.tr.T("telegram.caption", card.state.Score, len(card.state.Snake), card.mode, card.state.Seed)
+	client := g.telegram
+	g.setShareMessage(g.tr.T("telegram.sending"))
+	go func() {
+		img, err := g.renderShareCard(card)
+		var photo bytes.Buffer
+		if err == nil {
+			err = png.Encode(&photo, img)
+		}
+		if err == nil {
+			err = client.SendPhoto(context.Background(), photo.Bytes(), "snake.png", caption)
+		}
+		if err != nil {
+			log.Println(err)
+			g.setShareMessage(g.tr.T("telegram.failed"))
+			return
+		}
+		g.setShareMessage(g.tr.T("telegram.sent"))
+	}()
+}
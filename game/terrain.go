@@ -0,0 +1,82 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// drawTerrain draws the special tiles of the board visible through the camera: ice as pale blue cells with
+// white streaks, mud as brown cells with dark spots and spikes as rows of gray triangles. The tiles differ
+// in shape as well as in color, for colorblind players.
+func (g *Game) drawTerrain() {
+	terrain := g.eng.Terrain()
+	if len(terrain) == 0 {
+		return
+	}
+	firstX, firstY := int(math.Floor(g.cam.x)), int(math.Floor(g.cam.y))
+	for y := max(firstY, 0); y <= firstY+int(g.cam.cells)+1 && y < len(terrain); y++ {
+		for x := max(firstX, 0); x <= firstX+int(g.cam.cells)+1 && x < len(terrain[y]); x++ {
+			p := Point{X: float64(x), Y: float64(y)}
+			sx, sy := g.toScreen(p)
+			switch terrain.At(p) {
+			case engine.Ice:
+				g.drawIce(sx, sy)
+			case engine.Mud:
+				g.drawMud(sx, sy)
+			case engine.Spikes:
+				g.drawSpikes(sx, sy)
+			}
+		}
+	}
+}
+
+// drawIce draws an ice tile, on which the snake can't turn.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the cell.
+func (g *Game) drawIce(x, y float64) {
+	g.cv.SetFillStyle("#B3E5FC")
+	g.cv.FillRect(x, y, g.cellW, g.cellH)
+	g.cv.SetStrokeStyle("#FFFFFF")
+	g.cv.SetLineWidth(math.Max(1, g.cellW/12))
+	g.cv.BeginPath()
+	g.cv.MoveTo(x+g.cellW*0.2, y+g.cellH*0.6)
+	g.cv.LineTo(x+g.cellW*0.5, y+g.cellH*0.3)
+	g.cv.MoveTo(x+g.cellW*0.45, y+g.cellH*0.8)
+	g.cv.LineTo(x+g.cellW*0.8, y+g.cellH*0.45)
+	g.cv.Stroke()
+}
+
+// drawMud draws a mud tile, which holds the snake for a tick.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the cell.
+func (g *Game) drawMud(x, y float64) {
+	g.cv.SetFillStyle("#8D6E63")
+	g.cv.FillRect(x, y, g.cellW, g.cellH)
+	g.cv.SetFillStyle("#4E342E")
+	for _, spot := range [][2]float64{{0.3, 0.3}, {0.7, 0.45}, {0.4, 0.75}} {
+		g.cv.BeginPath()
+		g.cv.Arc(x+g.cellW*spot[0], y+g.cellH*spot[1], g.cellW*0.1, 0, 2*math.Pi, false)
+		g.cv.Fill()
+	}
+}
+
+// drawSpikes draws a spike tile, which trims the snake.
+//
+// Parameters:
+// - x, y (float64): The top-left corner of the cell.
+func (g *Game) drawSpikes(x, y float64) {
+	g.cv.SetFillStyle("#B0BEC5")
+	g.cv.BeginPath()
+	for i := range 3 {
+		left := x + g.cellW*float64(i)/3
+		g.cv.MoveTo(left, y+g.cellH*0.9)
+		g.cv.LineTo(left+g.cellW/6, y+g.cellH*0.2)
+		g.cv.LineTo(left+g.cellW/3, y+g.cellH*0.9)
+		g.cv.ClosePath()
+	}
+	g.cv.Fill()
+}
@@ -0,0 +1,83 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/DenisKhanov/Snake/stats"
+	"github.com/tfriedel6/canvas"
+)
+
+const (
+	thumbnailsDir = "thumbnails" // the directory with the thumbnails of the best games in the data directory
+	thumbnailPx   = 160          // the side of a thumbnail, in pixels
+	thumbnailSize = 120.0        // the side of a thumbnail on the statistics screen
+)
+
+// thumbnailPath returns the location of the thumbnail of the final board of the best game of a mode.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - mode (stats.Mode): The mode.
+//
+// Returns:
+// - string: The path to the PNG file, such as "thumbnails/best-20.png" or "thumbnails/best-20-assisted.png".
+func thumbnailPath(dataDir string, mode stats.Mode) string {
+	name := fmt.Sprintf("best-%d", mode.Cells)
+	if mode.Assisted {
+		name += "-assisted"
+	}
+	return filepath.Join(dataDir, thumbnailsDir, name+".png")
+}
+
+// saveThumbnail saves a small PNG snapshot of the final board of the game that has set the best score of its mode,
+// so the statistics screen can show what the record-setting snake looked like.
+//
+// It's called by the game logic goroutine when the snake dies.
+//
+// Parameters:
+// - mode (stats.Mode): The mode of the game.
+func (g *Game) saveThumbnail(mode stats.Mode) {
+	c, backend := newHeadlessGame(thumbnailPx, g.eng.BoardSize())
+	c.eng = g.eng
+	c.previewPal = g.pal()
+	c.drawBoard()
+	img := image.NewRGBA(image.Rect(0, 0, thumbnailPx, thumbnailPx))
+	draw.Draw(img, img.Rect, backend.Image, image.Point{}, draw.Src)
+	if err := writePNG(thumbnailPath(g.dataDir, mode), img); err != nil {
+		log.Println(err)
+	}
+}
+
+// loadThumbnail loads the thumbnail of the best game of a mode into the canvas.
+//
+// Parameters:
+// - mode (stats.Mode): The mode.
+//
+// Returns:
+// - *canvas.Image: The thumbnail, or nil if there is none.
+func (g *Game) loadThumbnail(mode stats.Mode) *canvas.Image {
+	if g.dataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(thumbnailPath(g.dataDir, mode))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("error reading thumbnail:", err)
+		}
+		return nil
+	}
+	img, err := g.cv.LoadImage(data)
+	if err != nil {
+		log.Println("error loading thumbnail:", err)
+		return nil
+	}
+	return img
+}
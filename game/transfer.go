@@ -0,0 +1,84 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+const settingsExportFile = "settings.json" // the name of the settings export in the exports directory
+
+// settingsExportPath returns the location the settings are exported to and imported from.
+func (g *Game) settingsExportPath() string {
+	return filepath.Join(g.dataDir, exportDir, settingsExportFile)
+}
+
+// exportSettings exports all settings into the exports directory of the data directory.
+//
+// Returns:
+// - string: The message describing the result of the export.
+func (g *Game) exportSettings() string {
+	if g.dataDir == "" {
+		return g.tr.T("settings.transfer_failed")
+	}
+	path := g.settingsExportPath()
+	if err := g.cfg.Export(path, version.Get().Version); err != nil {
+		log.Println(err)
+		return g.tr.T("settings.transfer_failed")
+	}
+	return g.tr.T("settings.exported", path)
+}
+
+// importSettings imports the settings exported into the exports directory, e.g. copied from another machine,
+// and applies them right away.
+//
+// Returns:
+// - string: The message describing the result of the import.
+func (g *Game) importSettings() string {
+	if g.dataDir == "" {
+		return g.tr.T("settings.transfer_failed")
+	}
+	old := *g.cfg
+	unknown, err := g.cfg.Import(g.settingsExportPath())
+	if err != nil {
+		log.Println(err)
+		return g.tr.T("settings.transfer_failed")
+	}
+	g.applyConfig(old)
+	g.saveConfig()
+	if len(unknown) > 0 {
+		log.Println("skipped unknown settings:", strings.Join(unknown, ", "))
+		return g.tr.T("settings.imported_skipped", len(unknown))
+	}
+	return g.tr.T("settings.imported")
+}
+
+// applyConfig applies the settings that have changed since the old configuration and take effect
+// only when they're switched, such as the language, the volumes or the size of the board.
+//
+// Parameters:
+// - old (config.Config): The configuration before the change.
+func (g *Game) applyConfig(old config.Config) {
+	if g.cfg.Language != old.Language {
+		g.loadLanguage()
+	}
+	g.applyVolumes()
+	g.applyVSync()
+	g.setUIScale(g.cfg.UIScale)
+	if g.cfg.Fullscreen != old.Fullscreen {
+		g.setFullscreen(g.cfg.Fullscreen)
+	}
+	if g.cfg.Assist != old.Assist {
+		g.setAssist(g.cfg.Assist)
+	}
+	if g.cfg.LargeCells != old.LargeCells {
+		g.restartGame()
+		g.setZoom(g.boardCells())
+	}
+	g.setGhost()
+	g.needRedrawStatic = true
+}
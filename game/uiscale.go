@@ -0,0 +1,66 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+// Limits of the UI scale, in percent.
+const (
+	minUIScale  = 75
+	maxUIScale  = 200
+	uiScaleStep = 25
+)
+
+// uiScale returns the size of the HUD text and menus as a fraction of their normal size.
+func (g *Game) uiScale() float64 {
+	return float64(max(minUIScale, min(g.cfg.UIScale, maxUIScale))) / 100
+}
+
+// setUIScale changes the UI scale and recalculates the layout, since the side panel becomes wider or narrower.
+//
+// Parameters:
+// - percent (int): The new UI scale in percent; it's clamped to the supported range.
+func (g *Game) setUIScale(percent int) {
+	g.cfg.UIScale = max(minUIScale, min(percent, maxUIScale))
+	g.layout(g.param.windowW, g.param.windowH)
+}
+
+// beginUI scales the canvas by the UI scale around the given point, so the text and menus drawn next
+// keep their positions relative to that point while growing or shrinking. Every call must be paired with endUI.
+//
+// Parameters:
+// - x, y (float64): The point that stays in place.
+func (g *Game) beginUI(x, y float64) {
+	s := g.uiScale()
+	g.cv.Save()
+	g.cv.Translate(x, y)
+	g.cv.Scale(s, s)
+	g.cv.Translate(-x, -y)
+}
+
+// endUI restores the canvas scaled by beginUI.
+func (g *Game) endUI() {
+	g.cv.Restore()
+}
+
+// beginPanel scales the canvas for drawing the side panel, which grows to the right of the game area.
+// Every call must be paired with endUI.
+//
+// Inside the panel the bottom of the game area is at panelH, not at g.param.gameH.
+func (g *Game) beginPanel() {
+	g.beginUI(g.param.gameW, 0)
+}
+
+// panelH returns the height of the side panel in the coordinates of the scaled panel.
+func (g *Game) panelH() float64 {
+	return g.param.gameH / g.uiScale()
+}
+
+// toPanel transforms layout coordinates, such as the mouse position, into the coordinates of the scaled side panel.
+//
+// Parameters:
+// - x, y (float64): The position in layout coordinates.
+//
+// Returns:
+// - float64, float64: The position in the side panel coordinates.
+func (g *Game) toPanel(x, y float64) (float64, float64) {
+	s := g.uiScale()
+	return g.param.gameW + (x-g.param.gameW)/s, y / s
+}
@@ -0,0 +1,97 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	maxUndos     = 3               // the number of fatal moves that can be undone in a game
+	undoWindow   = 3 * time.Second // how long after a fatal move it can be undone
+	undoSnapshot = 4               // the number of snapshots kept in the rewind buffer of the engine for undoing
+)
+
+// canUndo reports whether the move that has just ended the game can be undone: the game is a practice game,
+// played with the beginner assist and outside the weekly challenge, and it has undos left.
+func (g *Game) canUndo() bool {
+	return g.eng.Assist && g.challenge.Load() == nil && g.undos.Load() < maxUndos && g.eng.SavedStates() > 0
+}
+
+// saveUndoPoint saves the state of the game before a tick is played, for undoing the tick if it's fatal.
+// Only practice games save it. It's called by the game logic goroutine before the moves of the tick.
+func (g *Game) saveUndoPoint() {
+	if g.eng.Assist && !g.eng.GameOver {
+		g.eng.SaveState()
+	}
+}
+
+// endRun is called when the snake dies. In a practice game with undos left, the end of the game is put off
+// for undoWindow, so the player can undo the fatal move with U; otherwise the game is recorded at once.
+// It's called by the game logic goroutine.
+func (g *Game) endRun() {
+	if g.canUndo() {
+		g.undoUntil.Store(time.Now().Add(undoWindow).UnixNano())
+		return
+	}
+	g.finishRun()
+}
+
+// settleRun records the game whose end has been put off for an undo, once the undo window has passed,
+// or at once if force is set, e.g. because a new game starts. It does nothing if no game is waiting.
+//
+// Parameters:
+// - force (bool): Whether to record the game even if the undo window hasn't passed yet.
+func (g *Game) settleRun(force bool) {
+	until := g.undoUntil.Load()
+	if until == 0 || !force && time.Now().UnixNano() < until {
+		return
+	}
+	if g.undoUntil.CompareAndSwap(until, 0) {
+		g.finishRun()
+	}
+}
+
+// finishRun records the finished game: its replays, its statistics and its results in the challenge,
+// the speedrun and the online leaderboard. A game changed from the developer console isn't recorded.
+func (g *Game) finishRun() {
+	if g.tampered.Load() {
+		return
+	}
+	g.recordRun(g.saveReplays())
+	g.finishChallenge()
+	g.finishSpeedrun()
+	g.submitScore()
+}
+
+// undoLeft returns the time left to undo the fatal move, or 0 if it can't be undone.
+func (g *Game) undoLeft() time.Duration {
+	until := g.undoUntil.Load()
+	if until == 0 || !g.eng.GameOver {
+		return 0
+	}
+	return max(time.Until(time.Unix(0, until)), 0)
+}
+
+// undo rewinds the game to the tick before the fatal move, while the undo window is open. The game is paused,
+// so the player can choose another way before pressing P, and the move is removed from the replay.
+// Undone games are marked on the results with the number of undos used.
+func (g *Game) undo() {
+	until := g.undoUntil.Load()
+	if until == 0 || time.Now().UnixNano() >= until || !g.undoUntil.CompareAndSwap(until, 0) {
+		return
+	}
+	s, err := g.eng.LoadState(1)
+	if err != nil {
+		log.Println("error undoing the last move:", err)
+		g.finishRun()
+		return
+	}
+	g.undos.Add(1)
+	if rec := g.rec.Load(); rec != nil {
+		rec.Rewind(s.Tick)
+	}
+	g.paused = true
+	g.needUpdateInfo = true
+	g.events.publish(event{kind: eventRestart, speed: g.eng.Speed})
+}
@@ -0,0 +1,46 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"context"
+	"log"
+
+	"github.com/DenisKhanov/Snake/update"
+	"github.com/DenisKhanov/Snake/version"
+)
+
+// checkForUpdates looks for a newer release of the game in the background, if the player has opted in.
+//
+// The check never interrupts the game: errors (for example, when the computer is offline) are only logged,
+// and if a newer release is found, the side panel is redrawn with the update banner.
+func (g *Game) checkForUpdates() {
+	if !g.cfg.CheckUpdates || g.dataDir == "" {
+		return
+	}
+	go func() {
+		rel, err := update.Check(context.Background(), version.Get().Version, g.dataDir)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if rel != nil {
+			g.newRelease.Store(rel)
+			g.needRedrawStatic = true
+		}
+	}()
+}
+
+// drawUpdateBanner displays a small "Update available" banner at the top of the side panel.
+// Clicking the banner opens the release page.
+func (g *Game) drawUpdateBanner() {
+	rel := g.newRelease.Load()
+	if rel == nil {
+		return
+	}
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#FFA726")
+	g.cv.SetFont(g.fonts.small, 14)
+	text := g.tr.T("update.available", rel.Version)
+	g.cv.FillText(text, g.param.gameW+50, 20)
+	g.cv.Stroke()
+}
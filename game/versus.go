@@ -0,0 +1,549 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/leaderboard"
+	"github.com/DenisKhanov/Snake/netplay"
+)
+
+const (
+	versusConnectTimeout = 10 * time.Second // how long connecting to the match server may take
+	reconnectDelay       = time.Second      // the pause between two attempts to reconnect to a running match
+)
+
+// versusMatch is the state of an online versus game, as last reported by the match server.
+//
+// It's updated by the goroutine receiving the messages of the server and read by the render loop.
+// The player's own snake is predicted, so it reacts to the keys without waiting for the server;
+// the opponent's snake and the food are drawn as last reported.
+// Fields:
+// - mu: guards all fields but zoomed.
+// - client: the connection to the match server; nil until connected.
+// - url: the address of the match server.
+// - token: the reconnect token of the running game; empty until the game starts.
+// - left: whether the player has left the match; the connection isn't resumed then.
+// - status: the message key describing the stage of the match, e.g. "versus.waiting".
+// - errText: the error that ended the match, if any.
+// - reason: the reason the server has refused to let the player in a room, if it has.
+// - choice: the selected entry of the menu, one of the lobby constants.
+// - code: the code of the room to join, as typed in the menu.
+// - room: the last room message, while in a room.
+// - matches: the running games to watch, as last listed by the server.
+// - pick: the selected game of the list.
+// - watch: the identifier of the game to watch.
+// - ladderMode: the index of the ranked mode whose ladder is shown, in netplay.Modes.
+// - ladder: the best players of the shown ladder, as last listed by the server.
+// - delay: how late the game is shown to a spectator, in milliseconds.
+// - you: the index of the player in the match, or netplay.Spectator.
+// - names: the names of the players.
+// - cells: the size of the shared board; 0 until the game starts.
+// - speed: the interval between two ticks of the game, in milliseconds.
+// - state: the last state of the board.
+// - predictor: predicts the player's snake; nil until the game starts.
+// - over: the last message of the server, if the game is over.
+// - chat: the latest chat messages, the oldest first.
+// - typing: whether the player is typing a chat message.
+// - draft: the chat message being typed.
+// - zoomed: whether the camera has been fitted to the shared board; used only by the render loop.
+type versusMatch struct {
+	mu         sync.Mutex
+	client     *netplay.Client
+	url        string
+	token      string
+	left       bool
+	status     string
+	errText    string
+	reason     string
+	choice     int
+	code       string
+	room       *netplay.Message
+	matches    []netplay.MatchInfo
+	pick       int
+	watch      string
+	ladderMode int
+	ladder     []netplay.Rating
+	delay      int
+	you        int
+	names      []string
+	cells      int
+	speed      int
+	state      netplay.Message
+	predictor  *netplay.Predictor
+	over       *netplay.Message
+	chat       []chatLine
+	typing     bool
+	draft      string
+	zoomed     bool
+}
+
+// toggleVersus opens the menu of the online versus mode, or leaves the current game.
+//
+// The local game is paused while playing online: it stays where it was and continues when the player leaves.
+func (g *Game) toggleVersus() {
+	if g.versus.Load() != nil {
+		g.leaveVersus()
+		return
+	}
+	g.openVersus()
+}
+
+// openVersus shows the menu of the online versus mode instead of the local game.
+// Without a configured match server, the screen only tells how to set one up.
+func (g *Game) openVersus() {
+	m := &versusMatch{status: "versus.menu", ladderMode: modeIndex(engine.VersusSpeed)}
+	if g.cfg.VersusURL == "" {
+		m.status = "versus.no_server"
+	}
+	if !g.eng.GameOver {
+		g.paused = true
+	}
+	g.versus.Store(m)
+}
+
+// joinVersus connects to the match server in the background, joining the quick-match queue, creating a room,
+// entering the room with the typed code or watching the picked game, depending on the choice of the menu.
+//
+// Parameters:
+// - m (*versusMatch): The current match, showing the menu.
+func (g *Game) joinVersus(m *versusMatch) {
+	m.mu.Lock()
+	m.status = "versus.connecting"
+	m.url = g.cfg.VersusURL
+	choice, code, watch := m.choice, m.code, m.watch
+	m.mu.Unlock()
+	if g.cfg.PlayerID == "" {
+		g.cfg.PlayerID = leaderboard.NewID()
+		g.saveConfig()
+	}
+	url, name, id := g.cfg.VersusURL, cmp.Or(g.cfg.PlayerName, defaultPlayerName), g.cfg.PlayerID
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), versusConnectTimeout)
+		defer cancel()
+		var client *netplay.Client
+		var err error
+		switch choice {
+		case lobbyCreate:
+			client, err = netplay.CreateRoom(ctx, url, name, id)
+		case lobbyEnter:
+			client, err = netplay.EnterRoom(ctx, url, name, id, code)
+		case lobbyWatch:
+			client, err = netplay.Spectate(ctx, url, watch)
+		default:
+			client, err = netplay.Join(ctx, url, name, id)
+		}
+		if err != nil {
+			log.Println("error joining versus game:", err)
+			m.fail(err)
+			return
+		}
+		m.mu.Lock()
+		m.client = client
+		m.mu.Unlock()
+		if g.versus.Load() != m {
+			//the player has left while connecting
+			client.Leave()
+			return
+		}
+		m.receive(client)
+	}()
+}
+
+// leaveVersus leaves the match, which makes the opponent win a running game, and goes back to the local game.
+func (g *Game) leaveVersus() {
+	m := g.versus.Swap(nil)
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	client := m.client
+	m.left = true
+	m.mu.Unlock()
+	if client != nil {
+		client.Leave()
+	}
+	g.setZoom(g.boardCells())
+	g.needUpdateInfo = true
+}
+
+// handleVersusKey processes a key press during an online versus game: the chat, the menu and the room have
+// their own keys, the arrow keys turn the snake, ENTER goes back to the menu once the game is over, and ESC or O
+// leave the game. The other keys are ignored, except for muting the sounds.
+//
+// Parameters:
+// - m (*versusMatch): The current match.
+// - code (int): The code of the released key.
+// - rn (rune): The character of the released key.
+// - name (string): The name of the released key.
+func (g *Game) handleVersusKey(m *versusMatch, code int, rn rune, name string) {
+	if g.handleChatKey(m, rn, name) {
+		return
+	}
+	m.mu.Lock()
+	menu, browse, ratings, ended := m.status == "versus.menu", m.status == "versus.browse", m.status == "versus.ratings", m.ended()
+	inRoom := m.room != nil && m.cells == 0
+	m.mu.Unlock()
+	switch {
+	case menu && g.handleMenuKey(m, name):
+		return
+	case browse && g.handleBrowseKey(m, name):
+		return
+	case ratings && g.handleRatingsKey(m, name):
+		return
+	case inRoom && !ended && g.handleRoomKey(m, name):
+		return
+	}
+	switch name {
+	case "Escape", "KeyO":
+		g.leaveVersus()
+		return
+	case "Enter":
+		if ended {
+			g.leaveVersus()
+			g.openVersus()
+		}
+		return
+	case "KeyM":
+		g.toggleMute()
+		return
+	}
+	if code < 79 || code > 82 {
+		return
+	}
+	m.mu.Lock()
+	client, predictor, playing := m.client, m.predictor, m.over == nil
+	m.mu.Unlock()
+	if client == nil || predictor == nil || !playing {
+		return
+	}
+	dir := engine.Dir(0).FromKey(code)
+	seq, tick, ok := predictor.Turn(dir)
+	if !ok {
+		g.markRejected()
+		g.events.publish(event{kind: eventRejected})
+		return
+	}
+	go func() {
+		if err := client.Turn(dir, seq, tick); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+// receive handles the messages of the server until the match ends. Once the game starts, the prediction is ticked
+// at the speed of the game, and a dropped connection is resumed while the server keeps the player's snake in the game.
+func (m *versusMatch) receive(client *netplay.Client) {
+	stop := make(chan struct{})
+	defer close(stop)
+	for {
+		msg, err := client.Next()
+		if err != nil {
+			client.Close()
+			m.mu.Lock()
+			ended, token := m.ended() || m.left, m.token
+			m.mu.Unlock()
+			switch {
+			case ended:
+				return
+			case token == "":
+				m.fail(err)
+				return
+			}
+			log.Println("versus game connection lost:", err)
+			if client = m.reconnect(token); client == nil {
+				return
+			}
+			continue
+		}
+		m.mu.Lock()
+		switch msg.Type {
+		case netplay.TypeWait:
+			m.status = "versus.waiting"
+		case netplay.TypeRoom:
+			m.status = "versus.room"
+			m.room = &msg
+		case netplay.TypeError:
+			m.status = "versus." + msg.Reason
+			m.reason = msg.Reason
+		case netplay.TypeStart:
+			//a resumed game starts over with a new prediction, as the server counts the turns from the start again
+			m.status = "versus.playing"
+			m.you, m.names, m.cells, m.token = msg.You, msg.Names, msg.Cells, msg.Token
+			m.speed = cmp.Or(msg.Speed, engine.VersusSpeed)
+			if msg.You == netplay.Spectator {
+				m.status, m.delay = "versus.watching", msg.Delay
+				break
+			}
+			if m.predictor == nil {
+				go m.tickPredictor(time.Duration(m.speed)*time.Millisecond, stop)
+			}
+			m.predictor = netplay.NewPredictor(msg.You, msg.Cells)
+		case netplay.TypeState:
+			m.state = msg
+			if m.predictor != nil {
+				m.predictor.Reconcile(msg)
+			}
+		case netplay.TypeDelta:
+			//only the spectators get deltas, and always after a full state
+			if state, err := netplay.ApplyDelta(m.state, msg); err == nil {
+				m.state = state
+			} else {
+				log.Println("error applying versus delta:", err)
+			}
+		case netplay.TypeChat:
+			m.addChat(msg)
+		case netplay.TypeOver:
+			m.over = &msg
+		}
+		m.mu.Unlock()
+	}
+}
+
+// reconnect resumes the running game after the connection has dropped, trying again until the server
+// no longer keeps the player's snake in the game.
+//
+// Parameters:
+// - token (string): The reconnect token of the game.
+//
+// Returns:
+// - *netplay.Client: The new connection, or nil if the game can't be resumed or the player has left.
+func (m *versusMatch) reconnect(token string) *netplay.Client {
+	m.mu.Lock()
+	m.status = "versus.reconnecting"
+	url := m.url
+	m.mu.Unlock()
+	deadline := time.Now().Add(netplay.ReconnectGrace)
+	for {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		client, err := netplay.Resume(ctx, url, token)
+		cancel()
+		m.mu.Lock()
+		left := m.left
+		if err == nil && !left {
+			m.client = client
+		}
+		m.mu.Unlock()
+		switch {
+		case left:
+			if client != nil {
+				client.Leave()
+			}
+			return nil
+		case err == nil:
+			return client
+		case !time.Now().Add(reconnectDelay).Before(deadline):
+			log.Println("error resuming versus game:", err)
+			m.fail(err)
+			return nil
+		}
+		time.Sleep(reconnectDelay)
+	}
+}
+
+// tickPredictor advances the prediction at the speed of the versus game until stop is closed.
+func (m *versusMatch) tickPredictor(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.Lock()
+			p := m.predictor
+			m.mu.Unlock()
+			p.Tick()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ended reports whether the match has ended, with a result or an error; the caller must hold m.mu.
+func (m *versusMatch) ended() bool {
+	return m.over != nil || m.errText != "" || m.reason != ""
+}
+
+// fail ends the match with an error.
+func (m *versusMatch) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = "versus.error"
+	m.errText = err.Error()
+}
+
+// snapshot returns a copy of the match for drawing, so the render loop doesn't hold the lock while drawing.
+func (m *versusMatch) snapshot() *versusMatch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := &versusMatch{
+		status:     m.status,
+		errText:    m.errText,
+		reason:     m.reason,
+		choice:     m.choice,
+		code:       m.code,
+		room:       m.room,
+		matches:    m.matches,
+		pick:       m.pick,
+		ladderMode: m.ladderMode,
+		ladder:     m.ladder,
+		delay:      m.delay,
+		you:        m.you,
+		names:      m.names,
+		cells:      m.cells,
+		state:      m.state,
+		over:       m.over,
+		chat:       slices.Clone(m.chat),
+		typing:     m.typing,
+		draft:      m.draft,
+	}
+	//the predicted snake replaces the player's snake of the last state while the game runs
+	if m.predictor != nil && m.over == nil && m.you < len(m.state.Snakes) {
+		if parts := m.predictor.Snake(); parts != nil {
+			s.state.Snakes = slices.Clone(m.state.Snakes)
+			s.state.Snakes[m.you] = parts
+		}
+	}
+	return s
+}
+
+// head returns the position of the player's head on the shared board, as predicted,
+// or the center of the board for a spectator.
+//
+// Returns:
+// - engine.Point: The position of the head.
+// - bool: Whether the game has started and the player's snake is on the board.
+func (m *versusMatch) head() (engine.Point, bool) {
+	s := m.snapshot()
+	if s.you == netplay.Spectator {
+		return engine.Point{X: float64(s.cells) / 2, Y: float64(s.cells) / 2}, s.cells > 0
+	}
+	if s.you >= len(s.state.Snakes) || len(s.state.Snakes[s.you]) == 0 {
+		return engine.Point{}, false
+	}
+	return s.state.Snakes[s.you][0], true
+}
+
+// ownColors reports whether the snake of the player with the index is drawn in the colors of the palette:
+// the player's own snake, or the first one for a spectator.
+func (m *versusMatch) ownColors(i int) bool {
+	return i == m.you || (m.you == netplay.Spectator && i == 0)
+}
+
+// versusCells returns the size of the shared board of the online versus game, or 0 if no game has started.
+func (g *Game) versusCells() int {
+	m := g.versus.Load()
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cells
+}
+
+// drawVersus draws the shared board of the online versus game instead of the local game: the player's snake
+// in the colors of the palette, the opponent's snake in red, and the food, with the names and the lengths
+// of the snakes at the top and the stage of the match, or its result, in the middle. A spectator sees
+// the first snake in the colors of the palette.
+//
+// Parameters:
+// - m (*versusMatch): The current match.
+func (g *Game) drawVersus(m *versusMatch) {
+	s := m.snapshot()
+	if s.cells > 0 && !m.zoomed {
+		m.zoomed = true
+		g.setZoom(g.boardCells())
+	}
+	g.drawWorld()
+	g.clipGameArea()
+	g.drawGridGameArea()
+	if s.state.Food != nil {
+		x, y := g.toScreen(*s.state.Food)
+		g.drawApple(x+1, y+1, g.side)
+	}
+	for i, parts := range s.state.Snakes {
+		if !s.ownColors(i) {
+			opponent := *g.pal()
+			opponent.head, opponent.body, opponent.bodyAlt = "#E53935", "#EF5350", "#E57373"
+			g.previewPal = &opponent
+		}
+		if i < len(s.state.Alive) && !s.state.Alive[i] {
+			g.cv.SetGlobalAlpha(ghostAlpha)
+		}
+		g.drawSnakeParts(parts)
+		g.cv.SetGlobalAlpha(1)
+		g.previewPal = nil
+	}
+	g.cv.Restore()
+
+	if s.status == "versus.menu" || s.status == "versus.room" || s.status == "versus.browse" || s.status == "versus.ratings" {
+		g.drawLobby(s)
+		return
+	}
+	g.beginUI(g.gameAreaSP.X, g.gameAreaSP.Y)
+	defer g.endUI()
+	if len(s.names) == len(s.state.Snakes) && len(s.names) == engine.Players {
+		g.cv.SetFillStyle(0, 0, 0, 0.5)
+		g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, 30)
+		g.cv.SetFont(g.fonts.small, 16)
+		for i, name := range s.names {
+			color := "#E53935"
+			if s.ownColors(i) {
+				color = g.pal().head
+			}
+			key := "versus.player"
+			if i < len(s.state.Away) && s.state.Away[i] {
+				key = "versus.away"
+			}
+			g.cv.SetFillStyle(color)
+			g.cv.FillText(g.tr.T(key, name, len(s.state.Snakes[i])), g.gameAreaSP.X+10+float64(i)*g.param.gameW/2, g.gameAreaSP.Y+21)
+		}
+	}
+	if s.you == netplay.Spectator && s.over == nil && s.cells > 0 {
+		g.cv.SetFillStyle("#CFD8DC")
+		g.cv.SetFont(g.fonts.small, 14)
+		g.cv.FillText(g.tr.T("versus.watching", s.delay/1000), g.gameAreaSP.X+10, g.gameAreaSP.Y+48)
+	}
+	g.drawChat(s)
+	title, hint := s.status, "versus.leave_hint"
+	var args []any
+	switch {
+	case s.over != nil && s.over.Winner == engine.Draw:
+		title, hint = "versus.draw", "versus.again_hint"
+	case s.over != nil && s.you == netplay.Spectator && s.over.Winner >= 0 && s.over.Winner < len(s.names):
+		title, args, hint = "versus.winner", []any{s.names[s.over.Winner]}, "versus.again_hint"
+	case s.over != nil && s.over.Winner == s.you && s.over.Reason == netplay.ReasonLeft:
+		title, hint = "versus.opponent_left", "versus.again_hint"
+	case s.over != nil && s.over.Winner == s.you:
+		title, hint = "versus.win", "versus.again_hint"
+	case s.over != nil:
+		title, hint = "versus.lose", "versus.again_hint"
+	case s.errText != "" || s.reason != "":
+		hint = "versus.again_hint"
+	case s.status == "versus.no_server":
+		hint = "versus.setup_hint"
+	case s.status == "versus.reconnecting":
+	case s.cells > 0:
+		return
+	}
+	x, y := g.gameAreaSP.X+40, g.gameAreaSP.Y+g.param.gameH/2
+	g.cv.SetFillStyle(0, 0, 0, 0.6)
+	g.cv.FillRect(g.gameAreaSP.X, y-60, g.param.gameW, 120)
+	g.cv.SetFillStyle("#FFEE58")
+	g.cv.SetFont(g.fonts.main, 36)
+	g.cv.FillText(g.tr.T(title, args...), x, y)
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 15)
+	if s.errText != "" {
+		g.cv.FillText(s.errText, x, y+25)
+	}
+	if s.over != nil && s.you >= 0 && s.you < len(s.over.Ratings) && s.you < len(s.over.Deltas) {
+		g.cv.FillText(g.tr.T("versus.rating_change", s.over.Ratings[s.you], s.over.Deltas[s.you]), x, y+25)
+	}
+	g.cv.FillText(g.tr.T(hint), x, y+45)
+}
@@ -0,0 +1,68 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+	"time"
+)
+
+const watchdogTimeout = 3 * time.Second // how long the game logic may stay silent beyond a tick before it's considered stalled
+
+// heartbeat records that the game logic goroutine is alive.
+func (g *Game) heartbeat() {
+	g.lastTick.Store(time.Now().UnixNano())
+}
+
+// checkWatchdog detects a stalled game logic goroutine (a deadlock, or a timer that never fires again).
+//
+// It's called by the render loop on every frame: if the logic hasn't ticked for longer than the current
+// tick interval plus watchdogTimeout, the game is marked as stalled, and an error screen is shown
+// instead of a board that silently stopped moving.
+func (g *Game) checkWatchdog() {
+	if g.stalled {
+		return
+	}
+	silence := time.Since(time.Unix(0, g.lastTick.Load()))
+	if silence > g.eng.Interval()+watchdogTimeout {
+		log.Printf("game logic has not ticked for %s", silence.Round(time.Millisecond))
+		g.stalled = true
+	}
+}
+
+// restartLogic starts a new run after the game logic has stalled.
+//
+// The stalled goroutine can't be stopped from the outside, so it's abandoned: the generation counter
+// is increased, which makes the old goroutine exit if it ever wakes up, and a new one is started.
+func (g *Game) restartLogic() {
+	gen := g.logicGen.Add(1)
+	g.paused = false
+	g.restartGame()
+	g.heartbeat()
+	g.stalled = false
+	go g.handleGameLogic(gen)
+}
+
+// drawStalled displays the error screen shown when the game logic has stopped responding.
+//
+// Parameters:
+// - x, y (float64): The starting position for rendering the message.
+func (g *Game) drawStalled(x, y float64) {
+	g.cv.BeginPath()
+	g.cv.SetFillStyle(0, 0, 0, 0.6)
+	g.cv.FillRect(g.gameAreaSP.X, g.gameAreaSP.Y, g.param.gameW, g.param.gameH)
+	g.cv.Stroke()
+	g.beginUI(g.param.gameW/2, g.param.gameH/2)
+	defer g.endUI()
+
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#C2185B")
+	g.cv.SetFont(g.fonts.main, 30)
+	g.cv.FillText(g.tr.T("stalled.title"), x, y)
+	g.cv.Stroke()
+
+	g.cv.BeginPath()
+	g.cv.SetFillStyle("#CFD8DC")
+	g.cv.SetFont(g.fonts.small, 15)
+	g.cv.FillText(g.tr.T("stalled.hint"), x+10, y+40)
+	g.cv.Stroke()
+}
@@ -0,0 +1,53 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"math"
+)
+
+const (
+	gustAlpha     = 0.5 // the opacity of the arrow announcing a gust of wind, at the peak of its pulse
+	gustPulseFreq = 2.0 // pulses of the arrow per second; steady with the no flashing setting
+)
+
+// drawGust announces the next gust of wind with a large translucent arrow in the middle of the game area, pointing
+// where the wind will push the snake, and the number of ticks left until it blows. The arrow pulses gently,
+// unless the animations or the flashing effects are turned off.
+func (g *Game) drawGust() {
+	gust, ok := g.eng.NextGust()
+	if !ok {
+		return
+	}
+	d := gust.Dir.Exec(Point{})
+	cx := g.gameAreaSP.X + g.param.gameW/2
+	cy := g.gameAreaSP.Y + g.param.gameH/2
+	size := math.Min(g.param.gameW, g.param.gameH) * 0.2
+	alpha := gustAlpha
+	if !g.cfg.ReducedMotion {
+		alpha *= 0.4 + 0.6*g.flash(gustPulseFreq, g.renderClock)
+	}
+
+	g.cv.SetGlobalAlpha(alpha)
+	g.cv.SetFillStyle("#ECEFF1")
+	g.cv.SetStrokeStyle("#ECEFF1")
+	g.cv.SetLineWidth(size / 5)
+	g.cv.BeginPath()
+	g.cv.MoveTo(cx-d.X*size, cy-d.Y*size)
+	g.cv.LineTo(cx+d.X*size*0.4, cy+d.Y*size*0.4)
+	g.cv.Stroke()
+	// the arrowhead: its tip and the two corners of its base, across the direction of the wind
+	g.cv.BeginPath()
+	g.cv.MoveTo(cx+d.X*size, cy+d.Y*size)
+	g.cv.LineTo(cx+d.X*size*0.3-d.Y*size*0.45, cy+d.Y*size*0.3-d.X*size*0.45)
+	g.cv.LineTo(cx+d.X*size*0.3+d.Y*size*0.45, cy+d.Y*size*0.3+d.X*size*0.45)
+	g.cv.ClosePath()
+	g.cv.Fill()
+	g.cv.SetGlobalAlpha(1)
+
+	g.beginUI(cx, cy+size+30)
+	defer g.endUI()
+	g.cv.SetFont(g.fonts.middle, 18)
+	g.cv.SetFillStyle("#ECEFF1")
+	text := g.tr.T("hud.gust", gust.Tick-g.eng.Tick)
+	g.cv.FillText(text, cx-g.cv.MeasureText(text).Width/2, cy+size+30)
+}
@@ -0,0 +1,144 @@
+// Package game contains the core functionality for the Snake game, including game logic, rendering, geometry handling, and snake behavior.
+package game
+
+import (
+	"log"
+
+	"github.com/DenisKhanov/Snake/config"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// setFullscreen switches the game window between the windowed and the fullscreen mode.
+//
+// The fullscreen mode uses the desktop resolution; the layout is recalculated by the resize
+// handler, and the game area is letterboxed so the cells stay square.
+// The chosen mode is remembered in the configuration file.
+//
+// Parameters:
+// - fullscreen (bool): True to switch to fullscreen mode, false to switch to windowed mode.
+func (g *Game) setFullscreen(fullscreen bool) {
+	var flags uint32
+	if fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+		g.rememberWindowGeometry()
+	}
+	if err := g.wnd.Window.SetFullscreen(flags); err != nil {
+		log.Println("error switching fullscreen mode:", err)
+		return
+	}
+	g.cfg.Fullscreen = fullscreen
+	g.saveConfig()
+}
+
+// toggleFullscreen switches the game window to the mode opposite to the current one.
+func (g *Game) toggleFullscreen() {
+	g.setFullscreen(g.wnd.Window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != sdl.WINDOW_FULLSCREEN_DESKTOP)
+}
+
+// saveConfig writes the current configuration to the configuration file.
+//
+// Errors are only logged, since failing to remember the settings must not interrupt the game.
+func (g *Game) saveConfig() {
+	if g.dataDir == "" {
+		return
+	}
+	if err := g.cfg.Save(config.Path(g.dataDir)); err != nil {
+		log.Println(err)
+	}
+}
+
+// loadWindowGeometry reads the window geometry saved in the previous session.
+//
+// Parameters:
+// - dataDir (string): The data directory; the geometry is stored alongside the configuration file.
+//
+// Returns:
+// - *config.Window: The saved geometry, or nil if there is none or it cannot be read.
+func loadWindowGeometry(dataDir string) *config.Window {
+	if dataDir == "" {
+		return nil
+	}
+	geom, err := config.LoadWindow(config.WindowPath(dataDir))
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return geom
+}
+
+// placeWindow moves the window to its initial position.
+//
+// The monitor passed with the -display flag has the highest priority: the window is centered on it.
+// Otherwise the position saved in the previous session is used, provided the window's top-left corner
+// is visible on one of the connected displays, so the window can't get lost after a monitor has been
+// disconnected. If there is no usable saved position, the window is centered on the monitor from the configuration.
+//
+// Parameters:
+// - geom (*config.Window): The saved geometry, or nil if there is none.
+// - display (int): The index of the monitor from the command line; a negative value means "not set".
+func (g *Game) placeWindow(geom *config.Window, display int) {
+	displays, err := sdl.GetNumVideoDisplays()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if display < 0 && geom != nil {
+		for i := 0; i < displays; i++ {
+			bounds, err := sdl.GetDisplayBounds(i)
+			if err != nil {
+				continue
+			}
+			if int32(geom.X) >= bounds.X && int32(geom.X) < bounds.X+bounds.W &&
+				int32(geom.Y) >= bounds.Y && int32(geom.Y) < bounds.Y+bounds.H {
+				g.wnd.Window.SetPosition(int32(geom.X), int32(geom.Y))
+				return
+			}
+		}
+	}
+	if display < 0 {
+		display = g.cfg.Display
+	}
+	if display >= displays {
+		log.Printf("display %d not found, using the primary display", display)
+		display = 0
+	}
+	g.centerOnDisplay(display)
+}
+
+// centerOnDisplay moves the window to the center of the given monitor.
+//
+// Parameters:
+// - display (int): The index of the monitor.
+func (g *Game) centerOnDisplay(display int) {
+	bounds, err := sdl.GetDisplayBounds(display)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	w, h := g.wnd.Window.GetSize()
+	g.wnd.Window.SetPosition(bounds.X+(bounds.W-w)/2, bounds.Y+(bounds.H-h)/2)
+}
+
+// rememberWindowGeometry stores the current size and position of the window,
+// unless the window is in fullscreen mode, so the windowed geometry survives a fullscreen session.
+func (g *Game) rememberWindowGeometry() {
+	if g.wnd.Window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != 0 {
+		return
+	}
+	x, y := g.wnd.Window.GetPosition()
+	w, h := g.wnd.Window.GetSize()
+	g.geometry = config.Window{X: int(x), Y: int(y), Width: int(w), Height: int(h)}
+}
+
+// saveWindowGeometry writes the windowed geometry and the configuration (including the fullscreen state)
+// to disk. It's called when the game exits.
+func (g *Game) saveWindowGeometry() {
+	if g.dataDir == "" {
+		return
+	}
+	g.rememberWindowGeometry()
+	if err := g.geometry.Save(config.WindowPath(g.dataDir)); err != nil {
+		log.Println(err)
+	}
+	g.saveConfig()
+}
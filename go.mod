@@ -1,12 +1,18 @@
 module github.com/DenisKhanov/Snake
 
-go 1.22
+go 1.24.0
 
-require github.com/tfriedel6/canvas v0.12.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/tfriedel6/canvas v0.12.1
+	github.com/veandco/go-sdl2 v0.4.40
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.48.0
+)
 
 require (
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	github.com/veandco/go-sdl2 v0.4.40 // indirect
 	golang.org/x/image v0.22.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
 )
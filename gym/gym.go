@@ -0,0 +1,302 @@
+// Package gym wraps the headless engine into a reinforcement learning environment in the style of Gym:
+// Reset starts a game and returns the first observation, and Step plays an action and returns the next observation,
+// the reward and whether the episode is over.
+//
+// The observations are flat float32 vectors, ready to be fed to a neural network, in one of two encodings:
+// a grid tensor of the whole board (Grid) or a short vector of hand-picked features (Features). The actions
+// are the four directions. Every environment owns its engine, so any number of them can run in parallel.
+package gym
+
+import (
+	"math/rand"
+	"slices"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Encoding selects how the state of the game is turned into an observation.
+type Encoding int
+
+const (
+	// Grid encodes the whole board as GridChannels planes of cells×cells values, in the order
+	// [channel][y][x]: the body of the snake, its head and the food, each cell 1 where they are and 0 elsewhere,
+	// and a plane where every cell of the body holds the number of ticks until the tail leaves it,
+	// divided by the number of cells of the board.
+	Grid Encoding = iota
+	// Features encodes FeatureCount values, relative to the head of the snake: whether moving straight, left
+	// or right hits a wall or the body; the direction the snake moves in, one-hot; whether the food is up, right,
+	// down or left of the head; the distances to the food along x and y, divided by the size of the board;
+	// and the length of the snake divided by the number of cells of the board.
+	Features
+)
+
+const (
+	GridChannels = 4  // the number of planes of the Grid encoding
+	FeatureCount = 14 // the number of values of the Features encoding
+)
+
+// The actions: the directions of the snake on the screen, where y grows downwards.
+const (
+	ActionUp    = 0
+	ActionRight = 1
+	ActionDown  = 2
+	ActionLeft  = 3
+
+	ActionCount = 4 // the number of actions
+)
+
+// actionDirs are the engine directions of the actions; the engine's y axis grows in its Up direction,
+// which is down on the screen.
+var actionDirs = [ActionCount]engine.Dir{engine.Down, engine.Right, engine.Up, engine.Left}
+
+// ActionOf returns the action turning the snake in an engine direction, e.g. to record the moves of a player.
+//
+// Parameters:
+// - dir (engine.Dir): The direction.
+//
+// Returns:
+// - int: The action.
+func ActionOf(dir engine.Dir) int {
+	for action, d := range actionDirs {
+		if d == dir {
+			return action
+		}
+	}
+	return ActionRight
+}
+
+// Rewards are the rewards of the events of a game.
+// Fields:
+// - Food: the reward for eating the food.
+// - Death: the reward for hitting a wall, which ends the episode.
+// - Cut: the reward for biting the body, which shortens the snake.
+// - Tick: the reward for every tick, e.g. a small negative one to hurry the snake up.
+type Rewards struct {
+	Food  float64
+	Death float64
+	Cut   float64
+	Tick  float64
+}
+
+// DefaultRewards are the rewards used when Options.Rewards is nil.
+var DefaultRewards = Rewards{Food: 1, Death: -1, Cut: -0.5}
+
+// Options configures an environment.
+// Fields:
+// - Cells: the number of cells along each side of the board; 0 for engine.Cells.
+// - Seed: the seed of the first episode, the next episodes use the following seeds; 0 for random seeds.
+// - Encoding: the encoding of the observations.
+// - MaxTicks: the largest number of ticks of an episode, after which it's truncated; 0 for no limit.
+// - Rewards: the rewards; nil for DefaultRewards.
+type Options struct {
+	Cells    int
+	Seed     int64
+	Encoding Encoding
+	MaxTicks int
+	Rewards  *Rewards
+}
+
+// Env is a reinforcement learning environment playing headless games. It isn't safe for concurrent use.
+// Fields:
+// - opts: the options of the environment.
+// - rewards: the rewards of the events.
+// - eng: the engine of the current episode; nil before the first Reset.
+// - episodes: the number of episodes started.
+// - truncated: whether the last episode has been cut short by MaxTicks.
+type Env struct {
+	opts      Options
+	rewards   Rewards
+	eng       *engine.Engine
+	episodes  int
+	truncated bool
+}
+
+// New creates an environment. Call Reset to start the first episode.
+//
+// Parameters:
+// - opts (Options): The options of the environment.
+//
+// Returns:
+// - *Env: The environment.
+func New(opts Options) *Env {
+	if opts.Cells == 0 {
+		opts.Cells = engine.Cells
+	}
+	opts.Cells = max(opts.Cells, engine.MinCells)
+	env := &Env{opts: opts, rewards: DefaultRewards}
+	if opts.Rewards != nil {
+		env.rewards = *opts.Rewards
+	}
+	return env
+}
+
+// ObservationSize returns the number of values of an observation.
+func (env *Env) ObservationSize() int {
+	if env.opts.Encoding == Features {
+		return FeatureCount
+	}
+	return GridChannels * env.opts.Cells * env.opts.Cells
+}
+
+// Reset starts a new episode.
+//
+// Returns:
+// - []float32: The first observation of the episode.
+func (env *Env) Reset() []float32 {
+	seed := rand.Int63()
+	if env.opts.Seed != 0 {
+		seed = env.opts.Seed + int64(env.episodes)
+	}
+	env.episodes++
+	env.eng = engine.NewSized(seed, env.opts.Cells)
+	env.truncated = false
+	return env.observe()
+}
+
+// Step plays an action: the snake turns in its direction, unless it's the opposite of the current one,
+// and the game advances by a tick. Stepping an episode that is over starts a new one, as Reset does.
+//
+// Parameters:
+// - action (int): The action, one of the Action constants; other values keep the direction.
+//
+// Returns:
+// - []float32: The observation after the tick.
+// - float64: The reward for the tick.
+// - bool: Whether the episode is over: the snake has died or the episode has been truncated (see Truncated).
+func (env *Env) Step(action int) ([]float32, float64, bool) {
+	if env.eng == nil || env.eng.GameOver || env.truncated {
+		return env.Reset(), 0, false
+	}
+	if action >= 0 && action < ActionCount {
+		env.eng.Turn(actionDirs[action])
+	}
+	res := env.eng.Step()
+	reward := env.rewards.Tick
+	switch {
+	case res.Died:
+		reward += env.rewards.Death
+	case res.Ate:
+		reward += env.rewards.Food
+	}
+	if res.Cut {
+		reward += env.rewards.Cut
+	}
+	env.truncated = !env.eng.GameOver && env.opts.MaxTicks > 0 && env.eng.Tick >= env.opts.MaxTicks
+	return env.observe(), reward, env.eng.GameOver || env.truncated
+}
+
+// Truncated reports whether the last episode has ended because it reached MaxTicks rather than by the death
+// of the snake, which matters for bootstrapping the value of its last state.
+func (env *Env) Truncated() bool {
+	return env.truncated
+}
+
+// State returns the state of the game of the current episode, e.g. for the score or for rendering it.
+func (env *Env) State() engine.State {
+	if env.eng == nil {
+		return engine.State{}
+	}
+	return env.eng.Snapshot()
+}
+
+// observe encodes the state of the game of the current episode.
+func (env *Env) observe() []float32 {
+	return Observe(env.eng.Snapshot(), env.opts.Encoding)
+}
+
+// Observe encodes the state of a game as an observation, e.g. for a player trained with an environment
+// to play a game of the engine.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+// - enc (Encoding): The encoding.
+//
+// Returns:
+// - []float32: The observation.
+func Observe(s engine.State, enc Encoding) []float32 {
+	if enc == Features {
+		return features(s)
+	}
+	return grid(s)
+}
+
+// grid returns the observation of the Grid encoding.
+func grid(s engine.State) []float32 {
+	cells := s.Cells
+	plane := cells * cells
+	obs := make([]float32, GridChannels*plane)
+	set := func(channel int, p engine.Point, v float32) {
+		x, y := int(p.X), int(p.Y)
+		if x >= 0 && x < cells && y >= 0 && y < cells {
+			obs[channel*plane+y*cells+x] = v
+		}
+	}
+	for i, p := range s.Snake {
+		set(0, p, 1)
+		set(3, p, float32(len(s.Snake)-i)/float32(plane))
+	}
+	if len(s.Snake) > 0 {
+		set(1, s.Snake[0], 1)
+	}
+	set(2, s.Food, 1)
+	return obs
+}
+
+// features returns the observation of the Features encoding.
+func features(s engine.State) []float32 {
+	obs := make([]float32, 0, FeatureCount)
+	var head engine.Point
+	if len(s.Snake) > 0 {
+		head = s.Snake[0]
+	}
+	dir := s.Direction
+	cells := s.Cells
+	//the dangers straight ahead, to the left and to the right of the snake, as the player sees them
+	for _, d := range []engine.Dir{dir, TurnLeft(dir), TurnRight(dir)} {
+		next := d.Exec(head)
+		obs = append(obs, flag(engine.CollidesWithWall(next, cells) || slices.Contains(s.Snake, next)))
+	}
+	for _, d := range actionDirs {
+		obs = append(obs, flag(dir == d))
+	}
+	dx, dy := s.Food.X-head.X, s.Food.Y-head.Y
+	obs = append(obs, flag(dy < 0), flag(dx > 0), flag(dy > 0), flag(dx < 0))
+	obs = append(obs, float32(dx/float64(cells)), float32(dy/float64(cells)))
+	return append(obs, float32(len(s.Snake))/float32(cells*cells))
+}
+
+// TurnLeft returns the direction to the left of the given one, as seen on the screen.
+func TurnLeft(d engine.Dir) engine.Dir {
+	switch d {
+	case engine.Down:
+		return engine.Left
+	case engine.Left:
+		return engine.Up
+	case engine.Up:
+		return engine.Right
+	default:
+		return engine.Down
+	}
+}
+
+// TurnRight returns the direction to the right of the given one, as seen on the screen.
+func TurnRight(d engine.Dir) engine.Dir {
+	switch d {
+	case engine.Down:
+		return engine.Right
+	case engine.Right:
+		return engine.Up
+	case engine.Up:
+		return engine.Left
+	default:
+		return engine.Down
+	}
+}
+
+// flag encodes a bool as 1 or 0.
+func flag(b bool) float32 {
+	if b {
+		return 1
+	}
+	return 0
+}
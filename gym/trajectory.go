@@ -0,0 +1,263 @@
+// Package gym wraps the headless engine into a reinforcement learning environment in the style of Gym:
+// Reset starts a game and returns the first observation, and Step plays an action and returns the next observation,
+// the reward and whether the episode is over.
+//
+// The observations are flat float32 vectors, ready to be fed to a neural network, in one of two encodings:
+// a grid tensor of the whole board (Grid) or a short vector of hand-picked features (Features). The actions
+// are the four directions. Every environment owns its engine, so any number of them can run in parallel.
+package gym
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format is a file format trajectories can be written in.
+type Format string
+
+const (
+	CSV   Format = "csv"   // a row per step, for spreadsheets and pandas
+	JSONL Format = "jsonl" // a JSON object per line and step, for scripts
+	NPZ   Format = "npz"   // the arrays of NumPy, loaded with numpy.load
+)
+
+// FormatOf returns the trajectory format matching the extension of the file name.
+//
+// Parameters:
+// - path (string): The name of the file.
+//
+// Returns:
+// - Format: The format.
+// - error: An error if the extension isn't one of the supported formats.
+func FormatOf(path string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))); f {
+	case CSV, JSONL, NPZ:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported trajectory format %q, use .npz, .csv or .jsonl", filepath.Ext(path))
+	}
+}
+
+// Transition is a step of an episode: the observation the action was chosen from, the action, and what it led to.
+// Fields:
+// - Episode: the number of the episode, from 0.
+// - Tick: the number of the step in the episode, from 0.
+// - Obs: the observation before the action.
+// - Action: the action.
+// - Reward: the reward for the action.
+// - Done: whether the action has ended the episode.
+type Transition struct {
+	Episode int       `json:"episode"`
+	Tick    int       `json:"tick"`
+	Obs     []float32 `json:"obs"`
+	Action  int       `json:"action"`
+	Reward  float64   `json:"reward"`
+	Done    bool      `json:"done"`
+}
+
+// TrajectoryWriter writes the transitions of episodes to a file, e.g. for offline training.
+type TrajectoryWriter interface {
+	// Write adds a transition.
+	Write(t Transition) error
+	// Close finishes the file; the NPZ format writes all of its arrays only then.
+	Close() error
+}
+
+// CreateTrajectories creates a file for the transitions, in the format of its extension.
+//
+// Parameters:
+// - path (string): The name of the file; the extension picks the format.
+//
+// Returns:
+// - TrajectoryWriter: The writer; it must be closed.
+// - error: An error if the format isn't supported or the file cannot be created.
+func CreateTrajectories(path string) (TrajectoryWriter, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating trajectory file: %w", err)
+	}
+	out := &fileWriter{f: f, buf: bufio.NewWriter(f)}
+	switch format {
+	case CSV:
+		return &csvWriter{fileWriter: out, w: csv.NewWriter(out.buf)}, nil
+	case JSONL:
+		return &jsonlWriter{fileWriter: out, enc: json.NewEncoder(out.buf)}, nil
+	default:
+		return &npzWriter{fileWriter: out}, nil
+	}
+}
+
+// fileWriter is the buffered file the writers of all formats write to.
+type fileWriter struct {
+	f   *os.File
+	buf *bufio.Writer
+}
+
+// close flushes the buffer and closes the file.
+func (w *fileWriter) close() error {
+	err := w.buf.Flush()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing trajectory file: %w", err)
+	}
+	return nil
+}
+
+// csvWriter writes a row per transition: the episode, the tick, the action, the reward, whether the episode
+// is done, and the values of the observation in the obs_0, obs_1... columns.
+type csvWriter struct {
+	*fileWriter
+	w      *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(t Transition) error {
+	if !w.header {
+		w.header = true
+		header := []string{"episode", "tick", "action", "reward", "done"}
+		for i := range t.Obs {
+			header = append(header, "obs_"+strconv.Itoa(i))
+		}
+		if err := w.w.Write(header); err != nil {
+			return fmt.Errorf("error writing trajectory file: %w", err)
+		}
+	}
+	row := []string{strconv.Itoa(t.Episode), strconv.Itoa(t.Tick), strconv.Itoa(t.Action),
+		strconv.FormatFloat(t.Reward, 'g', -1, 64), strconv.FormatBool(t.Done)}
+	for _, v := range t.Obs {
+		row = append(row, strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	if err := w.w.Write(row); err != nil {
+		return fmt.Errorf("error writing trajectory file: %w", err)
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		w.close()
+		return fmt.Errorf("error writing trajectory file: %w", err)
+	}
+	return w.close()
+}
+
+// jsonlWriter writes a JSON object per transition and line.
+type jsonlWriter struct {
+	*fileWriter
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(t Transition) error {
+	if err := w.enc.Encode(t); err != nil {
+		return fmt.Errorf("error writing trajectory file: %w", err)
+	}
+	return nil
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.close()
+}
+
+// npzWriter collects the transitions and writes them on Close as the arrays of an NPZ archive: obs (float32,
+// steps×observation size), actions (int64), rewards (float32), dones (bool), episodes (int32) and ticks (int32).
+// The archive needs the number of steps before the arrays, so they're kept in memory until then.
+type npzWriter struct {
+	*fileWriter
+	obs      []float32
+	obsSize  int
+	actions  []int64
+	rewards  []float32
+	dones    []bool
+	episodes []int32
+	ticks    []int32
+}
+
+func (w *npzWriter) Write(t Transition) error {
+	if len(w.actions) == 0 {
+		w.obsSize = len(t.Obs)
+	}
+	if len(t.Obs) != w.obsSize {
+		return fmt.Errorf("observation of %d values in a trajectory of %d", len(t.Obs), w.obsSize)
+	}
+	w.obs = append(w.obs, t.Obs...)
+	w.actions = append(w.actions, int64(t.Action))
+	w.rewards = append(w.rewards, float32(t.Reward))
+	w.dones = append(w.dones, t.Done)
+	w.episodes = append(w.episodes, int32(t.Episode))
+	w.ticks = append(w.ticks, int32(t.Tick))
+	return nil
+}
+
+func (w *npzWriter) Close() error {
+	z := zip.NewWriter(w.buf)
+	n := len(w.actions)
+	arrays := []struct {
+		name  string
+		descr string
+		shape string
+		data  any
+	}{
+		{"obs", "<f4", fmt.Sprintf("(%d, %d)", n, w.obsSize), w.obs},
+		{"actions", "<i8", fmt.Sprintf("(%d,)", n), w.actions},
+		{"rewards", "<f4", fmt.Sprintf("(%d,)", n), w.rewards},
+		{"dones", "|b1", fmt.Sprintf("(%d,)", n), w.dones},
+		{"episodes", "<i4", fmt.Sprintf("(%d,)", n), w.episodes},
+		{"ticks", "<i4", fmt.Sprintf("(%d,)", n), w.ticks},
+	}
+	for _, a := range arrays {
+		f, err := z.Create(a.name + ".npy")
+		if err != nil {
+			w.close()
+			return fmt.Errorf("error writing trajectory file: %w", err)
+		}
+		if err = writeNPY(f, a.descr, a.shape, a.data); err != nil {
+			w.close()
+			return fmt.Errorf("error writing trajectory file: %w", err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		w.close()
+		return fmt.Errorf("error writing trajectory file: %w", err)
+	}
+	return w.close()
+}
+
+// writeNPY writes an array in the .npy format of NumPy, version 1.0: the magic string, the length of the header,
+// the header describing the type and the shape, padded to 64 bytes, and the data in little-endian order.
+//
+// Parameters:
+// - w (io.Writer): The destination.
+// - descr (string): The NumPy type of the values, e.g. "<f4".
+// - shape (string): The shape as a Python tuple, e.g. "(10, 4)".
+// - data (any): The values, a slice of a fixed-size type; bools are written as a byte each.
+//
+// Returns:
+// - error: An error if writing fails; otherwise, nil.
+func writeNPY(w io.Writer, descr, shape string, data any) error {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': %s, }", descr, shape)
+	//the magic string, the version and the length take 10 bytes, and the header ends with a newline
+	pad := 64 - (10+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+	prefix := append([]byte("\x93NUMPY\x01\x00"), 0, 0)
+	binary.LittleEndian.PutUint16(prefix[8:], uint16(len(header)))
+	if _, err := w.Write(append(prefix, header...)); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, data)
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+
+package i18n
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Detect returns the locale of the user interface of the operating system, e.g. "ru_RU",
+// or an empty string if it cannot be determined.
+//
+// Applications started from Finder don't get the LANG variable, so the locale is read
+// from the global user defaults when the variable isn't set.
+func Detect() string {
+	if v := os.Getenv("LANG"); v != "" && v != "C" {
+		return v
+	}
+	out, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !darwin
+
+package i18n
+
+import "os"
+
+// Detect returns the locale of the user interface of the operating system, e.g. "ru_RU.UTF-8",
+// or an empty string if it cannot be determined.
+func Detect() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package i18n
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// localeNameMaxLength is the maximum length of a locale name, including the terminating null character.
+const localeNameMaxLength = 85
+
+// Detect returns the locale of the user interface of the operating system, e.g. "ru-RU",
+// or an empty string if it cannot be determined.
+func Detect() string {
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultLocaleName")
+	buf := make([]uint16, localeNameMaxLength)
+	if ret, _, _ := proc.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,151 @@
+// Package i18n contains the message catalogs with the translations of all on-screen text of the Snake game.
+//
+// Translations are data, not code: every locale is a JSON file in the locales directory mapping
+// message keys to strings, which may contain fmt verbs for the values inserted into them.
+// The catalogs are embedded into the executable.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Fallback is the locale used for the messages missing in other locales.
+const Fallback = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog is the set of messages of a single locale.
+// Fields:
+// - lang: the locale of the catalog, e.g. "en".
+// - messages: the translated messages by key.
+// - fallback: the catalog used for the messages missing in this one; nil for the fallback locale.
+type Catalog struct {
+	lang     string
+	messages map[string]string
+	fallback *Catalog
+}
+
+// Load returns the catalog of the given locale.
+//
+// Unknown locales, and the messages missing in a locale, fall back to English.
+// Region suffixes are ignored, so "ru_RU" and "ru-RU" load the "ru" catalog.
+//
+// Parameters:
+// - lang (string): The locale, e.g. "en" or "ru_RU".
+//
+// Returns:
+// - *Catalog: The message catalog.
+// - error: An error if the catalog exists but cannot be decoded; the fallback catalog is returned then.
+func Load(lang string) (*Catalog, error) {
+	fallback, err := load(Fallback, nil)
+	if err != nil {
+		// the fallback catalog is embedded, so this only happens if it's broken at build time;
+		// the keys are shown instead of the messages then
+		return &Catalog{lang: Fallback}, err
+	}
+	lang = Normalize(lang)
+	if lang == Fallback || !slices.Contains(Available(), lang) {
+		return fallback, nil
+	}
+	c, err := load(lang, fallback)
+	if err != nil {
+		return fallback, err
+	}
+	return c, nil
+}
+
+// load decodes the catalog of the locale.
+func load(lang string, fallback *Catalog) (*Catalog, error) {
+	data, err := locales.ReadFile(path.Join("locales", lang+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading locale %s: %w", lang, err)
+	}
+	c := &Catalog{lang: lang, fallback: fallback}
+	if err = json.Unmarshal(data, &c.messages); err != nil {
+		return nil, fmt.Errorf("error decoding locale %s: %w", lang, err)
+	}
+	return c, nil
+}
+
+// Available returns the locales with a catalog, sorted.
+func Available() []string {
+	entries, _ := locales.ReadDir("locales")
+	langs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// Choose returns the available locale matching the given locale name, or the fallback locale.
+//
+// Parameters:
+// - lang (string): The locale name, e.g. the one returned by Detect.
+//
+// Returns:
+// - string: The language code of an available locale.
+func Choose(lang string) string {
+	lang = Normalize(lang)
+	if slices.Contains(Available(), lang) {
+		return lang
+	}
+	return Fallback
+}
+
+// Normalize reduces a locale name such as "ru_RU.UTF-8" or "en-US" to its language code.
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Lang returns the locale of the catalog.
+func (c *Catalog) Lang() string {
+	return c.lang
+}
+
+// T returns the translated message with the given key, formatted with the arguments if there are any.
+// If the message is missing in the catalog and in the fallback catalog, the key itself is returned,
+// so a missing translation is easy to spot on the screen.
+//
+// Parameters:
+// - key (string): The key of the message.
+// - args (...any): The values inserted into the message.
+//
+// Returns:
+// - string: The translated message.
+func (c *Catalog) T(key string, args ...any) string {
+	msg, ok := c.messages[key]
+	if !ok {
+		if c.fallback != nil {
+			return c.fallback.T(key, args...)
+		}
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Text returns all messages of the catalog and of its fallback catalog joined together.
+// It's used to find the fonts that can draw the language.
+func (c *Catalog) Text() string {
+	var b strings.Builder
+	for _, msg := range c.messages {
+		b.WriteString(msg)
+	}
+	if c.fallback != nil {
+		b.WriteString(c.fallback.Text())
+	}
+	return b.String()
+}
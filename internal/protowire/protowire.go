@@ -0,0 +1,149 @@
+// Package protowire encodes and decodes the low-level protobuf wire format: varints, tags and fields.
+//
+// The messages of the project are few and small, so they are encoded by hand on top of it rather than generated:
+// the wire package encodes the state of a game with it, and the botapi package the messages of the bot service.
+package protowire
+
+import "errors"
+
+// The wire types of protobuf.
+const (
+	Varint  = 0
+	Fixed64 = 1
+	Bytes   = 2
+	Fixed32 = 5
+)
+
+// ErrMalformed is returned when the data isn't a valid protobuf message.
+var ErrMalformed = errors.New("malformed protobuf message")
+
+// AppendVarint appends the value as a protobuf varint.
+func AppendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// AppendVarintField appends a varint field; zero values are omitted, as proto3 does.
+func AppendVarintField(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = AppendVarint(b, uint64(num)<<3|Varint)
+	return AppendVarint(b, v)
+}
+
+// AppendBoolField appends a bool field; false is omitted, as proto3 does.
+func AppendBoolField(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return AppendVarintField(b, num, 1)
+}
+
+// AppendBytesField appends a length-delimited field: an embedded message or a string.
+func AppendBytesField(b []byte, num int, v []byte) []byte {
+	b = AppendVarint(b, uint64(num)<<3|Bytes)
+	b = AppendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// ConsumeVarint decodes a varint from the start of b.
+//
+// Returns:
+// - uint64: The value.
+// - int: The number of bytes read, or -1 if b doesn't start with a valid varint.
+func ConsumeVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, -1
+}
+
+// ConsumeTag decodes the tag of a field from the start of b.
+//
+// Returns:
+// - int: The number of the field.
+// - int: The wire type of the field.
+// - int: The number of bytes read, or -1 if b doesn't start with a valid tag.
+func ConsumeTag(b []byte) (int, int, int) {
+	v, n := ConsumeVarint(b)
+	if n < 0 || v>>3 == 0 {
+		return 0, 0, -1
+	}
+	return int(v >> 3), int(v & 7), n
+}
+
+// SkipField returns the length of the value of a field of the wire type at the start of b, or -1 if it's invalid.
+func SkipField(b []byte, typ int) int {
+	switch typ {
+	case Varint:
+		_, n := ConsumeVarint(b)
+		return n
+	case Fixed64:
+		if len(b) < 8 {
+			return -1
+		}
+		return 8
+	case Fixed32:
+		if len(b) < 4 {
+			return -1
+		}
+		return 4
+	case Bytes:
+		l, n := ConsumeVarint(b)
+		if n < 0 || l > uint64(len(b)-n) {
+			return -1
+		}
+		return n + int(l)
+	default:
+		return -1
+	}
+}
+
+// EachField calls fn with every field of the message, in order. A varint field passes its value, a length-delimited
+// one its data; the fixed-size fields, which the messages of the project don't have, are skipped.
+//
+// Parameters:
+// - b ([]byte): The message.
+// - fn (func(num int, v uint64, data []byte) error): Called with every field; an error it returns stops the decoding.
+//
+// Returns:
+// - error: ErrMalformed if the message can't be decoded, or the error of fn.
+func EachField(b []byte, fn func(num int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := ConsumeTag(b)
+		if n < 0 {
+			return ErrMalformed
+		}
+		b = b[n:]
+		if n = SkipField(b, typ); n < 0 {
+			return ErrMalformed
+		}
+		var (
+			v    uint64
+			data []byte
+		)
+		switch typ {
+		case Varint:
+			v, _ = ConsumeVarint(b)
+		case Bytes:
+			_, m := ConsumeVarint(b)
+			data = b[m:n]
+		default:
+			b = b[n:]
+			continue
+		}
+		b = b[n:]
+		if err := fn(num, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
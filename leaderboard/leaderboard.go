@@ -0,0 +1,299 @@
+// Package leaderboard submits the scores of finished games to an online leaderboard.
+//
+// The leaderboard is opt-in: nothing is sent unless the player configures the URL of a leaderboard server.
+// Entries are POSTed as JSON over HTTPS. Entries that can't be sent, e.g. while the computer is offline,
+// are queued in the `leaderboard_queue.json` file of the data directory and sent again with the next submission
+// or on the next launch. Every entry has a random ID, so a server can ignore an entry sent twice.
+//
+// The package also has a small server compatible with the client, so communities can host their own leaderboards.
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	queueFile = "leaderboard_queue.json" // the name of the file with the queued entries in the data directory
+	queueMax  = 100                      // the largest number of queued entries; the oldest ones are dropped
+	timeout   = 10 * time.Second         // how long a single submission may take
+)
+
+// ErrInsecure is returned for leaderboard URLs that don't use HTTPS.
+var ErrInsecure = errors.New("the leaderboard URL must use https")
+
+// Entry is the score of a finished game submitted to the leaderboard.
+// Fields:
+// - ID: a random identifier of the entry, which lets the server ignore an entry sent twice.
+// - Name: the name of the player.
+// - Score: the final score.
+// - Cells: the side of the board in cells.
+// - Assisted: whether the beginner assist was used in the game.
+// - Seed: the seed of the game.
+// - ReplayHash: the SHA-256 hash of the game's replay, in hex; empty if the game wasn't recorded.
+// - Replay: the encoded replay of the game, which lets the server re-simulate the game and verify the score;
+// servers don't store it.
+// - GameVersion: the version of the game the score was achieved with.
+// - EndedAt: the time the game ended.
+type Entry struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Score       int       `json:"score"`
+	Cells       int       `json:"cells"`
+	Assisted    bool      `json:"assisted"`
+	Seed        int64     `json:"seed"`
+	ReplayHash  string    `json:"replay_hash,omitempty"`
+	Replay      []byte    `json:"replay,omitempty"`
+	GameVersion string    `json:"game_version"`
+	EndedAt     time.Time `json:"ended_at"`
+}
+
+// Result is the answer of the leaderboard server to a submitted entry.
+// Fields:
+// - Rank: the place of the entry among all entries of its mode, starting from 1.
+// - Total: the number of entries of the mode.
+type Result struct {
+	Rank  int `json:"rank"`
+	Total int `json:"total"`
+}
+
+// NewID returns a new random entry ID.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// HashReplay returns the hash of an encoded replay for Entry.ReplayHash.
+//
+// Parameters:
+// - data ([]byte): The encoded replay; nil if the game wasn't recorded.
+//
+// Returns:
+// - string: The SHA-256 hash in hex, or an empty string for no replay.
+func HashReplay(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Client submits entries to a leaderboard server. It's safe for concurrent use: the submissions are serialized,
+// so a queued entry is never sent twice at the same time.
+// Fields:
+// - url: the address the entries are POSTed to.
+// - profileURL: the address the profile is synced with, "profile" next to url.
+// - token: the token the entries are signed with; zero if the server doesn't require one.
+// - dataDir: the data directory with the queue of unsent entries.
+// - mu: serializes the submissions and the access to the queue.
+type Client struct {
+	url        string
+	profileURL string
+	token      Token
+	dataDir    string
+	mu         sync.Mutex
+}
+
+// New creates a client of the leaderboard server.
+//
+// Parameters:
+// - rawURL (string): The address the entries are POSTed to; it must use HTTPS.
+// - token (string): The token issued by the server in the "client:secret" form, or an empty string
+// if the server doesn't require one.
+// - dataDir (string): The data directory the unsent entries are queued in.
+//
+// Returns:
+// - *Client: The client.
+// - error: An error if the URL is invalid or doesn't use HTTPS, or the token is malformed.
+func New(rawURL, token, dataDir string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing leaderboard URL: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInsecure
+	}
+	c := &Client{url: rawURL, profileURL: u.ResolveReference(&url.URL{Path: "profile"}).String(), dataDir: dataDir}
+	if token != "" {
+		if c.token, err = ParseToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Submit queues the entry and sends all queued entries, the oldest first.
+//
+// Parameters:
+// - ctx (context.Context): The context for the requests.
+// - e (Entry): The entry of the finished game.
+//
+// Returns:
+// - *Result: The answer of the server to the entry; nil if the entry has stayed in the queue.
+// - error: An error if some entries couldn't be sent; they stay in the queue for the next attempt.
+func (c *Client) Submit(ctx context.Context, e Entry) (*Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queue, err := c.readQueue()
+	if err != nil {
+		return nil, err
+	}
+	queue = append(queue, e)
+	results, err := c.send(ctx, queue)
+	return results[e.ID], err
+}
+
+// Flush sends the queued entries, e.g. when the game is launched after an offline session.
+//
+// Parameters:
+// - ctx (context.Context): The context for the requests.
+//
+// Returns:
+// - error: An error if some entries couldn't be sent; they stay in the queue for the next attempt.
+func (c *Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queue, err := c.readQueue()
+	if err != nil || len(queue) == 0 {
+		return err
+	}
+	_, err = c.send(ctx, queue)
+	return err
+}
+
+// send posts the entries in order and saves the ones that haven't been sent back to the queue.
+//
+// Sending stops at the first network failure, as the following entries would fail too, and so it does when
+// the server refuses the token or limits the rate, so the entries are sent again once the token is fixed or
+// the limit has passed. Other entries rejected by the server are dropped, since sending them again wouldn't help.
+//
+// Returns:
+// - map[string]*Result: The answers of the server by entry ID.
+// - error: The first error that occurred, or nil.
+func (c *Client) send(ctx context.Context, queue []Entry) (map[string]*Result, error) {
+	results := make(map[string]*Result)
+	var firstErr error
+	for len(queue) > 0 {
+		res, err := c.post(ctx, queue[0])
+		var rejected *rejectedError
+		if err != nil && !errors.As(err, &rejected) {
+			firstErr = err
+			break
+		}
+		if err != nil {
+			firstErr = err
+		} else {
+			results[queue[0].ID] = res
+		}
+		queue = queue[1:]
+	}
+	if err := c.writeQueue(queue); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return results, firstErr
+}
+
+// rejectedError is returned when the server has refused an entry.
+type rejectedError struct {
+	status string
+}
+
+// Error returns the description of the rejection.
+func (e *rejectedError) Error() string {
+	return "leaderboard entry rejected: " + e.status
+}
+
+// post submits a single entry.
+func (c *Client) post(ctx context.Context, e Entry) (*Result, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding leaderboard entry: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating leaderboard request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token.Client != "" {
+		req.Header.Set(clientHeader, c.token.Client)
+		req.Header.Set(signatureHeader, c.token.Sign(body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error submitting to leaderboard: %w", err)
+	}
+	defer resp.Body.Close()
+	retry := resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && !retry {
+		return nil, &rejectedError{status: resp.Status}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("error submitting to leaderboard: %s", resp.Status)
+	}
+	var res Result
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("error decoding leaderboard answer: %w", err)
+	}
+	return &res, nil
+}
+
+// queuePath returns the location of the queue of unsent entries.
+func (c *Client) queuePath() string {
+	return filepath.Join(c.dataDir, queueFile)
+}
+
+// readQueue reads the unsent entries; a missing queue is empty.
+func (c *Client) readQueue() ([]Entry, error) {
+	data, err := os.ReadFile(c.queuePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading leaderboard queue %s: %w", c.queuePath(), err)
+	}
+	var queue []Entry
+	if err = json.Unmarshal(data, &queue); err != nil {
+		return nil, fmt.Errorf("error parsing leaderboard queue %s: %w", c.queuePath(), err)
+	}
+	return queue, nil
+}
+
+// writeQueue saves the unsent entries, keeping at most queueMax of the newest ones,
+// and removes the queue file when all entries have been sent.
+func (c *Client) writeQueue(queue []Entry) error {
+	path := c.queuePath()
+	if len(queue) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error removing leaderboard queue %s: %w", path, err)
+		}
+		return nil
+	}
+	queue = queue[max(0, len(queue)-queueMax):]
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding leaderboard queue: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing leaderboard queue %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package leaderboard submits the scores of finished games to an online leaderboard.
+//
+// The leaderboard is opt-in: nothing is sent unless the player configures the URL of a leaderboard server.
+// Entries are POSTed as JSON over HTTPS. Entries that can't be sent, e.g. while the computer is offline,
+// are queued in the `leaderboard_queue.json` file of the data directory and sent again with the next submission
+// or on the next launch. Every entry has a random ID, so a server can ignore an entry sent twice.
+//
+// The package also has a small server compatible with the client, so communities can host their own leaderboards.
+package leaderboard
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket per client: every client can make a burst of up to rate requests,
+// and the bucket refills at rate requests per period.
+// Fields:
+// - rate: the size of a bucket and the number of requests it refills per period.
+// - period: the time a bucket takes to refill completely.
+// - mu: guards buckets and swept.
+// - buckets: the buckets by client.
+// - swept: the time the full buckets were last dropped.
+type limiter struct {
+	rate    int
+	period  time.Duration
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// bucket is the state of a client's token bucket.
+// Fields:
+// - tokens: the requests the client can still make.
+// - at: the time tokens was last updated.
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// newLimiter creates a limiter allowing rate requests per period to every client.
+func newLimiter(rate int, period time.Duration) *limiter {
+	return &limiter{rate: rate, period: period, buckets: make(map[string]*bucket)}
+}
+
+// allow reports whether the client can make a request now, and counts the request if it can.
+//
+// Parameters:
+// - client (string): The client making the request.
+//
+// Returns:
+// - bool: Whether the request is allowed.
+func (l *limiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.swept) >= l.period {
+		l.forget(now)
+		l.swept = now
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate), at: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(float64(l.rate), b.tokens+float64(l.rate)*float64(now.Sub(b.at))/float64(l.period))
+	b.at = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// forget drops the buckets that have refilled completely, so the memory doesn't grow with every client ever seen.
+// The caller must hold l.mu.
+func (l *limiter) forget(now time.Time) {
+	for client, b := range l.buckets {
+		if now.Sub(b.at) >= l.period {
+			delete(l.buckets, client)
+		}
+	}
+}
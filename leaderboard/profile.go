@@ -0,0 +1,235 @@
+// Package leaderboard submits the scores of finished games to an online leaderboard.
+//
+// The leaderboard is opt-in: nothing is sent unless the player configures the URL of a leaderboard server.
+// Entries are POSTed as JSON over HTTPS. Entries that can't be sent, e.g. while the computer is offline,
+// are queued in the `leaderboard_queue.json` file of the data directory and sent again with the next submission
+// or on the next launch. Every entry has a random ID, so a server can ignore an entry sent twice.
+//
+// The package also has a small server compatible with the client, so communities can host their own leaderboards.
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/metrics"
+)
+
+const (
+	profileMax     = 4 << 20 // the largest profile, in bytes of JSON: both the sections sent and the whole merged profile
+	sectionsMax    = 16      // the largest number of sections of a profile
+	sectionNameMax = 32      // the longest name of a section of a profile, in bytes
+)
+
+// ErrNoAccount is returned when syncing a profile without a leaderboard token: the token identifies the account
+// the profile is kept under.
+var ErrNoAccount = errors.New("syncing the profile needs a leaderboard token")
+
+// errProfileTooLarge is returned when merging the sections would make the profile larger than profileMax, which
+// the clients couldn't read back, or give it more than sectionsMax sections.
+var errProfileTooLarge = errors.New("the merged profile would be too large")
+
+// Profile is the profile of a player kept by the leaderboard server under the account of the player's token,
+// so it can be synced across machines.
+//
+// The profile is made of independent sections, e.g. the settings or the history of the games, each a JSON
+// document the server doesn't look into. Conflicts are resolved section by section: the last write wins.
+// Fields:
+// - Sections: the sections by name.
+type Profile struct {
+	Sections map[string]Section `json:"sections"`
+}
+
+// Section is a section of a profile.
+// Fields:
+// - UpdatedAt: when the section was last changed on the machine that sent it.
+// - Data: the content of the section.
+type Section struct {
+	UpdatedAt time.Time       `json:"updated_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SyncProfile sends the sections of the profile changed on this machine and answers with the whole profile
+// kept by the server, where every section is the one written last, either the one sent or one sent
+// by another machine.
+//
+// Parameters:
+// - ctx (context.Context): The context for the request.
+// - p (Profile): The sections changed on this machine; may be empty to only fetch the profile.
+//
+// Returns:
+// - *Profile: The profile kept by the server.
+// - error: ErrNoAccount without a token, or an error if the request fails.
+func (c *Client) SyncProfile(ctx context.Context, p Profile) (*Profile, error) {
+	if c.token.Client == "" {
+		return nil, ErrNoAccount
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding profile: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.profileURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating profile request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(clientHeader, c.token.Client)
+	req.Header.Set(signatureHeader, c.token.Sign(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error syncing profile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error syncing profile: %s", resp.Status)
+	}
+	//the server never keeps a profile larger than profileMax, so a longer answer is an error
+	var synced Profile
+	if err = json.NewDecoder(io.LimitReader(resp.Body, profileMax)).Decode(&synced); err != nil {
+		return nil, fmt.Errorf("error decoding synced profile: %w", err)
+	}
+	return &synced, nil
+}
+
+// handleProfile merges the sections sent by a client into the profile of its account and answers
+// with the merged profile.
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Profiles == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(s.opts.Tokens) == 0 {
+		http.Error(w, "the server issues no tokens, so it has no accounts to keep profiles under", http.StatusForbidden)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, profileMax))
+	if err != nil {
+		http.Error(w, "invalid profile: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	client, ok := s.authenticate(r, body)
+	if !ok {
+		http.Error(w, "missing or invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if s.limiter != nil && !s.limiter.allow(client) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+	var p Profile
+	if err = json.Unmarshal(body, &p); err != nil {
+		http.Error(w, "invalid profile: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = validateProfile(p); err != nil {
+		http.Error(w, "invalid profile: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	merged, err := s.mergeProfile(client, p)
+	if errors.Is(err, errProfileTooLarge) {
+		http.Error(w, "invalid profile: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "error storing profile", http.StatusInternalServerError)
+		return
+	}
+	metrics.Default.Counter("snake_leaderboard_profile_syncs_total", "Profiles synced through the leaderboard.").Inc()
+	writeJSON(w, merged)
+}
+
+// validateProfile checks that the profile is well-formed.
+func validateProfile(p Profile) error {
+	if len(p.Sections) > sectionsMax {
+		return fmt.Errorf("more than %d sections", sectionsMax)
+	}
+	for name, sec := range p.Sections {
+		switch {
+		case name == "" || len(name) > sectionNameMax:
+			return fmt.Errorf("section names must have 1 to %d bytes", sectionNameMax)
+		case sec.UpdatedAt.IsZero():
+			return fmt.Errorf("section %s has no update time", name)
+		case len(sec.Data) == 0:
+			return fmt.Errorf("section %s has no data", name)
+		}
+	}
+	return nil
+}
+
+// mergeProfile merges the sections into the stored profile of the client, keeping the section written last
+// of every pair, and saves the profile if it has changed. The merged profile must fit in profileMax bytes,
+// so the clients can read it back; otherwise nothing is stored.
+//
+// Returns:
+// - *Profile: The merged profile.
+// - error: errProfileTooLarge if the merged profile is too large or has too many sections, or an error if the stored profile cannot be read
+// or the merged one saved.
+func (s *Server) mergeProfile(client string, p Profile) (*Profile, error) {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+	//the names of the clients are chosen by the operator, so they aren't used as file names directly
+	sum := sha256.Sum256([]byte(client))
+	path := filepath.Join(s.opts.Profiles, hex.EncodeToString(sum[:16])+".json")
+	stored := Profile{Sections: make(map[string]Section)}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err = json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("error parsing profile %s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("error reading profile %s: %w", path, err)
+	}
+	if stored.Sections == nil {
+		stored.Sections = make(map[string]Section)
+	}
+	changed := false
+	for name, sec := range p.Sections {
+		if old, ok := stored.Sections[name]; !ok || sec.UpdatedAt.After(old.UpdatedAt) {
+			stored.Sections[name] = sec
+			changed = true
+		}
+	}
+	if len(stored.Sections) > sectionsMax {
+		return nil, fmt.Errorf("%w: more than %d sections", errProfileTooLarge, sectionsMax)
+	}
+	//the answer is encoded like the stored file, so a profile that fits is read back whole
+	if data, err = json.Marshal(stored); err != nil {
+		return nil, fmt.Errorf("error encoding profile: %w", err)
+	}
+	if len(data) > profileMax {
+		return nil, fmt.Errorf("%w: more than %d bytes", errProfileTooLarge, profileMax)
+	}
+	if changed {
+		if err = os.MkdirAll(s.opts.Profiles, 0755); err != nil {
+			return nil, fmt.Errorf("error creating directory %s: %w", s.opts.Profiles, err)
+		}
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err != nil {
+			return nil, fmt.Errorf("error writing profile %s: %w", tmp, err)
+		}
+		if err = os.Rename(tmp, path); err != nil {
+			return nil, fmt.Errorf("error replacing profile %s: %w", path, err)
+		}
+	}
+	return &stored, nil
+}
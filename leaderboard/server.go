@@ -0,0 +1,490 @@
+// Package leaderboard submits the scores of finished games to an online leaderboard.
+//
+// The leaderboard is opt-in: nothing is sent unless the player configures the URL of a leaderboard server.
+// Entries are POSTed as JSON over HTTPS. Entries that can't be sent, e.g. while the computer is offline,
+// are queued in the `leaderboard_queue.json` file of the data directory and sent again with the next submission
+// or on the next launch. Every entry has a random ID, so a server can ignore an entry sent twice.
+//
+// The package also has a small server compatible with the client, so communities can host their own leaderboards.
+package leaderboard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/metrics"
+	"github.com/DenisKhanov/Snake/replay"
+)
+
+// entriesBucket is the bucket of the database the entries are stored in, as JSON by their IDs.
+var entriesBucket = []byte("entries")
+
+const (
+	nameMax      = 32        // the longest player name accepted by the server, in characters
+	bodyMax      = 256 << 10 // the largest accepted request body with the replay, in bytes
+	ticksMax     = 1 << 20   // the longest replay re-simulated by the server, in ticks
+	listDefault  = 10        // the number of entries listed when the request doesn't say
+	listMax      = 100       // the largest number of entries listed at once
+	modeEntryMax = 10000     // the largest number of entries kept per mode; the lowest scores are dropped
+)
+
+// verifyBuckets are the upper bounds of the buckets of the replay verification times, in seconds.
+var verifyBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Server is a self-hosted leaderboard server compatible with Client.
+//
+// It exposes a small JSON API:
+//   - POST /scores submits an Entry and answers with its Result: the rank among the entries of the same mode.
+//   - GET /scores?cells=20&assisted=false&limit=10 lists the best entries of a mode, the best first.
+//   - POST /profile merges the sections of a Profile into the profile kept under the token the request is signed with
+//     and answers with the merged profile; only if the server issues tokens and keeps profiles.
+//
+// The entries are stored in a bbolt database, a single file, so every accepted submission writes only
+// the new entry, and a crash never damages the board. They're also kept in memory for ranking. The replays sent
+// with the entries are only used for verification and aren't stored.
+// Fields:
+// - db: the database the entries are stored in.
+// - opts: the protection of the submissions.
+// - limiter: limits the rate of the submissions per client.
+// - mu: guards entries and ids.
+// - entries: the accepted entries.
+// - ids: the IDs of the accepted entries, for ignoring an entry sent twice.
+// - profileMu: serializes the changes of the profiles.
+type Server struct {
+	db        *bolt.DB
+	opts      Options
+	limiter   *limiter
+	mu        sync.Mutex
+	entries   []Entry
+	ids       map[string]bool
+	profileMu sync.Mutex
+}
+
+// Options configures how a Server protects the leaderboard from forged scores.
+// Fields:
+// - Tokens: the secrets of the issued tokens by client name; if there are any, only submissions signed
+// with one of the tokens are accepted, otherwise submissions aren't signed.
+// - Rate: the largest number of submissions accepted per minute from a client, identified by its token
+// or, without tokens, by its IP address; 0 means no limit.
+// - Verify: whether every submission must come with its replay, which is re-simulated to check the score.
+// - Profiles: the directory the synced profiles of the players are kept in, one file per token; empty if the server
+// doesn't keep profiles.
+type Options struct {
+	Tokens   map[string]string
+	Rate     int
+	Verify   bool
+	Profiles string
+}
+
+// NewServer creates a leaderboard server storing the entries in the given database file, loading the entries
+// stored there before. The server must be closed with Close.
+//
+// Parameters:
+// - path (string): The database file the entries are stored in; it's created if it doesn't exist.
+// - opts (Options): The protection of the submissions.
+//
+// Returns:
+// - *Server: The server.
+// - error: An error if the database cannot be opened or read, e.g. because another server is using it.
+func NewServer(path string, opts Options) (*Server, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening leaderboard %s: %w", path, err)
+	}
+	s := &Server{db: db, opts: opts, ids: make(map[string]bool)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(entriesBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			s.entries = append(s.entries, e)
+			s.ids[e.ID] = true
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error reading leaderboard %s: %w", path, err)
+	}
+	metrics.Default.GaugeFunc("snake_leaderboard_entries", "Entries kept by the leaderboard.", func() float64 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return float64(len(s.entries))
+	})
+	if opts.Rate > 0 {
+		s.limiter = newLimiter(opts.Rate, time.Minute)
+	}
+	return s, nil
+}
+
+// Close closes the database of the server.
+//
+// Returns:
+// - error: An error if the database cannot be closed cleanly.
+func (s *Server) Close() error {
+	return s.db.Close()
+}
+
+// Import adds the entries of a leaderboard kept in a JSON file by older versions of the server to the database,
+// skipping the entries already stored and trimming every mode to modeEntryMax entries.
+//
+// Parameters:
+// - path (string): The JSON file with the entries.
+//
+// Returns:
+// - int: The number of entries added.
+// - error: An error if the file cannot be read or parsed, or the entries cannot be stored.
+func (s *Server) Import(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading leaderboard %s: %w", path, err)
+	}
+	var entries []Entry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return 0, fmt.Errorf("error parsing leaderboard %s: %w", path, err)
+	}
+	added := 0
+	for _, e := range entries {
+		if validate(e) != nil || s.known(e.ID) {
+			continue
+		}
+		if _, err = s.add(e); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// known reports whether an entry with the ID has been stored.
+func (s *Server) known(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[id]
+}
+
+// ServeHTTP handles the requests of the JSON API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/profile" {
+		s.handleProfile(w, r)
+		return
+	}
+	if r.URL.Path != "/scores" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.handleSubmit(w, r)
+	case http.MethodGet:
+		s.handleList(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSubmit accepts a submitted entry and answers with its rank.
+//
+// The signature is checked before the rate limit, so forged requests can't use up the limit of a client,
+// and the rate limit before the replay is re-simulated, which is the most expensive part.
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bodyMax))
+	if err != nil {
+		countSubmission("invalid")
+		http.Error(w, "invalid entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	client, ok := s.authenticate(r, body)
+	if !ok {
+		countSubmission("unauthorized")
+		http.Error(w, "missing or invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if s.limiter != nil && !s.limiter.allow(client) {
+		countSubmission("limited")
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "too many submissions", http.StatusTooManyRequests)
+		return
+	}
+	var e Entry
+	if err = json.Unmarshal(body, &e); err != nil {
+		countSubmission("invalid")
+		http.Error(w, "invalid entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = validate(e); err != nil {
+		countSubmission("invalid")
+		http.Error(w, "invalid entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.opts.Verify {
+		start := time.Now()
+		err = verify(e)
+		metrics.Default.Histogram("snake_leaderboard_verify_seconds", "Time spent re-simulating the replays of the submissions.",
+			verifyBuckets).Observe(time.Since(start).Seconds())
+		if err != nil {
+			countSubmission("unverified")
+			http.Error(w, "unverified entry: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	e.Replay = nil
+	res, err := s.add(e)
+	if err != nil {
+		countSubmission("error")
+		http.Error(w, "error storing entry", http.StatusInternalServerError)
+		return
+	}
+	countSubmission("accepted")
+	writeJSON(w, res)
+}
+
+// countSubmission counts a submission in the metrics of the server by its result: accepted, invalid, unauthorized,
+// limited, unverified or error.
+func countSubmission(result string) {
+	metrics.Default.Counter("snake_leaderboard_submissions_total", "Scores submitted to the leaderboard, by result.",
+		"result", result).Inc()
+}
+
+// authenticate checks the signature of a submission and returns the client it's counted against by the rate limit:
+// the client name of the token, or the IP address of the sender if the server doesn't issue tokens.
+//
+// Parameters:
+// - r (*http.Request): The request.
+// - body ([]byte): The body of the request.
+//
+// Returns:
+// - string: The client.
+// - bool: Whether the submission is signed with a known token, or true if the server doesn't issue tokens.
+func (s *Server) authenticate(r *http.Request, body []byte) (string, bool) {
+	if len(s.opts.Tokens) == 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return host, true
+	}
+	client := r.Header.Get(clientHeader)
+	secret, ok := s.opts.Tokens[client]
+	if !ok || client == "" {
+		return "", false
+	}
+	want := Token{Client: client, Secret: secret}.Sign(body)
+	return client, hmac.Equal([]byte(want), []byte(r.Header.Get(signatureHeader)))
+}
+
+// handleList answers with the best entries of the requested mode.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	cells, err := strconv.Atoi(q.Get("cells"))
+	if err != nil {
+		http.Error(w, "invalid cells", http.StatusBadRequest)
+		return
+	}
+	assisted := q.Get("assisted") == "true"
+	limit := listDefault
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, s.list(cells, assisted, min(limit, listMax)))
+}
+
+// writeJSON writes the value as the JSON answer of a request.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "error encoding answer", http.StatusInternalServerError)
+	}
+}
+
+// validate checks that the entry is well-formed.
+func validate(e Entry) error {
+	switch {
+	case e.ID == "" || len(e.ID) > 64:
+		return errors.New("missing or too long id")
+	case e.Name == "" || utf8.RuneCountInString(e.Name) > nameMax:
+		return fmt.Errorf("the name must have 1 to %d characters", nameMax)
+	case e.Score < 0:
+		return errors.New("negative score")
+	case e.Cells < engine.MinCells:
+		return fmt.Errorf("the board must have at least %d cells", engine.MinCells)
+	}
+	return nil
+}
+
+// verify checks the entry against its replay: the replay must match the hash, end with the recorded result
+// when it's re-simulated, and have been played in the same mode, from the same seed and to the same score.
+func verify(e Entry) error {
+	if len(e.Replay) == 0 {
+		return errors.New("missing replay")
+	}
+	if HashReplay(e.Replay) != e.ReplayHash {
+		return errors.New("the replay doesn't match its hash")
+	}
+	r, err := replay.Decode(bytes.NewReader(e.Replay))
+	if err != nil {
+		return err
+	}
+	if r.Ticks > ticksMax {
+		return fmt.Errorf("the replay is longer than %d ticks", ticksMax)
+	}
+	eng, err := r.Play(nil)
+	if err != nil {
+		return err
+	}
+	if err = r.Check(eng); err != nil {
+		return err
+	}
+	if r.Seed != e.Seed || r.Cells != e.Cells || r.Score != e.Score || eng.Assisted() != e.Assisted {
+		return errors.New("the replay doesn't match the entry")
+	}
+	return nil
+}
+
+// add stores the entry, unless an entry with the same ID has already been stored, and returns its rank.
+//
+// Returns:
+// - *Result: The rank of the entry among the entries of its mode.
+// - error: An error if the entries cannot be saved; the entry isn't stored then.
+func (s *Server) add(e Entry) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ids[e.ID] {
+		entries, dropped := trim(append(s.entries, e), e.Cells, e.Assisted)
+		if err := s.store(e, dropped); err != nil {
+			return nil, err
+		}
+		s.entries = entries
+		s.ids = make(map[string]bool, len(entries))
+		for _, stored := range entries {
+			s.ids[stored.ID] = true
+		}
+	}
+	ranked := s.ranked(e.Cells, e.Assisted)
+	res := &Result{Rank: len(ranked) + 1, Total: len(ranked)}
+	for i, stored := range ranked {
+		if stored.ID == e.ID {
+			res.Rank = i + 1
+			break
+		}
+	}
+	return res, nil
+}
+
+// list returns the best entries of a mode, the best first.
+func (s *Server) list(cells int, assisted bool, limit int) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ranked := s.ranked(cells, assisted)
+	return ranked[:min(limit, len(ranked))]
+}
+
+// ranked returns the entries of a mode from the best to the worst; of two equal scores, the earlier one ranks higher.
+// The caller must hold s.mu.
+func (s *Server) ranked(cells int, assisted bool) []Entry {
+	ranked := []Entry{}
+	for _, e := range s.entries {
+		if e.Cells == cells && e.Assisted == assisted {
+			ranked = append(ranked, e)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].EndedAt.Before(ranked[j].EndedAt)
+	})
+	return ranked
+}
+
+// trim drops the lowest scores of a mode once it has more than modeEntryMax entries.
+//
+// Returns:
+// - []Entry: The entries kept.
+// - []string: The IDs of the dropped entries.
+func trim(entries []Entry, cells int, assisted bool) ([]Entry, []string) {
+	var mode []int
+	for i, e := range entries {
+		if e.Cells == cells && e.Assisted == assisted {
+			mode = append(mode, i)
+		}
+	}
+	if len(mode) <= modeEntryMax {
+		return entries, nil
+	}
+	sort.SliceStable(mode, func(i, j int) bool { return entries[mode[i]].Score > entries[mode[j]].Score })
+	drop := make(map[int]bool)
+	for _, i := range mode[modeEntryMax:] {
+		drop[i] = true
+	}
+	kept := make([]Entry, 0, len(entries)-len(drop))
+	var dropped []string
+	for i, e := range entries {
+		if drop[i] {
+			dropped = append(dropped, e.ID)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	return kept, dropped
+}
+
+// store writes a new entry to the database and deletes the dropped ones in a single transaction,
+// so the board is never left half-changed.
+//
+// Parameters:
+// - e (Entry): The new entry.
+// - dropped ([]string): The IDs of the entries dropped by trim.
+//
+// Returns:
+// - error: An error if the entries cannot be stored; nothing is changed then.
+func (s *Server) store(e Entry, dropped []string) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error encoding leaderboard entry: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		if err := b.Put([]byte(e.ID), data); err != nil {
+			return err
+		}
+		for _, id := range dropped {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error storing leaderboard entry: %w", err)
+	}
+	return nil
+}
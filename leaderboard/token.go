@@ -0,0 +1,135 @@
+// Package leaderboard submits the scores of finished games to an online leaderboard.
+//
+// The leaderboard is opt-in: nothing is sent unless the player configures the URL of a leaderboard server.
+// Entries are POSTed as JSON over HTTPS. Entries that can't be sent, e.g. while the computer is offline,
+// are queued in the `leaderboard_queue.json` file of the data directory and sent again with the next submission
+// or on the next launch. Every entry has a random ID, so a server can ignore an entry sent twice.
+//
+// The package also has a small server compatible with the client, so communities can host their own leaderboards.
+package leaderboard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	clientHeader    = "X-Snake-Client"    // the header with the client name of the token a submission is signed with
+	signatureHeader = "X-Snake-Signature" // the header with the HMAC-SHA256 signature of the submission, in hex
+)
+
+// ErrMalformedToken is returned for tokens that aren't in the "client:secret" form.
+var ErrMalformedToken = errors.New(`the leaderboard token must have the "client:secret" form`)
+
+// Token is a per-client token issued by a leaderboard server; the submissions are signed with its secret.
+// Fields:
+// - Client: the name of the client, sent with every submission.
+// - Secret: the key of the HMAC signatures, known only to the client and the server.
+type Token struct {
+	Client string
+	Secret string
+}
+
+// ParseToken parses a token in the "client:secret" form.
+//
+// Parameters:
+// - s (string): The token.
+//
+// Returns:
+// - Token: The parsed token.
+// - error: ErrMalformedToken if the token doesn't have the expected form.
+func ParseToken(s string) (Token, error) {
+	client, secret, ok := strings.Cut(s, ":")
+	if !ok || client == "" || secret == "" {
+		return Token{}, ErrMalformedToken
+	}
+	return Token{Client: client, Secret: secret}, nil
+}
+
+// String returns the token in the "client:secret" form.
+func (t Token) String() string {
+	return t.Client + ":" + t.Secret
+}
+
+// Sign returns the HMAC-SHA256 signature of a request body, in hex. The body of a submission includes
+// the hash of the replay, so the signature covers the replay too.
+//
+// Parameters:
+// - body ([]byte): The body of the request.
+//
+// Returns:
+// - string: The signature.
+func (t Token) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(t.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LoadTokens reads the tokens issued by a server, a JSON object mapping the client names to their secrets.
+//
+// Parameters:
+// - path (string): The file with the tokens.
+//
+// Returns:
+// - map[string]string: The secrets by client name; empty if the file doesn't exist.
+// - error: An error if the file exists but cannot be read or parsed.
+func LoadTokens(path string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokens %s: %w", path, err)
+	}
+	if err = json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("error parsing tokens %s: %w", path, err)
+	}
+	return tokens, nil
+}
+
+// IssueToken creates a token with a random secret for a client and adds it to the tokens file,
+// replacing the client's previous token.
+//
+// Parameters:
+// - path (string): The file with the tokens; it's created if needed.
+// - client (string): The name of the client.
+//
+// Returns:
+// - Token: The new token, to be given to the client.
+// - error: An error if the client name is invalid or the file cannot be read or written.
+func IssueToken(path, client string) (Token, error) {
+	if client == "" || strings.Contains(client, ":") {
+		return Token{}, errors.New(`the client name must be non-empty and can't contain ":"`)
+	}
+	tokens, err := LoadTokens(path)
+	if err != nil {
+		return Token{}, err
+	}
+	var b [32]byte
+	if _, err = rand.Read(b[:]); err != nil {
+		return Token{}, fmt.Errorf("error creating token: %w", err)
+	}
+	t := Token{Client: client, Secret: hex.EncodeToString(b[:])}
+	tokens[client] = t.Secret
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return Token{}, fmt.Errorf("error encoding tokens: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Token{}, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return Token{}, fmt.Errorf("error writing tokens %s: %w", path, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,238 @@
+// Package livecast streams the state of the game rendered in the window to browsers, so a run can be watched
+// on a second screen or captured by streaming software such as OBS.
+//
+// The server sends a compact JSON message over WebSocket after every tick and serves a tiny embedded web page
+// that draws them; it uses the WebSocket of the netplay package, so it has no dependencies. A page gets a full
+// Frame when it connects and every KeyframeTicks ticks, and a Delta with only the changes for the ticks between,
+// so a tick costs a few dozen bytes whatever the length of the snake.
+package livecast
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/netplay"
+	"github.com/DenisKhanov/Snake/wire"
+)
+
+// KeyframeTicks is the number of ticks between two full frames sent to a page; deltas are sent between.
+const KeyframeTicks = 50
+
+//go:embed viewer.html
+var viewerPage []byte
+
+// Frame is the full state of the game after a tick.
+// Fields:
+// - Tick: the number of ticks played.
+// - Cells: the number of cells along each side of the board.
+// - Snake: the coordinates of the segments of the snake, the head first; (0, 0) is the top left cell.
+// - Food: the coordinates of the food.
+// - Score: the current score.
+// - Over: whether the game has ended.
+type Frame struct {
+	Tick  int      `json:"t"`
+	Cells int      `json:"n"`
+	Snake [][2]int `json:"s"`
+	Food  [2]int   `json:"f"`
+	Score int      `json:"sc"`
+	Over  bool     `json:"o,omitempty"`
+}
+
+// Delta is the change of the state of the game during the tick after the previous frame or delta; it has no n,
+// which tells it from a Frame.
+// Fields:
+// - Tick: the number of ticks played, one more than in the previous message.
+// - Heads: the new segments at the head of the snake, the newest first.
+// - Drop: the number of segments removed from the tail of the snake.
+// - Food: the new coordinates of the food, or nil if it hasn't moved.
+// - Score, Over: the same as in Frame.
+type Delta struct {
+	Tick  int      `json:"t"`
+	Heads [][2]int `json:"h,omitempty"`
+	Drop  int      `json:"d,omitempty"`
+	Food  *[2]int  `json:"f,omitempty"`
+	Score int      `json:"sc"`
+	Over  bool     `json:"o,omitempty"`
+}
+
+// diff returns the delta between two consecutive frames.
+func diff(prev, next Frame) Delta {
+	d := Delta{Tick: next.Tick, Score: next.Score, Over: next.Over}
+	d.Heads, d.Drop = wire.DiffSnake(prev.Snake, next.Snake)
+	if next.Food != prev.Food {
+		food := next.Food
+		d.Food = &food
+	}
+	return d
+}
+
+// FrameOf returns the frame of the game of the engine.
+//
+// Parameters:
+// - e (*engine.Engine): The engine running the game.
+//
+// Returns:
+// - Frame: The state of the game.
+func FrameOf(e *engine.Engine) Frame {
+	snake := make([][2]int, 0, len(e.Snake.Parts))
+	for _, p := range e.Snake.Parts {
+		snake = append(snake, [2]int{int(p.X), int(p.Y)})
+	}
+	return Frame{
+		Tick:  e.Tick,
+		Cells: e.BoardSize(),
+		Snake: snake,
+		Food:  [2]int{int(e.Food.X), int(e.Food.Y)},
+		Score: e.Score,
+		Over:  e.GameOver,
+	}
+}
+
+// viewer is a connected page.
+// Fields:
+// - queue: the message not sent to the page yet, if any.
+// - sent: the tick of the last message queued for the page, or -1 if none.
+// - keyframe: the tick of the last full frame queued for the page.
+type viewer struct {
+	queue    chan json.RawMessage
+	sent     int
+	keyframe int
+}
+
+// Caster serves the web page at / and streams the frames to the pages over WebSocket at /ws.
+// Any number of pages can watch at once; a page too slow to keep up skips to a full frame of the latest tick.
+// Fields:
+// - mu: guards viewers, last and key.
+// - viewers: the connected pages.
+// - last: the latest frame, the next delta is taken from.
+// - key: the latest frame encoded, sent to the pages right after they connect; nil before the first one.
+type Caster struct {
+	mu      sync.Mutex
+	viewers map[*viewer]struct{}
+	last    Frame
+	key     json.RawMessage
+}
+
+// NewCaster creates the server of the live view.
+func NewCaster() *Caster {
+	return &Caster{viewers: make(map[*viewer]struct{})}
+}
+
+// Publish sends the frame to the connected pages: as a delta to the pages that have got the previous tick,
+// and in full to the others and every KeyframeTicks ticks.
+//
+// It's called by the game logic goroutine after every tick.
+func (c *Caster) Publish(f Frame) {
+	key, err := json.Marshal(f)
+	if err != nil {
+		log.Println("error encoding live frame:", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var delta json.RawMessage
+	if c.key != nil && f.Cells == c.last.Cells && f.Tick == c.last.Tick+1 {
+		if delta, err = json.Marshal(diff(c.last, f)); err != nil {
+			log.Println("error encoding live delta:", err)
+		}
+	}
+	for v := range c.viewers {
+		if delta != nil && v.sent == c.last.Tick && f.Tick-v.keyframe < KeyframeTicks {
+			//the delta fits only if the page has got the previous message
+			select {
+			case v.queue <- delta:
+				v.sent = f.Tick
+				continue
+			default:
+			}
+		}
+		//the full frame replaces the message the page hasn't got yet
+		select {
+		case <-v.queue:
+		default:
+		}
+		v.queue <- key
+		v.sent, v.keyframe = f.Tick, f.Tick
+	}
+	c.last, c.key = f, key
+}
+
+// ServeHTTP serves the web page and the WebSocket of the frames.
+func (c *Caster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewerPage)
+	case "/ws":
+		c.stream(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// stream sends the frames to a page until it disconnects.
+func (c *Caster) stream(w http.ResponseWriter, r *http.Request) {
+	//any page the player visits could otherwise connect to the live view and read the game
+	if !netplay.SameOrigin(r) {
+		http.Error(w, "the live view can only be watched on its own page", http.StatusForbidden)
+		return
+	}
+	conn, err := netplay.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	v := &viewer{queue: make(chan json.RawMessage, 1), sent: -1}
+	c.mu.Lock()
+	if c.key != nil {
+		v.queue <- c.key
+		v.sent, v.keyframe = c.last.Tick, c.last.Tick
+	}
+	c.viewers[v] = struct{}{}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.viewers, v)
+		c.mu.Unlock()
+	}()
+	//the page sends nothing, but reading notices when it's closed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var msg any
+		for conn.ReadJSON(&msg) == nil {
+		}
+	}()
+	for {
+		select {
+		case data := <-v.queue:
+			if conn.WriteJSON(data) != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// ListenAndServe serves the live view on the address.
+//
+// Parameters:
+// - addr (string): The address to listen on, e.g. "localhost:8090".
+// - c (*Caster): The server of the live view.
+//
+// Returns:
+// - error: The error that has stopped the server.
+func ListenAndServe(addr string, c *Caster) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           c,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,402 @@
+// Package metrics exposes the counters, gauges and histograms of the servers of the game in the Prometheus text
+// format, so the operators of hosted instances can monitor them.
+//
+// It implements the small part of the format the servers need, so it has no dependencies. The metrics are
+// registered in a Registry on first use, and the same name and labels always give the same metric, so a metric
+// can be looked up again wherever it's updated.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DenisKhanov/Snake/version"
+)
+
+// DurationBuckets are the upper bounds of the histogram buckets for durations from a few seconds to an hour,
+// in seconds: the lengths of games and matches.
+var DurationBuckets = []float64{5, 15, 30, 60, 120, 300, 600, 1200, 3600}
+
+// Default is the registry the servers register their metrics in.
+var Default = NewRegistry()
+
+// The types of the metric families, as written in the TYPE lines.
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Registry holds the metrics and writes them in the text format. It's safe for concurrent use.
+// Fields:
+// - mu: guards families.
+// - families: the metric families by name, in the order they've been registered in.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// family is the metrics of the same name, differing in their labels.
+// Fields:
+// - name: the name of the metrics.
+// - help: the description of the metrics.
+// - typ: the type of the metrics: typeCounter, typeGauge or typeHistogram.
+// - series: the metrics by their formatted labels, e.g. `{result="accepted"}`.
+// - order: the formatted labels in the order the metrics have been registered in.
+type family struct {
+	name   string
+	help   string
+	typ    string
+	series map[string]metric
+	order  []string
+}
+
+// metric is a metric that writes its samples.
+type metric interface {
+	write(w *bufio.Writer, name, labels string)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter returns the counter of the name and the labels, registering it first if needed.
+//
+// Parameters:
+// - name (string): The name of the counter, ending with _total by convention.
+// - help (string): The description of the counter.
+// - labels (...string): The names and the values of the labels, in pairs.
+//
+// Returns:
+// - *Counter: The counter.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.metric(name, help, typeCounter, labels, func() metric { return &Counter{} }).(*Counter)
+}
+
+// Gauge returns the gauge of the name and the labels, registering it first if needed.
+//
+// Parameters:
+// - name (string): The name of the gauge.
+// - help (string): The description of the gauge.
+// - labels (...string): The names and the values of the labels, in pairs.
+//
+// Returns:
+// - *Gauge: The gauge.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.metric(name, help, typeGauge, labels, func() metric { return &Gauge{} }).(*Gauge)
+}
+
+// GaugeFunc registers a gauge whose value is read from the function whenever the metrics are written,
+// replacing the function registered before under the same name and labels.
+//
+// Parameters:
+// - name (string): The name of the gauge.
+// - help (string): The description of the gauge.
+// - f (func() float64): Returns the current value; called from the goroutine writing the metrics.
+// - labels (...string): The names and the values of the labels, in pairs.
+func (r *Registry) GaugeFunc(name, help string, f func() float64, labels ...string) {
+	r.metric(name, help, typeGauge, labels, func() metric { return &gaugeFunc{} }).(*gaugeFunc).f.Store(&f)
+}
+
+// Histogram returns the histogram of the name and the labels, registering it first if needed.
+//
+// Parameters:
+// - name (string): The name of the histogram.
+// - help (string): The description of the histogram.
+// - buckets ([]float64): The upper bounds of the buckets, in ascending order; used only when the histogram is registered.
+// - labels (...string): The names and the values of the labels, in pairs.
+//
+// Returns:
+// - *Histogram: The histogram.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return r.metric(name, help, typeHistogram, labels, func() metric {
+		return &Histogram{bounds: slices.Clone(buckets), counts: make([]uint64, len(buckets))}
+	}).(*Histogram)
+}
+
+// metric returns the metric of the name and the labels, creating it with create if it isn't registered yet.
+// Registering a name again with another type is a programming error and panics.
+func (r *Registry) metric(name, help, typ string, labels []string, create func() metric) metric {
+	key := formatLabels(labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.families, func(f *family) bool { return f.name == name })
+	if i < 0 {
+		r.families = append(r.families, &family{name: name, help: help, typ: typ, series: make(map[string]metric)})
+		i = len(r.families) - 1
+	}
+	f := r.families[i]
+	if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s registered as a %s and a %s", name, f.typ, typ))
+	}
+	m, ok := f.series[key]
+	if !ok {
+		m = create()
+		f.series[key] = m
+		f.order = append(f.order, key)
+	}
+	return m
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+//
+// Parameters:
+// - w (io.Writer): Where the metrics are written.
+//
+// Returns:
+// - int64: The number of bytes written.
+// - error: The error of the writer.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	series := make([][]metric, len(families))
+	orders := make([][]string, len(families))
+	for i, f := range families {
+		orders[i] = slices.Clone(f.order)
+		for _, key := range f.order {
+			series[i] = append(series[i], f.series[key])
+		}
+	}
+	r.mu.Unlock()
+	for i, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ)
+		for j, m := range series[i] {
+			m.write(bw, f.name, orders[i][j])
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP answers with all metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// ListenAndServe serves the metrics of the Default registry at /metrics on the address, together with
+// the metrics of the process: the number of goroutines, the start time and the version of the build.
+//
+// Parameters:
+// - addr (string): The address to listen on, e.g. "localhost:9100".
+//
+// Returns:
+// - error: The error that has stopped the server.
+func ListenAndServe(addr string) error {
+	start := float64(time.Now().Unix())
+	Default.GaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch in seconds.",
+		func() float64 { return start })
+	Default.GaugeFunc("go_goroutines", "Number of goroutines that currently exist.",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	info := version.Get()
+	Default.Gauge("snake_build_info", "The version of the running executable; the value is always 1.",
+		"version", info.Version, "commit", info.ShortCommit(), "goversion", runtime.Version()).Set(1)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// Counter is a value that only goes up, e.g. the number of requests served.
+type Counter struct {
+	v atomicFloat
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add adds a non-negative value to the counter; negative values are ignored.
+func (c *Counter) Add(v float64) {
+	if v > 0 {
+		c.v.add(v)
+	}
+}
+
+// write writes the sample of the counter.
+func (c *Counter) write(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, c.v.load())
+}
+
+// Gauge is a value that goes up and down, e.g. the number of games in progress.
+type Gauge struct {
+	v atomicFloat
+}
+
+// Set sets the value of the gauge.
+func (g *Gauge) Set(v float64) {
+	g.v.store(v)
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// write writes the sample of the gauge.
+func (g *Gauge) write(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, g.v.load())
+}
+
+// gaugeFunc is a gauge whose value is read from a function.
+type gaugeFunc struct {
+	f atomic.Pointer[func() float64]
+}
+
+// write writes the sample of the gauge.
+func (g *gaugeFunc) write(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, (*g.f.Load())())
+}
+
+// Histogram counts observed values, e.g. durations, in buckets.
+// Fields:
+// - mu: guards the fields below.
+// - bounds: the upper bounds of the buckets, in ascending order.
+// - counts: the number of observations of each bucket, not cumulative.
+// - sum: the sum of all observations.
+// - count: the number of observations.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Observe adds an observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// write writes the cumulative buckets, the sum and the count of the histogram.
+func (h *Histogram) write(w *bufio.Writer, name, labels string) {
+	h.mu.Lock()
+	counts, sum, count := slices.Clone(h.counts), h.sum, h.count
+	h.mu.Unlock()
+	//the le label goes after the labels of the histogram
+	prefix := "{"
+	if labels != "" {
+		prefix = strings.TrimSuffix(labels, "}") + ","
+	}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		writeSample(w, name+"_bucket", prefix+`le="`+formatFloat(bound)+`"}`, float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", prefix+`le="+Inf"}`, float64(count))
+	writeSample(w, name+"_sum", labels, sum)
+	writeSample(w, name+"_count", labels, float64(count))
+}
+
+// atomicFloat is a float64 that can be updated from many goroutines.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+// load returns the value.
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// store sets the value.
+func (f *atomicFloat) store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+// add adds to the value.
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// writeSample writes a line of a sample.
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats a value the way the text format expects it.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatLabels formats the label pairs as in the samples, e.g. `{result="accepted"}`; an odd last name is ignored.
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// escapeHelp escapes the description of a metric for the HELP line.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes to the underlying writer and counts the bytes.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
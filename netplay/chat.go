@@ -0,0 +1,97 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	ChatMax    = 100              // the longest chat message, in characters; longer messages are cut
+	chatBurst  = 5                // the number of chat messages a player can send at once
+	chatPeriod = 10 * time.Second // the time a player's chat allowance takes to refill completely
+)
+
+// Emotes are the quick emotes a player can send instead of a chat message.
+var Emotes = []string{"hi", "gg", "nice", "oops", "wow"}
+
+// chatAllowance is the token bucket limiting the chat messages of a player. It's used only by the goroutine
+// receiving the messages of the player.
+// Fields:
+// - tokens: the number of messages the player can send now.
+// - at: when tokens was last updated.
+type chatAllowance struct {
+	tokens float64
+	at     time.Time
+}
+
+// allow reports whether the player can send a chat message now, and takes it from the allowance if so.
+func (a *chatAllowance) allow(now time.Time) bool {
+	if a.at.IsZero() {
+		a.tokens = chatBurst
+	} else {
+		a.tokens = min(chatBurst, a.tokens+now.Sub(a.at).Seconds()*chatBurst/chatPeriod.Seconds())
+	}
+	a.at = now
+	if a.tokens < 1 {
+		return false
+	}
+	a.tokens--
+	return true
+}
+
+// sanitizeChat returns the chat message to forward, with the control and invisible characters removed
+// and the text cut to ChatMax characters.
+//
+// Returns:
+// - Message: The message to forward, with only the text or the emote of the original one.
+// - bool: Whether the message has anything to forward: a known emote or a text that isn't blank.
+func sanitizeChat(m Message) (Message, bool) {
+	if m.Emote != "" {
+		return Message{Type: TypeChat, Emote: m.Emote}, slices.Contains(Emotes, m.Emote)
+	}
+	text := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(m.Text, ""))
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > ChatMax {
+		text = strings.TrimSpace(string(runes[:ChatMax]))
+	}
+	return Message{Type: TypeChat, Text: text}, text != ""
+}
+
+// chat sends the chat message of the player, signed with the player's name and index, to the players of its room
+// or match, the player included. The message is dropped if the player is in neither.
+func (s *Server) chat(p *player, m Message) {
+	s.mu.Lock()
+	mates := p.mates
+	s.mu.Unlock()
+	from := slices.Index(mates, p)
+	if from < 0 {
+		return
+	}
+	m.Name, m.From = p.name, from
+	for _, mate := range mates {
+		mate.conn.WriteJSON(m)
+	}
+}
+
+// setMates sets the players the chat messages of each of the players are sent to; the caller must hold s.mu.
+func setMates(players []*player) {
+	for _, p := range players {
+		p.mates = slices.Clone(players)
+	}
+}
@@ -0,0 +1,230 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Client is the connection of a player to a match server.
+// Next must be called from a single goroutine, but the other methods can be called from any goroutine.
+type Client struct {
+	conn *Conn
+}
+
+// Join connects to the match server and joins the quick-match queue, which pairs the player with the next one.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting may take.
+// - rawURL (string): The address of the server, e.g. wss://example.com/match.
+// - name (string): The name of the player, shown to the opponent.
+// - id (string): The secret identifier of the player, which the ratings are kept under; empty for unranked matches.
+//
+// Returns:
+// - *Client: The client; the caller must close it.
+// - error: An error if the server can't be reached.
+func Join(ctx context.Context, rawURL, name, id string) (*Client, error) {
+	return connect(ctx, rawURL, Message{Type: TypeJoin, Name: name, ID: id})
+}
+
+// CreateRoom connects to the match server and creates a private room; the room message tells its code.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting may take.
+// - rawURL (string): The address of the server.
+// - name (string): The name of the player, shown to the opponent.
+// - id (string): The secret identifier of the player, which the ratings are kept under; empty for unranked matches.
+//
+// Returns:
+// - *Client: The client; the caller must close it.
+// - error: An error if the server can't be reached.
+func CreateRoom(ctx context.Context, rawURL, name, id string) (*Client, error) {
+	return connect(ctx, rawURL, Message{Type: TypeCreate, Name: name, ID: id})
+}
+
+// EnterRoom connects to the match server and joins the room with the code. If there is no such room,
+// or it's full, the server answers with an error message.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting may take.
+// - rawURL (string): The address of the server.
+// - name (string): The name of the player, shown to the opponent.
+// - id (string): The secret identifier of the player, which the ratings are kept under; empty for unranked matches.
+// - code (string): The code of the room, told by its host.
+//
+// Returns:
+// - *Client: The client; the caller must close it.
+// - error: An error if the server can't be reached.
+func EnterRoom(ctx context.Context, rawURL, name, id, code string) (*Client, error) {
+	return connect(ctx, rawURL, Message{Type: TypeEnter, Name: name, ID: id, Room: code})
+}
+
+// Resume reconnects to a running match after the connection has dropped. If the match has ended in the meantime,
+// the server answers with an error message.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting may take.
+// - rawURL (string): The address of the server.
+// - token (string): The reconnect token of the start message of the match.
+//
+// Returns:
+// - *Client: The client; the caller must close it.
+// - error: An error if the server can't be reached.
+func Resume(ctx context.Context, rawURL, token string) (*Client, error) {
+	return connect(ctx, rawURL, Message{Type: TypeResume, Token: token})
+}
+
+// ListMatches asks the match server for the running matches a spectator can watch.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting and the answer may take.
+// - rawURL (string): The address of the server.
+//
+// Returns:
+// - []MatchInfo: The running matches, the longest-running first.
+// - error: An error if the server can't be reached or its answer can't be read.
+func ListMatches(ctx context.Context, rawURL string) ([]MatchInfo, error) {
+	c, err := connect(ctx, rawURL, Message{Type: TypeList})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.conn.SetReadDeadline(deadline)
+	}
+	m, err := c.Next()
+	if err != nil {
+		return nil, fmt.Errorf("error reading match list: %w", err)
+	}
+	if m.Type != TypeMatches {
+		return nil, fmt.Errorf("expected the match list, got %q", m.Type)
+	}
+	return m.Matches, nil
+}
+
+// TopRatings asks the match server for the best players of the rating ladder of a ranked mode.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting and the answer may take.
+// - rawURL (string): The address of the server.
+// - mode (string): The name of the mode, one of Modes.
+//
+// Returns:
+// - []Rating: The best RatingsTop players, the highest rating first.
+// - error: An error if the server can't be reached or its answer can't be read.
+func TopRatings(ctx context.Context, rawURL, mode string) ([]Rating, error) {
+	c, err := connect(ctx, rawURL, Message{Type: TypeRatings, Mode: mode})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.conn.SetReadDeadline(deadline)
+	}
+	m, err := c.Next()
+	if err != nil {
+		return nil, fmt.Errorf("error reading ratings: %w", err)
+	}
+	if m.Type != TypeRatings {
+		return nil, fmt.Errorf("expected the ratings, got %q", m.Type)
+	}
+	return m.Ladder, nil
+}
+
+// Spectate connects to the match server to watch a running match; the server sends its messages with a delay.
+// If the match has ended in the meantime, the server answers with an error message.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting may take.
+// - rawURL (string): The address of the server.
+// - id (string): The identifier of the match, from ListMatches.
+//
+// Returns:
+// - *Client: The client; the caller must close it.
+// - error: An error if the server can't be reached.
+func Spectate(ctx context.Context, rawURL, id string) (*Client, error) {
+	return connect(ctx, rawURL, Message{Type: TypeSpectate, Match: id})
+}
+
+// connect opens a connection to the match server and sends the first message.
+func connect(ctx context.Context, rawURL string, first Message) (*Client, error) {
+	conn, err := Dial(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.WriteJSON(first); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Next waits for the next message of the server.
+//
+// Returns:
+// - Message: The message.
+// - error: io.EOF if the server has closed the connection, or a reading error.
+func (c *Client) Next() (Message, error) {
+	var m Message
+	err := c.conn.ReadJSON(&m)
+	return m, err
+}
+
+// Turn asks the server to turn the player's snake.
+//
+// Parameters:
+// - dir (engine.Dir): The new direction.
+// - seq (int): The sequence number of the turn, see Predictor.Turn.
+// - tick (int): The tick the turn has been predicted at.
+func (c *Client) Turn(dir engine.Dir, seq, tick int) error {
+	return c.conn.WriteJSON(Message{Type: TypeTurn, Dir: dir, Seq: seq, Tick: tick})
+}
+
+// Ready tells the server whether the player is ready to start the match of the room.
+func (c *Client) Ready(ready bool) error {
+	return c.conn.WriteJSON(Message{Type: TypeReady, Ready: ready})
+}
+
+// Settings changes the board and the speed of the match of the room; the server ignores it unless the player is the host.
+//
+// Parameters:
+// - cells (int): The size of the board, between engine.MinCells and CellsMax.
+// - speed (int): The interval between two ticks in milliseconds, between engine.MinSpeed and engine.StartSpeed.
+func (c *Client) Settings(cells, speed int) error {
+	return c.conn.WriteJSON(Message{Type: TypeSettings, Cells: cells, Speed: speed})
+}
+
+// Chat sends a chat message to the players of the room or the match.
+//
+// Parameters:
+// - text (string): The text of the message; the server cuts it to ChatMax characters.
+func (c *Client) Chat(text string) error {
+	return c.conn.WriteJSON(Message{Type: TypeChat, Text: text})
+}
+
+// Emote sends a quick emote to the players of the room or the match.
+//
+// Parameters:
+// - emote (string): The emote, one of Emotes.
+func (c *Client) Emote(emote string) error {
+	return c.conn.WriteJSON(Message{Type: TypeChat, Emote: emote})
+}
+
+// Leave leaves the match, or the room, and closes the connection; the opponent wins a running match at once.
+func (c *Client) Leave() error {
+	c.conn.WriteJSON(Message{Type: TypeLeave})
+	return c.conn.Close()
+}
+
+// Close closes the connection. In a running match, it counts as a dropped connection: the player can resume
+// the match within ReconnectGrace.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
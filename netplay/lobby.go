@@ -0,0 +1,205 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"crypto/rand"
+	mathrand "math/rand"
+	"slices"
+	"strings"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	CellsMax    = 60                         // the largest board a host can choose
+	CodeLength  = 4                          // the number of letters of a room code
+	CodeLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ" // the letters of the room codes, without I and O, which look like digits
+	lobbyMax    = 8                          // the largest number of queued room messages of a player; further ones are dropped
+)
+
+// room is a private room: its host creates it, another player joins it by its code, and the match starts
+// once both players are ready, with the board and the speed chosen by the host.
+// All fields are guarded by the mutex of the server.
+// Fields:
+// - code: the code players join the room by.
+// - players: the players in the room, the host first.
+// - ready: whether the players are ready, indexed like players.
+// - cells: the size of the board of the match.
+// - speed: the interval between two ticks of the match, in milliseconds.
+// - started: whether the match has started; the room is no longer listed then.
+type room struct {
+	code    string
+	players []*player
+	ready   []bool
+	cells   int
+	speed   int
+	started bool
+}
+
+// createRoom creates a room with the player as its host and sends the player the room message.
+func (s *Server) createRoom(p *player) *room {
+	var out outbox
+	defer out.send()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := newCode()
+	for s.rooms[code] != nil {
+		code = newCode()
+	}
+	r := &room{code: code, players: []*player{p}, ready: []bool{false}, cells: s.cells, speed: engine.VersusSpeed}
+	s.rooms[code] = r
+	r.announce(&out, s.ratings)
+	return r
+}
+
+// newCode returns a random room code, drawn from crypto/rand so the codes of the private rooms can't be predicted.
+func newCode() string {
+	b := make([]byte, CodeLength)
+	rand.Read(b)
+	for i := range b {
+		//256 isn't a multiple of the number of letters, so the bytes above the last multiple are drawn again
+		for int(b[i]) >= 256/len(CodeLetters)*len(CodeLetters) {
+			rand.Read(b[i : i+1])
+		}
+		b[i] = CodeLetters[int(b[i])%len(CodeLetters)]
+	}
+	return string(b)
+}
+
+// enterRoom adds the player to the room with the code and sends both players the room message.
+//
+// Parameters:
+// - p (*player): The player.
+// - code (string): The code of the room; the case and the surrounding spaces are ignored.
+//
+// Returns:
+// - *room: The room, or nil if the player can't enter it.
+// - string: ReasonNoRoom or ReasonFull if the player can't enter the room.
+func (s *Server) enterRoom(p *player, code string) (*room, string) {
+	var out outbox
+	defer out.send()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[strings.ToUpper(strings.TrimSpace(code))]
+	switch {
+	case r == nil:
+		return nil, ReasonNoRoom
+	case len(r.players) >= engine.Players:
+		return nil, ReasonFull
+	}
+	r.players = append(r.players, p)
+	r.ready = append(r.ready, false)
+	r.announce(&out, s.ratings)
+	return r, ""
+}
+
+// lobby handles the room messages of the player until the match of the room starts and ends,
+// or the player leaves. The match is played in the goroutine of the player who gets ready last.
+func (s *Server) lobby(p *player, r *room) {
+	for {
+		select {
+		case m := <-p.lobby:
+			if s.update(p, r, m) {
+				s.runMatch([engine.Players]*player(r.players), mathrand.Int63(), r.cells, r.speed)
+				return
+			}
+		case <-p.done:
+			return
+		case <-p.gone:
+			if s.leaveRoom(p, r) {
+				p.conn.Close()
+				return
+			}
+			<-p.done
+			return
+		}
+	}
+}
+
+// update applies a ready or settings message of the player to the room and sends the players the new room message.
+// Only the host can change the settings, which makes both players unready, so nobody starts a game they haven't seen.
+//
+// Returns:
+// - bool: Whether both players are ready and the caller must play the match; the room is started and removed then.
+func (s *Server) update(p *player, r *room, m Message) bool {
+	var out outbox
+	defer out.send()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.Index(r.players, p)
+	if r.started || i < 0 {
+		return false
+	}
+	switch m.Type {
+	case TypeReady:
+		r.ready[i] = m.Ready
+	case TypeSettings:
+		if i != 0 {
+			return false
+		}
+		r.cells = min(max(m.Cells, engine.MinCells), CellsMax)
+		r.speed = min(max(m.Speed, engine.MinSpeed), engine.StartSpeed)
+		clear(r.ready)
+	}
+	if len(r.players) == engine.Players && !slices.Contains(r.ready, false) {
+		r.started = true
+		delete(s.rooms, r.code)
+		return true
+	}
+	r.announce(&out, s.ratings)
+	return false
+}
+
+// leaveRoom removes the player whose connection has been lost from the room; the other player, if any, becomes the host.
+// An empty room is removed.
+//
+// Returns:
+// - bool: Whether the player has left; false if the match has already started, and the player is left to it.
+func (s *Server) leaveRoom(p *player, r *room) bool {
+	var out outbox
+	defer out.send()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.started {
+		return false
+	}
+	if i := slices.Index(r.players, p); i >= 0 {
+		r.players = slices.Delete(r.players, i, i+1)
+		r.ready = slices.Delete(r.ready, i, i+1)
+	}
+	if len(r.players) == 0 {
+		delete(s.rooms, r.code)
+		return true
+	}
+	r.announce(&out, s.ratings)
+	return true
+}
+
+// announce adds the room message for every player of the room to the outbox, with their ratings if the match
+// would be ranked, and makes them chat with each other; the caller must hold the mutex of the server, so the room
+// messages are stamped before the start message of the match and can't be sent after it.
+func (r *room) announce(out *outbox, ratings *Ratings) {
+	current := ratings.of(r.speed, r.players)
+	setMates(r.players)
+	names := make([]string, len(r.players))
+	for i, p := range r.players {
+		names[i] = p.name
+	}
+	for i, p := range r.players {
+		out.add(p, Message{
+			Type:    TypeRoom,
+			Room:    r.code,
+			You:     i,
+			Names:   names,
+			Readies: slices.Clone(r.ready),
+			Cells:   r.cells,
+			Speed:   r.speed,
+			Ratings: current,
+		})
+	}
+}
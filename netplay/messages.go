@@ -0,0 +1,246 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"slices"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/wire"
+)
+
+// The types of the messages. A player either joins the quick-match queue or a room; a match goes like this:
+//
+//	client → server: join     {name, id}                               (quick match)
+//	server → client: wait                                              (until the next player joins the queue)
+//
+//	client → server: create   {name, id}                               (or: enter {name, id, room} to join a room by its code)
+//	server → client: room     {room, you, names, readies, cells, speed, ratings} (after every change of the room)
+//	client → server: settings {cells, speed}                           (the host only; makes both players unready)
+//	client → server: ready    {ready}                                  (the match starts when both players are ready)
+//	server → client: error    {reason}                                 (the room doesn't exist or is full)
+//
+//	server → client: start    {you, names, cells, speed, token, ratings}
+//	client → server: turn     {dir, seq, tick}                         (any time during the game)
+//	server → client: state    {tick, snakes, dirs, alive, food, acks, away} (after every tick)
+//	client → server: leave                                             (the player gives up the game)
+//	server → client: over     {winner, reason, ratings, deltas}
+//
+//	client → server: chat     {text} or {emote}                        (any time in a room or a game)
+//	server → client: chat     {from, name, text} or {from, name, emote} (to both players, the sender included)
+//
+// A spectator watches a running match with a delay, so it can't coach one of the players:
+//
+//	client → server: list                                              (the connection is closed after the answer)
+//	server → client: matches  {matches}
+//	client → server: spectate {match}
+//	server → client: start    {you: Spectator, names, cells, speed, delay}
+//	server → client: state, over                                       (each as sent to the players, delay ms later)
+//	server → client: delta    {tick, heads, drops, dirs, alive, food, away} (instead of most states)
+//
+// A spectator gets a full state first and then every KeyframeTicks ticks; the states in between come as deltas,
+// which only tell how the snakes have moved and where the food has gone, so a tick costs a few dozen bytes
+// whatever the length of the snakes. ApplyDelta turns the previous state into the next one; a spectator
+// that has missed a state gets a full one instead of the next delta.
+//
+// The ladders of the ranked modes can be listed the same way:
+//
+//	client → server: ratings  {mode}                                   (the connection is closed after the answer)
+//	server → client: ratings  {mode, ladder}
+//
+// The server closes the connection after the over and error messages. The host of a room is always the player 0
+// of the room message; when the host leaves, the other player becomes the host.
+//
+// A player whose connection drops during the game isn't out of it at once: the snake keeps moving straight
+// for ReconnectGrace, and a new connection sending resume {token} with the token of the start message takes
+// the seat back. The server answers it with a new start message, and the turns are counted from 1 again.
+// A token the server no longer knows is answered with an error message.
+//
+// The matches played in one of the Modes are ranked if both players send an id: a secret random identifier
+// their ratings are kept under. The room and start messages then tell the ratings of the players,
+// and the over message their new ratings and how much they have changed.
+//
+// The server strips the control characters from the chat messages, cuts them to ChatMax characters,
+// and drops the messages of a player who sends too many of them, as well as unknown emotes.
+//
+// The server is the single source of truth for the ticks: it applies at most one turn of every player per tick,
+// in the order they were sent, and acknowledges the sequence number of the last turn it has applied or rejected,
+// so a client predicting its snake knows which of its turns are already included in a state.
+const (
+	TypeJoin     = "join"
+	TypeWait     = "wait"
+	TypeCreate   = "create"
+	TypeEnter    = "enter"
+	TypeRoom     = "room"
+	TypeSettings = "settings"
+	TypeReady    = "ready"
+	TypeError    = "error"
+	TypeStart    = "start"
+	TypeTurn     = "turn"
+	TypeState    = "state"
+	TypeDelta    = "delta"
+	TypeLeave    = "leave"
+	TypeResume   = "resume"
+	TypeChat     = "chat"
+	TypeList     = "list"
+	TypeMatches  = "matches"
+	TypeSpectate = "spectate"
+	TypeRatings  = "ratings"
+	TypeOver     = "over"
+)
+
+// The reasons of the over and error messages.
+const (
+	ReasonLeft    = "left"     // over: the opponent has left the game
+	ReasonNoRoom  = "no_room"  // error: there is no room with the code
+	ReasonFull    = "full"     // error: the room already has two players, or the match as many spectators as allowed
+	ReasonNoMatch = "no_match" // error: the match to resume or to watch has ended
+)
+
+// Message is a message of the match protocol; the fields used depend on its type.
+// Fields:
+// - Type: the type of the message, one of the Type constants.
+// - Name: join, create, enter: the name of the player; chat: the name of the sender.
+// - ID: join, create, enter: the secret identifier of the player, which the ratings are kept under; optional.
+// - Room: enter, room: the code of the room.
+// - You: start, room: the index of the player the message is sent to; start: Spectator for a spectator.
+// - Names: start, room: the names of the players, indexed by player.
+// - Readies: room: whether the players are ready, indexed by player.
+// - Ready: ready: whether the player is ready.
+// - Cells: start, room, settings: the size of the board.
+// - Speed: start, room, settings: the interval between two ticks, in milliseconds.
+// - Token: start, resume: the token the player reconnects to the match with.
+// - Delay: start: how late a spectator gets the messages of the match, in milliseconds.
+// - Match: spectate: the identifier of the match to watch.
+// - Matches: matches: the running matches.
+// - Mode: ratings: the name of the mode of the ladder, one of Modes.
+// - Ladder: ratings: the best players of the ladder, the highest rating first.
+// - Ratings: room, start, over: the ratings of the players in the mode of the match, indexed by player;
+// only for a ranked match, and after it for the over message.
+// - Deltas: over: how much the ratings of the players have changed, indexed by player.
+// - Dir: turn: the new direction of the player's snake.
+// - Seq: turn: the sequence number of the turn, counted by the client from 1.
+// - Tick: turn: the tick the client has predicted the turn at; state, delta: the number of ticks played.
+// - Snakes: state: the positions of the snakes' segments, the heads first, indexed by player.
+// - Heads: delta: the new segments at the heads of the snakes, the newest first, indexed by player.
+// - Drops: delta: the number of segments removed from the tails of the snakes, indexed by player.
+// - Dirs: state, delta: the directions the snakes move in, indexed by player.
+// - Alive: state, delta: whether the snakes are alive, indexed by player.
+// - Food: state: the position of the food; delta: the new position of the food, only if it has moved.
+// - Acks: state: the sequence number of the last turn the server has processed, indexed by player.
+// - Away: state, delta: whether the players are disconnected and may reconnect, indexed by player.
+// - Winner: over: the index of the winning player, or engine.Draw.
+// - Text: chat: the text of the message.
+// - Emote: chat: the emote sent instead of a text, one of Emotes.
+// - From: chat: the index of the sender in the room or the match.
+// - Reason: over: why the game has ended early, ReasonLeft if a player has left; error: one of the Reason constants.
+type Message struct {
+	Type    string           `json:"type"`
+	Name    string           `json:"name,omitempty"`
+	ID      string           `json:"id,omitempty"`
+	Room    string           `json:"room,omitempty"`
+	You     int              `json:"you,omitempty"`
+	Names   []string         `json:"names,omitempty"`
+	Readies []bool           `json:"readies,omitempty"`
+	Ready   bool             `json:"ready,omitempty"`
+	Cells   int              `json:"cells,omitempty"`
+	Speed   int              `json:"speed,omitempty"`
+	Token   string           `json:"token,omitempty"`
+	Delay   int              `json:"delay,omitempty"`
+	Match   string           `json:"match,omitempty"`
+	Matches []MatchInfo      `json:"matches,omitempty"`
+	Mode    string           `json:"mode,omitempty"`
+	Ladder  []Rating         `json:"ladder,omitempty"`
+	Ratings []int            `json:"ratings,omitempty"`
+	Deltas  []int            `json:"deltas,omitempty"`
+	Dir     engine.Dir       `json:"dir,omitempty"`
+	Seq     int              `json:"seq,omitempty"`
+	Tick    int              `json:"tick,omitempty"`
+	Snakes  [][]engine.Point `json:"snakes,omitempty"`
+	Heads   [][]engine.Point `json:"heads,omitempty"`
+	Drops   []int            `json:"drops,omitempty"`
+	Dirs    []engine.Dir     `json:"dirs,omitempty"`
+	Alive   []bool           `json:"alive,omitempty"`
+	Food    *engine.Point    `json:"food,omitempty"`
+	Acks    []int            `json:"acks,omitempty"`
+	Away    []bool           `json:"away,omitempty"`
+	Winner  int              `json:"winner,omitempty"`
+	Text    string           `json:"text,omitempty"`
+	Emote   string           `json:"emote,omitempty"`
+	From    int              `json:"from,omitempty"`
+	Reason  string           `json:"reason,omitempty"`
+}
+
+// stateMessage returns the state message describing the game.
+//
+// Parameters:
+// - v (*engine.Versus): The game.
+// - acks ([]int): The sequence numbers of the last turns processed, indexed by player.
+// - away ([]bool): Whether the players are disconnected, indexed by player.
+func stateMessage(v *engine.Versus, acks []int, away []bool) Message {
+	food := v.Food
+	m := Message{Type: TypeState, Tick: v.Tick, Food: &food, Alive: append([]bool(nil), v.Alive[:]...), Acks: acks, Away: away}
+	for _, s := range v.Snakes {
+		m.Snakes = append(m.Snakes, slices.Clone(s.Parts))
+		m.Dirs = append(m.Dirs, s.Direction)
+	}
+	return m
+}
+
+// deltaMessage returns the delta message turning one state message into the next one.
+//
+// Parameters:
+// - prev (Message): The earlier state message.
+// - next (Message): The later state message.
+//
+// Returns:
+// - Message: The delta message.
+// - bool: False if the messages have different numbers of snakes, which a delta can't describe.
+func deltaMessage(prev, next Message) (Message, bool) {
+	if len(prev.Snakes) != len(next.Snakes) {
+		return Message{}, false
+	}
+	m := Message{Type: TypeDelta, Tick: next.Tick, Dirs: next.Dirs, Alive: next.Alive, Away: next.Away}
+	for i := range next.Snakes {
+		heads, drop := wire.DiffSnake(prev.Snakes[i], next.Snakes[i])
+		m.Heads = append(m.Heads, heads)
+		m.Drops = append(m.Drops, drop)
+	}
+	if prev.Food == nil || next.Food != nil && *next.Food != *prev.Food {
+		m.Food = next.Food
+	}
+	return m, true
+}
+
+// ApplyDelta returns the state message a delta message turns the state message into.
+//
+// Parameters:
+// - state (Message): The state message the delta follows.
+// - delta (Message): The delta message.
+//
+// Returns:
+// - Message: The next state message; the acknowledgements of the turns aren't part of the deltas, so it keeps
+// those of state.
+// - error: An error if the delta doesn't follow the state.
+func ApplyDelta(state, delta Message) (Message, error) {
+	if state.Type != TypeState || len(delta.Heads) != len(state.Snakes) || len(delta.Drops) != len(state.Snakes) {
+		return state, wire.ErrMismatch
+	}
+	next := state
+	next.Tick, next.Dirs, next.Alive, next.Away = delta.Tick, delta.Dirs, delta.Alive, delta.Away
+	next.Snakes = make([][]engine.Point, len(state.Snakes))
+	for i, snake := range state.Snakes {
+		var err error
+		if next.Snakes[i], err = wire.ApplySnake(snake, delta.Heads[i], delta.Drops[i]); err != nil {
+			return state, err
+		}
+	}
+	if delta.Food != nil {
+		next.Food = delta.Food
+	}
+	return next, nil
+}
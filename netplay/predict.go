@@ -0,0 +1,178 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// AheadMax is the largest number of ticks the prediction runs ahead of the last state of the server.
+// At engine.VersusSpeed it covers a latency of 450ms; with more, the snake waits for the server.
+const AheadMax = 3
+
+// pendingTurn is a turn sent to the server but not acknowledged yet.
+// Fields:
+// - seq: the sequence number of the turn.
+// - tick: the predicted tick the turn was made at.
+// - dir: the new direction.
+type pendingTurn struct {
+	seq  int
+	tick int
+	dir  engine.Dir
+}
+
+// Predictor predicts the player's own snake, so it reacts to the keys right away instead of a round trip later.
+//
+// The client ticks the predictor at the speed of the game, so the prediction runs ahead of the server by about
+// the latency. A turn is applied to the predicted snake at once and sent to the server. When a state of the server
+// arrives, the predictor reconciles: it starts over from the authoritative snake, drops the turns the server has
+// acknowledged, and re-applies the pending ones while re-simulating the ticks up to the predicted one. If the server
+// has applied a turn at a later tick than predicted, the snake is corrected by the difference.
+//
+// The prediction only moves the snake; collisions, deaths and the food are decided by the server.
+// It's safe for concurrent use.
+// Fields:
+// - mu: guards all fields.
+// - you: the index of the player.
+// - cells: the size of the board.
+// - server: the last state of the server.
+// - seq: the sequence number of the last turn.
+// - pending: the turns not acknowledged by the server, in order.
+// - tick: the predicted tick.
+// - snake: the predicted snake at the predicted tick.
+// - turned: whether the snake has turned during the predicted tick.
+type Predictor struct {
+	mu      sync.Mutex
+	you     int
+	cells   int
+	server  Message
+	seq     int
+	pending []pendingTurn
+	tick    int
+	snake   *engine.Snake
+	turned  bool
+}
+
+// NewPredictor creates a predictor of the player's snake.
+//
+// Parameters:
+// - you (int): The index of the player, from the start message.
+// - cells (int): The size of the board, from the start message.
+//
+// Returns:
+// - *Predictor: The predictor; it predicts nothing until the first state is reconciled.
+func NewPredictor(you, cells int) *Predictor {
+	return &Predictor{you: you, cells: cells}
+}
+
+// Turn turns the predicted snake, if the turn is allowed, and records it as pending.
+//
+// Parameters:
+// - dir (engine.Dir): The new direction.
+//
+// Returns:
+// - int: The sequence number to send the turn with.
+// - int: The predicted tick of the turn.
+// - bool: Whether the turn is allowed and must be sent to the server.
+func (p *Predictor) Turn(dir engine.Dir) (int, int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snake == nil || p.turned || p.snake.Direction.CheckParallel(dir) || p.snake.Direction == dir {
+		return 0, 0, false
+	}
+	p.seq++
+	p.pending = append(p.pending, pendingTurn{seq: p.seq, tick: p.tick, dir: dir})
+	p.snake.Direction = dir
+	p.turned = true
+	return p.seq, p.tick, true
+}
+
+// Tick advances the prediction by one tick, unless it's already AheadMax ticks ahead of the server.
+func (p *Predictor) Tick() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snake == nil || p.tick-p.server.Tick >= AheadMax {
+		return
+	}
+	p.step()
+}
+
+// Reconcile replaces the prediction with the authoritative state and predicts again from it.
+//
+// Parameters:
+// - state (Message): The state message of the server.
+func (p *Predictor) Reconcile(state Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.you >= len(state.Snakes) || p.you >= len(state.Dirs) || state.Tick < p.server.Tick {
+		return
+	}
+	p.server = state
+	if p.you < len(state.Acks) {
+		acked := state.Acks[p.you]
+		p.pending = slices.DeleteFunc(p.pending, func(t pendingTurn) bool { return t.seq <= acked })
+	}
+	target := min(max(p.tick, state.Tick), state.Tick+AheadMax)
+	p.snake = &engine.Snake{
+		Direction: state.Dirs[p.you],
+		Parts:     slices.Clone(state.Snakes[p.you]),
+		Size:      len(state.Snakes[p.you]),
+	}
+	p.tick = state.Tick
+	p.turned = false
+	if p.you < len(state.Alive) && !state.Alive[p.you] {
+		return
+	}
+	next := 0
+	for {
+		//the turns made before this tick but not processed by the server yet are applied now, in order
+		for next < len(p.pending) && p.pending[next].tick <= p.tick && !p.turned {
+			if !p.snake.Direction.CheckParallel(p.pending[next].dir) {
+				p.snake.Direction = p.pending[next].dir
+				p.turned = true
+			}
+			next++
+		}
+		if p.tick >= target {
+			break
+		}
+		p.step()
+	}
+}
+
+// step moves the predicted snake by one tick; the caller must hold p.mu. The snake stops at the walls,
+// since only the server decides whether it dies, and it grows when it reaches the food known from the server.
+func (p *Predictor) step() {
+	p.tick++
+	p.turned = false
+	if len(p.snake.Parts) == 0 {
+		return
+	}
+	newPos := p.snake.Direction.Exec(p.snake.Head())
+	if engine.CollidesWithWall(newPos, p.cells) {
+		return
+	}
+	p.snake.CutIfSnake(newPos)
+	if p.server.Food != nil && newPos == *p.server.Food {
+		p.snake.Add(newPos)
+		return
+	}
+	p.snake.Move(p.snake.Direction)
+}
+
+// Snake returns the segments of the predicted snake, the head first, or nil before the first state.
+func (p *Predictor) Snake() []engine.Point {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snake == nil {
+		return nil
+	}
+	return slices.Clone(p.snake.Parts)
+}
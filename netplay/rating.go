@@ -0,0 +1,249 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	RatingStart = 1500 // the rating of a player's first ranked match in a mode
+	RatingsTop  = 10   // the number of players of a ladder sent to the clients
+	ratingK     = 32   // the largest change of a rating after a single match
+	idMax       = 64   // the longest player identifier accepted, in bytes; longer ones are ignored
+)
+
+// Mode is a ranked mode of the versus game: each has its own rating ladder.
+// Fields:
+// - Name: the name of the mode, also the name of its ladder.
+// - Speed: the interval between two ticks, in milliseconds.
+type Mode struct {
+	Name  string
+	Speed int
+}
+
+// Modes are the ranked modes, from the slowest. The matches played at another speed aren't ranked.
+var Modes = []Mode{
+	{"relaxed", 200},
+	{"classic", engine.VersusSpeed},
+	{"blitz", 100},
+}
+
+// ModeOf returns the name of the ranked mode of the speed, or an empty string if the speed isn't ranked.
+func ModeOf(speed int) string {
+	if i := slices.IndexFunc(Modes, func(m Mode) bool { return m.Speed == speed }); i >= 0 {
+		return Modes[i].Name
+	}
+	return ""
+}
+
+// Rating is the rating of a player on the ladder of a mode.
+// Fields:
+// - Name: the name the player has last played under.
+// - Rating: the Elo rating of the player.
+// - Games: the number of ranked matches the player has played.
+// - Wins: the number of ranked matches the player has won.
+type Rating struct {
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+}
+
+// Ratings are the Elo ratings of the players of a match server, with a ladder per mode, kept in a JSON file.
+//
+// A player is known by the hash of the secret identifier the client sends, so the file and the ladders
+// never reveal the identifiers, and another player can't play under someone else's rating.
+// Fields:
+// - path: the file the ratings are stored in.
+// - mu: guards ladders.
+// - ladders: the ratings by mode name and player key.
+type Ratings struct {
+	path    string
+	mu      sync.Mutex
+	ladders map[string]map[string]*Rating
+}
+
+// LoadRatings creates the ratings of a match server stored in the given file, loading the ratings stored there before.
+//
+// Parameters:
+// - path (string): The file the ratings are stored in; it's created after the first ranked match.
+//
+// Returns:
+// - *Ratings: The ratings.
+// - error: An error if the file exists but cannot be read or parsed.
+func LoadRatings(path string) (*Ratings, error) {
+	r := &Ratings{path: path, ladders: make(map[string]map[string]*Rating)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading ratings %s: %w", path, err)
+	}
+	if err = json.Unmarshal(data, &r.ladders); err != nil {
+		return nil, fmt.Errorf("error parsing ratings %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// playerKey returns the key the ratings of the player with the secret identifier are kept under,
+// or an empty string if the identifier is missing or too long.
+func playerKey(id string) string {
+	if id == "" || len(id) > idMax {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ranked reports whether a match of the players at the speed changes their ratings: the mode must be ranked,
+// and the players must be known and different, so nobody can farm a rating against themselves.
+func (r *Ratings) ranked(speed int, players []*player) bool {
+	if r == nil || ModeOf(speed) == "" {
+		return false
+	}
+	keys := make([]string, len(players))
+	for i, p := range players {
+		if p.key == "" || slices.Contains(keys[:i], p.key) {
+			return false
+		}
+		keys[i] = p.key
+	}
+	return true
+}
+
+// of returns the current ratings of the players in the mode of the speed, or nil if their match isn't ranked.
+//
+// Parameters:
+// - speed (int): The interval between two ticks of the match, in milliseconds.
+// - players ([]*player): The players.
+func (r *Ratings) of(speed int, players []*player) []int {
+	if !r.ranked(speed, players) {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ladder := r.ladders[ModeOf(speed)]
+	ratings := make([]int, len(players))
+	for i, p := range players {
+		ratings[i] = RatingStart
+		if e := ladder[p.key]; e != nil {
+			ratings[i] = e.Rating
+		}
+	}
+	return ratings
+}
+
+// record updates the ratings of the players of a ranked match with its result and saves them.
+// Each player gains what the opponent loses: the more the result is a surprise, the more they change.
+//
+// Parameters:
+// - speed (int): The interval between two ticks of the match, in milliseconds.
+// - players ([engine.Players]*player): The players.
+// - winner (int): The index of the winning player, or engine.Draw.
+//
+// Returns:
+// - []int: The new ratings of the players, or nil if the match isn't ranked.
+// - []int: The changes of the ratings, indexed by player.
+func (r *Ratings) record(speed int, players [engine.Players]*player, winner int) ([]int, []int) {
+	if !r.ranked(speed, players[:]) {
+		return nil, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mode := ModeOf(speed)
+	ladder := r.ladders[mode]
+	if ladder == nil {
+		ladder = make(map[string]*Rating)
+		r.ladders[mode] = ladder
+	}
+	var entries [engine.Players]*Rating
+	for i, p := range players {
+		entries[i] = cmp.Or(ladder[p.key], &Rating{Rating: RatingStart})
+		entries[i].Name = p.name
+		ladder[p.key] = entries[i]
+	}
+	//the expected score of the first player; the second player's is the rest
+	expected := 1 / (1 + math.Pow(10, float64(entries[1].Rating-entries[0].Rating)/400))
+	score := 0.5
+	switch winner {
+	case 0:
+		score = 1
+	case 1:
+		score = 0
+	}
+	change := int(math.Round(ratingK * (score - expected)))
+	deltas := []int{change, -change}
+	ratings := make([]int, len(entries))
+	for i, e := range entries {
+		e.Rating += deltas[i]
+		e.Games++
+		if i == winner {
+			e.Wins++
+		}
+		ratings[i] = e.Rating
+	}
+	if err := saveRatings(r.path, r.ladders); err != nil {
+		log.Println(err)
+	}
+	return ratings, deltas
+}
+
+// top returns the best players of the ladder of the mode, the highest rating first.
+//
+// Parameters:
+// - mode (string): The name of the mode.
+// - n (int): The largest number of players returned.
+func (r *Ratings) top(mode string, n int) []Rating {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ladder := make([]Rating, 0, len(r.ladders[mode]))
+	for _, e := range r.ladders[mode] {
+		ladder = append(ladder, *e)
+	}
+	slices.SortFunc(ladder, func(a, b Rating) int {
+		return cmp.Or(b.Rating-a.Rating, b.Games-a.Games, cmp.Compare(a.Name, b.Name))
+	})
+	return ladder[:min(n, len(ladder))]
+}
+
+// saveRatings writes the ratings to the file through a temporary file, so a crash never leaves damaged ratings behind.
+func saveRatings(path string, ladders map[string]map[string]*Rating) error {
+	data, err := json.Marshal(ladders)
+	if err != nil {
+		return fmt.Errorf("error encoding ratings: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing ratings %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error replacing ratings %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// ReconnectGrace is how long the snake of a disconnected player stays in the game, moving straight,
+// before the player loses; the player can reconnect and take it back in the meantime.
+const ReconnectGrace = 10 * time.Second
+
+// seat is the place of a player in a running match, found by the reconnect token of the player.
+// Fields:
+// - rejoins: hands the new connection of the player over to the match.
+// - index: the index of the player in the match.
+type seat struct {
+	rejoins chan<- rejoin
+	index   int
+}
+
+// rejoin is a player who has reconnected to a running match.
+// Fields:
+// - index: the index of the player in the match.
+// - p: the new connection of the player.
+type rejoin struct {
+	index int
+	p     *player
+}
+
+// newToken returns a random reconnect token.
+func newToken() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// resume hands the player over to the match of the token, which replaces the old connection of the player
+// with the new one on its next tick.
+//
+// Returns:
+// - bool: Whether the match of the token is still running.
+func (s *Server) resume(p *player, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seat, ok := s.seats[token]
+	if !ok {
+		return false
+	}
+	//the match removes its seats under the lock before it drains the channel, so the player can't be missed
+	select {
+	case seat.rejoins <- rejoin{index: seat.index, p: p}:
+		return true
+	default:
+		return false
+	}
+}
+
+// addSeats registers the seats of a starting match and returns the reconnect tokens of its players.
+func (s *Server) addSeats(rejoins chan<- rejoin, n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = newToken()
+		s.seats[tokens[i]] = seat{rejoins: rejoins, index: i}
+	}
+	return tokens
+}
+
+// removeSeats unregisters the seats of a match that has ended, so its players can no longer reconnect.
+func (s *Server) removeSeats(tokens []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tokens {
+		delete(s.seats, t)
+	}
+}
@@ -0,0 +1,493 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/metrics"
+)
+
+const (
+	nameMax     = 32               // the longest player name, in characters; longer names are cut
+	joinTimeout = 10 * time.Second // how long the server waits for the join message of a new connection
+	turnsMax    = 4                // the largest number of turns queued for the next ticks; further turns are dropped
+)
+
+// Server is a match server: it pairs the players of the quick-match queue in the order they connect,
+// hosts the private rooms, plays their games and streams them to the spectators. Any number of matches
+// can run at the same time, each in the goroutine of the request of one of its players.
+// Fields:
+// - cells: the size of the board of the quick matches, and the initial one of the rooms.
+// - delay: how late the spectators get the messages of the matches.
+// - ratings: the ratings of the players of the ranked matches; nil if the matches aren't ranked.
+// - mu: guards waiting, the rooms, the seats and the live matches.
+// - waiting: the player of the quick-match queue waiting for an opponent, or nil.
+// - rooms: the rooms whose match hasn't started yet, by code.
+// - seats: the places of the players in the running matches, by reconnect token.
+// - live: the running matches, by identifier.
+type Server struct {
+	cells   int
+	delay   time.Duration
+	ratings *Ratings
+	mu      sync.Mutex
+	waiting *player
+	rooms   map[string]*room
+	seats   map[string]seat
+	live    map[string]*liveMatch
+}
+
+// player is a player connected to the server.
+// Fields:
+// - conn: the connection to the player's client.
+// - name: the name of the player.
+// - key: the key of the player's ratings, from the identifier of the join message; empty if the player hasn't sent one.
+// - turns: the turn messages received from the client, for the next ticks.
+// - lobby: the ready and settings messages received from the client while in a room.
+// - gone: closed when the connection is lost.
+// - left: set before gone is closed if the player has left the game on purpose, rather than lost the connection.
+// - done: closed when the player's match has ended, or the player has reconnected to it with another connection.
+// - mates: the players of the player's room or match, the player included, who get its chat messages;
+// guarded by the mutex of the server.
+// - chat: limits the chat messages of the player; used only by the goroutine receiving its messages.
+// - stamped: the stamp of the latest message queued in an outbox for the player; guarded by the mutex of the server.
+// - sendMu: guards sent and orders the writes of the stamped messages.
+// - sent: the stamp of the latest stamped message sent to the player.
+type player struct {
+	conn    *Conn
+	name    string
+	key     string
+	turns   chan Message
+	lobby   chan Message
+	gone    chan struct{}
+	left    atomic.Bool
+	done    chan struct{}
+	mates   []*player
+	chat    chatAllowance
+	stamped uint64
+	sendMu  sync.Mutex
+	sent    uint64
+}
+
+// outgoing is a message for a player, stamped while the mutex of the server is held.
+type outgoing struct {
+	p     *player
+	stamp uint64
+	m     Message
+}
+
+// outbox collects the messages decided while the mutex of the server is held, to send them once it's released:
+// a slow client then delays only its own messages, not every room, pairing and metrics read of the server.
+type outbox []outgoing
+
+// add stamps the message for the player and adds it to the outbox; the caller must hold the mutex of the server.
+func (o *outbox) add(p *player, m Message) {
+	*o = append(*o, outgoing{p: p, stamp: p.stamp(), m: m})
+}
+
+// send sends the messages of the outbox; the caller must not hold the mutex of the server.
+func (o *outbox) send() {
+	for _, out := range *o {
+		out.p.deliver(out.stamp, out.m)
+	}
+}
+
+// stamp returns the stamp of a new message for the player, larger than those of the earlier ones;
+// the caller must hold the mutex of the server.
+func (p *player) stamp() uint64 {
+	p.stamped++
+	return p.stamped
+}
+
+// deliver sends the stamped message to the player, unless a message stamped later has already been sent:
+// the room, wait and start messages each replace the previous ones, so a late one is dropped rather than sent
+// out of order. Failed writes are ignored, like in broadcast.
+func (p *player) deliver(stamp uint64, m Message) {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	if stamp < p.sent {
+		return
+	}
+	p.sent = stamp
+	p.conn.WriteJSON(m)
+}
+
+// NewServer creates a match server.
+//
+// Parameters:
+// - cells (int): The size of the board of the quick matches, and the initial one of the rooms.
+// - delay (time.Duration): How late the spectators get the messages of the matches, so they can't coach the players.
+// - ratings (*Ratings): The ratings of the players, updated after every ranked match; nil disables the ranked matches.
+//
+// Returns:
+// - *Server: The server.
+func NewServer(cells int, delay time.Duration, ratings *Ratings) *Server {
+	s := &Server{
+		cells:   min(max(cells, engine.MinCells), CellsMax),
+		delay:   max(delay, 0),
+		ratings: ratings,
+		rooms:   make(map[string]*room),
+		seats:   make(map[string]seat),
+		live:    make(map[string]*liveMatch),
+	}
+	s.registerMetrics()
+	return s
+}
+
+// registerMetrics registers the gauges of the server, which are read from its state when the metrics are written.
+func (s *Server) registerMetrics() {
+	gauge := func(name, help string, f func() int) {
+		metrics.Default.GaugeFunc(name, help, func() float64 {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return float64(f())
+		})
+	}
+	gauge("snake_match_active", "Matches in progress.", func() int { return len(s.live) })
+	gauge("snake_match_rooms", "Private rooms waiting for their match to start.", func() int { return len(s.rooms) })
+	gauge("snake_match_waiting", "Players in the quick-match queue.", func() int {
+		if s.waiting != nil {
+			return 1
+		}
+		return 0
+	})
+	gauge("snake_match_spectators", "Spectators watching the matches.", func() int {
+		n := 0
+		for _, m := range s.live {
+			n += len(m.spectators)
+		}
+		return n
+	})
+}
+
+// ServeHTTP accepts WebSocket connections at /match and puts the players in the quick-match queue or in their rooms,
+// and the spectators in their matches.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/match" {
+		http.NotFound(w, r)
+		return
+	}
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	p, first, err := join(conn)
+	if err != nil {
+		log.Println("player failed to join:", err)
+		conn.Close()
+		return
+	}
+	go s.receive(p)
+
+	switch first.Type {
+	case TypeCreate:
+		s.lobby(p, s.createRoom(p))
+		return
+	case TypeEnter:
+		room, reason := s.enterRoom(p, first.Room)
+		if room == nil {
+			conn.WriteJSON(Message{Type: TypeError, Reason: reason})
+			conn.Close()
+			return
+		}
+		s.lobby(p, room)
+		return
+	case TypeList:
+		conn.WriteJSON(Message{Type: TypeMatches, Matches: s.matches()})
+		conn.Close()
+		return
+	case TypeSpectate:
+		s.watch(p, first.Match)
+		return
+	case TypeRatings:
+		conn.WriteJSON(Message{Type: TypeRatings, Mode: first.Mode, Ladder: s.ratings.top(first.Mode, RatingsTop)})
+		conn.Close()
+		return
+	case TypeResume:
+		if !s.resume(p, first.Token) {
+			conn.WriteJSON(Message{Type: TypeError, Reason: ReasonNoMatch})
+			conn.Close()
+			return
+		}
+		<-p.done
+		return
+	}
+	if opponent := s.pair(p); opponent != nil {
+		s.runMatch([engine.Players]*player{opponent, p}, rand.Int63(), s.cells, engine.VersusSpeed)
+		return
+	}
+	select {
+	case <-p.done:
+	case <-p.gone:
+		s.mu.Lock()
+		waiting := s.waiting == p
+		if waiting {
+			s.waiting = nil
+		}
+		s.mu.Unlock()
+		if waiting {
+			p.conn.Close()
+			return
+		}
+		<-p.done
+	}
+}
+
+// pair returns the waiting player as the opponent of the new player, or, if nobody is waiting, makes the new player
+// wait for an opponent. A waiting player whose connection has been lost is replaced.
+func (s *Server) pair(p *player) *player {
+	var out outbox
+	defer out.send()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	opponent := s.waiting
+	if opponent != nil && !isClosed(opponent.gone) {
+		s.waiting = nil
+		return opponent
+	}
+	if opponent != nil {
+		opponent.conn.Close()
+		close(opponent.done)
+	}
+	s.waiting = p
+	//the message is stamped under the lock, so it can't be sent after the start message of a match
+	out.add(p, Message{Type: TypeWait})
+	return nil
+}
+
+// isClosed reports whether the channel is closed.
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// join reads the first message of a new connection: a join, create or enter message with the name of the player,
+// a resume message with a reconnect token, or a list, spectate or ratings message.
+func join(conn *Conn) (*player, Message, error) {
+	conn.conn.SetReadDeadline(time.Now().Add(joinTimeout))
+	var m Message
+	if err := conn.ReadJSON(&m); err != nil {
+		return nil, m, err
+	}
+	conn.conn.SetReadDeadline(time.Time{})
+	name := strings.TrimSpace(m.Name)
+	switch {
+	case m.Type == TypeResume && m.Token != "", m.Type == TypeList, m.Type == TypeSpectate, m.Type == TypeRatings:
+	case (m.Type != TypeJoin && m.Type != TypeCreate && m.Type != TypeEnter) || name == "" || !utf8.ValidString(name):
+		return nil, m, fmt.Errorf("expected a join, create or enter message with a name, got %q", m.Type)
+	}
+	if runes := []rune(name); len(runes) > nameMax {
+		name = string(runes[:nameMax])
+	}
+	return &player{
+		conn:  conn,
+		name:  name,
+		key:   playerKey(m.ID),
+		turns: make(chan Message, turnsMax),
+		lobby: make(chan Message, lobbyMax),
+		gone:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}, m, nil
+}
+
+// receive reads the messages of the player until the connection is lost or closed, or the player leaves,
+// and then closes gone. The turns and the room messages are queued for the match and the room,
+// and the chat messages are sent right away; the other messages are ignored.
+func (s *Server) receive(p *player) {
+	defer close(p.gone)
+	for {
+		var m Message
+		if err := p.conn.ReadJSON(&m); err != nil {
+			return
+		}
+		queue := p.turns
+		switch m.Type {
+		case TypeTurn:
+		case TypeReady, TypeSettings:
+			queue = p.lobby
+		case TypeChat:
+			if m, ok := sanitizeChat(m); ok && p.chat.allow(time.Now()) {
+				s.chat(p, m)
+			}
+			continue
+		case TypeLeave:
+			p.left.Store(true)
+			return
+		default:
+			continue
+		}
+		select {
+		case queue <- m:
+		default:
+		}
+	}
+}
+
+// runMatch plays a versus game of two players, sending them the state of the board after every tick,
+// and closes their connections when the game is over. A player leaving the game loses it; a player whose
+// connection drops loses it only if the player doesn't reconnect within ReconnectGrace.
+// The ratings of the players are updated with the result of a ranked match.
+//
+// Parameters:
+// - players ([engine.Players]*player): The players.
+// - seed (int64): The seed of the game.
+// - cells (int): The size of the board.
+// - speed (int): The interval between two ticks, in milliseconds.
+func (s *Server) runMatch(players [engine.Players]*player, seed int64, cells, speed int) {
+	v := engine.NewVersus(seed, cells, speed)
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.name
+	}
+	rejoins := make(chan rejoin, engine.Players)
+	tokens := s.addSeats(rejoins, len(players))
+	live := s.addLive(names, v.BoardSize(), v.Speed)
+	ratings := s.ratings.of(v.Speed, players[:])
+	var stamps [engine.Players]uint64
+	s.mu.Lock()
+	setMates(players[:])
+	for i, p := range players {
+		stamps[i] = p.stamp()
+	}
+	s.mu.Unlock()
+	start := func(i int) {
+		players[i].deliver(stamps[i], Message{
+			Type:    TypeStart,
+			You:     i,
+			Names:   names,
+			Cells:   v.BoardSize(),
+			Speed:   v.Speed,
+			Token:   tokens[i],
+			Ratings: ratings,
+		})
+	}
+	for i := range players {
+		start(i)
+	}
+	acks := make([]int, len(players))
+	//the end of the grace window of a disconnected player; zero while the player is connected
+	var away [engine.Players]time.Time
+	state := stateMessage(v, acks, awayFlags(away))
+	broadcast(players, state)
+	s.broadcastLive(live, state, v.Tick)
+
+	ticker := time.NewTicker(v.Interval())
+	defer ticker.Stop()
+	ticks := metrics.Default.Counter("snake_match_ticks_total", "Ticks played by the matches.")
+	started := time.Now()
+	over := Message{Type: TypeOver}
+	for !v.Over && over.Reason == "" {
+		select {
+		case <-ticker.C:
+		case r := <-rejoins:
+			//the reconnected player takes the seat back, and the turns are counted from the start again
+			old := players[r.index]
+			old.conn.Close()
+			close(old.done)
+			r.p.name, r.p.key = old.name, old.key
+			players[r.index], away[r.index], acks[r.index] = r.p, time.Time{}, 0
+			s.mu.Lock()
+			setMates(players[:])
+			stamps[r.index] = r.p.stamp()
+			s.mu.Unlock()
+			start(r.index)
+			continue
+		}
+		now := time.Now()
+		expired := [engine.Players]bool{}
+		for i, p := range players {
+			if away[i].IsZero() && isClosed(p.gone) {
+				away[i] = now.Add(ReconnectGrace)
+				if p.left.Load() {
+					away[i] = now
+				}
+			}
+			expired[i] = !away[i].IsZero() && !now.Before(away[i])
+		}
+		switch {
+		case expired[0] && expired[1]:
+			over.Winner, over.Reason = engine.Draw, ReasonLeft
+			continue
+		case expired[0]:
+			over.Winner, over.Reason = 1, ReasonLeft
+			continue
+		case expired[1]:
+			over.Winner, over.Reason = 0, ReasonLeft
+			continue
+		}
+		//a single turn per tick: the following ones wait for the next ticks, like in the single-player game;
+		//the snake of a disconnected player gets no turns and moves straight
+		for i, p := range players {
+			select {
+			case turn := <-p.turns:
+				v.Turn(i, turn.Dir)
+				acks[i] = turn.Seq
+			default:
+			}
+		}
+		v.Step()
+		ticks.Inc()
+		state := stateMessage(v, slices.Clone(acks), awayFlags(away))
+		broadcast(players, state)
+		s.broadcastLive(live, state, v.Tick)
+	}
+	if over.Reason == "" {
+		over.Winner = v.Winner
+	}
+	reason := cmp.Or(over.Reason, "finished")
+	metrics.Default.Counter("snake_matches_total", "Matches played, by how they ended: finished or left.", "reason", reason).Inc()
+	metrics.Default.Histogram("snake_match_duration_seconds", "Duration of the matches.", metrics.DurationBuckets).
+		Observe(time.Since(started).Seconds())
+	over.Ratings, over.Deltas = s.ratings.record(v.Speed, players, over.Winner)
+	s.removeSeats(tokens)
+	//a player who has reconnected right before the end gets the result too
+	for len(rejoins) > 0 {
+		r := <-rejoins
+		players[r.index].conn.Close()
+		close(players[r.index].done)
+		players[r.index] = r.p
+	}
+	broadcast(players, over)
+	s.broadcastLive(live, over, v.Tick)
+	s.removeLive(live)
+	for _, p := range players {
+		p.conn.Close()
+		close(p.done)
+	}
+}
+
+// awayFlags returns whether the players are disconnected, from the ends of their grace windows.
+func awayFlags(away [engine.Players]time.Time) []bool {
+	flags := make([]bool, len(away))
+	for i, t := range away {
+		flags[i] = !t.IsZero()
+	}
+	return flags
+}
+
+// broadcast sends the message to all players. Failed writes are ignored: the lost connection
+// is noticed by the receiving goroutine of the player.
+func broadcast(players [engine.Players]*player, m Message) {
+	for _, p := range players {
+		p.conn.WriteJSON(m)
+	}
+}
@@ -0,0 +1,218 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+const (
+	Spectator      = -1 // the index of the player in the start message sent to a spectator
+	spectatorsMax  = 32 // the largest number of spectators of a match
+	spectatorSlack = 64 // the number of messages a spectator may lag behind the delay before it's dropped
+	KeyframeTicks  = 50 // the number of ticks between two full states sent to a spectator; deltas are sent between
+)
+
+// MatchInfo describes a running match in the list of the matches to watch.
+// Fields:
+// - ID: the identifier to spectate the match with.
+// - Names: the names of the players.
+// - Cells: the size of the board.
+// - Speed: the interval between two ticks, in milliseconds.
+// - Tick: the number of ticks played so far.
+// - Spectators: the number of spectators.
+type MatchInfo struct {
+	ID         string   `json:"id"`
+	Names      []string `json:"names"`
+	Cells      int      `json:"cells"`
+	Speed      int      `json:"speed"`
+	Tick       int      `json:"tick"`
+	Spectators int      `json:"spectators"`
+}
+
+// liveMatch is a running match, as listed for the spectators.
+// Fields:
+// - info: the description of the match; guarded by the mutex of the server.
+// - spectators: the spectators of the match; guarded by the mutex of the server.
+// - last: the last state message of the match, the next delta is taken from; guarded by the mutex of the server.
+type liveMatch struct {
+	info       MatchInfo
+	spectators []*spectator
+	last       Message
+}
+
+// spectator is a client watching a match.
+// Fields:
+// - queue: the messages of the match, each sent once the delay has passed since it was queued;
+// closed when the match ends or the spectator lags too far behind.
+type spectator struct {
+	queue chan delayed
+}
+
+// delayed is a message of a match waiting for the delay of the spectators.
+// Fields:
+// - at: when the message has been sent to the players.
+// - m: the message.
+// - delta: for a state message, the delta message from the previous state; nil for the first state.
+// - base: the tick of the previous state, which the delta follows.
+type delayed struct {
+	at    time.Time
+	m     Message
+	delta *Message
+	base  int
+}
+
+// addLive lists a starting match for the spectators.
+//
+// Parameters:
+// - names ([]string): The names of the players.
+// - cells (int): The size of the board.
+// - speed (int): The interval between two ticks, in milliseconds.
+func (s *Server) addLive(names []string, cells, speed int) *liveMatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := newToken()[:8]
+	live := &liveMatch{info: MatchInfo{ID: id, Names: names, Cells: cells, Speed: speed}}
+	s.live[id] = live
+	return live
+}
+
+// broadcastLive queues the message for the spectators of the match, with the delta from the previous state
+// for a state message. A spectator whose queue is full is dropped.
+//
+// Parameters:
+// - live (*liveMatch): The match.
+// - m (Message): The message sent to the players.
+// - tick (int): The number of ticks played so far.
+func (s *Server) broadcastLive(live *liveMatch, m Message, tick int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live.info.Tick = tick
+	d := delayed{at: time.Now(), m: m}
+	if m.Type == TypeState {
+		if delta, ok := deltaMessage(live.last, m); ok && live.last.Type == TypeState {
+			d.delta, d.base = &delta, live.last.Tick
+		}
+		live.last = m
+	}
+	live.spectators = slices.DeleteFunc(live.spectators, func(sp *spectator) bool {
+		select {
+		case sp.queue <- d:
+			return false
+		default:
+			close(sp.queue)
+			return true
+		}
+	})
+}
+
+// removeLive unlists a match that has ended and closes the queues of its spectators, after its last messages.
+func (s *Server) removeLive(live *liveMatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.live, live.info.ID)
+	for _, sp := range live.spectators {
+		close(sp.queue)
+	}
+	live.spectators = nil
+}
+
+// matches returns the running matches, the longest-running first.
+func (s *Server) matches() []MatchInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]MatchInfo, 0, len(s.live))
+	for _, live := range s.live {
+		info := live.info
+		info.Spectators = len(live.spectators)
+		list = append(list, info)
+	}
+	slices.SortFunc(list, func(a, b MatchInfo) int { return cmp.Or(b.Tick-a.Tick, cmp.Compare(a.ID, b.ID)) })
+	return list
+}
+
+// watch sends the player the messages of the match with the identifier, each once the delay of the server
+// has passed, until the match ends or the player leaves. A state is sent as a delta from the previous one,
+// except every KeyframeTicks ticks and when the spectator hasn't got the previous one.
+//
+// Parameters:
+// - p (*player): The spectator.
+// - id (string): The identifier of the match, from the list of the matches.
+func (s *Server) watch(p *player, id string) {
+	s.mu.Lock()
+	live := s.live[id]
+	switch {
+	case live == nil:
+		s.mu.Unlock()
+		p.conn.WriteJSON(Message{Type: TypeError, Reason: ReasonNoMatch})
+		p.conn.Close()
+		return
+	case len(live.spectators) >= spectatorsMax:
+		s.mu.Unlock()
+		p.conn.WriteJSON(Message{Type: TypeError, Reason: ReasonFull})
+		p.conn.Close()
+		return
+	}
+	queued := int(s.delay/(time.Duration(live.info.Speed)*time.Millisecond)) + spectatorSlack
+	sp := &spectator{queue: make(chan delayed, queued)}
+	live.spectators = append(live.spectators, sp)
+	start := Message{
+		Type:  TypeStart,
+		You:   Spectator,
+		Names: live.info.Names,
+		Cells: live.info.Cells,
+		Speed: live.info.Speed,
+		Delay: int(s.delay / time.Millisecond),
+	}
+	s.mu.Unlock()
+	defer p.conn.Close()
+	defer s.dropSpectator(live, sp)
+
+	p.conn.WriteJSON(start)
+	//the ticks of the last state and the last full state sent
+	sent, keyframe := -1, 0
+	for {
+		var d delayed
+		var ok bool
+		select {
+		case d, ok = <-sp.queue:
+			if !ok {
+				return
+			}
+		case <-p.gone:
+			return
+		}
+		if wait := time.Until(d.at.Add(s.delay)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-p.gone:
+				return
+			}
+		}
+		m := d.m
+		if m.Type == TypeState {
+			if d.delta != nil && d.base == sent && m.Tick-keyframe < KeyframeTicks {
+				m = *d.delta
+			} else {
+				keyframe = m.Tick
+			}
+			sent = d.m.Tick
+		}
+		if err := p.conn.WriteJSON(m); err != nil {
+			return
+		}
+	}
+}
+
+// dropSpectator removes the spectator from the match, if it's still there.
+func (s *Server) dropSpectator(live *liveMatch, sp *spectator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live.spectators = slices.DeleteFunc(live.spectators, func(other *spectator) bool { return other == sp })
+}
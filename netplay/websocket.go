@@ -0,0 +1,383 @@
+// Package netplay implements the online versus mode: two players connect to a match server over WebSocket,
+// and the server plays a versus game on a shared board, sending the state of the board to both players after every tick.
+//
+// The server is authoritative: the clients only send the turns of their snakes, so both players always see
+// the same board and the same food. The package has the message schemas, the match server and the client,
+// and a minimal WebSocket implementation (RFC 6455) on top of net/http, so it has no dependencies.
+package netplay
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	acceptGUID   = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11" // the key suffix of the WebSocket handshake, fixed by RFC 6455
+	messageMax   = 64 << 10                               // the largest accepted message, in bytes
+	writeTimeout = 5 * time.Second                        // how long writing a frame may take before the connection is dropped
+)
+
+// the frame opcodes used by the connection
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// ErrHandshake is returned when the WebSocket handshake fails.
+var ErrHandshake = errors.New("websocket handshake failed")
+
+// Conn is a WebSocket connection carrying JSON messages in text frames.
+//
+// Reading isn't safe for concurrent use, but writing is: a goroutine can read while others write.
+// Pings are answered automatically while reading.
+// Fields:
+// - conn: the underlying network connection.
+// - br: the buffered reader of the connection.
+// - client: whether this is the client side, which masks its frames.
+// - wmu: serializes the writes.
+type Conn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool
+	wmu    sync.Mutex
+}
+
+// Upgrade turns an HTTP request into a WebSocket connection. If the request isn't a valid WebSocket
+// handshake, an error is sent to the client.
+//
+// Parameters:
+// - w (http.ResponseWriter): The response writer of the request.
+// - r (*http.Request): The request.
+//
+// Returns:
+// - *Conn: The connection; the caller must close it.
+// - error: ErrHandshake if the request isn't a valid handshake, or an error if the connection can't be taken over.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") ||
+		!headerHas(r.Header, "Upgrade", "websocket") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "expected a websocket handshake", http.StatusBadRequest)
+		return nil, ErrHandshake
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("%w: the connection can't be hijacked", ErrHandshake)
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("error taking over connection: %w", err)
+	}
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err = conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error writing handshake: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// SameOrigin reports whether the WebSocket handshake comes from a page of the same host as the server, or from
+// one of the allowed origins. Browsers send the Origin header with every handshake, but don't stop a page from
+// connecting anywhere, so a server whose messages only its own pages may read must check it. Handshakes without
+// the header come from programs rather than pages, and are accepted.
+//
+// Parameters:
+// - r (*http.Request): The handshake request.
+// - allowed (...string): Other accepted origins, e.g. "https://example.com".
+//
+// Returns:
+// - bool: Whether the handshake may be accepted.
+func SameOrigin(r *http.Request, allowed ...string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(origin, a) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// Dial opens a WebSocket connection to the server.
+//
+// Parameters:
+// - ctx (context.Context): Limits the time connecting and the handshake may take.
+// - rawURL (string): The address of the server, with the ws or wss (TLS) scheme.
+//
+// Returns:
+// - *Conn: The connection; the caller must close it.
+// - error: An error if the address is invalid, the server can't be reached or the handshake fails.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing match server URL: %w", err)
+	}
+	var dialer interface {
+		DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+	}
+	port := "80"
+	switch u.Scheme {
+	case "ws":
+		dialer = &net.Dialer{}
+	case "wss":
+		dialer = &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		port = "443"
+	default:
+		return nil, fmt.Errorf("the match server URL must use ws or wss, not %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to match server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := handshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// handshake sends the handshake request of the client and checks the answer of the server.
+func handshake(conn net.Conn, u *url.URL) (*Conn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("error creating handshake key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Path: u.EscapedPath(), RawQuery: u.RawQuery},
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("error sending handshake: %w", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading handshake: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("%w: %s", ErrHandshake, resp.Status)
+	}
+	return &Conn{conn: conn, br: br, client: true}, nil
+}
+
+// acceptKey returns the key the server answers the handshake key of the client with.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHas reports whether a comma-separated header contains the token, ignoring the case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadJSON reads the next message and decodes it into v.
+//
+// Returns:
+// - error: io.EOF if the other side has closed the connection, or a reading or decoding error.
+func (c *Conn) ReadJSON(v any) error {
+	data, err := c.read()
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error decoding message: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON encodes v and sends it as a single text message.
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding message: %w", err)
+	}
+	return c.writeFrame(opText, data)
+}
+
+// Close sends a close frame, if the connection is still open, and closes the connection.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, nil)
+	return c.conn.Close()
+}
+
+// read reads the frames of the next data message, answering the control frames between them.
+func (c *Conn) read() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err = c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeFrame(opClose, nil)
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			if (op == opContinuation) == (msg == nil) {
+				return nil, errors.New("websocket: unexpected continuation frame")
+			}
+			if len(msg)+len(payload) > messageMax {
+				return nil, errors.New("websocket: message too large")
+			}
+			if msg == nil {
+				msg = []byte{}
+			}
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+//
+// Returns:
+// - bool: Whether this is the final frame of a message.
+// - byte: The opcode of the frame.
+// - []byte: The payload.
+// - error: A reading or protocol error.
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op := head[0]&0x80 != 0, head[0]&0x0F
+	masked, n := head[1]&0x80 != 0, uint64(head[1]&0x7F)
+	if masked == c.client {
+		return false, 0, nil, errors.New("websocket: invalid frame masking")
+	}
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > messageMax {
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame writes a single final frame, masking it on the client side.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	frame := []byte{0x80 | op}
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return fmt.Errorf("error creating frame mask: %w", err)
+		}
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(frame); err != nil {
+		return fmt.Errorf("error writing websocket frame: %w", err)
+	}
+	return nil
+}
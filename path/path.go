@@ -0,0 +1,326 @@
+// Package path finds the shortest safe paths over the board of a game, for the bots, the hints and anything else
+// that needs to know how the snake can get somewhere.
+//
+// The board is described by a Grid: its walls, the obstacles and the cells of the snake's body. The body is
+// not a fixed obstacle: the tail leaves a cell on every tick, so a segment blocks its cell only until the tail
+// has passed it. A path may therefore go through a cell the body still occupies, if the snake gets there after
+// its tail has left. Paths are searched with A* (Find) or breadth-first (BFS and Distances); all of them move
+// one cell per tick and never stand still, like the snake.
+package path
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// forever is the tick from which a cell blocked for good is free.
+const forever = math.MaxInt
+
+// dirs are the directions tried from every cell, in the order paths of equal length are preferred.
+var dirs = []engine.Dir{engine.Up, engine.Right, engine.Down, engine.Left}
+
+// Grid is the board a path is searched on: for every cell, the tick from which the snake may enter it.
+// Fields:
+// - cells: the number of cells along each side of the board.
+// - freeAt: the tick from which each cell is free, by index; 0 for a free cell, forever for an obstacle.
+type Grid struct {
+	cells  int
+	freeAt []int
+}
+
+// NewGrid creates an empty board.
+//
+// Parameters:
+// - cells (int): The number of cells along each side of the board.
+//
+// Returns:
+// - *Grid: The board, without any obstacles.
+func NewGrid(cells int) *Grid {
+	cells = max(cells, 0)
+	return &Grid{cells: cells, freeAt: make([]int, cells*cells)}
+}
+
+// FromState creates the board of a game: the snake's body blocks its cells until the tail has left them
+// (see engine.State.EnterableAt). The head is where the paths start, so it doesn't block its cell.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+//
+// Returns:
+// - *Grid: The board.
+func FromState(s engine.State) *Grid {
+	g := NewGrid(s.Cells)
+	for i := 1; i < len(s.Snake); i++ {
+		g.BlockUntil(s.Snake[i], s.EnterableAt(i))
+	}
+	return g
+}
+
+// Cells returns the number of cells along each side of the board.
+func (g *Grid) Cells() int {
+	return g.cells
+}
+
+// Block blocks a cell for good, e.g. with an obstacle. Cells outside the board are ignored.
+//
+// Parameters:
+// - p (engine.Point): The cell.
+func (g *Grid) Block(p engine.Point) {
+	g.BlockUntil(p, forever)
+}
+
+// BlockUntil blocks a cell until the given tick, e.g. for a part of the body the tail will leave.
+// A cell stays blocked until the latest tick it has been blocked until. Cells outside the board are ignored.
+//
+// Parameters:
+// - p (engine.Point): The cell.
+// - tick (int): The tick from which the cell is free, counted from the start of the path: a path can enter
+// the cell with its tick-th step.
+func (g *Grid) BlockUntil(p engine.Point, tick int) {
+	if i, ok := g.index(p); ok {
+		g.freeAt[i] = max(g.freeAt[i], tick)
+	}
+}
+
+// Free reports whether the snake may enter a cell with the given step of a path: the cell is on the board,
+// and nothing blocks it at that time.
+//
+// Parameters:
+// - p (engine.Point): The cell.
+// - step (int): The number of the step entering the cell, from 1.
+func (g *Grid) Free(p engine.Point, step int) bool {
+	i, ok := g.index(p)
+	return ok && g.freeAt[i] <= step
+}
+
+// index returns the index of a cell in freeAt.
+//
+// Returns:
+// - int: The index.
+// - bool: False if the cell is outside the board.
+func (g *Grid) index(p engine.Point) (int, bool) {
+	if engine.CollidesWithWall(p, g.cells) || p != (engine.Point{X: math.Trunc(p.X), Y: math.Trunc(p.Y)}) {
+		return 0, false
+	}
+	return int(p.Y)*g.cells + int(p.X), true
+}
+
+// point returns the cell of an index in freeAt.
+func (g *Grid) point(i int) engine.Point {
+	return engine.Point{X: float64(i % g.cells), Y: float64(i / g.cells)}
+}
+
+// Find returns the shortest safe path from one cell to another, searched with A*.
+//
+// Parameters:
+// - g (*Grid): The board.
+// - from (engine.Point): The cell the path starts in, usually the head of the snake.
+// - to (engine.Point): The target cell, e.g. the food.
+//
+// Returns:
+// - []engine.Point: The cells of the path in order, without from and with to.
+// - bool: False if there's no safe path, or from is to.
+func Find(g *Grid, from, to engine.Point) ([]engine.Point, bool) {
+	start, ok := g.index(from)
+	if !ok || from == to {
+		return nil, false
+	}
+	if _, ok = g.index(to); !ok {
+		return nil, false
+	}
+	steps := make([]int, len(g.freeAt))
+	for i := range steps {
+		steps[i] = -1
+	}
+	steps[start] = 0
+	prev := make([]int, len(g.freeAt))
+	open := &queue{{cell: start, cost: distance(from, to)}}
+	for seq := 1; open.Len() > 0; {
+		n := heap.Pop(open).(node)
+		p := g.point(n.cell)
+		if p == to {
+			return trace(g, prev, start, n.cell), true
+		}
+		if n.cost-distance(p, to) > steps[n.cell] {
+			continue //a shorter way to the cell has been found since
+		}
+		step := steps[n.cell] + 1
+		for _, dir := range dirs {
+			next := dir.Exec(p)
+			if !g.Free(next, step) {
+				continue
+			}
+			i, _ := g.index(next)
+			if steps[i] >= 0 && steps[i] <= step {
+				continue
+			}
+			steps[i], prev[i] = step, n.cell
+			heap.Push(open, node{cell: i, cost: step + distance(next, to), seq: seq})
+			seq++
+		}
+	}
+	return nil, false
+}
+
+// BFS returns the shortest safe path from one cell to another, searched breadth-first. It finds a path
+// as short as the one of Find, but explores more of the board to find it.
+//
+// Parameters:
+// - g (*Grid): The board.
+// - from (engine.Point): The cell the path starts in, usually the head of the snake.
+// - to (engine.Point): The target cell, e.g. the food.
+//
+// Returns:
+// - []engine.Point: The cells of the path in order, without from and with to.
+// - bool: False if there's no safe path, or from is to.
+func BFS(g *Grid, from, to engine.Point) ([]engine.Point, bool) {
+	start, ok := g.index(from)
+	if !ok {
+		return nil, false
+	}
+	target, ok := g.index(to)
+	if !ok {
+		return nil, false
+	}
+	prev := make([]int, len(g.freeAt))
+	dist := g.search(start, func(cell, from int) bool {
+		prev[cell] = from
+		return cell == target
+	})
+	if dist[target] < 0 || target == start {
+		return nil, false
+	}
+	return trace(g, prev, start, target), true
+}
+
+// Distances returns the length of the shortest safe path from a cell to every cell of the board.
+//
+// Parameters:
+// - g (*Grid): The board.
+// - from (engine.Point): The cell the paths start in.
+//
+// Returns:
+// - []int: The lengths by cell, indexed by y*cells+x; -1 for the cells that can't be reached and for from itself.
+func Distances(g *Grid, from engine.Point) []int {
+	start, ok := g.index(from)
+	if !ok {
+		dist := make([]int, len(g.freeAt))
+		for i := range dist {
+			dist[i] = -1
+		}
+		return dist
+	}
+	return g.search(start, func(int, int) bool { return false })
+}
+
+// search runs a breadth-first search from a cell, calling visit with every cell reached, and the cell
+// it has been reached from, until visit returns true.
+//
+// Returns:
+// - []int: The lengths of the shortest paths to the cells reached, by index; -1 for the others and for start.
+func (g *Grid) search(start int, visit func(cell, from int) bool) []int {
+	dist := make([]int, len(g.freeAt))
+	for i := range dist {
+		dist[i] = -1
+	}
+	seen := make([]bool, len(g.freeAt))
+	seen[start] = true
+	frontier := []int{start}
+	for step := 1; len(frontier) > 0; step++ {
+		var next []int
+		for _, cell := range frontier {
+			p := g.point(cell)
+			for _, dir := range dirs {
+				n := dir.Exec(p)
+				if !g.Free(n, step) {
+					continue
+				}
+				i, _ := g.index(n)
+				if seen[i] {
+					continue
+				}
+				seen[i], dist[i] = true, step
+				if visit(i, cell) {
+					return dist
+				}
+				next = append(next, i)
+			}
+		}
+		frontier = next
+	}
+	return dist
+}
+
+// trace walks the path back from its end to its start.
+func trace(g *Grid, prev []int, start, end int) []engine.Point {
+	var path []engine.Point
+	for cell := end; cell != start; cell = prev[cell] {
+		path = append(path, g.point(cell))
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// Direction returns the direction of the first step of a path.
+//
+// Parameters:
+// - from (engine.Point): The cell the path starts in.
+// - path ([]engine.Point): The path, e.g. from Find.
+//
+// Returns:
+// - engine.Dir: The direction leading from from to the first cell of the path.
+// - bool: False if the path is empty or doesn't start next to from.
+func Direction(from engine.Point, path []engine.Point) (engine.Dir, bool) {
+	if len(path) == 0 {
+		return 0, false
+	}
+	for _, dir := range dirs {
+		if dir.Exec(from) == path[0] {
+			return dir, true
+		}
+	}
+	return 0, false
+}
+
+// distance returns the Manhattan distance between two cells: the length of the shortest path on an empty board,
+// which A* uses as its estimate.
+func distance(a, b engine.Point) int {
+	return int(math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y))
+}
+
+// node is a cell on the open list of A*.
+// Fields:
+// - cell: the index of the cell.
+// - cost: the length of the path to the cell plus the estimated distance to the target.
+// - seq: the order the cell has been added in, so of two cells of equal cost the older one is explored first.
+type node struct {
+	cell int
+	cost int
+	seq  int
+}
+
+// queue is the open list of A*, a priority queue of nodes, the cheapest first.
+type queue []node
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) { *q = append(*q, x.(node)) }
+
+func (q *queue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,44 @@
+// Package player defines who controls a snake: a Player chooses the direction of the snake before every tick.
+//
+// The game accepts any Player for the snake the player controls, whether it's steered by the keyboard (Keyboard)
+// or by an AI (Greedy), so the same rules and the same loop drive the games of humans and bots alike:
+// the headless simulations, a demo played by the computer or a tournament between bots.
+package player
+
+import (
+	"math"
+	"slices"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Greedy is a simple AI: it heads for the food by the shortest way, avoiding the walls and, when possible,
+// the snake's own body. It doesn't plan ahead, so it regularly traps itself.
+type Greedy struct{}
+
+// NextMove chooses the direction leading closest to the food.
+func (Greedy) NextMove(s engine.State) engine.Dir {
+	if len(s.Snake) == 0 {
+		return s.Direction
+	}
+	head := s.Snake[0]
+	current := s.Direction
+	bestDir, bestCost := current, math.Inf(1)
+	for _, dir := range []engine.Dir{engine.Up, engine.Right, engine.Down, engine.Left} {
+		if dir != current && current.CheckParallel(dir) {
+			continue
+		}
+		next := dir.Exec(head)
+		if engine.CollidesWithWall(next, s.Cells) {
+			continue
+		}
+		cost := math.Abs(next.X-s.Food.X) + math.Abs(next.Y-s.Food.Y)
+		if slices.Contains(s.Snake, next) {
+			cost += float64(2 * s.Cells)
+		}
+		if cost < bestCost {
+			bestDir, bestCost = dir, cost
+		}
+	}
+	return bestDir
+}
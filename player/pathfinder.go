@@ -0,0 +1,28 @@
+// Package player defines who controls a snake: a Player chooses the direction of the snake before every tick.
+//
+// The game accepts any Player for the snake the player controls, whether it's steered by the keyboard (Keyboard)
+// or by an AI (Greedy), so the same rules and the same loop drive the games of humans and bots alike:
+// the headless simulations, a demo played by the computer or a tournament between bots.
+package player
+
+import (
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/path"
+)
+
+// Pathfinder is an AI that follows the shortest safe path to the food, around its own body. When the body
+// cuts it off from the food, it plays like Greedy until a path opens.
+type Pathfinder struct{}
+
+// NextMove chooses the first step of the shortest safe path to the food.
+func (Pathfinder) NextMove(s engine.State) engine.Dir {
+	if len(s.Snake) == 0 {
+		return s.Direction
+	}
+	if p, ok := path.Find(path.FromState(s), s.Snake[0], s.Food); ok {
+		if dir, ok := path.Direction(s.Snake[0], p); ok {
+			return dir
+		}
+	}
+	return Greedy{}.NextMove(s)
+}
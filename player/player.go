@@ -0,0 +1,119 @@
+// Package player defines who controls a snake: a Player chooses the direction of the snake before every tick.
+//
+// The game accepts any Player for the snake the player controls, whether it's steered by the keyboard (Keyboard)
+// or by an AI (Greedy), so the same rules and the same loop drive the games of humans and bots alike:
+// the headless simulations, a demo played by the computer or a tournament between bots.
+package player
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Player chooses the moves of a snake.
+//
+// NextMove is called before every tick with the state of the game and returns the direction the snake should move in
+// during the tick; returning the current direction keeps the snake going straight. A direction the rules don't allow,
+// such as reversing, is rejected by the engine and the snake keeps its direction.
+type Player interface {
+	NextMove(s engine.State) engine.Dir
+}
+
+// Func adapts an ordinary function to the Player interface.
+type Func func(s engine.State) engine.Dir
+
+// NextMove calls f.
+func (f Func) NextMove(s engine.State) engine.Dir {
+	return f(s)
+}
+
+// Keyboard is the Player of a human: it plays the turns pressed on the keyboard since the last tick.
+//
+// Like the engine, it accepts a single turn per tick, so two quick key presses can't make the snake reverse,
+// and it rejects a turn opposite to the direction of the snake right away, so the game can tell the player at once.
+// It's safe for concurrent use: the keys are pressed on the main thread while the game logic asks for the moves.
+// Fields:
+// - mu: guards pending and pressed.
+// - pending: the direction pressed since the last tick.
+// - pressed: whether a direction has been pressed since the last tick.
+type Keyboard struct {
+	mu      sync.Mutex
+	pending engine.Dir
+	pressed bool
+}
+
+// NewKeyboard creates the Player of a human.
+func NewKeyboard() *Keyboard {
+	return &Keyboard{}
+}
+
+// Press records a turn pressed on the keyboard, to be played on the next tick.
+//
+// Parameters:
+// - dir (engine.Dir): The direction pressed.
+// - current (engine.Dir): The direction the snake moves in.
+//
+// Returns:
+// - bool: False if the turn is rejected, because it reverses the snake or another turn is already pending.
+func (k *Keyboard) Press(dir, current engine.Dir) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.pressed || current.CheckParallel(dir) {
+		return false
+	}
+	k.pending, k.pressed = dir, true
+	return true
+}
+
+// Pending returns the turn pressed since the last tick, e.g. to show where the snake is about to move.
+//
+// Returns:
+// - engine.Dir: The direction pressed.
+// - bool: Whether a turn is pending.
+func (k *Keyboard) Pending() (engine.Dir, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pending, k.pressed
+}
+
+// NextMove plays the turn pressed since the last tick, if any.
+func (k *Keyboard) NextMove(s engine.State) engine.Dir {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.pressed {
+		return s.Direction
+	}
+	k.pressed = false
+	return k.pending
+}
+
+// builtin are the AI players built into the game, by name.
+var builtin = map[string]func() Player{
+	"greedy": func() Player { return Greedy{} },
+	"path":   func() Player { return Pathfinder{} },
+}
+
+// New creates a built-in AI player.
+//
+// Parameters:
+// - name (string): The name of the player, one of Names.
+//
+// Returns:
+// - Player: The player.
+// - error: An error if there's no player of that name.
+func New(name string) (Player, error) {
+	newPlayer, ok := builtin[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown player %q, expected one of %v", name, Names())
+	}
+	return newPlayer(), nil
+}
+
+// Names returns the names of the built-in AI players, sorted.
+func Names() []string {
+	return slices.Sorted(maps.Keys(builtin))
+}
@@ -0,0 +1,105 @@
+// Package replay contains the replay file format of the Snake game and the functions for re-simulating
+// recorded games with the engine.
+//
+// The engine is deterministic, so a replay only stores the seed and the rules of the game (the board size,
+// its terrain, the wind and the ticks the beginner assist was switched at) and the directions chosen by the player together
+// with the ticks they were chosen at; everything else is re-created by simulation.
+package replay
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Recorder records a game while it's played.
+//
+// The inputs are recorded by the input handlers and the result by the game logic, so a recorder
+// is safe for concurrent use.
+type Recorder struct {
+	mu sync.Mutex
+	r  Replay
+}
+
+// NewRecorder starts recording the game of the engine, which must have just been started.
+//
+// Parameters:
+// - e (*engine.Engine): The engine running the game.
+// - gameVersion (string): The version of the game.
+//
+// Returns:
+// - *Recorder: The recorder.
+func NewRecorder(e *engine.Engine, gameVersion string) *Recorder {
+	rec := &Recorder{r: Replay{GameVersion: gameVersion, Seed: e.Seed(), Cells: e.BoardSize(), Terrain: e.Terrain(), Wind: e.Wind}}
+	if e.Assist {
+		rec.r.AssistToggles = []int{0}
+	}
+	return rec
+}
+
+// Continue resumes recording a game from the replay recorded so far, e.g. when a saved game is loaded.
+//
+// Parameters:
+// - r (*Replay): The replay recorded so far.
+//
+// Returns:
+// - *Recorder: The recorder.
+func Continue(r *Replay) *Recorder {
+	rec := &Recorder{r: *r}
+	rec.r.Inputs = slices.Clone(r.Inputs)
+	rec.r.AssistToggles = slices.Clone(r.AssistToggles)
+	return rec
+}
+
+// Turn records a direction change accepted by the engine.
+//
+// Parameters:
+// - tick (int): The number of ticks played before the direction was changed.
+// - dir (engine.Dir): The new direction.
+func (rec *Recorder) Turn(tick int, dir engine.Dir) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.Inputs = append(rec.r.Inputs, Input{Tick: tick, Dir: dir})
+}
+
+// ToggleAssist records that the beginner assist has been switched on or off.
+//
+// Parameters:
+// - tick (int): The number of ticks played before the assist was switched.
+func (rec *Recorder) ToggleAssist(tick int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.AssistToggles = append(rec.r.AssistToggles, tick)
+}
+
+// Rewind removes the inputs recorded from the given tick on, when the game is rewound to it, e.g. to undo a move:
+// the replay then shows the game as it continues from there.
+//
+// Parameters:
+// - tick (int): The number of ticks played in the state the game has been rewound to.
+func (rec *Recorder) Rewind(tick int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.r.Inputs = slices.DeleteFunc(rec.r.Inputs, func(in Input) bool { return in.Tick >= tick })
+	rec.r.AssistToggles = slices.DeleteFunc(rec.r.AssistToggles, func(t int) bool { return t >= tick })
+}
+
+// Replay returns a copy of the recording with the current state of the game as its result.
+//
+// Parameters:
+// - e (*engine.Engine): The engine running the game.
+//
+// Returns:
+// - *Replay: The recorded game.
+func (rec *Recorder) Replay(e *engine.Engine) *Replay {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	r := rec.r
+	r.Inputs = slices.Clone(rec.r.Inputs)
+	r.AssistToggles = slices.Clone(rec.r.AssistToggles)
+	r.Ticks = e.Tick
+	r.Score = e.Score
+	r.Length = len(e.Snake.Parts)
+	return &r
+}
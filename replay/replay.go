@@ -0,0 +1,375 @@
+// Package replay contains the replay file format of the Snake game and the functions for re-simulating
+// recorded games with the engine.
+//
+// The engine is deterministic, so a replay only stores the seed and the rules of the game (the board size,
+// its terrain, the wind and the ticks the beginner assist was switched at) and the directions chosen by the player together
+// with the ticks they were chosen at; everything else is re-created by simulation.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+const (
+	magic   = "SNKR" // the first bytes of every replay file
+	version = 5      // the current version of the replay format
+)
+
+// ErrFormat is returned when the data isn't a valid replay.
+var ErrFormat = errors.New("invalid replay format")
+
+// ErrMismatch is returned when the re-simulated game doesn't end with the result recorded in the replay,
+// because the replay is corrupted or has been tampered with, or the rules have changed since it was recorded.
+var ErrMismatch = errors.New("replay doesn't match its recorded result")
+
+// Input is a single direction change made by the player.
+// Fields:
+// - Tick: the number of ticks played before the direction was changed.
+// - Dir: the new direction.
+type Input struct {
+	Tick int
+	Dir  engine.Dir
+}
+
+// Replay is a recorded game.
+// Fields:
+// - Seed: the seed the game was started from.
+// - Cells: the size of the board the game was played on.
+// - Inputs: the direction changes in the order they were made.
+// - Ticks: the number of ticks the game lasted.
+// - Score: the final score, used to check that the re-simulation matches the original game.
+// - Length: the final length of the snake.
+// - GameVersion: the version of the game the replay was recorded with, empty for replays of format version 1.
+// - AssistToggles: the ticks the beginner assist was switched on or off at, in order; the assist is off at the start.
+// - Terrain: the special tiles of the board; empty for a plain board and for replays of format versions 1 to 3.
+// - Wind: whether gusts of wind blew during the game; always false for replays of format versions 1 to 4.
+type Replay struct {
+	GameVersion   string
+	Seed          int64
+	Cells         int
+	Inputs        []Input
+	Ticks         int
+	Score         int
+	Length        int
+	AssistToggles []int
+	Terrain       engine.Terrain
+	Wind          bool
+}
+
+// Load reads a replay from the file at the given path.
+//
+// Parameters:
+// - path (string): The path to the replay file.
+//
+// Returns:
+// - *Replay: The loaded replay.
+// - error: An error if the file cannot be read or isn't a valid replay.
+func Load(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading replay %s: %w", path, err)
+	}
+	r, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding replay %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the replay to the file at the given path, creating the directory if needed.
+//
+// Parameters:
+// - path (string): The path to the replay file.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (r *Replay) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing replay %s: %w", path, err)
+	}
+	return nil
+}
+
+// Encode writes the replay in the binary replay format.
+//
+// The format is compact: after the magic bytes, the format version and the game version, all numbers are stored as
+// variable-length integers, and every input takes two or three bytes (the number of ticks since
+// the previous input and the direction). The assist toggles follow the inputs, stored as the numbers of ticks
+// since the previous toggle, and the rows of the terrain follow them, each stored as its length and its tiles.
+// The last byte tells whether the wind blew.
+//
+// Parameters:
+// - w (io.Writer): The destination of the encoded replay.
+//
+// Returns:
+// - error: An error if writing fails; otherwise, nil.
+func (r *Replay) Encode(w io.Writer) error {
+	buf := []byte(magic)
+	buf = binary.AppendUvarint(buf, version)
+	buf = binary.AppendUvarint(buf, uint64(len(r.GameVersion)))
+	buf = append(buf, r.GameVersion...)
+	buf = binary.AppendVarint(buf, r.Seed)
+	for _, v := range []int{r.Cells, r.Ticks, r.Score, r.Length, len(r.Inputs)} {
+		buf = binary.AppendUvarint(buf, uint64(v))
+	}
+	prev := 0
+	for _, in := range r.Inputs {
+		buf = binary.AppendUvarint(buf, uint64(in.Tick-prev))
+		buf = append(buf, byte(in.Dir))
+		prev = in.Tick
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(r.AssistToggles)))
+	prev = 0
+	for _, tick := range r.AssistToggles {
+		buf = binary.AppendUvarint(buf, uint64(tick-prev))
+		prev = tick
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(r.Terrain)))
+	for _, row := range r.Terrain {
+		buf = binary.AppendUvarint(buf, uint64(len(row)))
+		buf = append(buf, row...)
+	}
+	wind := byte(0)
+	if r.Wind {
+		wind = 1
+	}
+	buf = append(buf, wind)
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("error writing replay: %w", err)
+	}
+	return nil
+}
+
+// Decode reads a replay in the binary replay format.
+//
+// Parameters:
+// - rd (io.Reader): The source of the encoded replay.
+//
+// Returns:
+// - *Replay: The decoded replay.
+// - error: ErrFormat if the data isn't a valid replay, or a reading error.
+func Decode(rd io.Reader) (*Replay, error) {
+	br := bufio.NewReader(rd)
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, head); err != nil || string(head) != magic {
+		return nil, ErrFormat
+	}
+	v, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	if v < 1 || v > version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrFormat, v)
+	}
+	r := &Replay{}
+	if v >= 2 {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > 256 {
+			return nil, ErrFormat
+		}
+		gv := make([]byte, n)
+		if _, err = io.ReadFull(br, gv); err != nil {
+			return nil, ErrFormat
+		}
+		r.GameVersion = string(gv)
+	}
+	if r.Seed, err = binary.ReadVarint(br); err != nil {
+		return nil, ErrFormat
+	}
+	var count int
+	for _, dst := range []*int{&r.Cells, &r.Ticks, &r.Score, &r.Length, &count} {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		*dst = int(n)
+	}
+	tick := 0
+	r.Inputs = make([]Input, 0, count)
+	for i := 0; i < count; i++ {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		dir, err := br.ReadByte()
+		if err != nil || engine.Dir(dir) > engine.Left {
+			return nil, ErrFormat
+		}
+		tick += int(delta)
+		r.Inputs = append(r.Inputs, Input{Tick: tick, Dir: engine.Dir(dir)})
+	}
+	if v >= 3 {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > uint64(r.Ticks)+1 {
+			return nil, ErrFormat
+		}
+		tick = 0
+		for i := uint64(0); i < n; i++ {
+			delta, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, ErrFormat
+			}
+			tick += int(delta)
+			r.AssistToggles = append(r.AssistToggles, tick)
+		}
+	}
+	if v >= 4 {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > uint64(r.Cells) {
+			return nil, ErrFormat
+		}
+		for i := uint64(0); i < n; i++ {
+			size, err := binary.ReadUvarint(br)
+			if err != nil || size > uint64(r.Cells) {
+				return nil, ErrFormat
+			}
+			row := make([]byte, size)
+			if _, err = io.ReadFull(br, row); err != nil {
+				return nil, ErrFormat
+			}
+			r.Terrain = append(r.Terrain, string(row))
+		}
+		if r.Terrain.Validate() != nil {
+			return nil, ErrFormat
+		}
+	}
+	if v >= 5 {
+		wind, err := br.ReadByte()
+		if err != nil || wind > 1 {
+			return nil, ErrFormat
+		}
+		r.Wind = wind == 1
+	}
+	return r, nil
+}
+
+// Play re-simulates the recorded game with a new engine.
+//
+// The frame function is called once with the initial state and then after every tick,
+// which lets the caller render or inspect the game as it unfolds.
+//
+// Parameters:
+// - frame (func(*engine.Engine)): The function called for every state of the game; may be nil.
+//
+// Returns:
+// - *engine.Engine: The engine in the final state of the game.
+// - error: An error if the replay was recorded on a board the engine doesn't support.
+func (r *Replay) Play(frame func(e *engine.Engine)) (*engine.Engine, error) {
+	p, err := NewPlayer(r)
+	if err != nil {
+		return nil, err
+	}
+	if frame == nil {
+		frame = func(*engine.Engine) {}
+	}
+	frame(p.Engine())
+	for !p.Done() {
+		p.Step()
+		frame(p.Engine())
+	}
+	return p.Engine(), nil
+}
+
+// Check compares the final state of a re-simulated game with the result recorded in the replay.
+//
+// Parameters:
+// - e (*engine.Engine): The engine in the final state of the re-simulated game.
+//
+// Returns:
+// - error: An error wrapping ErrMismatch if the tick, the score or the length of the snake differ; otherwise, nil.
+func (r *Replay) Check(e *engine.Engine) error {
+	if e.Tick != r.Ticks || e.Score != r.Score || len(e.Snake.Parts) != r.Length {
+		return fmt.Errorf("%w: recorded score %d and length %d at tick %d, re-simulated score %d and length %d at tick %d",
+			ErrMismatch, r.Score, r.Length, r.Ticks, e.Score, len(e.Snake.Parts), e.Tick)
+	}
+	return nil
+}
+
+// Verify re-simulates the recorded game headlessly and checks that it ends with the recorded result,
+// so a score is only accepted if it can actually be played with the recorded inputs.
+//
+// Returns:
+// - error: An error if the replay can't be played or doesn't match its result; otherwise, nil.
+func (r *Replay) Verify() error {
+	e, err := r.Play(nil)
+	if err != nil {
+		return err
+	}
+	return r.Check(e)
+}
+
+// Player re-simulates a recorded game one tick at a time, so it can run in lockstep with a game being played.
+// Fields:
+// - r: the replay being played.
+// - e: the engine re-simulating the game.
+// - next: the index of the next input to apply.
+// - nextToggle: the index of the next assist toggle to apply.
+type Player struct {
+	r          *Replay
+	e          *engine.Engine
+	next       int
+	nextToggle int
+}
+
+// NewPlayer creates a player with a new engine at the start of the recorded game.
+//
+// Parameters:
+// - r (*Replay): The replay to play.
+//
+// Returns:
+// - *Player: The player.
+// - error: An error if the replay was recorded on a board the engine doesn't support.
+func NewPlayer(r *Replay) (*Player, error) {
+	if r.Cells < engine.MinCells {
+		return nil, fmt.Errorf("replay board size %d isn't supported", r.Cells)
+	}
+	e := engine.NewSized(r.Seed, r.Cells)
+	if err := e.SetTerrain(r.Terrain); err != nil {
+		return nil, err
+	}
+	e.Wind = r.Wind
+	return &Player{r: r, e: e}, nil
+}
+
+// Engine returns the engine re-simulating the game.
+func (p *Player) Engine() *engine.Engine {
+	return p.e
+}
+
+// Done reports whether the recorded game has been played to its end.
+func (p *Player) Done() bool {
+	return p.e.GameOver || p.e.Tick >= p.r.Ticks
+}
+
+// Step plays a single tick of the recorded game, applying the inputs chosen before it. It does nothing once
+// the game has been played to its end.
+func (p *Player) Step() {
+	if p.Done() {
+		return
+	}
+	for p.nextToggle < len(p.r.AssistToggles) && p.r.AssistToggles[p.nextToggle] <= p.e.Tick {
+		p.e.Assist = !p.e.Assist
+		p.nextToggle++
+	}
+	for p.next < len(p.r.Inputs) && p.r.Inputs[p.next].Tick <= p.e.Tick {
+		p.e.Turn(p.r.Inputs[p.next].Dir)
+		p.next++
+	}
+	p.e.Step()
+}
@@ -0,0 +1,456 @@
+// Package restapi serves headless games over a small JSON API, so web frontends, classroom exercises and scripts
+// can play the game with nothing but HTTP.
+//
+// A client creates a game, sends moves that turn the snake and advance the game by a number of ticks, reads
+// the state of the game at any time and fetches the result once the game is over. Unlike the gRPC bot API,
+// every request is independent, so the API can be used from a browser or with curl.
+package restapi
+
+import (
+	"cmp"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/wire"
+)
+
+const (
+	CellsMax    = 60               // the largest board a game can be played on
+	GamesMax    = 1000             // the default largest number of games kept at once
+	idleMax     = 30 * time.Minute // how long a game is kept after its last request
+	bodyMax     = 4 << 10          // the largest accepted request body, in bytes
+	ticksMax    = 1000             // the largest number of ticks a single move advances the game by
+	sweepPeriod = time.Minute      // how often the idle games are looked for
+)
+
+// Point is a cell of the board; (0, 0) is the top left cell, and y grows downwards, as on the screen.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// State is the state of a game, as answered to the clients.
+// Fields:
+// - ID: the identifier of the game, used in the paths of its requests.
+// - Seed: the seed the game was started from; the same seed and moves always lead to the same game.
+// - Cells: the number of cells along each side of the board.
+// - Tick: the number of ticks played.
+// - Snake: the segments of the snake, the head first.
+// - Dir: the direction the snake moves in: "up", "right", "down" or "left".
+// - Food: the position of the food.
+// - Score: the current score.
+// - Length: the length of the snake.
+// - Speed: the interval between two ticks in the game with a window, in milliseconds.
+// - GameOver: whether the game has ended.
+type State struct {
+	ID       string  `json:"id"`
+	Seed     int64   `json:"seed"`
+	Cells    int     `json:"cells"`
+	Tick     int     `json:"tick"`
+	Snake    []Point `json:"snake"`
+	Dir      string  `json:"dir"`
+	Food     Point   `json:"food"`
+	Score    int     `json:"score"`
+	Length   int     `json:"length"`
+	Speed    int     `json:"speed"`
+	GameOver bool    `json:"game_over"`
+}
+
+// NewGame is the body of a request creating a game; both fields are optional.
+// Fields:
+// - Seed: the seed of the game; 0 for a random one.
+// - Cells: the size of the board; 0 for the default one of the server.
+type NewGame struct {
+	Seed  int64 `json:"seed,omitempty"`
+	Cells int   `json:"cells,omitempty"`
+}
+
+// Move is the body of a request playing a move.
+// Fields:
+// - Dir: the new direction of the snake: "up", "right", "down" or "left"; empty to keep the direction.
+// - Ticks: the number of ticks the game is advanced by; 0 means 1. The game stops advancing once it's over.
+type Move struct {
+	Dir   string `json:"dir,omitempty"`
+	Ticks int    `json:"ticks,omitempty"`
+}
+
+// MoveResult is the answer to a move.
+// Fields:
+// - Turned: whether the snake has turned; a turn back into the snake is rejected.
+// - Played: the number of ticks played.
+// - Ate: the number of food items eaten during the move.
+// - Cut: the number of times the snake has bitten itself during the move.
+// - Died: whether the snake has hit a wall during the move.
+// - Won: whether the snake has filled the whole board during the move, which ends the game with a win.
+// - State: the state of the game after the move.
+type MoveResult struct {
+	Turned bool  `json:"turned"`
+	Played int   `json:"played"`
+	Ate    int   `json:"ate"`
+	Cut    int   `json:"cut"`
+	Died   bool  `json:"died"`
+	Won    bool  `json:"won,omitempty"`
+	State  State `json:"state"`
+}
+
+// Result is the result of a finished game.
+// Fields:
+// - ID, Seed, Cells: the same as in State.
+// - Score: the final score.
+// - Length: the final length of the snake.
+// - Food: the number of food items eaten.
+// - Ticks: the number of ticks played.
+// - Duration: the game time the game would have lasted in the window, in milliseconds.
+type Result struct {
+	ID       string `json:"id"`
+	Seed     int64  `json:"seed"`
+	Cells    int    `json:"cells"`
+	Score    int    `json:"score"`
+	Length   int    `json:"length"`
+	Food     int    `json:"food"`
+	Ticks    int    `json:"ticks"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// game is a game kept by the server.
+// Fields:
+// - mu: guards e and used; the moves of a game are played one at a time.
+// - e: the engine running the game.
+// - used: when the game was last requested.
+type game struct {
+	mu   sync.Mutex
+	e    *engine.Engine
+	used time.Time
+}
+
+// Server serves headless games over the JSON API:
+//   - POST /games creates a game from a NewGame body and answers with its State.
+//   - GET /games/{id} answers with the State of the game.
+//   - POST /games/{id}/moves plays a Move and answers with its MoveResult.
+//   - GET /games/{id}/snapshot answers with the complete state of the game in the versioned encoding
+//     of the wire package: protobuf if the request accepts wire.MediaTypeProtobuf, JSON otherwise.
+//   - GET /games/{id}/result answers with the Result of the game, or 409 Conflict while it's still going on.
+//   - DELETE /games/{id} ends and removes the game.
+//
+// The games are kept in memory only; a game nobody has requested for idleMax is removed.
+// Fields:
+// - cells: the size of the board of the games whose request doesn't choose one.
+// - gamesMax: the largest number of games kept at once.
+// - mux: routes the requests.
+// - mu: guards games and swept.
+// - games: the games by their identifiers.
+// - swept: when the idle games were last looked for.
+type Server struct {
+	cells    int
+	gamesMax int
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	games    map[string]*game
+	swept    time.Time
+}
+
+// NewServer creates the server of the API.
+//
+// Parameters:
+// - cells (int): The size of the board of the games whose request doesn't choose one.
+// - gamesMax (int): The largest number of games kept at once; 0 for GamesMax.
+//
+// Returns:
+// - *Server: The server.
+func NewServer(cells, gamesMax int) *Server {
+	s := &Server{
+		cells:    min(max(cells, engine.MinCells), CellsMax),
+		gamesMax: cmp.Or(gamesMax, GamesMax),
+		mux:      http.NewServeMux(),
+		games:    make(map[string]*game),
+	}
+	s.mux.HandleFunc("POST /games", s.handleCreate)
+	s.mux.HandleFunc("GET /games/{id}", s.handleState)
+	s.mux.HandleFunc("POST /games/{id}/moves", s.handleMove)
+	s.mux.HandleFunc("GET /games/{id}/snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("GET /games/{id}/result", s.handleResult)
+	s.mux.HandleFunc("DELETE /games/{id}", s.handleDelete)
+	return s
+}
+
+// ServeHTTP handles the requests of the API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleCreate creates a game.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req NewGame
+	if err := readJSON(w, r, &req); err != nil {
+		http.Error(w, "invalid game: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Cells != 0 && (req.Cells < engine.MinCells || req.Cells > CellsMax) {
+		http.Error(w, fmt.Sprintf("invalid game: the board must have %d to %d cells", engine.MinCells, CellsMax), http.StatusBadRequest)
+		return
+	}
+	if req.Seed == 0 {
+		req.Seed = rand.Int63()
+	}
+	g := &game{e: engine.NewSized(req.Seed, cmp.Or(req.Cells, s.cells)), used: time.Now()}
+	id, ok := s.add(g)
+	if !ok {
+		http.Error(w, "too many games, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Location", "/games/"+id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, stateOf(id, g.e))
+}
+
+// handleState answers with the state of a game.
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	g := s.game(w, id)
+	if g == nil {
+		return
+	}
+	defer g.mu.Unlock()
+	writeJSON(w, stateOf(id, g.e))
+}
+
+// handleMove turns the snake and advances a game.
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	var m Move
+	if err := readJSON(w, r, &m); err != nil {
+		http.Error(w, "invalid move: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	dir, ok := parseDir(m.Dir)
+	if m.Dir != "" && !ok {
+		http.Error(w, "invalid move: unknown direction "+m.Dir, http.StatusBadRequest)
+		return
+	}
+	if m.Ticks < 0 || m.Ticks > ticksMax {
+		http.Error(w, fmt.Sprintf("invalid move: ticks must be 0 to %d", ticksMax), http.StatusBadRequest)
+		return
+	}
+	id := r.PathValue("id")
+	g := s.game(w, id)
+	if g == nil {
+		return
+	}
+	defer g.mu.Unlock()
+	if g.e.GameOver {
+		http.Error(w, "the game is over", http.StatusConflict)
+		return
+	}
+	var res MoveResult
+	if m.Dir != "" {
+		res.Turned = g.e.Turn(dir)
+	}
+	for range max(m.Ticks, 1) {
+		if g.e.GameOver {
+			break
+		}
+		step := g.e.Step()
+		res.Played++
+		if step.Ate {
+			res.Ate++
+		}
+		if step.Cut {
+			res.Cut++
+		}
+		res.Died = step.Died
+		res.Won = step.Won
+	}
+	res.State = stateOf(id, g.e)
+	writeJSON(w, res)
+}
+
+// handleSnapshot answers with the complete state of a game, in protobuf or JSON.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	g := s.game(w, r.PathValue("id"))
+	if g == nil {
+		return
+	}
+	state := g.e.Snapshot()
+	g.mu.Unlock()
+	w.Header().Set("Vary", "Accept")
+	if strings.Contains(r.Header.Get("Accept"), wire.MediaTypeProtobuf) {
+		w.Header().Set("Content-Type", wire.MediaTypeProtobuf)
+		w.Write(wire.MarshalState(state))
+		return
+	}
+	writeJSON(w, wire.NewState(state))
+}
+
+// handleResult answers with the result of a finished game.
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	g := s.game(w, id)
+	if g == nil {
+		return
+	}
+	defer g.mu.Unlock()
+	if !g.e.GameOver {
+		http.Error(w, "the game is still going on", http.StatusConflict)
+		return
+	}
+	writeJSON(w, Result{
+		ID:       id,
+		Seed:     g.e.Seed(),
+		Cells:    g.e.BoardSize(),
+		Score:    g.e.Score,
+		Length:   g.e.Snake.Len(),
+		Food:     g.e.AteFood,
+		Ticks:    g.e.Tick,
+		Duration: g.e.Elapsed.Milliseconds(),
+	})
+}
+
+// handleDelete removes a game.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, ok := s.games[r.PathValue("id")]
+	delete(s.games, r.PathValue("id"))
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "no such game", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// add adds a new game under a new random identifier, removing the idle games first if it's time to.
+//
+// Returns:
+// - string: The identifier of the game.
+// - bool: False if the server already keeps as many games as it can.
+func (s *Server) add(g *game) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) >= sweepPeriod || len(s.games) >= s.gamesMax {
+		s.swept = now
+		for id, old := range s.games {
+			if old.mu.TryLock() {
+				if now.Sub(old.used) >= idleMax {
+					delete(s.games, id)
+				}
+				old.mu.Unlock()
+			}
+		}
+	}
+	if len(s.games) >= s.gamesMax {
+		return "", false
+	}
+	id := newID()
+	s.games[id] = g
+	return id, true
+}
+
+// game returns the game with the identifier, locked, and marks it as used; the caller unlocks it.
+// If there's no such game, it answers with 404 Not Found and returns nil.
+func (s *Server) game(w http.ResponseWriter, id string) *game {
+	s.mu.Lock()
+	g := s.games[id]
+	s.mu.Unlock()
+	if g == nil {
+		http.Error(w, "no such game", http.StatusNotFound)
+		return nil
+	}
+	g.mu.Lock()
+	g.used = time.Now()
+	return g
+}
+
+// stateOf returns the state of the game of the engine.
+func stateOf(id string, e *engine.Engine) State {
+	snake := make([]Point, len(e.Snake.Parts))
+	for i, p := range e.Snake.Parts {
+		snake[i] = pointOf(p)
+	}
+	return State{
+		ID:       id,
+		Seed:     e.Seed(),
+		Cells:    e.BoardSize(),
+		Tick:     e.Tick,
+		Snake:    snake,
+		Dir:      dirName(e.Snake.Direction),
+		Food:     pointOf(e.Food),
+		Score:    e.Score,
+		Length:   e.Snake.Len(),
+		Speed:    e.Speed,
+		GameOver: e.GameOver,
+	}
+}
+
+// pointOf returns the cell of the engine position; the engine's y axis is the screen's.
+func pointOf(p engine.Point) Point {
+	return Point{X: int(p.X), Y: int(p.Y)}
+}
+
+// dirName returns the name of the engine direction on the screen: the engine's Up direction moves down the screen.
+func dirName(d engine.Dir) string {
+	switch d {
+	case engine.Down:
+		return "up"
+	case engine.Right:
+		return "right"
+	case engine.Up:
+		return "down"
+	default:
+		return "left"
+	}
+}
+
+// parseDir returns the engine direction of the name of a screen direction.
+//
+// Returns:
+// - engine.Dir: The direction.
+// - bool: False if the name is unknown.
+func parseDir(name string) (engine.Dir, bool) {
+	switch name {
+	case "up":
+		return engine.Down, true
+	case "right":
+		return engine.Right, true
+	case "down":
+		return engine.Up, true
+	case "left":
+		return engine.Left, true
+	default:
+		return 0, false
+	}
+}
+
+// newID returns a new random game identifier.
+func newID() string {
+	var b [12]byte
+	_, _ = crand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// readJSON decodes the JSON body of the request into v; an empty body leaves v unchanged.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, bodyMax)).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// writeJSON writes the value as the JSON answer of a request.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "error encoding answer", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,162 @@
+// Package saves keeps the games saved by the player in numbered save slots, so a paused game
+// can be continued later, even after the game has been closed.
+//
+// Every slot is a JSON file in the `saves` directory of the data directory. The same directory holds
+// the autosave, which is updated while playing, so a game interrupted by a crash or a power loss can be resumed.
+package saves
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/wire"
+)
+
+const (
+	Count        = 3               // the number of save slots
+	dirName      = "saves"         // the name of the directory with the save slots in the data directory
+	autosaveFile = "autosave.json" // the name of the autosave file
+)
+
+// Slot is a game saved in a save slot.
+// Fields:
+// - SavedAt: the time the game was saved.
+// - GameVersion: the version of the game that saved it.
+// - State: the complete state of the game engine, in the versioned encoding of the wire package; the games saved
+// before the encoding was versioned have version 0.
+// - Replay: the game so far in the binary replay format, so it keeps being recorded after it's loaded;
+// empty for games saved by older versions.
+type Slot struct {
+	SavedAt     time.Time  `json:"saved_at"`
+	GameVersion string     `json:"game_version"`
+	State       wire.State `json:"state"`
+	Replay      []byte     `json:"replay,omitempty"`
+}
+
+// Path returns the location of the file of the save slot.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - n (int): The number of the slot, from 1 to Count.
+//
+// Returns:
+// - string: The path to the file of the slot.
+func Path(dataDir string, n int) string {
+	return filepath.Join(dataDir, dirName, fmt.Sprintf("slot%d.json", n))
+}
+
+// Load reads the game saved in the slot.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - n (int): The number of the slot, from 1 to Count.
+//
+// Returns:
+// - *Slot: The saved game, or nil if the slot is empty.
+// - error: An error if the file of the slot exists but cannot be read or parsed.
+func Load(dataDir string, n int) (*Slot, error) {
+	return load(Path(dataDir, n))
+}
+
+// load reads the saved game from the file; a missing file means an empty slot.
+func load(path string) (*Slot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	var s Slot
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the game into the slot, replacing the game saved there before.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - n (int): The number of the slot, from 1 to Count.
+// - s (*Slot): The game to save.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func Save(dataDir string, n int, s *Slot) error {
+	return save(Path(dataDir, n), s)
+}
+
+// save writes the game into the file, creating the directory if needed.
+func save(path string, s *Slot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// AutosavePath returns the location of the autosave file.
+func AutosavePath(dataDir string) string {
+	return filepath.Join(dataDir, dirName, autosaveFile)
+}
+
+// LoadAutosave reads the autosave.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - *Slot: The autosaved game, or nil if there is none.
+// - error: An error if the autosave exists but cannot be read or parsed.
+func LoadAutosave(dataDir string) (*Slot, error) {
+	return load(AutosavePath(dataDir))
+}
+
+// Autosave replaces the autosave with the game.
+//
+// The file is written next to the autosave and renamed over it, so a crash during the write
+// never leaves a broken autosave behind.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - s (*Slot): The game to save.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func Autosave(dataDir string, s *Slot) error {
+	path := AutosavePath(dataDir)
+	tmp := path + ".tmp"
+	if err := save(tmp, s); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// RemoveAutosave deletes the autosave, if there is one.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - error: An error if the autosave exists but cannot be deleted.
+func RemoveAutosave(dataDir string) error {
+	err := os.Remove(AutosavePath(dataDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing autosave: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package speedrun keeps the split times of speedruns and the personal best splits of every mode.
+//
+// A split is taken every SplitEvery food items eaten; the personal bests are stored in the `speedrun.json`
+// file of the data directory.
+package speedrun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	fileName   = "speedrun.json" // the name of the file with the personal bests in the data directory
+	SplitEvery = 10              // the number of food items eaten between two splits
+)
+
+// Key returns the key of the personal best of a mode.
+//
+// Parameters:
+// - cells (int): The side of the board in cells.
+// - assisted (bool): Whether the beginner assist was used.
+//
+// Returns:
+// - string: The key, such as "20" or "20-assisted".
+func Key(cells int, assisted bool) string {
+	if assisted {
+		return fmt.Sprintf("%d-assisted", cells)
+	}
+	return fmt.Sprint(cells)
+}
+
+// Better reports whether a run is better than the personal best: it has reached more splits,
+// or as many splits in a shorter time.
+//
+// Parameters:
+// - run ([]time.Duration): The split times of the run.
+// - pb ([]time.Duration): The split times of the personal best.
+//
+// Returns:
+// - bool: Whether the run is the new personal best.
+func Better(run, pb []time.Duration) bool {
+	if len(run) != len(pb) {
+		return len(run) > len(pb)
+	}
+	return len(run) > 0 && run[len(run)-1] < pb[len(pb)-1]
+}
+
+// PBs holds the personal best splits of every mode. It's safe for concurrent use.
+// Fields:
+// - mu: guards splits.
+// - splits: the split times of the personal best of every mode, by the key of the mode.
+type PBs struct {
+	mu     sync.Mutex
+	splits map[string][]time.Duration
+}
+
+// New creates an empty set of personal bests.
+func New() *PBs {
+	return &PBs{splits: make(map[string][]time.Duration)}
+}
+
+// Path returns the location of the file with the personal bests.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - string: The path to the file.
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, fileName)
+}
+
+// Load reads the personal bests from the data directory.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - *PBs: The personal bests; empty if the file doesn't exist or cannot be read.
+// - error: An error if the file exists but cannot be read or parsed.
+func Load(dataDir string) (*PBs, error) {
+	p := New()
+	data, err := os.ReadFile(Path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, fmt.Errorf("error reading personal bests %s: %w", Path(dataDir), err)
+	}
+	if err = json.Unmarshal(data, &p.splits); err != nil {
+		return New(), fmt.Errorf("error parsing personal bests %s: %w", Path(dataDir), err)
+	}
+	return p, nil
+}
+
+// Save writes the personal bests, creating the directory if needed.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (p *PBs) Save(dataDir string) error {
+	p.mu.Lock()
+	data, err := json.MarshalIndent(p.splits, "", "  ")
+	p.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding personal bests: %w", err)
+	}
+	path := Path(dataDir)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing personal bests %s: %w", path, err)
+	}
+	return nil
+}
+
+// Get returns the personal best splits of a mode.
+//
+// Parameters:
+// - key (string): The key of the mode, see Key.
+//
+// Returns:
+// - []time.Duration: The split times; empty if the mode has no personal best yet.
+func (p *PBs) Get(key string) []time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.splits[key]
+}
+
+// Update replaces the personal best of a mode with the run if the run is better.
+//
+// Parameters:
+// - key (string): The key of the mode, see Key.
+// - run ([]time.Duration): The split times of the run.
+//
+// Returns:
+// - bool: Whether the run is the new personal best.
+func (p *PBs) Update(key string, run []time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !Better(run, p.splits[key]) {
+		return false
+	}
+	p.splits[key] = append([]time.Duration(nil), run...)
+	return true
+}
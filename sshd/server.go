@@ -0,0 +1,143 @@
+// Package sshd is an SSH server for interactive terminal sessions, so the game can be played with any SSH client.
+//
+// The protocol itself (the key exchange, the encryption and the integrity of the packets, the flow control
+// of the channels) is left to golang.org/x/crypto/ssh; the package runs one session per connection on top of it.
+// Every user is let in without a password, as the server only hosts a game; the user name is just a name.
+package sshd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DenisKhanov/Snake/metrics"
+	"golang.org/x/crypto/ssh"
+)
+
+const handshakeTimeout = 20 * time.Second // how long a new client may take to exchange the keys and log in
+
+// Server is an SSH server running a handler for every interactive session.
+// Fields:
+// - HostKey: the key the server proves its identity with; the clients remember it on the first connection.
+// - Handler: runs a session; the session ends when it returns.
+type Server struct {
+	HostKey ed25519.PrivateKey
+	Handler func(*Session)
+}
+
+// Serve accepts the connections of the listener, each in its own goroutine, until the listener fails.
+//
+// Returns:
+// - error: The error of the listener, or an error if the host key can't be used.
+func (s *Server) Serve(ln net.Listener) error {
+	signer, err := ssh.NewSignerFromKey(s.HostKey)
+	if err != nil {
+		return fmt.Errorf("error using host key: %w", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true, ServerVersion: "SSH-2.0-SnakeGO"}
+	config.AddHostKey(signer)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn, config)
+	}
+}
+
+// serveConn logs the client in and runs its session until the client disconnects or the session ends.
+func (s *Server) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	metrics.Default.Counter("snake_ssh_connections_total", "Connections accepted by the SSH server.").Inc()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	sc, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		metrics.Default.Counter("snake_ssh_handshake_failures_total", "SSH connections that failed to log in.").Inc()
+		log.Printf("ssh client %s failed to connect: %v", conn.RemoteAddr(), err)
+		return
+	}
+	active := metrics.Default.Gauge("snake_ssh_connections", "Logged-in SSH connections.")
+	active.Inc()
+	defer active.Dec()
+	conn.SetDeadline(time.Time{})
+	go ssh.DiscardRequests(reqs)
+	var session *Session
+	for ch := range chans {
+		if ch.ChannelType() != "session" || session != nil {
+			ch.Reject(ssh.Prohibited, "only one session is supported")
+			continue
+		}
+		channel, requests, err := ch.Accept()
+		if err != nil {
+			log.Printf("ssh client %s failed to open a session: %v", conn.RemoteAddr(), err)
+			return
+		}
+		session = newSession(sc, channel, s.Handler)
+		go session.serve(requests)
+	}
+	if err = sc.Wait(); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		log.Printf("ssh client %s disconnected: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// LoadHostKey loads the host key of the server from the file, or generates a new one and saves it there
+// if the file doesn't exist yet, so the clients see the same key after a restart.
+//
+// Parameters:
+// - path (string): The file with the key, in PEM-encoded PKCS #8.
+//
+// Returns:
+// - ed25519.PrivateKey: The host key.
+// - error: An error if the file can't be read, parsed or created.
+func LoadHostKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return generateHostKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading host key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error parsing host key %s: no PEM block", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing host key %s: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("error parsing host key %s: not an Ed25519 key", path)
+	}
+	return ed, nil
+}
+
+// generateHostKey generates a new host key and saves it to the file, readable by the owner only.
+func generateHostKey(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating host key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding host key: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("error writing host key %s: %w", path, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,142 @@
+package sshd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startServer starts a server of the handler on a local port and returns a client config trusting its host key.
+func startServer(t *testing.T, handler func(*Session)) (string, *ssh.ClientConfig) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go (&Server{HostKey: key, Handler: handler}).Serve(ln)
+	hostKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ClientConfig{User: "alice", HostKeyCallback: ssh.FixedHostKey(hostKey), Timeout: 5 * time.Second}
+	return ln.Addr().String(), config
+}
+
+func TestSession(t *testing.T) {
+	addr, config := startServer(t, func(s *Session) {
+		cols, rows := s.Size()
+		fmt.Fprintf(s, "%s %s %dx%d\n", s.User, s.Term, cols, rows)
+		for cols == 100 {
+			<-s.Resized()
+			cols, rows = s.Size()
+		}
+		fmt.Fprintf(s, "%dx%d\n", cols, rows)
+		line := make([]byte, 16)
+		n, _ := s.Read(line)
+		fmt.Fprintf(s, "got %s", line[:n])
+	})
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	session.Stdout = &out
+	in, err := session.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = session.RequestPty("xterm-256color", 30, 100, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	if err = session.WindowChange(40, 120); err != nil {
+		t.Fatal(err)
+	}
+	in.Write([]byte("q"))
+	if err = session.Wait(); err != nil {
+		t.Fatalf("the shell exited with %v, want status 0", err)
+	}
+	if want := "alice xterm-256color 100x30\n120x40\ngot q"; out.String() != want {
+		t.Errorf("output %q, want %q", out.String(), want)
+	}
+}
+
+func TestOneSessionPerConnection(t *testing.T) {
+	addr, config := startServer(t, func(s *Session) { <-s.Done() })
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err = client.NewSession(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.NewSession(); err == nil {
+		t.Error("a second session was opened")
+	}
+	if _, err = client.Dial("tcp", "127.0.0.1:22"); err == nil {
+		t.Error("a port forwarding was opened")
+	}
+}
+
+func TestDoneOnDisconnect(t *testing.T) {
+	done := make(chan struct{})
+	addr, config := startServer(t, func(s *Session) {
+		<-s.Done()
+		if _, err := s.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Read after the disconnection returned %v, want io.EOF", err)
+		}
+		close(done)
+	})
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session wasn't closed when the client disconnected")
+	}
+}
+
+func TestLoadHostKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "ssh_host_ed25519_key")
+	generated, err := LoadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !generated.Equal(loaded) {
+		t.Error("the host key changed after it was saved")
+	}
+}
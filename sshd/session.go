@@ -0,0 +1,209 @@
+// Package sshd is an SSH server for interactive terminal sessions, so the game can be played with any SSH client.
+//
+// The protocol itself (the key exchange, the encryption and the integrity of the packets, the flow control
+// of the channels) is left to golang.org/x/crypto/ssh; the package runs one session per connection on top of it.
+// Every user is let in without a password, as the server only hosts a game; the user name is just a name.
+package sshd
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const closeTimeout = 5 * time.Second // how long the server waits for the client to close the session after it has
+
+// Session is an interactive session of a client: the terminal the handler reads the keys from and writes to.
+// Fields:
+// - User: the name the client has logged in as.
+// - Term: the terminal type of the client, e.g. "xterm-256color"; empty if the client hasn't asked for a terminal.
+// - conn: the connection of the client.
+// - ch: the session channel, which carries the input and the output of the terminal.
+// - handler: runs the session.
+// - mu: guards the size of the terminal.
+// - cols, rows: the size of the terminal of the client.
+// - started: whether the handler has been started.
+// - resized: receives a value when the terminal of the client is resized.
+// - done: closed when the session is closed.
+type Session struct {
+	User string
+	Term string
+
+	conn    ssh.Conn
+	ch      ssh.Channel
+	handler func(*Session)
+	mu      sync.Mutex
+	cols    int
+	rows    int
+	started bool
+	resized chan struct{}
+	done    chan struct{}
+}
+
+// ptyRequest is the payload of a "pty-req" channel request (RFC 4254, section 6.2).
+type ptyRequest struct {
+	Term   string
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+	Modes  string
+}
+
+// windowChange is the payload of a "window-change" channel request (RFC 4254, section 6.7).
+type windowChange struct {
+	Cols   uint32
+	Rows   uint32
+	Width  uint32
+	Height uint32
+}
+
+// exitStatus is the payload of an "exit-status" channel request (RFC 4254, section 6.10).
+type exitStatus struct {
+	Status uint32
+}
+
+// newSession creates the session of a session channel the client has opened.
+//
+// Parameters:
+// - conn (ssh.Conn): The connection of the client.
+// - ch (ssh.Channel): The accepted session channel.
+// - handler (func(*Session)): Runs the session once the client asks for a shell.
+//
+// Returns:
+// - *Session: The session.
+func newSession(conn ssh.Conn, ch ssh.Channel, handler func(*Session)) *Session {
+	return &Session{
+		User:    conn.User(),
+		conn:    conn,
+		ch:      ch,
+		handler: handler,
+		cols:    80,
+		rows:    24,
+		resized: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// serve handles the channel requests of the client until the session is closed.
+//
+// Parameters:
+// - requests (<-chan *ssh.Request): The requests of the session channel; closed with the channel.
+func (s *Session) serve(requests <-chan *ssh.Request) {
+	defer close(s.done)
+	for req := range requests {
+		ok := s.request(req)
+		if req.WantReply {
+			req.Reply(ok, nil)
+		}
+	}
+}
+
+// request handles a channel request of the client.
+//
+// Returns:
+// - bool: Whether the request has succeeded.
+func (s *Session) request(req *ssh.Request) bool {
+	switch req.Type {
+	case "pty-req":
+		var pty ptyRequest
+		if ssh.Unmarshal(req.Payload, &pty) != nil {
+			return false
+		}
+		s.Term = pty.Term
+		s.setSize(pty.Cols, pty.Rows)
+		return true
+	case "window-change":
+		var size windowChange
+		if ssh.Unmarshal(req.Payload, &size) != nil {
+			return false
+		}
+		s.setSize(size.Cols, size.Rows)
+		return true
+	case "env":
+		return true
+	case "shell":
+		if s.started || s.handler == nil {
+			return false
+		}
+		s.started = true
+		go s.run()
+		return true
+	}
+	return false
+}
+
+// setSize sets the size of the terminal and reports the change.
+func (s *Session) setSize(cols, rows uint32) {
+	if cols == 0 || rows == 0 || cols > 1000 || rows > 1000 {
+		return
+	}
+	s.mu.Lock()
+	s.cols, s.rows = int(cols), int(rows)
+	s.mu.Unlock()
+	select {
+	case s.resized <- struct{}{}:
+	default:
+	}
+}
+
+// run runs the handler and closes the session once it returns: the client is told that the shell has exited,
+// and the connection is closed once the client has closed its side too.
+func (s *Session) run() {
+	s.handler(s)
+	s.ch.SendRequest("exit-status", false, ssh.Marshal(exitStatus{}))
+	s.ch.CloseWrite()
+	s.ch.Close()
+	//the client closes its side in reply; a client that doesn't is disconnected anyway
+	select {
+	case <-s.done:
+	case <-time.After(closeTimeout):
+	}
+	s.conn.Close()
+}
+
+// Read waits for the input of the client, usually the keys pressed in the terminal.
+//
+// Parameters:
+// - b ([]byte): The buffer to read into.
+//
+// Returns:
+// - int: The number of bytes read.
+// - error: io.EOF once the client has sent all its input or the session is closed.
+func (s *Session) Read(b []byte) (int, error) {
+	return s.ch.Read(b)
+}
+
+// Write sends the output to the terminal of the client, waiting while the client can't accept more.
+//
+// Parameters:
+// - b ([]byte): The output.
+//
+// Returns:
+// - int: The number of bytes sent.
+// - error: io.EOF if the session is closed, or the error of the connection.
+func (s *Session) Write(b []byte) (int, error) {
+	return s.ch.Write(b)
+}
+
+// Size returns the size of the terminal of the client.
+//
+// Returns:
+// - int: The number of columns.
+// - int: The number of rows.
+func (s *Session) Size() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cols, s.rows
+}
+
+// Resized returns a channel receiving a value when the terminal of the client is resized.
+func (s *Session) Resized() <-chan struct{} {
+	return s.resized
+}
+
+// Done returns a channel closed when the client closes the session or disconnects.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
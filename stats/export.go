@@ -0,0 +1,174 @@
+// Package stats keeps the history of the games played and summarizes it into lifetime statistics.
+//
+// Every finished game is appended as a JSON line to the history file in the data directory, so the history
+// survives crashes and grows without rewriting the file.
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a machine-readable format the history can be exported to.
+type Format string
+
+const (
+	CSV  Format = "csv"  // a table with a row per game, for spreadsheets
+	JSON Format = "json" // all games together with the statistics of every mode
+)
+
+// FormatOf returns the export format matching the extension of the file name.
+//
+// Parameters:
+// - path (string): The name of the exported file.
+//
+// Returns:
+// - Format: The format.
+// - error: An error if the extension isn't one of the supported formats.
+func FormatOf(path string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))); f {
+	case CSV, JSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, use .csv or .json", filepath.Ext(path))
+	}
+}
+
+// export is the document written by the JSON export.
+// Fields:
+// - ExportedAt: the time of the export.
+// - Runs: all games in the order they were played.
+// - Modes: the statistics of every mode played.
+type export struct {
+	ExportedAt time.Time     `json:"exported_at"`
+	Runs       []Run         `json:"runs"`
+	Modes      []modeSummary `json:"modes"`
+}
+
+// modeSummary is the JSON form of Summary.
+type modeSummary struct {
+	Cells     int     `json:"cells"`
+	Assisted  bool    `json:"assisted"`
+	Games     int     `json:"games"`
+	Best      int     `json:"best"`
+	Longest   int     `json:"longest"`
+	MostTicks int     `json:"most_ticks"`
+	Average   float64 `json:"average"`
+	Food      int     `json:"food"`
+	Ticks     int     `json:"ticks"`
+	Recent    []int   `json:"recent"`
+}
+
+// Write encodes the games in the given format.
+//
+// Parameters:
+// - w (io.Writer): The destination of the export.
+// - runs ([]Run): The games in the order they were played.
+// - format (Format): The format of the export.
+//
+// Returns:
+// - error: An error if the format isn't supported or writing fails; otherwise, nil.
+func Write(w io.Writer, runs []Run, format Format) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, runs)
+	case JSON:
+		return writeJSON(w, runs)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writeCSV writes a header and a row per game; the death cell is empty for games recorded without it.
+func writeCSV(w io.Writer, runs []Run) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ended_at", "seed", "cells", "assisted", "score", "length", "food", "ticks", "death_x", "death_y", "challenge",
+		"duration_s", "cause", "undos"})
+	for _, r := range runs {
+		deathX, deathY := "", ""
+		if r.Death != nil {
+			deathX, deathY = strconv.Itoa(r.Death.X), strconv.Itoa(r.Death.Y)
+		}
+		cw.Write([]string{
+			r.EndedAt.Format(time.RFC3339),
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(r.Cells),
+			strconv.FormatBool(r.Assisted),
+			strconv.Itoa(r.Score),
+			strconv.Itoa(r.Length),
+			strconv.Itoa(r.Food),
+			strconv.Itoa(r.Ticks),
+			deathX,
+			deathY,
+			r.Challenge,
+			strconv.FormatFloat(r.Duration.Seconds(), 'f', 1, 64),
+			r.Cause,
+			strconv.Itoa(r.Undos),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes the games together with the statistics of every mode as an indented JSON document.
+func writeJSON(w io.Writer, runs []Run) error {
+	doc := export{ExportedAt: time.Now(), Runs: runs, Modes: []modeSummary{}}
+	if doc.Runs == nil {
+		doc.Runs = []Run{}
+	}
+	for _, s := range Summarize(runs) {
+		doc.Modes = append(doc.Modes, modeSummary{
+			Cells: s.Cells, Assisted: s.Assisted, Games: s.Games, Best: s.Best,
+			Longest: s.Longest, MostTicks: s.MostTicks,
+			Average: s.Average(), Food: s.Food, Ticks: s.Ticks, Recent: s.Recent,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("error writing JSON: %w", err)
+	}
+	return nil
+}
+
+// Export reads the history and writes it to a file in the format matching the file's extension.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - path (string): The path of the exported file (.csv or .json); the directory is created if needed.
+//
+// Returns:
+// - int: The number of exported games.
+// - error: An error if the history cannot be read or the file cannot be written.
+func Export(dataDir, path string) (int, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return 0, err
+	}
+	runs, err := Load(dataDir)
+	if err != nil {
+		return 0, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+	if err = Write(file, runs, format); err != nil {
+		return 0, fmt.Errorf("error exporting to %s: %w", path, err)
+	}
+	return len(runs), nil
+}
@@ -0,0 +1,64 @@
+// Package stats keeps the history of the games played and summarizes it into lifetime statistics.
+//
+// Every finished game is appended as a JSON line to the history file in the data directory, so the history
+// survives crashes and grows without rewriting the file.
+package stats
+
+const (
+	xpTicks      = 20 // the number of ticks survived that give a single XP point
+	xpLevelScale = 50 // the XP needed for a level grows by this amount with every level
+)
+
+// XP returns the experience points awarded for a game: a point for every point of the score
+// and for every xpTicks ticks survived.
+//
+// Parameters:
+// - r (Run): The finished game.
+//
+// Returns:
+// - int: The XP awarded for the game.
+func XP(r Run) int {
+	return r.Score + r.Ticks/xpTicks
+}
+
+// TotalXP returns the experience points awarded for all games.
+//
+// Parameters:
+// - runs ([]Run): The games.
+//
+// Returns:
+// - int: The sum of the XP awarded for the games.
+func TotalXP(runs []Run) int {
+	total := 0
+	for _, r := range runs {
+		total += XP(r)
+	}
+	return total
+}
+
+// LevelXP returns the total XP needed to reach the player level: 0 for level 1, 100 for level 2, 300 for level 3
+// and so on, every level needing 100 XP more than the previous one.
+//
+// Parameters:
+// - level (int): The player level, starting from 1.
+//
+// Returns:
+// - int: The total XP needed for the level.
+func LevelXP(level int) int {
+	return xpLevelScale * level * (level - 1)
+}
+
+// Level returns the player level reached with the total XP.
+//
+// Parameters:
+// - xp (int): The total XP.
+//
+// Returns:
+// - int: The player level, starting from 1.
+func Level(xp int) int {
+	level := 1
+	for LevelXP(level+1) <= xp {
+		level++
+	}
+	return level
+}
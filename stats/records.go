@@ -0,0 +1,66 @@
+// Package stats keeps the history of the games played and summarizes it into lifetime statistics.
+//
+// Every finished game is appended as a JSON line to the history file in the data directory, so the history
+// survives crashes and grows without rewriting the file.
+package stats
+
+// Record is the best result achieved with a set of rules.
+//
+// Each field is the best of all games of the mode on its own, so the best score and the longest game
+// may come from different games.
+// Fields:
+// - Score: the best score.
+// - Length: the longest snake.
+// - Ticks: the longest game, in ticks.
+type Record struct {
+	Score  int
+	Length int
+	Ticks  int
+}
+
+// Records holds the best results of every mode that has been played.
+type Records map[Mode]Record
+
+// NewRecords computes the best results of every mode from the history.
+//
+// Parameters:
+// - runs ([]Run): The games.
+//
+// Returns:
+// - Records: The best results by mode.
+func NewRecords(runs []Run) Records {
+	r := make(Records)
+	for _, run := range runs {
+		r[run.Mode()] = r[run.Mode()].with(run)
+	}
+	return r
+}
+
+// With returns a copy of the records updated with a finished game; the records themselves are not changed,
+// so they can be shared with readers in other goroutines.
+//
+// Parameters:
+// - run (Run): The finished game.
+//
+// Returns:
+// - Records: The updated records.
+// - bool: Whether the game has beaten the best score of its mode; the first game of a mode with a score above 0
+// always does.
+func (r Records) With(run Run) (Records, bool) {
+	updated := make(Records, len(r)+1)
+	for mode, rec := range r {
+		updated[mode] = rec
+	}
+	old := r[run.Mode()]
+	updated[run.Mode()] = old.with(run)
+	return updated, run.Score > old.Score
+}
+
+// with returns the record updated with the game.
+func (rec Record) with(run Run) Record {
+	return Record{
+		Score:  max(rec.Score, run.Score),
+		Length: max(rec.Length, run.Length),
+		Ticks:  max(rec.Ticks, run.Ticks),
+	}
+}
@@ -0,0 +1,298 @@
+// Package stats keeps the history of the games played and summarizes it into lifetime statistics.
+//
+// Every finished game is appended as a JSON line to the history file in the data directory, so the history
+// survives crashes and grows without rewriting the file.
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	fileName  = "history.jsonl" // the name of the history file in the data directory
+	recentMax = 30              // the number of the latest scores kept in a summary
+)
+
+// Run is a finished game.
+// Fields:
+// - EndedAt: the time the game ended.
+// - Seed: the seed of the game.
+// - Cells: the side of the board in cells.
+// - Assisted: whether the beginner assist was used in the game.
+// - Score: the final score.
+// - Length: the final length of the snake.
+// - Food: the number of food items eaten.
+// - Ticks: the number of ticks played.
+// - Death: the cell the snake's head was in when the game ended; nil for games recorded by older versions.
+// - Challenge: the week of the weekly challenge the game was played in, such as "2026-W42"; empty for other games.
+// - Duration: the time the game was played for, without the pauses; 0 for games recorded by older versions.
+// - Cause: how the game ended, one of the Cause constants; empty for games recorded by older versions.
+// - Replay: the name of the file with the replay of the game in the replays directory; empty if it wasn't saved.
+// - Undos: the number of fatal moves undone in the game, a practice game.
+type Run struct {
+	EndedAt   time.Time     `json:"ended_at"`
+	Seed      int64         `json:"seed"`
+	Cells     int           `json:"cells"`
+	Assisted  bool          `json:"assisted"`
+	Score     int           `json:"score"`
+	Length    int           `json:"length"`
+	Food      int           `json:"food"`
+	Ticks     int           `json:"ticks"`
+	Death     *Cell         `json:"death,omitempty"`
+	Challenge string        `json:"challenge,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Cause     string        `json:"cause,omitempty"`
+	Replay    string        `json:"replay,omitempty"`
+	Undos     int           `json:"undos,omitempty"`
+}
+
+// CauseWall is the cause of a game that ended with the snake hitting a wall.
+const CauseWall = "wall"
+
+// Cell is a cell of the board.
+// Fields:
+// - X, Y: the column and the row of the cell, counted from the top-left corner.
+type Cell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Mode is the set of rules a game is played with: the board size and the difficulty.
+// Fields:
+// - Cells: the side of the board in cells.
+// - Assisted: whether the beginner assist was used.
+type Mode struct {
+	Cells    int
+	Assisted bool
+}
+
+// Mode returns the rules the game was played with.
+func (r Run) Mode() Mode {
+	return Mode{Cells: r.Cells, Assisted: r.Assisted}
+}
+
+// Summary is the lifetime statistics of the games played with the same rules.
+// Fields:
+// - Mode: the rules of the games.
+// - Games: the number of games.
+// - Best: the best score.
+// - Longest: the length of the longest snake.
+// - MostTicks: the number of ticks of the longest game.
+// - Total: the sum of the scores.
+// - Food: the number of food items eaten.
+// - Ticks: the number of ticks played.
+// - Recent: the scores of the latest games, the oldest first.
+type Summary struct {
+	Mode
+	Games     int
+	Best      int
+	Longest   int
+	MostTicks int
+	Total     int
+	Food      int
+	Ticks     int
+	Recent    []int
+}
+
+// Average returns the average score of the games.
+func (s Summary) Average() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.Total) / float64(s.Games)
+}
+
+// Path returns the location of the history file.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - string: The path to the history file.
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, fileName)
+}
+
+// Load reads all games from the history.
+//
+// Lines that can't be parsed, e.g. a line cut short by a crash, are skipped, so a damaged line never hides
+// the rest of the history.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+//
+// Returns:
+// - []Run: The games in the order they were played; empty if no game has been played yet.
+// - error: An error if the history file exists but cannot be read.
+func Load(dataDir string) ([]Run, error) {
+	data, err := os.ReadFile(Path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading history %s: %w", Path(dataDir), err)
+	}
+	var runs []Run
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var run Run
+		if err = json.Unmarshal(sc.Bytes(), &run); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// Append adds a finished game to the history, creating the file and its directory if needed.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - run (Run): The finished game.
+//
+// Returns:
+// - error: An error if the game cannot be written; otherwise, nil.
+func Append(dataDir string, run Run) error {
+	path := Path(dataDir)
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("error encoding game for history: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history %s: %w", path, err)
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing history %s: %w", path, err)
+	}
+	return nil
+}
+
+// Replace replaces the whole history, e.g. with the one synced from another machine. The new history is written
+// next to the old one first, so a failure never leaves it half-written.
+//
+// Parameters:
+// - dataDir (string): The data directory.
+// - runs ([]Run): The games in the order they were played.
+//
+// Returns:
+// - error: An error if the history cannot be written; otherwise, nil.
+func Replace(dataDir string, runs []Run) error {
+	path := Path(dataDir)
+	var buf bytes.Buffer
+	for _, run := range runs {
+		line, err := json.Marshal(run)
+		if err != nil {
+			return fmt.Errorf("error encoding game for history: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing history %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error replacing history %s: %w", path, err)
+	}
+	return nil
+}
+
+// Latest returns the latest games, the newest first.
+//
+// Parameters:
+// - runs ([]Run): The games in the order they were played.
+// - n (int): The largest number of games returned.
+//
+// Returns:
+// - []Run: At most n latest games, the newest first.
+func Latest(runs []Run, n int) []Run {
+	latest := make([]Run, 0, min(n, len(runs)))
+	for i := len(runs) - 1; i >= 0 && len(latest) < n; i-- {
+		latest = append(latest, runs[i])
+	}
+	return latest
+}
+
+// Deaths counts the games that ended in every cell of a board of the given size.
+//
+// Parameters:
+// - runs ([]Run): The games.
+// - cells (int): The side of the board in cells; only the games played on a board of this size are counted.
+//
+// Returns:
+// - [][]int: The number of games that ended in every cell, indexed by the row and then by the column.
+// - int: The largest number of games that ended in a single cell.
+func Deaths(runs []Run, cells int) ([][]int, int) {
+	grid := make([][]int, cells)
+	for y := range grid {
+		grid[y] = make([]int, cells)
+	}
+	top := 0
+	for _, run := range runs {
+		d := run.Death
+		if run.Cells != cells || d == nil || d.X < 0 || d.Y < 0 || d.X >= cells || d.Y >= cells {
+			continue
+		}
+		grid[d.Y][d.X]++
+		top = max(top, grid[d.Y][d.X])
+	}
+	return grid, top
+}
+
+// Summarize groups the games by their rules and computes the statistics of every group.
+//
+// Parameters:
+// - runs ([]Run): The games in the order they were played.
+//
+// Returns:
+// - []Summary: The statistics of every mode that has been played, the standard board first
+// (larger boards before smaller ones) and unassisted games before assisted ones.
+func Summarize(runs []Run) []Summary {
+	byMode := make(map[Mode]*Summary)
+	for _, run := range runs {
+		s, ok := byMode[run.Mode()]
+		if !ok {
+			s = &Summary{Mode: run.Mode()}
+			byMode[run.Mode()] = s
+		}
+		s.Games++
+		s.Best = max(s.Best, run.Score)
+		s.Longest = max(s.Longest, run.Length)
+		s.MostTicks = max(s.MostTicks, run.Ticks)
+		s.Total += run.Score
+		s.Food += run.Food
+		s.Ticks += run.Ticks
+		s.Recent = append(s.Recent, run.Score)
+		if len(s.Recent) > recentMax {
+			s.Recent = s.Recent[1:]
+		}
+	}
+	summaries := make([]Summary, 0, len(byMode))
+	for _, s := range byMode {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Cells != b.Cells {
+			return a.Cells > b.Cells
+		}
+		return !a.Assisted && b.Assisted
+	})
+	return summaries
+}
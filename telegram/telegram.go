@@ -0,0 +1,125 @@
+// Package telegram sends the summaries of finished games to a Telegram chat through the Bot API.
+//
+// The integration is opt-in: nothing is sent unless the player creates a bot with @BotFather and puts its token
+// and the identifier of the chat into the configuration. The player sends every summary on purpose,
+// from the game over screen.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	apiURL     = "https://api.telegram.org" // the address of the Bot API
+	timeout    = 30 * time.Second           // how long sending a photo may take
+	captionMax = 1024                       // the longest caption of a photo accepted by the Bot API, in characters
+)
+
+// tokenPattern matches the tokens issued by @BotFather: the identifier of the bot and a secret.
+var tokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
+
+// ErrInvalidToken is returned for bot tokens that don't look like the ones issued by @BotFather.
+var ErrInvalidToken = errors.New("invalid Telegram bot token")
+
+// Client sends photos to a chat on behalf of a bot.
+// Fields:
+// - token: the token of the bot; it's part of the address of every request, so it's never logged.
+// - chat: the identifier of the chat, e.g. "123456789" or "@channel".
+// - base: the address of the Bot API.
+type Client struct {
+	token string
+	chat  string
+	base  string
+}
+
+// New creates a client sending to the chat on behalf of the bot.
+//
+// Parameters:
+// - token (string): The token of the bot issued by @BotFather, e.g. "123456:ABC-DEF".
+// - chat (string): The identifier of the chat the bot sends to: the numeric identifier of a user or a group,
+// or the @username of a channel.
+//
+// Returns:
+// - *Client: The client.
+// - error: An error if the token is malformed or the chat is missing.
+func New(token, chat string) (*Client, error) {
+	if !tokenPattern.MatchString(token) {
+		return nil, ErrInvalidToken
+	}
+	if chat == "" {
+		return nil, errors.New("missing Telegram chat")
+	}
+	return &Client{token: token, chat: chat, base: apiURL}, nil
+}
+
+// answer is the answer of the Bot API.
+// Fields:
+// - OK: whether the request has succeeded.
+// - Description: why the request has failed.
+type answer struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// SendPhoto sends a PNG image with a caption to the chat.
+//
+// Parameters:
+// - ctx (context.Context): The context for the request.
+// - photo ([]byte): The PNG image.
+// - name (string): The file name of the image, e.g. "snake.png".
+// - caption (string): The text under the image; cut to the longest caption Telegram accepts.
+//
+// Returns:
+// - error: An error if the photo can't be sent or Telegram refuses it.
+func (c *Client) SendPhoto(ctx context.Context, photo []byte, name, caption string) error {
+	if utf8.RuneCountInString(caption) > captionMax {
+		caption = string([]rune(caption)[:captionMax])
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("chat_id", c.chat)
+	mw.WriteField("caption", caption)
+	part, err := mw.CreateFormFile("photo", name)
+	if err != nil {
+		return fmt.Errorf("error encoding Telegram request: %w", err)
+	}
+	part.Write(photo)
+	if err = mw.Close(); err != nil {
+		return fmt.Errorf("error encoding Telegram request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/bot"+c.token+"/sendPhoto", &body)
+	if err != nil {
+		return fmt.Errorf("error creating Telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		//the error of the client quotes the address, which has the token in it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("error sending to Telegram: %w", err)
+	}
+	defer resp.Body.Close()
+	var a answer
+	if err = json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return fmt.Errorf("error decoding Telegram answer: %s", resp.Status)
+	}
+	if !a.OK {
+		return fmt.Errorf("Telegram refused the photo: %s", a.Description)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package train evolves bots for the game with a genetic algorithm, playing headless games to rate them.
+//
+// A bot is a small neural network (Genome) reading the features of the gym package, the dangers around the head
+// and the direction of the food, and choosing between going straight, turning left and turning right.
+// Evolve breeds generations of such networks: the best ones are kept, the others are replaced with crossovers
+// of good parents and mutated, so the bots get better at the game from generation to generation.
+package train
+
+import (
+	"cmp"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// tournamentSize is the number of genomes drawn for every choice of a parent; the fittest of them is chosen.
+const tournamentSize = 3
+
+// Config configures the evolution. The zero values of the fields are replaced with the defaults.
+// Fields:
+// - Population: the number of genomes of a generation; 100 by default.
+// - Generations: the number of generations to breed; 50 by default.
+// - Games: the number of games every genome plays to be rated; 5 by default. All genomes of a generation play
+// the same seeds, and every generation plays new ones.
+// - Cells: the size of the board; engine.Cells by default.
+// - Seed: the seed of the evolution: of the first weights, of the breeding and of the games.
+// - Hidden: the number of hidden neurons; 8 by default, negative for a network without a hidden layer.
+// - Elite: the number of the fittest genomes copied unchanged into the next generation; a tenth of the population
+// by default.
+// - MutationRate: the probability that a weight of a child is mutated; 0.1 by default.
+// - MutationScale: the standard deviation of the mutations; 0.5 by default.
+// - MaxTicks: the largest number of ticks of a game; 5000 by default.
+// - Start: a genome to start from, e.g. the champion of an earlier evolution; nil to start from random networks.
+type Config struct {
+	Population    int
+	Generations   int
+	Games         int
+	Cells         int
+	Seed          int64
+	Hidden        int
+	Elite         int
+	MutationRate  float64
+	MutationScale float64
+	MaxTicks      int
+	Start         *Genome
+}
+
+// withDefaults returns the configuration with the defaults filled in.
+func (c Config) withDefaults() Config {
+	c.Population = max(cmp.Or(c.Population, 100), 2)
+	c.Generations = cmp.Or(c.Generations, 50)
+	c.Games = cmp.Or(c.Games, 5)
+	c.Cells = max(cmp.Or(c.Cells, engine.Cells), engine.MinCells)
+	c.Hidden = cmp.Or(c.Hidden, 8)
+	c.Elite = min(cmp.Or(c.Elite, max(c.Population/10, 1)), c.Population)
+	c.MutationRate = cmp.Or(c.MutationRate, 0.1)
+	c.MutationScale = cmp.Or(c.MutationScale, 0.5)
+	c.MaxTicks = cmp.Or(c.MaxTicks, 5000)
+	return c
+}
+
+// Generation summarizes a rated generation.
+// Fields:
+// - Number: the number of the generation, from 1.
+// - Best: the fittest genome of the generation.
+// - Mean: the average fitness of the generation.
+type Generation struct {
+	Number int
+	Best   Genome
+	Mean   float64
+}
+
+// Evolve breeds the generations and returns the fittest genome of the last one.
+//
+// Every genome plays Games headless games, in parallel on all processors, and its fitness is the average
+// of its scores plus a hundredth of a point per tick survived, so the first generations, which rarely
+// eat anything, learn to stay alive first. A game also ends when the snake hasn't eaten for as many ticks
+// as the board has cells, so a snake going round in circles can't survive forever.
+//
+// Parameters:
+// - cfg (Config): The configuration of the evolution.
+// - progress (func(Generation) bool): Called after every generation; returning false stops the evolution.
+// May be nil.
+//
+// Returns:
+// - Genome: The fittest genome of the last generation.
+func Evolve(cfg Config, progress func(Generation) bool) Genome {
+	cfg = cfg.withDefaults()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	layers := layersOf(cfg.Hidden)
+	if cfg.Start != nil {
+		layers = cfg.Start.Layers
+	}
+	population := make([]Genome, cfg.Population)
+	for i := range population {
+		g := Genome{Version: genomeVersion, Layers: layers, Weights: make([]float64, weightCount(layers))}
+		switch {
+		case cfg.Start != nil && i == 0:
+			copy(g.Weights, cfg.Start.Weights)
+		case cfg.Start != nil:
+			copy(g.Weights, cfg.Start.Weights)
+			mutate(rng, g.Weights, cfg.MutationRate, cfg.MutationScale)
+		default:
+			for w := range g.Weights {
+				g.Weights[w] = rng.NormFloat64()
+			}
+		}
+		population[i] = g
+	}
+
+	var best Genome
+	for gen := 1; gen <= cfg.Generations; gen++ {
+		seed := cfg.Seed + int64(gen-1)*int64(cfg.Games)
+		mean := rate(population, cfg, seed)
+		for i := range population {
+			population[i].Generation = gen
+		}
+		slices.SortStableFunc(population, func(a, b Genome) int { return cmp.Compare(b.Fitness, a.Fitness) })
+		best = population[0]
+		best.Weights = slices.Clone(best.Weights)
+		if progress != nil && !progress(Generation{Number: gen, Best: best, Mean: mean}) {
+			break
+		}
+		if gen < cfg.Generations {
+			population = breed(rng, population, cfg)
+		}
+	}
+	return best
+}
+
+// rate plays the games of every genome of the generation and sets their fitness.
+//
+// Returns:
+// - float64: The average fitness of the generation.
+func rate(population []Genome, cfg Config, seed int64) float64 {
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				total := 0.0
+				for k := range cfg.Games {
+					total += fitness(&population[i], seed+int64(k), cfg)
+				}
+				population[i].Fitness = total / float64(cfg.Games)
+			}
+		}()
+	}
+	for i := range population {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	total := 0.0
+	for _, g := range population {
+		total += g.Fitness
+	}
+	return total / float64(len(population))
+}
+
+// fitness plays a game with the genome and rates it.
+func fitness(g *Genome, seed int64, cfg Config) float64 {
+	e := engine.NewSized(seed, cfg.Cells)
+	hungry := 0
+	for !e.GameOver && e.Tick < cfg.MaxTicks && hungry < cfg.Cells*cfg.Cells {
+		if dir := g.NextMove(e.Snapshot()); dir != e.Snake.Direction {
+			e.Turn(dir)
+		}
+		if e.Step().Ate {
+			hungry = 0
+		} else {
+			hungry++
+		}
+	}
+	return float64(e.Score) + float64(e.Tick)/100
+}
+
+// breed creates the next generation from a rated one, sorted by fitness: the elite is kept, and every other
+// genome is a child of two parents chosen by tournaments, with every weight taken from either parent
+// and then possibly mutated.
+func breed(rng *rand.Rand, population []Genome, cfg Config) []Genome {
+	next := make([]Genome, 0, len(population))
+	for _, g := range population[:cfg.Elite] {
+		g.Weights = slices.Clone(g.Weights)
+		next = append(next, g)
+	}
+	for len(next) < len(population) {
+		a, b := tournament(rng, population), tournament(rng, population)
+		child := Genome{Version: genomeVersion, Layers: a.Layers, Weights: make([]float64, len(a.Weights))}
+		for w := range child.Weights {
+			if rng.Intn(2) == 0 {
+				child.Weights[w] = a.Weights[w]
+			} else {
+				child.Weights[w] = b.Weights[w]
+			}
+		}
+		mutate(rng, child.Weights, cfg.MutationRate, cfg.MutationScale)
+		next = append(next, child)
+	}
+	return next
+}
+
+// tournament draws tournamentSize genomes and returns the fittest one.
+func tournament(rng *rand.Rand, population []Genome) *Genome {
+	best := &population[rng.Intn(len(population))]
+	for range tournamentSize - 1 {
+		if g := &population[rng.Intn(len(population))]; g.Fitness > best.Fitness {
+			best = g
+		}
+	}
+	return best
+}
+
+// mutate adds a normally distributed change to every weight with the given probability.
+func mutate(rng *rand.Rand, weights []float64, rate, scale float64) {
+	for w := range weights {
+		if rng.Float64() < rate {
+			weights[w] += rng.NormFloat64() * scale
+		}
+	}
+}
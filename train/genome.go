@@ -0,0 +1,161 @@
+// Package train evolves bots for the game with a genetic algorithm, playing headless games to rate them.
+//
+// A bot is a small neural network (Genome) reading the features of the gym package, the dangers around the head
+// and the direction of the food, and choosing between going straight, turning left and turning right.
+// Evolve breeds generations of such networks: the best ones are kept, the others are replaced with crossovers
+// of good parents and mutated, so the bots get better at the game from generation to generation.
+package train
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/gym"
+)
+
+// genomeVersion is the version of the format of the genome files.
+const genomeVersion = 1
+
+// outputs are the moves the network chooses between, relative to the direction of the snake.
+const outputs = 3
+
+// Genome is the network of an evolved bot. It plays the game as a player.Player.
+//
+// The network has fully connected layers: tanh on the hidden ones and none on the output one, whose largest
+// value picks the move: straight, left or right, as seen on the screen.
+// Fields:
+// - Version: the version of the format of the genome files.
+// - Layers: the number of neurons of every layer, from the gym.FeatureCount inputs to the 3 outputs.
+// - Weights: the weights and biases of the layers in order; for every neuron of a layer, the weights
+// of its inputs and then its bias.
+// - Fitness: the average fitness of the genome in the generation it has been rated in.
+// - Generation: the number of the generation the genome has been rated in.
+type Genome struct {
+	Version    int       `json:"version"`
+	Layers     []int     `json:"layers"`
+	Weights    []float64 `json:"weights"`
+	Fitness    float64   `json:"fitness"`
+	Generation int       `json:"generation"`
+}
+
+// weightCount returns the number of weights and biases of a network with the given layers.
+func weightCount(layers []int) int {
+	n := 0
+	for l := 1; l < len(layers); l++ {
+		n += (layers[l-1] + 1) * layers[l]
+	}
+	return n
+}
+
+// layersOf returns the layers of a network with the given number of hidden neurons.
+func layersOf(hidden int) []int {
+	if hidden <= 0 {
+		return []int{gym.FeatureCount, outputs}
+	}
+	return []int{gym.FeatureCount, hidden, outputs}
+}
+
+// NextMove feeds the features of the state to the network and plays the move with the largest output.
+func (g *Genome) NextMove(s engine.State) engine.Dir {
+	values := g.forward(gym.Observe(s, gym.Features))
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	switch best {
+	case 1:
+		return gym.TurnLeft(s.Direction)
+	case 2:
+		return gym.TurnRight(s.Direction)
+	default:
+		return s.Direction
+	}
+}
+
+// forward computes the outputs of the network.
+func (g *Genome) forward(obs []float32) []float64 {
+	in := make([]float64, len(obs))
+	for i, v := range obs {
+		in[i] = float64(v)
+	}
+	w := g.Weights
+	for l := 1; l < len(g.Layers); l++ {
+		out := make([]float64, g.Layers[l])
+		for j := range out {
+			sum := w[len(in)] //the bias follows the weights of the inputs
+			for i, x := range in {
+				sum += w[i] * x
+			}
+			w = w[len(in)+1:]
+			if l < len(g.Layers)-1 {
+				sum = math.Tanh(sum)
+			}
+			out[j] = sum
+		}
+		in = out
+	}
+	return in
+}
+
+// check reports whether the genome describes a network the game can play with.
+func (g *Genome) check() error {
+	switch {
+	case g.Version > genomeVersion:
+		return fmt.Errorf("genome format version %d is newer than the supported version %d", g.Version, genomeVersion)
+	case len(g.Layers) < 2 || g.Layers[0] != gym.FeatureCount || g.Layers[len(g.Layers)-1] != outputs:
+		return fmt.Errorf("genome has layers %v, expected %d inputs and %d outputs", g.Layers, gym.FeatureCount, outputs)
+	case len(g.Weights) != weightCount(g.Layers):
+		return fmt.Errorf("genome has %d weights, expected %d", len(g.Weights), weightCount(g.Layers))
+	}
+	return nil
+}
+
+// Save writes the genome to a JSON file.
+//
+// Parameters:
+// - path (string): The file.
+//
+// Returns:
+// - error: An error if the file cannot be written; otherwise, nil.
+func (g *Genome) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding genome: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing genome %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error replacing genome %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadGenome reads a genome saved by Save.
+//
+// Parameters:
+// - path (string): The file.
+//
+// Returns:
+// - *Genome: The genome.
+// - error: An error if the file cannot be read or doesn't describe a valid network.
+func LoadGenome(path string) (*Genome, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading genome: %w", err)
+	}
+	var g Genome
+	if err = json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("error parsing genome %s: %w", path, err)
+	}
+	if err = g.check(); err != nil {
+		return nil, fmt.Errorf("invalid genome %s: %w", path, err)
+	}
+	return &g, nil
+}
@@ -0,0 +1,149 @@
+// Package tui plays the game in a text terminal: it draws the board with ANSI escape sequences and reads
+// the keys from the terminal, so the game can be played without a window, e.g. over SSH.
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	ScoresTop = 10 // the number of scores kept in the high score table
+	nameMax   = 16 // the longest player name, in characters; longer names are cut
+)
+
+// Score is an entry of the high score table.
+// Fields:
+// - Name: the name of the player.
+// - Score: the score of the game.
+// - Date: when the game was played.
+type Score struct {
+	Name  string    `json:"name"`
+	Score int       `json:"score"`
+	Date  time.Time `json:"date"`
+}
+
+// Scores is a high score table shared by all the players of a server and kept in a file.
+// It's safe for concurrent use.
+// Fields:
+// - path: the file the table is kept in.
+// - mu: guards entries.
+// - entries: the best scores, the best first.
+type Scores struct {
+	path    string
+	mu      sync.Mutex
+	entries []Score
+}
+
+// LoadScores loads the high score table from the file; a missing file is an empty table.
+//
+// Parameters:
+// - path (string): The file the table is kept in.
+//
+// Returns:
+// - *Scores: The table.
+// - error: An error if the file can't be read or parsed.
+func LoadScores(path string) (*Scores, error) {
+	s := &Scores{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading scores %s: %w", path, err)
+	}
+	if err = json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("error parsing scores %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Top returns the high score table, the best first.
+func (s *Scores) Top() []Score {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.entries)
+}
+
+// Add adds the score of a finished game to the table if it's good enough and saves the table.
+//
+// Parameters:
+// - name (string): The name of the player.
+// - score (int): The score of the game.
+//
+// Returns:
+// - int: The place of the score in the table, from 1, or 0 if it hasn't made it.
+func (s *Scores) Add(name string, score int) int {
+	if score <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	//an equal score doesn't push out an older one
+	i := slices.IndexFunc(s.entries, func(e Score) bool { return e.Score < score })
+	if i < 0 {
+		i = len(s.entries)
+	}
+	if i >= ScoresTop {
+		return 0
+	}
+	s.entries = slices.Insert(s.entries, i, Score{Name: name, Score: score, Date: time.Now().UTC()})
+	s.entries = s.entries[:min(len(s.entries), ScoresTop)]
+	if err := saveScores(s.path, s.entries); err != nil {
+		log.Println(err)
+	}
+	return i + 1
+}
+
+// saveScores writes the table to the file through a temporary file, so a crash never leaves a damaged table behind.
+func saveScores(path string, entries []Score) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("error encoding scores: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing scores %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error replacing scores %s: %w", path, err)
+	}
+	return nil
+}
+
+// PlayerName returns the name a player is shown under: the name without control characters, cut to nameMax
+// characters, or "player" if nothing is left.
+//
+// Parameters:
+// - name (string): The name the player has chosen, e.g. the user name of an SSH login.
+//
+// Returns:
+// - string: The name to show.
+func PlayerName(name string) string {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if runes := []rune(name); len(runes) > nameMax {
+		name = string(runes[:nameMax])
+	}
+	if name == "" {
+		return "player"
+	}
+	return name
+}
@@ -0,0 +1,312 @@
+// Package tui plays the game in a text terminal: it draws the board with ANSI escape sequences and reads
+// the keys from the terminal, so the game can be played without a window, e.g. over SSH.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/metrics"
+)
+
+// ANSI escape sequences used by the renderer.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // switch to the alternate screen and hide the cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l" // show the cursor and return to the main screen
+	home        = "\x1b[H"               // move the cursor to the top left corner
+	clearLine   = "\x1b[K"               // clear the rest of the line
+	clearBelow  = "\x1b[J"               // clear the rest of the screen
+	reset       = "\x1b[0m"
+
+	colorSnake = "\x1b[42m"
+	colorHead  = "\x1b[102m"
+	colorFood  = "\x1b[41m"
+	colorWall  = "\x1b[100m"
+	colorTitle = "\x1b[1;32m"
+	colorDim   = "\x1b[2m"
+)
+
+const panelWidth = 28 // the width of the side panel with the score and the high scores, in columns
+
+// Terminal is the terminal a game is played in, e.g. an SSH session.
+type Terminal interface {
+	io.ReadWriter
+	// Size returns the number of columns and rows of the terminal.
+	Size() (int, int)
+	// Resized returns a channel receiving a value when the terminal is resized.
+	Resized() <-chan struct{}
+	// Done returns a channel closed when the terminal is gone.
+	Done() <-chan struct{}
+}
+
+// key is an action of the player read from the terminal.
+type key int
+
+const (
+	keyTurn key = iota
+	keyPause
+	keyRestart
+	keyQuit
+)
+
+// input is a key pressed by the player.
+// Fields:
+// - key: the action.
+// - dir: the new direction of the snake, for keyTurn.
+type input struct {
+	key key
+	dir engine.Dir
+}
+
+// session is a player's game in a terminal.
+// Fields:
+// - t: the terminal.
+// - name: the name of the player.
+// - scores: the high score table shared by all players.
+// - e: the engine of the current game.
+// - paused: whether the game is paused.
+// - place: the place of the score of the finished game in the high score table, from 1; 0 if it hasn't made it.
+type session struct {
+	t      Terminal
+	name   string
+	scores *Scores
+	e      *engine.Engine
+	paused bool
+	place  int
+}
+
+// Play plays games in the terminal until the player quits or the terminal is gone.
+//
+// The arrow keys or WASD turn the snake, P pauses the game, Enter or Space starts a new game once it's over,
+// and Q or Ctrl+C quits. Every finished game is added to the high score table.
+//
+// Parameters:
+// - t (Terminal): The terminal.
+// - name (string): The name of the player in the high score table.
+// - scores (*Scores): The high score table shared by all players.
+// - cells (int): The number of cells along each side of the board.
+func Play(t Terminal, name string, scores *Scores, cells int) {
+	s := &session{t: t, name: name, scores: scores, e: engine.NewSized(rand.Int63(), cells)}
+	players := metrics.Default.Gauge("snake_terminal_players", "Players playing in a terminal.")
+	players.Inc()
+	defer players.Dec()
+	ticks := metrics.Default.Counter("snake_terminal_ticks_total", "Ticks played by the games in terminals.")
+	inputs, quit := make(chan input), make(chan struct{})
+	defer close(quit)
+	go readInput(t, inputs, quit)
+	io.WriteString(t, enterScreen)
+	defer io.WriteString(t, reset+leaveScreen)
+	tick := time.NewTimer(s.e.Interval())
+	defer tick.Stop()
+	for {
+		if s.draw() != nil {
+			return
+		}
+		select {
+		case <-t.Done():
+			return
+		case <-t.Resized():
+			//the screen is redrawn for the new size
+			io.WriteString(t, home+clearBelow)
+		case in, ok := <-inputs:
+			if !ok || in.key == keyQuit {
+				return
+			}
+			s.handle(in, tick)
+		case <-tick.C:
+			if s.paused || s.e.GameOver {
+				continue
+			}
+			s.e.Step()
+			ticks.Inc()
+			if s.e.GameOver {
+				s.place = s.scores.Add(s.name, s.e.Score)
+				metrics.Default.Counter("snake_terminal_games_total", "Games finished in terminals.").Inc()
+				metrics.Default.Histogram("snake_terminal_game_duration_seconds", "Game time of the games finished in terminals.",
+					metrics.DurationBuckets).Observe(s.e.Elapsed.Seconds())
+			} else {
+				tick.Reset(s.e.Interval())
+			}
+		}
+	}
+}
+
+// handle handles a key pressed by the player.
+func (s *session) handle(in input, tick *time.Timer) {
+	switch {
+	case in.key == keyRestart && s.e.GameOver:
+		s.e.Reset(rand.Int63())
+		s.paused = false
+		s.place = 0
+		tick.Reset(s.e.Interval())
+	case in.key == keyPause && !s.e.GameOver:
+		s.paused = !s.paused
+		if s.paused {
+			tick.Stop()
+		} else {
+			tick.Reset(s.e.Interval())
+		}
+	case in.key == keyTurn && !s.paused:
+		s.e.Turn(in.dir)
+	}
+}
+
+// readInput reads the keys from the terminal and sends them to the channel until the terminal is gone,
+// then closes the channel; it stops sending once quit is closed.
+func readInput(r io.Reader, inputs chan<- input, quit <-chan struct{}) {
+	defer close(inputs)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, in := range parseKeys(buf[:n]) {
+			select {
+			case inputs <- in:
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+// parseKeys returns the actions of the keys in the input of the terminal; the other keys are ignored.
+// The arrow keys come as escape sequences, either ESC [ A or ESC O A depending on the mode of the terminal.
+// The engine's Up direction moves down the screen, as the board is drawn with y growing downwards.
+func parseKeys(b []byte) []input {
+	var inputs []input
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+			c = b[i+2]
+			i += 2
+			switch c {
+			case 'A':
+				c = 'w'
+			case 'B':
+				c = 's'
+			case 'C':
+				c = 'd'
+			case 'D':
+				c = 'a'
+			default:
+				continue
+			}
+		}
+		switch c {
+		case 'w', 'W':
+			inputs = append(inputs, input{key: keyTurn, dir: engine.Down})
+		case 's', 'S':
+			inputs = append(inputs, input{key: keyTurn, dir: engine.Up})
+		case 'a', 'A':
+			inputs = append(inputs, input{key: keyTurn, dir: engine.Left})
+		case 'd', 'D':
+			inputs = append(inputs, input{key: keyTurn, dir: engine.Right})
+		case 'p', 'P':
+			inputs = append(inputs, input{key: keyPause})
+		case '\r', '\n', ' ':
+			inputs = append(inputs, input{key: keyRestart})
+		case 'q', 'Q', 0x03, 0x04:
+			inputs = append(inputs, input{key: keyQuit})
+		}
+	}
+	return inputs
+}
+
+// draw draws the whole screen: the board, and the side panel if the terminal is wide enough.
+// Every cell is two columns wide, so the board looks square.
+func (s *session) draw() error {
+	cells := s.e.BoardSize()
+	width, height := 2*cells+4, cells+2
+	cols, rows := s.t.Size()
+	var b strings.Builder
+	b.WriteString(home)
+	if cols < width || rows < height+1 {
+		fmt.Fprintf(&b, "%sTerminal too small: %dx%d needed, %dx%d available.%s%s", reset, width, height+1, cols, rows, clearLine, clearBelow)
+		_, err := io.WriteString(s.t, b.String())
+		return err
+	}
+	panel := s.panel()
+	if cols < width+panelWidth {
+		panel = nil
+	}
+	board := make([][]string, cells)
+	for y := range board {
+		board[y] = make([]string, cells)
+	}
+	board[int(s.e.Food.Y)][int(s.e.Food.X)] = colorFood
+	for i, p := range s.e.Snake.Parts {
+		if engine.CollidesWithWall(p, cells) {
+			continue
+		}
+		board[int(p.Y)][int(p.X)] = colorSnake
+		if i == 0 {
+			board[int(p.Y)][int(p.X)] = colorHead
+		}
+	}
+	for y := -1; y <= cells; y++ {
+		current := ""
+		setColor := func(color string) {
+			if color != current {
+				b.WriteString(reset + color)
+				current = color
+			}
+		}
+		for x := -1; x <= cells; x++ {
+			if x < 0 || y < 0 || x == cells || y == cells {
+				setColor(colorWall)
+			} else {
+				setColor(board[y][x])
+			}
+			b.WriteString("  ")
+		}
+		b.WriteString(reset + "  ")
+		if y+1 < len(panel) {
+			b.WriteString(panel[y+1])
+		}
+		b.WriteString(clearLine + "\r\n")
+	}
+	b.WriteString(reset + colorDim + s.status() + reset + clearLine + clearBelow)
+	_, err := io.WriteString(s.t, b.String())
+	return err
+}
+
+// status returns the line under the board: the state of the game and the keys.
+func (s *session) status() string {
+	switch {
+	case s.e.GameOver && s.place > 0:
+		return fmt.Sprintf("Game over: #%d in the high scores! Enter: play again, Q: quit", s.place)
+	case s.e.GameOver:
+		return "Game over. Enter: play again, Q: quit"
+	case s.paused:
+		return "Paused. P: resume, Q: quit"
+	default:
+		return "Arrows/WASD: turn, P: pause, Q: quit"
+	}
+}
+
+// panel returns the lines of the side panel: the score of the game and the high score table.
+func (s *session) panel() []string {
+	lines := []string{
+		colorTitle + "SNAKE" + reset,
+		"",
+		"Player: " + s.name,
+		fmt.Sprintf("Score:  %d", s.e.Score),
+		fmt.Sprintf("Length: %d", s.e.Snake.Len()),
+		"",
+		colorTitle + "High scores" + reset,
+	}
+	top := s.scores.Top()
+	if len(top) == 0 {
+		lines = append(lines, colorDim+"No scores yet"+reset)
+	}
+	for i, e := range top {
+		lines = append(lines, fmt.Sprintf("%2d. %-16s %6d", i+1, e.Name, e.Score))
+	}
+	return lines
+}
@@ -0,0 +1,170 @@
+// Package update checks whether a newer release of the Snake game has been published on GitHub.
+//
+// The check is designed to be silent and safe offline: it uses a short timeout, and the result is cached
+// in the data directory, so GitHub is queried at most once a day.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	latestURL = "https://api.github.com/repos/DenisKhanov/Snake/releases/latest"
+	cacheFile = "update.json"  // name of the cache file in the data directory
+	cacheTTL  = 24 * time.Hour // how long a cached result is used before GitHub is queried again
+	timeout   = 5 * time.Second
+)
+
+// Release describes a published release of the game.
+// Fields:
+// - Version: the tag of the release, e.g. "v1.2.0".
+// - URL: the address of the release page.
+type Release struct {
+	Version string `json:"tag_name"`
+	URL     string `json:"html_url"`
+}
+
+// cache is the content of the cache file.
+type cache struct {
+	Checked time.Time `json:"checked"`
+	Latest  Release   `json:"latest"`
+}
+
+// Check finds the latest release and reports whether it is newer than the current version.
+//
+// A cached result younger than a day is used without querying GitHub. Development builds,
+// whose version isn't a release tag, are never reported as outdated.
+//
+// Parameters:
+// - ctx (context.Context): The context for the request.
+// - current (string): The version of the running game.
+// - dataDir (string): The data directory the result is cached in.
+//
+// Returns:
+// - *Release: The latest release if it's newer than the current version, otherwise nil.
+// - error: An error if the latest release cannot be determined.
+func Check(ctx context.Context, current, dataDir string) (*Release, error) {
+	if _, ok := parse(current); !ok {
+		return nil, nil
+	}
+	path := filepath.Join(dataDir, cacheFile)
+	latest, err := readCache(path)
+	if err != nil {
+		if latest, err = fetch(ctx); err != nil {
+			return nil, err
+		}
+		writeCache(path, latest)
+	}
+	if !Newer(latest.Version, current) {
+		return nil, nil
+	}
+	return &latest, nil
+}
+
+// fetch queries the GitHub releases API for the latest release.
+func fetch(ctx context.Context) (Release, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
+	if err != nil {
+		return Release{}, fmt.Errorf("error creating update request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("error checking for updates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("error checking for updates: %s", resp.Status)
+	}
+	var rel Release
+	if err = json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return Release{}, fmt.Errorf("error decoding release: %w", err)
+	}
+	return rel, nil
+}
+
+// readCache returns the cached latest release, or an error if there is no fresh cached result.
+func readCache(path string) (Release, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Release{}, err
+	}
+	var c cache
+	if err = json.Unmarshal(data, &c); err != nil {
+		return Release{}, err
+	}
+	if time.Since(c.Checked) > cacheTTL || c.Checked.After(time.Now()) {
+		return Release{}, errors.New("cached update check has expired")
+	}
+	return c.Latest, nil
+}
+
+// writeCache stores the latest release in the cache file. Failures are ignored:
+// without the cache the check is simply repeated on the next launch.
+func writeCache(path string, latest Release) {
+	data, err := json.Marshal(cache{Checked: time.Now(), Latest: latest})
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		_ = os.WriteFile(path, data, 0644)
+	}
+}
+
+// Newer reports whether version a is newer than version b.
+// Versions are compared as "vMAJOR.MINOR.PATCH"; anything after a "-" or "+" is ignored.
+// A version that cannot be parsed is never newer.
+//
+// Parameters:
+// - a, b (string): The versions to compare.
+//
+// Returns:
+// - bool: True if a is newer than b.
+func Newer(a, b string) bool {
+	va, ok := parse(a)
+	if !ok {
+		return false
+	}
+	vb, ok := parse(b)
+	if !ok {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parse splits the version into its major, minor and patch numbers.
+func parse(v string) ([3]int, bool) {
+	var n [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return n, false
+	}
+	for i, p := range parts {
+		num, err := strconv.Atoi(p)
+		if err != nil || num < 0 {
+			return n, false
+		}
+		n[i] = num
+	}
+	return n, true
+}
@@ -0,0 +1,91 @@
+// Package version provides the version information embedded into the Snake game executable.
+//
+// Release builds set the variables of this package with the linker:
+//
+//	go build -ldflags "-X github.com/DenisKhanov/Snake/version.Version=v1.1.0 \
+//	  -X github.com/DenisKhanov/Snake/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/DenisKhanov/Snake/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// For other builds the values are taken from the build information recorded by the Go toolchain.
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+var (
+	Version = "" // the release version, e.g. "v1.1.0"
+	Commit  = "" // the hash of the commit the executable was built from
+	Date    = "" // the build date in RFC 3339 format
+)
+
+// Info describes the build of the running executable.
+// Fields:
+// - Version: the release version, "dev" for development builds.
+// - Commit: the hash of the commit, empty if unknown.
+// - Date: the build date (or the commit date), empty if unknown.
+// - Modified: whether the executable was built from a working tree with uncommitted changes.
+type Info struct {
+	Version  string
+	Commit   string
+	Date     string
+	Modified bool
+}
+
+// Get returns the version information of the running executable.
+//
+// The values set with the linker take precedence over the build information recorded by the Go toolchain.
+//
+// Returns:
+// - Info: The version information.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// ShortCommit returns the first seven characters of the commit hash.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// String formats the version information for humans, e.g. "v1.1.0 (3df50f6, 2026-10-01T12:00:00Z)".
+func (i Info) String() string {
+	s := i.Version
+	if i.Commit == "" {
+		return s
+	}
+	commit := i.ShortCommit()
+	if i.Modified {
+		commit += "-dirty"
+	}
+	if i.Date == "" {
+		return fmt.Sprintf("%s (%s)", s, commit)
+	}
+	return fmt.Sprintf("%s (%s, %s)", s, commit, i.Date)
+}
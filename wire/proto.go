@@ -0,0 +1,250 @@
+// Package wire defines the stable, versioned encoding of the state of a game and of its changes from tick to tick,
+// shared by the save slots, the servers and the clients, in JSON and in protobuf (see state.proto in this directory).
+//
+// The encoding only ever grows, so data written by any version can be read by any other:
+//   - fields are added, but never removed, renamed, renumbered or given a new meaning;
+//   - the readers skip the fields they don't know, so older readers can read the data of newer writers;
+//   - missing fields keep their zero values, so newer readers can read the data of older writers;
+//   - every message carries the version of the writer, bumped whenever fields are added, so a reader can tell
+//     that it may have skipped some; data written before the encoding was versioned has version 0.
+package wire
+
+import (
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/internal/protowire"
+)
+
+// ErrMalformed is returned when the data isn't a valid protobuf message.
+var ErrMalformed = protowire.ErrMalformed
+
+// MarshalState encodes the state as the State message of state.proto, marked with the current version.
+//
+// Parameters:
+// - s (engine.State): The state of the game.
+//
+// Returns:
+// - []byte: The message.
+func MarshalState(s engine.State) []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, Version)
+	b = protowire.AppendVarintField(b, 2, uint64(s.Seed))
+	b = protowire.AppendVarintField(b, 3, uint64(s.Draws))
+	b = protowire.AppendVarintField(b, 4, uint64(s.Cells))
+	for _, p := range s.Snake {
+		b = protowire.AppendBytesField(b, 5, marshalPoint(p))
+	}
+	b = protowire.AppendVarintField(b, 6, uint64(s.Size))
+	b = protowire.AppendVarintField(b, 7, uint64(s.Direction))
+	b = protowire.AppendBytesField(b, 8, marshalPoint(s.Food))
+	b = protowire.AppendVarintField(b, 9, uint64(s.Score))
+	b = protowire.AppendVarintField(b, 10, uint64(s.AteFood))
+	b = protowire.AppendVarintField(b, 11, uint64(s.Speed))
+	b = protowire.AppendVarintField(b, 12, uint64(s.Tick))
+	b = protowire.AppendVarintField(b, 13, uint64(s.Elapsed))
+	b = protowire.AppendBoolField(b, 14, s.GameOver)
+	b = protowire.AppendBoolField(b, 15, s.Turned)
+	b = protowire.AppendBoolField(b, 16, s.Assist)
+	b = protowire.AppendBoolField(b, 17, s.Assisted)
+	for _, row := range s.Terrain {
+		b = protowire.AppendBytesField(b, 18, []byte(row))
+	}
+	b = protowire.AppendVarintField(b, 19, uint64(s.Stuck))
+	return protowire.AppendBoolField(b, 20, s.Wind)
+}
+
+// UnmarshalState decodes the State message of state.proto. The fields it doesn't know are skipped,
+// so it reads the states of newer versions too.
+//
+// Parameters:
+// - b ([]byte): The message.
+//
+// Returns:
+// - State: The state and the version it has been written with.
+// - error: ErrMalformed if the message can't be decoded.
+func UnmarshalState(b []byte) (State, error) {
+	var s State
+	err := protowire.EachField(b, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			s.Version = int(v)
+		case 2:
+			s.Seed = int64(v)
+		case 3:
+			s.Draws = int(int64(v))
+		case 4:
+			s.Cells = int(int32(v))
+		case 5:
+			p, err := unmarshalPoint(data)
+			if err != nil {
+				return err
+			}
+			s.Snake = append(s.Snake, p)
+		case 6:
+			s.Size = int(int32(v))
+		case 7:
+			s.Direction = engine.Dir(v)
+		case 8:
+			p, err := unmarshalPoint(data)
+			if err != nil {
+				return err
+			}
+			s.Food = p
+		case 9:
+			s.Score = int(int32(v))
+		case 10:
+			s.AteFood = int(int32(v))
+		case 11:
+			s.Speed = int(int32(v))
+		case 12:
+			s.Tick = int(int64(v))
+		case 13:
+			s.Elapsed = time.Duration(v)
+		case 14:
+			s.GameOver = v != 0
+		case 15:
+			s.Turned = v != 0
+		case 16:
+			s.Assist = v != 0
+		case 17:
+			s.Assisted = v != 0
+		case 18:
+			s.Terrain = append(s.Terrain, string(data))
+		case 19:
+			s.Stuck = int(int32(v))
+		case 20:
+			s.Wind = v != 0
+		}
+		return nil
+	})
+	return s, err
+}
+
+// MarshalDelta encodes the delta as the Delta message of state.proto, marked with the current version.
+//
+// Parameters:
+// - d (Delta): The delta.
+//
+// Returns:
+// - []byte: The message.
+func MarshalDelta(d Delta) []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, Version)
+	b = protowire.AppendVarintField(b, 2, uint64(d.Tick))
+	for _, p := range d.Heads {
+		b = protowire.AppendBytesField(b, 3, marshalPoint(p))
+	}
+	b = protowire.AppendVarintField(b, 4, uint64(d.Drop))
+	if d.Food != nil {
+		b = protowire.AppendBytesField(b, 5, marshalPoint(*d.Food))
+	}
+	b = protowire.AppendVarintField(b, 6, uint64(d.Draws))
+	b = protowire.AppendVarintField(b, 7, uint64(d.Size))
+	b = protowire.AppendVarintField(b, 8, uint64(d.Direction))
+	b = protowire.AppendVarintField(b, 9, uint64(d.Score))
+	b = protowire.AppendVarintField(b, 10, uint64(d.AteFood))
+	b = protowire.AppendVarintField(b, 11, uint64(d.Speed))
+	b = protowire.AppendVarintField(b, 12, uint64(d.Elapsed))
+	b = protowire.AppendBoolField(b, 13, d.GameOver)
+	b = protowire.AppendBoolField(b, 14, d.Turned)
+	b = protowire.AppendBoolField(b, 15, d.Assist)
+	b = protowire.AppendBoolField(b, 16, d.Assisted)
+	b = protowire.AppendVarintField(b, 17, uint64(d.Stuck))
+	return protowire.AppendBoolField(b, 18, d.Wind)
+}
+
+// UnmarshalDelta decodes the Delta message of state.proto. The fields it doesn't know are skipped,
+// so it reads the deltas of newer versions too.
+//
+// Parameters:
+// - b ([]byte): The message.
+//
+// Returns:
+// - Delta: The delta and the version it has been written with.
+// - error: ErrMalformed if the message can't be decoded.
+func UnmarshalDelta(b []byte) (Delta, error) {
+	var d Delta
+	err := protowire.EachField(b, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			d.Version = int(v)
+		case 2:
+			d.Tick = int(int64(v))
+		case 3:
+			p, err := unmarshalPoint(data)
+			if err != nil {
+				return err
+			}
+			d.Heads = append(d.Heads, p)
+		case 4:
+			d.Drop = int(int32(v))
+		case 5:
+			p, err := unmarshalPoint(data)
+			if err != nil {
+				return err
+			}
+			d.Food = &p
+		case 6:
+			d.Draws = int(int64(v))
+		case 7:
+			d.Size = int(int32(v))
+		case 8:
+			d.Direction = engine.Dir(v)
+		case 9:
+			d.Score = int(int32(v))
+		case 10:
+			d.AteFood = int(int32(v))
+		case 11:
+			d.Speed = int(int32(v))
+		case 12:
+			d.Elapsed = time.Duration(v)
+		case 13:
+			d.GameOver = v != 0
+		case 14:
+			d.Turned = v != 0
+		case 15:
+			d.Assist = v != 0
+		case 16:
+			d.Assisted = v != 0
+		case 17:
+			d.Stuck = int(int32(v))
+		case 18:
+			d.Wind = v != 0
+		}
+		return nil
+	})
+	return d, err
+}
+
+// marshalPoint encodes the point as the Point message of state.proto.
+func marshalPoint(p engine.Point) []byte {
+	var b []byte
+	b = protowire.AppendVarintField(b, 1, zigzag(int64(p.X)))
+	return protowire.AppendVarintField(b, 2, zigzag(int64(p.Y)))
+}
+
+// unmarshalPoint decodes the Point message of state.proto.
+func unmarshalPoint(b []byte) (engine.Point, error) {
+	var p engine.Point
+	err := protowire.EachField(b, func(num int, v uint64, _ []byte) error {
+		switch num {
+		case 1:
+			p.X = float64(int32(unzigzag(v)))
+		case 2:
+			p.Y = float64(int32(unzigzag(v)))
+		}
+		return nil
+	})
+	return p, err
+}
+
+// zigzag maps a signed integer to an unsigned one with a short varint for small magnitudes, as sint32 does.
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// unzigzag reverses zigzag.
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,214 @@
+// Package wire defines the stable, versioned encoding of the state of a game and of its changes from tick to tick,
+// shared by the save slots, the servers and the clients, in JSON and in protobuf (see state.proto in this directory).
+//
+// The encoding only ever grows, so data written by any version can be read by any other:
+//   - fields are added, but never removed, renamed, renumbered or given a new meaning;
+//   - the readers skip the fields they don't know, so older readers can read the data of newer writers;
+//   - missing fields keep their zero values, so newer readers can read the data of older writers;
+//   - every message carries the version of the writer, bumped whenever fields are added, so a reader can tell
+//     that it may have skipped some; data written before the encoding was versioned has version 0.
+package wire
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+)
+
+// Version is the version of the encoding written by this version of the game.
+const Version = 3
+
+// The media types of the encodings, e.g. for the Content-Type and Accept headers.
+const (
+	MediaTypeJSON     = "application/json"
+	MediaTypeProtobuf = "application/x-protobuf"
+)
+
+// ErrMismatch is returned when a delta is applied to a state it doesn't follow.
+var ErrMismatch = errors.New("delta doesn't follow the state")
+
+// State is the versioned encoding of the complete state of a game.
+//
+// The JSON encoding has the fields of engine.State and the version next to them, so the states saved before
+// the encoding was versioned are read as version 0. The direction is the one of the engine: 0 moves down
+// the screen (y grows), 1 right, 2 up and 3 left; (0, 0) is the top left cell.
+// Fields:
+// - Version: the version of the encoding of the writer.
+// - State: the state of the game.
+type State struct {
+	Version int `json:"version"`
+	engine.State
+}
+
+// NewState wraps the state of a game for encoding.
+//
+// Parameters:
+// - s (engine.State): The state, e.g. taken by engine.Engine.Snapshot.
+//
+// Returns:
+// - State: The state marked with the current version of the encoding.
+func NewState(s engine.State) State {
+	return State{Version: Version, State: s}
+}
+
+// Newer reports whether the state has been written by a newer version of the encoding, so the reader
+// may have skipped some of its fields.
+func (s State) Newer() bool {
+	return s.Version > Version
+}
+
+// Delta is the change of the state of a game during one or more ticks: the cells the head of the snake has moved to,
+// the number of cells the tail has left and the food if it has moved, plus the new values of the scalar fields,
+// which take a few bytes each. A delta of a tick is usually a few dozen bytes, whatever the length of the snake.
+// Fields:
+// - Version: the version of the encoding of the writer.
+// - Tick: the number of ticks played.
+// - Heads: the new segments at the head of the snake, the newest first; usually one per tick.
+// - Drop: the number of segments removed from the tail of the snake.
+// - Food: the new position of the food, or nil if it hasn't moved.
+// - Draws, Size, Direction, Score, AteFood, Speed, Elapsed, GameOver, Turned, Assist, Assisted, Stuck, Wind:
+// the new values of the fields of engine.State.
+type Delta struct {
+	Version   int            `json:"version"`
+	Tick      int            `json:"tick"`
+	Heads     []engine.Point `json:"heads,omitempty"`
+	Drop      int            `json:"drop,omitempty"`
+	Food      *engine.Point  `json:"food,omitempty"`
+	Draws     int            `json:"draws"`
+	Size      int            `json:"size"`
+	Direction engine.Dir     `json:"direction"`
+	Score     int            `json:"score"`
+	AteFood   int            `json:"ate_food"`
+	Speed     int            `json:"speed"`
+	Elapsed   time.Duration  `json:"elapsed,omitempty"`
+	GameOver  bool           `json:"game_over,omitempty"`
+	Turned    bool           `json:"turned,omitempty"`
+	Assist    bool           `json:"assist,omitempty"`
+	Assisted  bool           `json:"assisted,omitempty"`
+	Stuck     int            `json:"stuck,omitempty"`
+	Wind      bool           `json:"wind,omitempty"`
+}
+
+// Newer reports whether the delta has been written by a newer version of the encoding.
+func (d Delta) Newer() bool {
+	return d.Version > Version
+}
+
+// Diff returns the delta turning one state of a game into a later one.
+//
+// Parameters:
+// - prev (engine.State): The earlier state.
+// - next (engine.State): The later state.
+//
+// Returns:
+// - Delta: The change.
+// - bool: False if the states are of different games (another seed, board or terrain), which a delta can't describe.
+// The terrain never changes during a game, so the deltas don't carry it.
+func Diff(prev, next engine.State) (Delta, bool) {
+	if prev.Seed != next.Seed || prev.Cells != next.Cells || !slices.Equal(prev.Terrain, next.Terrain) {
+		return Delta{}, false
+	}
+	d := Delta{
+		Version:   Version,
+		Tick:      next.Tick,
+		Draws:     next.Draws,
+		Size:      next.Size,
+		Direction: next.Direction,
+		Score:     next.Score,
+		AteFood:   next.AteFood,
+		Speed:     next.Speed,
+		Elapsed:   next.Elapsed,
+		GameOver:  next.GameOver,
+		Turned:    next.Turned,
+		Assist:    next.Assist,
+		Assisted:  next.Assisted,
+		Stuck:     next.Stuck,
+		Wind:      next.Wind,
+	}
+	d.Heads, d.Drop = DiffSnake(prev.Snake, next.Snake)
+	if next.Food != prev.Food {
+		food := next.Food
+		d.Food = &food
+	}
+	return d, true
+}
+
+// Apply returns the state the delta turns the state into.
+//
+// Parameters:
+// - prev (engine.State): The state the delta has been taken from.
+//
+// Returns:
+// - engine.State: The new state; prev is left unchanged.
+// - error: ErrMismatch if the delta can't follow the state, e.g. it drops more segments than the snake has.
+func (d Delta) Apply(prev engine.State) (engine.State, error) {
+	snake, err := ApplySnake(prev.Snake, d.Heads, d.Drop)
+	if err != nil || d.Tick < prev.Tick {
+		return prev, ErrMismatch
+	}
+	next := prev
+	next.Snake = snake
+	if d.Food != nil {
+		next.Food = *d.Food
+	}
+	next.Tick = d.Tick
+	next.Draws = d.Draws
+	next.Size = d.Size
+	next.Direction = d.Direction
+	next.Score = d.Score
+	next.AteFood = d.AteFood
+	next.Speed = d.Speed
+	next.Elapsed = d.Elapsed
+	next.GameOver = d.GameOver
+	next.Turned = d.Turned
+	next.Assist = d.Assist
+	next.Assisted = d.Assisted
+	next.Stuck = d.Stuck
+	next.Wind = d.Wind
+	return next, nil
+}
+
+// DiffSnake describes how a snake has moved: the new segments at its head and the number of segments
+// its tail has left. A snake that has moved by a cell has a single new head; one that has eaten loses
+// no segments at the tail.
+//
+// Parameters:
+// - prev ([]P): The segments of the snake before, the head first.
+// - next ([]P): The segments of the snake after, the head first.
+//
+// Returns:
+// - []P: The new segments at the head, the newest first; all of next if it doesn't continue prev.
+// - int: The number of segments removed from the tail of prev.
+func DiffSnake[P comparable](prev, next []P) ([]P, int) {
+	//the segments of the snake behind the new heads are the front segments of the old snake
+	heads := len(next)
+	for k := range next {
+		kept := len(next) - k
+		if kept <= len(prev) && next[k] == prev[0] && slices.Equal(next[k:], prev[:kept]) {
+			heads = k
+			break
+		}
+	}
+	return slices.Clone(next[:heads]), len(prev) - (len(next) - heads)
+}
+
+// ApplySnake moves the snake as described by DiffSnake.
+//
+// Parameters:
+// - prev ([]P): The segments of the snake before, the head first.
+// - heads ([]P): The new segments at the head, the newest first.
+// - drop (int): The number of segments removed from the tail.
+//
+// Returns:
+// - []P: The segments of the snake after; prev is left unchanged.
+// - error: ErrMismatch if the snake doesn't have the segments to remove.
+func ApplySnake[P any](prev, heads []P, drop int) ([]P, error) {
+	if drop < 0 || drop > len(prev) {
+		return nil, ErrMismatch
+	}
+	next := make([]P, 0, len(heads)+len(prev)-drop)
+	next = append(next, heads...)
+	return append(next, prev[:len(prev)-drop]...), nil
+}
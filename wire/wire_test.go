@@ -0,0 +1,203 @@
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DenisKhanov/Snake/engine"
+	"github.com/DenisKhanov/Snake/internal/protowire"
+)
+
+// states are states of games covering every field of engine.State, each in its own case.
+var states = []struct {
+	name  string
+	state engine.State
+}{
+	{name: "new game", state: engine.State{Seed: 1, Cells: 20, Snake: []engine.Point{{X: 10, Y: 10}}, Size: 1, Speed: 150}},
+	{name: "in progress", state: engine.State{
+		Seed: -42, Draws: 7, Cells: 12, Snake: []engine.Point{{X: 3, Y: 4}, {X: 3, Y: 5}, {X: 2, Y: 5}}, Size: 3,
+		Direction: engine.Left, Food: engine.Point{X: 11, Y: 0}, Score: 300, AteFood: 2, Speed: 130, Tick: 95,
+		Elapsed: 12*time.Second + 350*time.Millisecond, Turned: true,
+	}},
+	{name: "over with assist", state: engine.State{
+		Seed: 5, Draws: 1, Cells: 10, Snake: []engine.Point{{X: 0, Y: 9}}, Size: 1, Direction: engine.Up,
+		Tick: 8, GameOver: true, Assist: true, Assisted: true,
+	}},
+	{name: "terrain", state: engine.State{
+		Seed: 9, Cells: 6, Snake: []engine.Point{{X: 1, Y: 1}}, Size: 1,
+		Terrain: engine.Terrain{"..~~", "", "%.!"}, Stuck: 1,
+	}},
+	{name: "wind", state: engine.State{Seed: 2, Cells: 8, Snake: []engine.Point{{X: 4, Y: 4}}, Size: 1, Tick: 39, Wind: true}},
+}
+
+func TestStateProtobuf(t *testing.T) {
+	for _, tt := range states {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalState(MarshalState(tt.state))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := NewState(tt.state); !reflect.DeepEqual(got, want) {
+				t.Errorf("UnmarshalState(MarshalState()) = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	for _, tt := range states {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewState(tt.state))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got State
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if want := NewState(tt.state); !reflect.DeepEqual(got, want) {
+				t.Errorf("JSON round trip = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalState(t *testing.T) {
+	s := states[1].state
+	newer := MarshalState(s)
+	newer = protowire.AppendVarintField(newer, 1000, 5)
+	newer = protowire.AppendBytesField(newer, 1001, []byte("a field of a newer version"))
+	newer = protowire.AppendVarint(newer, 1002<<3|protowire.Fixed64)
+	newer = append(newer, 1, 2, 3, 4, 5, 6, 7, 8)
+	tests := []struct {
+		name string
+		data []byte
+		want State
+		err  error
+	}{
+		{name: "empty", data: nil, want: State{}},
+		{name: "unknown fields skipped", data: newer, want: NewState(s)},
+		{name: "version only", data: protowire.AppendVarintField(nil, 1, Version+1), want: State{Version: Version + 1}},
+		{name: "truncated", data: MarshalState(s)[:10], err: ErrMalformed},
+		{name: "field number 0", data: []byte{0x00, 0x01}, err: ErrMalformed},
+		{name: "bad length", data: []byte{5<<3 | protowire.Bytes, 0x7f, 0x08}, err: ErrMalformed},
+		{name: "bad point", data: protowire.AppendBytesField(nil, 8, []byte{0x08}), err: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalState(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UnmarshalState() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalState() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got, _ := UnmarshalState(protowire.AppendVarintField(nil, 1, Version+1)); !got.Newer() {
+		t.Error("a state of a newer version isn't reported as newer")
+	}
+}
+
+func TestDiffApply(t *testing.T) {
+	head := []engine.Point{{X: 5, Y: 5}, {X: 5, Y: 6}, {X: 5, Y: 7}}
+	prev := engine.State{
+		Seed: 3, Draws: 2, Cells: 10, Snake: head, Size: 3, Food: engine.Point{X: 1, Y: 1}, Speed: 150, Tick: 10,
+		Terrain: engine.Terrain{"", "", "", "", "", "....%"}, Wind: true,
+	}
+	moved := prev
+	moved.Snake = []engine.Point{{X: 5, Y: 4}, {X: 5, Y: 5}, {X: 5, Y: 6}}
+	moved.Tick, moved.Elapsed = 11, 150*time.Millisecond
+	ate := moved
+	ate.Snake = []engine.Point{{X: 4, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 6}, {X: 5, Y: 7}}
+	ate.Size, ate.Direction, ate.Food, ate.Draws, ate.Score, ate.AteFood = 4, engine.Left, engine.Point{X: 8, Y: 2}, 3, 100, 1
+	ate.Turned, ate.Assist, ate.Assisted, ate.Speed = true, true, true, 145
+	mud := prev
+	mud.Snake = []engine.Point{{X: 4, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 6}}
+	mud.Tick, mud.Stuck, mud.Direction = 11, 1, engine.Left
+	over := moved
+	over.Tick, over.GameOver = 12, true
+	calm := moved
+	calm.Wind = false
+	tests := []struct {
+		name  string
+		prev  engine.State
+		next  engine.State
+		heads int // the expected number of new segments at the head
+		food  bool
+	}{
+		{name: "same state", prev: prev, next: prev},
+		{name: "move", prev: prev, next: moved, heads: 1},
+		{name: "eat and turn", prev: prev, next: ate, heads: 1, food: true},
+		{name: "stuck in mud", prev: prev, next: mud, heads: 1},
+		{name: "several ticks", prev: prev, next: over, heads: 1},
+		{name: "wind stops", prev: prev, next: calm, heads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := Diff(tt.prev, tt.next)
+			if !ok {
+				t.Fatal("Diff() can't describe the change")
+			}
+			if len(d.Heads) != tt.heads || (d.Food != nil) != tt.food {
+				t.Errorf("Diff() has %d heads and food %v, want %d heads and food %v", len(d.Heads), d.Food, tt.heads, tt.food)
+			}
+			// the delta is sent as protobuf, so it's applied after a round trip
+			decoded, err := UnmarshalDelta(MarshalDelta(d))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decoded.Apply(tt.prev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.next) {
+				t.Errorf("Apply() = %+v, want %+v", got, tt.next)
+			}
+		})
+	}
+}
+
+func TestDiffOtherGame(t *testing.T) {
+	prev := engine.State{Seed: 3, Cells: 10, Snake: []engine.Point{{X: 5, Y: 5}}, Terrain: engine.Terrain{"~"}}
+	tests := []struct {
+		name   string
+		change func(s *engine.State)
+	}{
+		{name: "seed", change: func(s *engine.State) { s.Seed++ }},
+		{name: "board", change: func(s *engine.State) { s.Cells = 12 }},
+		{name: "terrain", change: func(s *engine.State) { s.Terrain = engine.Terrain{"%"} }},
+		{name: "no terrain", change: func(s *engine.State) { s.Terrain = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := prev
+			tt.change(&next)
+			if _, ok := Diff(prev, next); ok {
+				t.Error("Diff() describes a change to another game")
+			}
+		})
+	}
+}
+
+func TestApplyMismatch(t *testing.T) {
+	prev := engine.State{Seed: 3, Cells: 10, Snake: []engine.Point{{X: 5, Y: 5}, {X: 5, Y: 6}}, Tick: 10}
+	tests := []struct {
+		name  string
+		delta Delta
+	}{
+		{name: "drops more than the snake has", delta: Delta{Tick: 11, Drop: 3}},
+		{name: "negative drop", delta: Delta{Tick: 11, Drop: -1}},
+		{name: "earlier tick", delta: Delta{Tick: 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.delta.Apply(prev); !errors.Is(err, ErrMismatch) {
+				t.Errorf("Apply() error = %v, want ErrMismatch", err)
+			}
+		})
+	}
+}